@@ -0,0 +1,83 @@
+package apikey
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/model"
+	"net/http"
+)
+
+type CreateRequest struct {
+	Name   string        `json:"name" binding:"required"`
+	Scopes []model.Scope `json:"scopes" binding:"required,min=1"`
+}
+
+// CreateResponse is the created key together with its plaintext, which is only ever returned here.
+type CreateResponse struct {
+	*model.APIKey
+	Key string `json:"key"`
+}
+
+type Controller interface {
+	CreateAPIKey(ctx *gin.Context)
+	ListAPIKeys(ctx *gin.Context)
+	RevokeAPIKey(ctx *gin.Context)
+}
+
+type controller struct {
+	service Service
+}
+
+func NewController(service Service) Controller {
+	return &controller{service: service}
+}
+
+func (c *controller) CreateAPIKey(ctx *gin.Context) {
+	var request CreateRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	key, plaintext, err := c.service.CreateAPIKey(ctx.Request.Context(), request.Name, request.Scopes)
+	if err != nil {
+		var unknownScope model.ErrUnknownScope
+		if errors.As(err, &unknownScope) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusCreated, CreateResponse{APIKey: key, Key: plaintext})
+}
+
+func (c *controller) ListAPIKeys(ctx *gin.Context) {
+	keys, err := c.service.ListAPIKeys(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if keys == nil {
+		keys = []*model.APIKey{}
+	}
+	ctx.JSON(http.StatusOK, keys)
+}
+
+func (c *controller) RevokeAPIKey(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err = c.service.RevokeAPIKey(ctx.Request.Context(), id); err != nil {
+		var notFound model.ErrAPIKeyNotFound
+		if errors.As(err, &notFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{})
+}
@@ -0,0 +1,96 @@
+package apikey
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mock_apikey_service "github.com/ngereci/xm_interview/mocks/mock_apikey/service"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRouter(t *testing.T) (*mock_apikey_service.MockService, *gin.Engine) {
+	gin.SetMode(gin.TestMode)
+	service := mock_apikey_service.NewMockService(gomock.NewController(t))
+	controller := NewController(service)
+	router := gin.New()
+	router.POST("/api-keys", controller.CreateAPIKey)
+	router.GET("/api-keys", controller.ListAPIKeys)
+	router.DELETE("/api-keys/:id", controller.RevokeAPIKey)
+	return service, router
+}
+
+func serve(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return w
+}
+
+func TestController_CreateAPIKey(t *testing.T) {
+	service, router := newTestRouter(t)
+	key := &model.APIKey{
+		ID:        uuid.MustParse("56f86115-a58f-43db-8a1b-9aa2908f7a18"),
+		TenantID:  "tenant-a",
+		Name:      "batch",
+		Hash:      "secret hash",
+		Scopes:    []model.Scope{model.ScopeCompaniesRead},
+		CreatedAt: time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	service.EXPECT().CreateAPIKey(gomock.Any(), "batch", []model.Scope{model.ScopeCompaniesRead}).Return(key, "ck_plaintext", nil)
+
+	w := serve(router, http.MethodPost, "/api-keys", `{"name":"batch","scopes":["companies:read"]}`)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{
+		"id":"56f86115-a58f-43db-8a1b-9aa2908f7a18",
+		"tenant_id":"tenant-a",
+		"name":"batch",
+		"scopes":["companies:read"],
+		"created_at":"2023-05-01T12:00:00Z",
+		"key":"ck_plaintext"
+	}`, w.Body.String())
+}
+
+func TestController_CreateAPIKey_BadRequest(t *testing.T) {
+	service, router := newTestRouter(t)
+	service.EXPECT().CreateAPIKey(gomock.Any(), "batch", []model.Scope{"unknown"}).Return(nil, "", model.ErrUnknownScope{Scope: "unknown"})
+
+	assert.Equal(t, http.StatusBadRequest, serve(router, http.MethodPost, "/api-keys", `{"name":"batch","scopes":[]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(router, http.MethodPost, "/api-keys", `{"scopes":["admin"]}`).Code)
+	w := serve(router, http.MethodPost, "/api-keys", `{"name":"batch","scopes":["unknown"]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"unknown scope unknown"}`, w.Body.String())
+}
+
+func TestController_ListAPIKeys(t *testing.T) {
+	service, router := newTestRouter(t)
+	service.EXPECT().ListAPIKeys(gomock.Any()).Return(nil, nil)
+
+	w := serve(router, http.MethodGet, "/api-keys", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var keys []model.APIKey
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &keys))
+	assert.NotNil(t, keys)
+	assert.Empty(t, keys)
+}
+
+func TestController_RevokeAPIKey(t *testing.T) {
+	service, router := newTestRouter(t)
+	revoked, missing, failing := uuid.New(), uuid.New(), uuid.New()
+	service.EXPECT().RevokeAPIKey(gomock.Any(), revoked).Return(nil)
+	service.EXPECT().RevokeAPIKey(gomock.Any(), missing).Return(model.ErrAPIKeyNotFound{Id: missing})
+	service.EXPECT().RevokeAPIKey(gomock.Any(), failing).Return(errors.New("unavailable"))
+
+	assert.Equal(t, http.StatusOK, serve(router, http.MethodDelete, "/api-keys/"+revoked.String(), "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(router, http.MethodDelete, "/api-keys/"+missing.String(), "").Code)
+	assert.Equal(t, http.StatusInternalServerError, serve(router, http.MethodDelete, "/api-keys/"+failing.String(), "").Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, serve(router, http.MethodDelete, "/api-keys/invalid", "").Code)
+}
@@ -0,0 +1,171 @@
+package apikey
+
+import (
+	"context"
+	"github.com/gocql/gocql"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/ngereci/xm_interview/tenant"
+	"sort"
+	"time"
+)
+
+// Repository stores the keys of all tenants, the service restricts them to the tenant of the caller.
+type Repository interface {
+	Create(ctx context.Context, key *model.APIKey) error
+	// GetByID returns nil without error when the key does not exist
+	GetByID(ctx context.Context, id uuid.UUID) (*model.APIKey, error)
+	// List returns all keys, revoked ones included, oldest first
+	List(ctx context.Context) ([]*model.APIKey, error)
+	// Revoke returns model.ErrAPIKeyNotFound when the key does not exist
+	Revoke(ctx context.Context, id uuid.UUID, revokedAt time.Time) error
+	// Touch records that the key was used at usedAt
+	Touch(ctx context.Context, id uuid.UUID, usedAt time.Time) error
+}
+
+type apiKeyRepository struct {
+	session *gocql.Session
+}
+
+// NewRepository creates a Repository on the api_key table of the Cassandra keyspace.
+func NewRepository(session *gocql.Session) Repository {
+	return &apiKeyRepository{session: session}
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	err := r.session.Query(`
+		INSERT INTO api_key (id, tenant_id, name, hash, scopes, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, key.ID.String(), key.TenantID, key.Name, key.Hash, scopeStrings(key.Scopes), key.CreatedAt).WithContext(ctx).Exec()
+	if err != nil {
+		logging.FromContext(ctx).Errorf("api key:%v Create error:%v", key.ID, err)
+	}
+	return err
+}
+
+func (r *apiKeyRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.APIKey, error) {
+	scanner := r.session.Query(`
+		SELECT id, tenant_id, name, hash, scopes, created_at, last_used_at, revoked_at
+		FROM api_key
+		WHERE id = ?
+	`, id.String()).WithContext(ctx).Iter().Scanner()
+	keys, err := scanAPIKeys(scanner)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("api key:%v GetByID error:%v", id, err)
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return keys[0], nil
+}
+
+func (r *apiKeyRepository) List(ctx context.Context) ([]*model.APIKey, error) {
+	scanner := r.session.Query(`
+		SELECT id, tenant_id, name, hash, scopes, created_at, last_used_at, revoked_at
+		FROM api_key
+	`).WithContext(ctx).Iter().Scanner()
+	keys, err := scanAPIKeys(scanner)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("api key List error:%v", err)
+		return nil, err
+	}
+	sortAPIKeys(keys)
+	return keys, nil
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, id uuid.UUID, revokedAt time.Time) error {
+	applied, err := r.session.Query(`
+		UPDATE api_key
+		SET revoked_at = ?
+		WHERE id = ?
+		IF EXISTS
+	`, revokedAt, id.String()).WithContext(ctx).MapScanCAS(map[string]any{})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("api key:%v Revoke error:%v", id, err)
+		return err
+	}
+	if !applied {
+		return model.ErrAPIKeyNotFound{Id: id}
+	}
+	return nil
+}
+
+func (r *apiKeyRepository) Touch(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	err := r.session.Query(`
+		UPDATE api_key
+		SET last_used_at = ?
+		WHERE id = ?
+	`, usedAt, id.String()).WithContext(ctx).Exec()
+	if err != nil {
+		logging.FromContext(ctx).Errorf("api key:%v Touch error:%v", id, err)
+	}
+	return err
+}
+
+func scanAPIKeys(scanner gocql.Scanner) ([]*model.APIKey, error) {
+	var keys []*model.APIKey
+	for scanner.Next() {
+		var (
+			id         gocql.UUID
+			scopes     []string
+			lastUsedAt time.Time
+			revokedAt  time.Time
+			key        model.APIKey
+		)
+		if err := scanner.Scan(&id, &key.TenantID, &key.Name, &key.Hash, &scopes, &key.CreatedAt, &lastUsedAt, &revokedAt); err != nil {
+			return nil, err
+		}
+		key.ID = uuid.UUID(id)
+		key.TenantID = legacyTenant(key.TenantID)
+		key.Scopes = toScopes(scopes)
+		key.LastUsedAt = optionalTime(lastUsedAt)
+		key.RevokedAt = optionalTime(revokedAt)
+		keys = append(keys, &key)
+	}
+	return keys, scanner.Err()
+}
+
+func scopeStrings(scopes []model.Scope) []string {
+	values := make([]string, len(scopes))
+	for i, scope := range scopes {
+		values[i] = string(scope)
+	}
+	return values
+}
+
+func toScopes(values []string) []model.Scope {
+	scopes := make([]model.Scope, len(values))
+	for i, value := range values {
+		scopes[i] = model.Scope(value)
+	}
+	return scopes
+}
+
+// legacyTenant maps the missing tenant of keys created before tenants existed to the default tenant
+func legacyTenant(tenantID string) string {
+	if tenantID == "" {
+		return tenant.Default
+	}
+	return tenantID
+}
+
+// optionalTime maps the zero time of an unset column to nil
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+// sortAPIKeys orders keys oldest first, keys created at the same time by id
+func sortAPIKeys(keys []*model.APIKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID.String() < keys[j].ID.String()
+	})
+}
@@ -0,0 +1,145 @@
+package apikey
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/model"
+	bolt "go.etcd.io/bbolt"
+	"time"
+)
+
+var boltAPIKeyBucket = []byte("api_key")
+
+// boltAPIKey is the stored form of a key, model.APIKey does not marshal its hash.
+type boltAPIKey struct {
+	model.APIKey
+	Hash string `json:"hash"`
+}
+
+type boltAPIKeyRepository struct {
+	db *bolt.DB
+}
+
+// NewBoltRepository creates a Repository on an embedded bbolt database file, for single node and local use.
+func NewBoltRepository(db *bolt.DB) (Repository, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltAPIKeyBucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &boltAPIKeyRepository{db: db}, nil
+}
+
+func (r *boltAPIKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		return putBoltAPIKey(tx, key)
+	})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("api key:%v Create error:%v", key.ID, err)
+	}
+	return err
+}
+
+func (r *boltAPIKeyRepository) GetByID(ctx context.Context, id uuid.UUID) (key *model.APIKey, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	err = r.db.View(func(tx *bolt.Tx) error {
+		key, err = getBoltAPIKey(tx, id)
+		return err
+	})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("api key:%v GetByID error:%v", id, err)
+		return nil, err
+	}
+	return key, nil
+}
+
+func (r *boltAPIKeyRepository) List(ctx context.Context) ([]*model.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var keys []*model.APIKey
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltAPIKeyBucket).ForEach(func(_, value []byte) error {
+			key, err := unmarshalBoltAPIKey(value)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, key)
+			return nil
+		})
+	})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("api key List error:%v", err)
+		return nil, err
+	}
+	sortAPIKeys(keys)
+	return keys, nil
+}
+
+func (r *boltAPIKeyRepository) Revoke(ctx context.Context, id uuid.UUID, revokedAt time.Time) error {
+	return r.update(ctx, id, func(key *model.APIKey) {
+		key.RevokedAt = &revokedAt
+	})
+}
+
+func (r *boltAPIKeyRepository) Touch(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	return r.update(ctx, id, func(key *model.APIKey) {
+		key.LastUsedAt = &usedAt
+	})
+}
+
+func (r *boltAPIKeyRepository) update(ctx context.Context, id uuid.UUID, change func(key *model.APIKey)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		key, err := getBoltAPIKey(tx, id)
+		if err != nil {
+			return err
+		}
+		if key == nil {
+			return model.ErrAPIKeyNotFound{Id: id}
+		}
+		change(key)
+		return putBoltAPIKey(tx, key)
+	})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("api key:%v update error:%v", id, err)
+	}
+	return err
+}
+
+func getBoltAPIKey(tx *bolt.Tx, id uuid.UUID) (*model.APIKey, error) {
+	value := tx.Bucket(boltAPIKeyBucket).Get(id[:])
+	if value == nil {
+		return nil, nil
+	}
+	return unmarshalBoltAPIKey(value)
+}
+
+func putBoltAPIKey(tx *bolt.Tx, key *model.APIKey) error {
+	value, err := json.Marshal(boltAPIKey{APIKey: *key, Hash: key.Hash})
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(boltAPIKeyBucket).Put(key.ID[:], value)
+}
+
+func unmarshalBoltAPIKey(value []byte) (*model.APIKey, error) {
+	var stored boltAPIKey
+	if err := json.Unmarshal(value, &stored); err != nil {
+		return nil, err
+	}
+	stored.APIKey.Hash = stored.Hash
+	stored.APIKey.TenantID = legacyTenant(stored.APIKey.TenantID)
+	return &stored.APIKey, nil
+}
@@ -0,0 +1,24 @@
+package apikey_test
+
+import (
+	"github.com/ngereci/xm_interview/apikey"
+	"github.com/ngereci/xm_interview/apikey/apikeytest"
+	bolt "go.etcd.io/bbolt"
+	"path/filepath"
+	"testing"
+)
+
+func TestBoltRepository(t *testing.T) {
+	apikeytest.RunRepositorySuite(t, func(t *testing.T) apikey.Repository {
+		database, err := bolt.Open(filepath.Join(t.TempDir(), "companies.db"), 0o600, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { database.Close() })
+		repo, err := apikey.NewBoltRepository(database)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repo
+	})
+}
@@ -0,0 +1,88 @@
+package apikey
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/model"
+	"sync"
+	"time"
+)
+
+type memoryAPIKeyRepository struct {
+	mu   sync.RWMutex
+	keys map[uuid.UUID]model.APIKey
+}
+
+// NewMemoryRepository creates a Repository keeping the keys in memory, for tests and as a reference implementation.
+func NewMemoryRepository() Repository {
+	return &memoryAPIKeyRepository{keys: make(map[uuid.UUID]model.APIKey)}
+}
+
+func (r *memoryAPIKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[key.ID] = copyAPIKey(key)
+	return nil
+}
+
+func (r *memoryAPIKeyRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, nil
+	}
+	stored := copyAPIKey(&key)
+	return &stored, nil
+}
+
+func (r *memoryAPIKeyRepository) List(ctx context.Context) ([]*model.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	keys := make([]*model.APIKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		stored := copyAPIKey(&key)
+		keys = append(keys, &stored)
+	}
+	sortAPIKeys(keys)
+	return keys, nil
+}
+
+func (r *memoryAPIKeyRepository) Revoke(ctx context.Context, id uuid.UUID, revokedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, ok := r.keys[id]
+	if !ok {
+		return model.ErrAPIKeyNotFound{Id: id}
+	}
+	key.RevokedAt = &revokedAt
+	r.keys[id] = key
+	return nil
+}
+
+func (r *memoryAPIKeyRepository) Touch(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, ok := r.keys[id]
+	if !ok {
+		return model.ErrAPIKeyNotFound{Id: id}
+	}
+	key.LastUsedAt = &usedAt
+	r.keys[id] = key
+	return nil
+}
+
+// copyAPIKey copies the scopes and timestamps too, so that callers can not change the stored key
+func copyAPIKey(key *model.APIKey) model.APIKey {
+	stored := *key
+	stored.Scopes = append([]model.Scope(nil), key.Scopes...)
+	if key.LastUsedAt != nil {
+		lastUsedAt := *key.LastUsedAt
+		stored.LastUsedAt = &lastUsedAt
+	}
+	if key.RevokedAt != nil {
+		revokedAt := *key.RevokedAt
+		stored.RevokedAt = &revokedAt
+	}
+	return stored
+}
@@ -0,0 +1,13 @@
+package apikey_test
+
+import (
+	"github.com/ngereci/xm_interview/apikey"
+	"github.com/ngereci/xm_interview/apikey/apikeytest"
+	"testing"
+)
+
+func TestMemoryRepository(t *testing.T) {
+	apikeytest.RunRepositorySuite(t, func(t *testing.T) apikey.Repository {
+		return apikey.NewMemoryRepository()
+	})
+}
@@ -0,0 +1,129 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/model"
+	"strings"
+	"time"
+)
+
+type postgresAPIKeyRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository creates a Repository on the api_key table of the
+// Postgres database, see db/migrations/postgres for the schema.
+func NewPostgresRepository(db *sql.DB) Repository {
+	return &postgresAPIKeyRepository{db: db}
+}
+
+func (r *postgresAPIKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO api_key (id, tenant_id, name, hash, scopes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, key.ID, key.TenantID, key.Name, key.Hash, strings.Join(scopeStrings(key.Scopes), " "), key.CreatedAt)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("api key:%v Create error:%v", key.ID, err)
+	}
+	return err
+}
+
+func (r *postgresAPIKeyRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, tenant_id, name, hash, scopes, created_at, last_used_at, revoked_at
+		FROM api_key
+		WHERE id = $1
+	`, id)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("api key:%v GetByID error:%v", id, err)
+		return nil, err
+	}
+	keys, err := scanPostgresAPIKeys(rows)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("api key:%v GetByID error:%v", id, err)
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return keys[0], nil
+}
+
+func (r *postgresAPIKeyRepository) List(ctx context.Context) ([]*model.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, tenant_id, name, hash, scopes, created_at, last_used_at, revoked_at
+		FROM api_key
+		ORDER BY created_at, id
+	`)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("api key List error:%v", err)
+		return nil, err
+	}
+	keys, err := scanPostgresAPIKeys(rows)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("api key List error:%v", err)
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *postgresAPIKeyRepository) Revoke(ctx context.Context, id uuid.UUID, revokedAt time.Time) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE api_key
+		SET revoked_at = $1
+		WHERE id = $2
+	`, revokedAt, id)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("api key:%v Revoke error:%v", id, err)
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return model.ErrAPIKeyNotFound{Id: id}
+	}
+	return nil
+}
+
+func (r *postgresAPIKeyRepository) Touch(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE api_key
+		SET last_used_at = $1
+		WHERE id = $2
+	`, usedAt, id)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("api key:%v Touch error:%v", id, err)
+	}
+	return err
+}
+
+func scanPostgresAPIKeys(rows *sql.Rows) ([]*model.APIKey, error) {
+	defer rows.Close()
+	var keys []*model.APIKey
+	for rows.Next() {
+		var (
+			key        model.APIKey
+			scopes     string
+			lastUsedAt sql.NullTime
+			revokedAt  sql.NullTime
+		)
+		if err := rows.Scan(&key.ID, &key.TenantID, &key.Name, &key.Hash, &scopes, &key.CreatedAt, &lastUsedAt, &revokedAt); err != nil {
+			return nil, err
+		}
+		key.Scopes = toScopes(strings.Fields(scopes))
+		key.CreatedAt = key.CreatedAt.UTC()
+		if lastUsedAt.Valid {
+			key.LastUsedAt = optionalTime(lastUsedAt.Time)
+		}
+		if revokedAt.Valid {
+			key.RevokedAt = optionalTime(revokedAt.Time)
+		}
+		keys = append(keys, &key)
+	}
+	return keys, rows.Err()
+}
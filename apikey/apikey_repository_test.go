@@ -0,0 +1,76 @@
+//go:build integration
+// +build integration
+
+package apikey_test
+
+import (
+	"database/sql"
+	"github.com/gocql/gocql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/ngereci/xm_interview/apikey"
+	"github.com/ngereci/xm_interview/apikey/apikeytest"
+	"github.com/ngereci/xm_interview/db"
+	"github.com/ngereci/xm_interview/env"
+	"github.com/spf13/viper"
+	"testing"
+)
+
+func readTestConfig(t *testing.T) {
+	viper.SetConfigFile("../config/config_test.env")
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	viper.AutomaticEnv()
+}
+
+func TestCassandraRepository(t *testing.T) {
+	readTestConfig(t)
+	cluster := gocql.NewCluster(viper.GetString(env.COMPANY_CASSANDRA_HOST))
+	cluster.Keyspace = viper.GetString(env.COMPANY_CASSANDRA_KEYSPACE)
+	cluster.Consistency = gocql.Quorum
+	if err := db.CreateKeyspace(cluster, viper.GetInt(env.COMPANY_CASSANDRA_REPLICATION_FACTOR)); err != nil {
+		t.Fatal(err)
+	}
+	session, err := cluster.CreateSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+	migrator, err := db.NewCassandraMigrator(session, viper.GetDuration(env.COMPANY_CASSANDRA_MIGRATION_LOCK_TIMEOUT))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = migrator.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	apikeytest.RunRepositorySuite(t, func(t *testing.T) apikey.Repository {
+		if err := session.Query(`TRUNCATE api_key`).Exec(); err != nil {
+			t.Fatal(err)
+		}
+		return apikey.NewRepository(session)
+	})
+}
+
+func TestPostgresRepository(t *testing.T) {
+	readTestConfig(t)
+	database, err := sql.Open("pgx", viper.GetString(env.COMPANY_POSTGRES_DSN))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	migrator, err := db.NewPostgresMigrator(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = migrator.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	apikeytest.RunRepositorySuite(t, func(t *testing.T) apikey.Repository {
+		if _, err := database.Exec(`TRUNCATE api_key`); err != nil {
+			t.Fatal(err)
+		}
+		return apikey.NewPostgresRepository(database)
+	})
+}
@@ -0,0 +1,163 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/ngereci/xm_interview/tenant"
+	"strings"
+	"time"
+)
+
+const (
+	// keyPrefix starts every key, so that leaked keys are easy to recognize
+	keyPrefix = "ck"
+	// secretBytes is the entropy of a key, which is why a fast hash is good enough to store it
+	secretBytes = 32
+	// lastUsedResolution bounds how often the last use of a key is written
+	lastUsedResolution = time.Minute
+)
+
+// Service manages the keys of the tenant in the context of each call, see tenant.WithID.
+// Authenticate is the exception, it resolves keys of every tenant.
+type Service interface {
+	// CreateAPIKey returns the new key and its plaintext, which is not stored and can not be retrieved again.
+	CreateAPIKey(ctx context.Context, name string, scopes []model.Scope) (*model.APIKey, string, error)
+	ListAPIKeys(ctx context.Context) ([]*model.APIKey, error)
+	// RevokeAPIKey returns model.ErrAPIKeyNotFound for the keys of other tenants
+	RevokeAPIKey(ctx context.Context, id uuid.UUID) error
+	// Authenticate returns the key a plaintext belongs to, or model.ErrInvalidAPIKey.
+	Authenticate(ctx context.Context, plaintext string) (*model.APIKey, error)
+}
+
+type apiKeyService struct {
+	repo Repository
+	now  func() time.Time
+}
+
+func NewService(repo Repository) Service {
+	return &apiKeyService{repo: repo, now: time.Now}
+}
+
+func (s *apiKeyService) CreateAPIKey(ctx context.Context, name string, scopes []model.Scope) (*model.APIKey, string, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, scope := range scopes {
+		if !knownScope(scope) {
+			return nil, "", model.ErrUnknownScope{Scope: scope}
+		}
+	}
+	secret := make([]byte, secretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+	key := &model.APIKey{
+		ID:       uuid.New(),
+		TenantID: tenantID,
+		Name:     name,
+		Scopes:   scopes,
+		// Cassandra stores timestamps in milliseconds
+		CreatedAt: s.now().UTC().Truncate(time.Millisecond),
+	}
+	plaintext := strings.Join([]string{keyPrefix, hex.EncodeToString(key.ID[:]), base64.RawURLEncoding.EncodeToString(secret)}, "_")
+	key.Hash = hashKey(plaintext)
+	if err := s.repo.Create(ctx, key); err != nil {
+		return nil, "", err
+	}
+	logging.FromContext(ctx).Infof("api key:%v name:%v created with scopes:%v", key.ID, key.Name, key.Scopes)
+	return key, plaintext, nil
+}
+
+func (s *apiKeyService) ListAPIKeys(ctx context.Context) ([]*model.APIKey, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tenantKeys := keys[:0]
+	for _, key := range keys {
+		if key.TenantID == tenantID {
+			tenantKeys = append(tenantKeys, key)
+		}
+	}
+	return tenantKeys, nil
+}
+
+func (s *apiKeyService) RevokeAPIKey(ctx context.Context, id uuid.UUID) error {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	key, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if key == nil || key.TenantID != tenantID {
+		return model.ErrAPIKeyNotFound{Id: id}
+	}
+	if err = s.repo.Revoke(ctx, id, s.now().UTC().Truncate(time.Millisecond)); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Infof("api key:%v revoked", id)
+	return nil
+}
+
+func (s *apiKeyService) Authenticate(ctx context.Context, plaintext string) (*model.APIKey, error) {
+	id, ok := parseKeyID(plaintext)
+	if !ok {
+		return nil, model.ErrInvalidAPIKey
+	}
+	key, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil || key.RevokedAt != nil || subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hashKey(plaintext))) != 1 {
+		return nil, model.ErrInvalidAPIKey
+	}
+	now := s.now().UTC().Truncate(time.Millisecond)
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedResolution {
+		// a failed write only loses the timestamp, the request is authenticated anyway
+		if err = s.repo.Touch(ctx, key.ID, now); err == nil {
+			key.LastUsedAt = &now
+		}
+	}
+	return key, nil
+}
+
+// parseKeyID extracts the id of the key from a plaintext of the form ck_<id>_<secret>
+func parseKeyID(plaintext string) (uuid.UUID, bool) {
+	parts := strings.SplitN(plaintext, "_", 3)
+	if len(parts) != 3 || parts[0] != keyPrefix || parts[2] == "" {
+		return uuid.UUID{}, false
+	}
+	id, err := hex.DecodeString(parts[1])
+	if err != nil || len(id) != len(uuid.UUID{}) {
+		return uuid.UUID{}, false
+	}
+	return uuid.UUID(id), true
+}
+
+func hashKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
+
+func knownScope(scope model.Scope) bool {
+	for _, known := range model.Scopes {
+		if scope == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/ngereci/xm_interview/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestService() (*apiKeyService, *time.Time) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	service := &apiKeyService{repo: NewMemoryRepository(), now: func() time.Time { return now }}
+	return service, &now
+}
+
+func TestApiKeyService_CreateAndAuthenticate(t *testing.T) {
+	ctx := tenant.WithID(context.Background(), "tenant-a")
+	service, now := newTestService()
+
+	created, plaintext, err := service.CreateAPIKey(ctx, "batch", []model.Scope{model.ScopeCompaniesRead})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(plaintext, keyPrefix+"_"))
+	assert.NotContains(t, created.Hash, plaintext)
+	assert.Equal(t, *now, created.CreatedAt)
+
+	authenticated, err := service.Authenticate(ctx, plaintext)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, authenticated.ID)
+	assert.Equal(t, *now, *authenticated.LastUsedAt)
+
+	// the last use is only written once per resolution
+	*now = now.Add(lastUsedResolution / 2)
+	authenticated, err = service.Authenticate(ctx, plaintext)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-lastUsedResolution/2), *authenticated.LastUsedAt)
+	*now = now.Add(lastUsedResolution)
+	authenticated, err = service.Authenticate(ctx, plaintext)
+	require.NoError(t, err)
+	assert.Equal(t, *now, *authenticated.LastUsedAt)
+}
+
+func TestApiKeyService_Authenticate_Invalid(t *testing.T) {
+	ctx := tenant.WithID(context.Background(), "tenant-a")
+	service, _ := newTestService()
+	created, plaintext, err := service.CreateAPIKey(ctx, "batch", []model.Scope{model.ScopeCompaniesRead})
+	require.NoError(t, err)
+
+	tests := map[string]string{
+		"empty":          "",
+		"malformed":      "not-a-key",
+		"wrong prefix":   "xx" + strings.TrimPrefix(plaintext, keyPrefix),
+		"wrong secret":   plaintext[:len(plaintext)-4] + "AAAA",
+		"unknown id":     keyPrefix + "_" + strings.ReplaceAll(uuid.New().String(), "-", "") + "_secret",
+		"id not hex":     keyPrefix + "_zz_secret",
+		"missing secret": keyPrefix + "_" + strings.ReplaceAll(created.ID.String(), "-", "") + "_",
+	}
+	for name, key := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := service.Authenticate(ctx, key)
+			assert.Equal(t, model.ErrInvalidAPIKey, err)
+		})
+	}
+
+	t.Run("revoked", func(t *testing.T) {
+		require.NoError(t, service.RevokeAPIKey(ctx, created.ID))
+		_, err := service.Authenticate(ctx, plaintext)
+		assert.Equal(t, model.ErrInvalidAPIKey, err)
+	})
+}
+
+func TestApiKeyService_CreateAPIKey_UnknownScope(t *testing.T) {
+	service, _ := newTestService()
+
+	_, _, err := service.CreateAPIKey(tenant.WithID(context.Background(), "tenant-a"), "batch", []model.Scope{"companies:delete"})
+
+	assert.Equal(t, model.ErrUnknownScope{Scope: "companies:delete"}, err)
+}
+
+func TestApiKeyService_RevokeAPIKey_NotFound(t *testing.T) {
+	service, _ := newTestService()
+	id := uuid.New()
+
+	err := service.RevokeAPIKey(tenant.WithID(context.Background(), "tenant-a"), id)
+
+	var notFound model.ErrAPIKeyNotFound
+	assert.True(t, errors.As(err, &notFound))
+}
+
+func TestApiKeyService_TenantIsolation(t *testing.T) {
+	ctx := tenant.WithID(context.Background(), "tenant-a")
+	otherCtx := tenant.WithID(context.Background(), "tenant-b")
+	service, _ := newTestService()
+	created, plaintext, err := service.CreateAPIKey(ctx, "batch", []model.Scope{model.ScopeCompaniesRead})
+	require.NoError(t, err)
+	assert.Equal(t, "tenant-a", created.TenantID)
+	_, _, err = service.CreateAPIKey(otherCtx, "other", []model.Scope{model.ScopeCompaniesRead})
+	require.NoError(t, err)
+
+	keys, err := service.ListAPIKeys(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, created.ID, keys[0].ID)
+
+	var notFound model.ErrAPIKeyNotFound
+	assert.True(t, errors.As(service.RevokeAPIKey(otherCtx, created.ID), &notFound))
+	authenticated, err := service.Authenticate(otherCtx, plaintext)
+	require.NoError(t, err)
+	assert.Equal(t, "tenant-a", authenticated.TenantID)
+
+	_, _, err = service.CreateAPIKey(context.Background(), "batch", []model.Scope{model.ScopeCompaniesRead})
+	assert.Equal(t, tenant.ErrMissing, err)
+}
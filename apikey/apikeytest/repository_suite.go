@@ -0,0 +1,111 @@
+// Package apikeytest provides a conformance test suite for apikey.Repository implementations.
+package apikeytest
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/apikey"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// NewRepository returns an empty repository, it is called once per test.
+type NewRepository func(t *testing.T) apikey.Repository
+
+// RunRepositorySuite runs the contract every apikey.Repository implementation has to fulfil.
+func RunRepositorySuite(t *testing.T, newRepository NewRepository) {
+	t.Run("CreateAndGet", func(t *testing.T) { testCreateAndGet(t, newRepository(t)) })
+	t.Run("GetNotFound", func(t *testing.T) { testGetNotFound(t, newRepository(t)) })
+	t.Run("List", func(t *testing.T) { testList(t, newRepository(t)) })
+	t.Run("Revoke", func(t *testing.T) { testRevoke(t, newRepository(t)) })
+	t.Run("Touch", func(t *testing.T) { testTouch(t, newRepository(t)) })
+}
+
+// newAPIKey returns a key created at createdAt, timestamps are stored in milliseconds
+func newAPIKey(name string, createdAt time.Time) *model.APIKey {
+	return &model.APIKey{
+		ID:        uuid.New(),
+		TenantID:  "tenant-a",
+		Name:      name,
+		Hash:      "hash of " + name,
+		Scopes:    []model.Scope{model.ScopeCompaniesRead, model.ScopeCompaniesWrite},
+		CreatedAt: createdAt.UTC().Truncate(time.Millisecond),
+	}
+}
+
+func testCreateAndGet(t *testing.T, repo apikey.Repository) {
+	ctx := context.Background()
+	created := newAPIKey("create", time.Now())
+	require.NoError(t, repo.Create(ctx, created))
+
+	found, err := repo.GetByID(ctx, created.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, created.ID, found.ID)
+	assert.Equal(t, created.TenantID, found.TenantID)
+	assert.Equal(t, created.Name, found.Name)
+	assert.Equal(t, created.Hash, found.Hash)
+	assert.ElementsMatch(t, created.Scopes, found.Scopes)
+	assert.True(t, created.CreatedAt.Equal(found.CreatedAt))
+	assert.Nil(t, found.LastUsedAt)
+	assert.Nil(t, found.RevokedAt)
+}
+
+func testGetNotFound(t *testing.T, repo apikey.Repository) {
+	found, err := repo.GetByID(context.Background(), uuid.New())
+	require.NoError(t, err)
+	assert.Nil(t, found)
+}
+
+func testList(t *testing.T, repo apikey.Repository) {
+	ctx := context.Background()
+	now := time.Now()
+	second := newAPIKey("second", now)
+	first := newAPIKey("first", now.Add(-time.Hour))
+	third := newAPIKey("third", now.Add(time.Hour))
+	for _, key := range []*model.APIKey{second, first, third} {
+		require.NoError(t, repo.Create(ctx, key))
+	}
+	require.NoError(t, repo.Revoke(ctx, second.ID, now))
+
+	keys, err := repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 3)
+	assert.Equal(t, []uuid.UUID{first.ID, second.ID, third.ID}, []uuid.UUID{keys[0].ID, keys[1].ID, keys[2].ID})
+	assert.NotNil(t, keys[1].RevokedAt)
+}
+
+func testRevoke(t *testing.T, repo apikey.Repository) {
+	ctx := context.Background()
+	key := newAPIKey("revoke", time.Now())
+	require.NoError(t, repo.Create(ctx, key))
+	revokedAt := time.Now().UTC().Truncate(time.Millisecond)
+
+	require.NoError(t, repo.Revoke(ctx, key.ID, revokedAt))
+
+	found, err := repo.GetByID(ctx, key.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found.RevokedAt)
+	assert.True(t, revokedAt.Equal(*found.RevokedAt))
+
+	missing := uuid.New()
+	assert.Equal(t, model.ErrAPIKeyNotFound{Id: missing}, repo.Revoke(ctx, missing, revokedAt))
+}
+
+func testTouch(t *testing.T, repo apikey.Repository) {
+	ctx := context.Background()
+	key := newAPIKey("touch", time.Now())
+	require.NoError(t, repo.Create(ctx, key))
+	usedAt := time.Now().UTC().Truncate(time.Millisecond)
+
+	require.NoError(t, repo.Touch(ctx, key.ID, usedAt))
+
+	found, err := repo.GetByID(ctx, key.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found.LastUsedAt)
+	assert.True(t, usedAt.Equal(*found.LastUsedAt))
+	assert.Nil(t, found.RevokedAt)
+}
@@ -1,15 +1,18 @@
 package auth
 
 import (
-	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/ngereci/xm_interview/env"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/tenant"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"golang.org/x/crypto/bcrypt"
+	"math"
 	"math/rand"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -22,10 +25,18 @@ type LoginResponse struct {
 	Token string `json:"token"`
 }
 
-type Controller struct{}
+type Controller struct {
+	lockout *loginLockout
+}
 
+// NewAuthController creates the controller, repeated failed logins lock the username out as configured
+// by COMPANY_LOGIN_MAX_FAILURES, COMPANY_LOGIN_LOCKOUT and COMPANY_LOGIN_MAX_LOCKOUT.
 func NewAuthController() *Controller {
-	return &Controller{}
+	return &Controller{lockout: newLoginLockout(
+		viper.GetInt(env.COMPANY_LOGIN_MAX_FAILURES),
+		viper.GetDuration(env.COMPANY_LOGIN_LOCKOUT),
+		viper.GetDuration(env.COMPANY_LOGIN_MAX_LOCKOUT),
+	)}
 }
 
 // Login authenticates a user and returns a JWT token
@@ -36,12 +47,23 @@ func (a *Controller) Login(c *gin.Context) {
 		return
 	}
 
-	if !a.authenticate(request) {
+	if lockedFor := a.lockout.lockedFor(request.Username); lockedFor > 0 {
+		tooManyFailedLogins(c, lockedFor)
+		return
+	}
+	tenantID, ok := a.authenticate(request)
+	if !ok {
+		if lockedFor := a.lockout.failed(request.Username); lockedFor > 0 {
+			logging.FromContext(c.Request.Context()).Warnf("username:%v locked out for %v after failed logins", request.Username, lockedFor)
+			tooManyFailedLogins(c, lockedFor)
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
 		return
 	}
+	a.lockout.succeeded(request.Username)
 
-	tokenString, err := a.createToken(request.Username)
+	tokenString, err := a.createToken(request.Username, tenantID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
 		return
@@ -54,36 +76,44 @@ func (a *Controller) Login(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// CreateToken generates a JWT token for the given username
-func (a *Controller) createToken(username string) (string, error) {
+func tooManyFailedLogins(c *gin.Context, lockedFor time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockedFor.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed login attempts"})
+}
+
+// CreateToken generates a JWT token for the given username, the user acts for the tenant tenantID
+func (a *Controller) createToken(username string, tenantID string) (string, error) {
 	expiration := time.Duration(rand.Int31n(viper.GetInt32(env.COMPANY_JWT_EXPIRE_TIME))) * time.Second
 	claims := jwt.MapClaims{
-		"username": username,
-		"exp":      time.Now().Add(expiration).Unix(),
+		"username":   username,
+		tenant.Claim: tenantID,
+		"exp":        time.Now().Add(expiration).Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(viper.GetString(env.COMPANY_JWT_SECRET_KEY)))
 }
 
-func (a *Controller) authenticate(request LoginRequest) bool {
+// authenticate returns the tenant of the user if the password is valid
+func (a *Controller) authenticate(request LoginRequest) (string, bool) {
 	// TODO
 	// In a real application, the username and password would be validated
-	// against a database of users. For simplicity, we will just use a hardcoded
-	// username and password.
+	// against a database of users, which also knows their tenants. For simplicity,
+	// we will just use a hardcoded username and password of the default tenant.
 	const (
 		validUsername = "admin"
 		validPassword = "admin"
 	)
 	if request.Username != validUsername {
-		return false
+		return "", false
 	}
 	validPasswordHash, err := bcrypt.GenerateFromPassword([]byte(validPassword), 0)
 	if err != nil {
 		log.Errorf("unable to generate password hash, error: %v", err)
-		return false
+		return "", false
+	}
+	if bcrypt.CompareHashAndPassword(validPasswordHash, []byte(request.Password)) != nil {
+		return "", false
 	}
-	err = bcrypt.CompareHashAndPassword(validPasswordHash, []byte(request.Password))
-	fmt.Print(err)
-	return err == nil
+	return tenant.Default, true
 }
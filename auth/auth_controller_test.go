@@ -2,7 +2,9 @@ package auth
 
 import (
 	"encoding/json"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/ngereci/xm_interview/env"
+	"github.com/ngereci/xm_interview/tenant"
 	"github.com/spf13/viper"
 	"net/http"
 	"net/http/httptest"
@@ -53,6 +55,15 @@ func TestController_Login(t *testing.T) {
 	bodyString := w.Body.String()
 	assert.True(t, strings.Contains(bodyString, expectedResponseBodyPart))
 
+	// the admin user acts for the default tenant
+	var response LoginResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(response.Token, claims, func(*jwt.Token) (interface{}, error) {
+		return []byte("test-key"), nil
+	}, jwt.WithoutClaimsValidation())
+	assert.NoError(t, err)
+	assert.Equal(t, tenant.Default, claims[tenant.Claim])
 }
 
 func TestController_Login_InvalidRequest(t *testing.T) {
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/ngereci/xm_interview/model"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryInterceptor authenticates gRPC calls like Authenticate does HTTP requests, with the token of the authorization
+// metadata or the API key of the x-api-key metadata. An API key needs the scope scopes maps the full method name of
+// the call to, API keys can not call methods missing from scopes. Users with a token have every scope.
+func (a *AuthMiddleware) UnaryInterceptor(scopes map[string]model.Scope) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if plaintext := firstValue(md, APIKeyHeader); plaintext != "" && a.apiKeys != nil {
+			key, err := a.apiKeys.Authenticate(ctx, plaintext)
+			if err != nil {
+				if errors.Is(err, model.ErrInvalidAPIKey) {
+					return nil, status.Error(codes.Unauthenticated, "Invalid API key")
+				}
+				return nil, status.Error(codes.Internal, "Failed to verify API key")
+			}
+			scope, ok := scopes[info.FullMethod]
+			if !ok {
+				return nil, status.Errorf(codes.PermissionDenied, "API keys can not call %v", info.FullMethod)
+			}
+			if !key.HasScope(scope) {
+				return nil, status.Error(codes.PermissionDenied, ErrMissingScope{Scope: scope}.Error())
+			}
+			return handler(authenticatedContext(WithAPIKey(ctx, key), "apikey:"+key.ID.String(), key.TenantID), req)
+		}
+
+		claims, tenantID, err := a.verifyToken(firstValue(md, "Authorization"))
+		if err != nil {
+			if errors.Is(err, errTokenClaims) {
+				return nil, status.Error(codes.Internal, err.Error())
+			}
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return handler(authenticatedContext(ctx, claims["username"], tenantID), req)
+	}
+}
+
+// firstValue returns the first value of the metadata key
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/ngereci/xm_interview/tenant"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+const (
+	readMethod  = "/company.v1.CompanyService/GetCompany"
+	writeMethod = "/company.v1.CompanyService/CreateCompany"
+)
+
+func TestAuthMiddleware_UnaryInterceptor(t *testing.T) {
+	secretKey := "secret"
+	key := &model.APIKey{ID: uuid.MustParse("56f86115-a58f-43db-8a1b-9aa2908f7a18"), TenantID: "tenant-b", Name: "batch", Scopes: []model.Scope{model.ScopeCompaniesRead}}
+	middleware := NewAuthMiddleware(secretKey, apiKeyAuthenticatorFunc(func(ctx context.Context, plaintext string) (*model.APIKey, error) {
+		switch plaintext {
+		case "valid":
+			return key, nil
+		case "failing":
+			return nil, errors.New("unavailable")
+		default:
+			return nil, model.ErrInvalidAPIKey
+		}
+	}))
+	interceptor := middleware.UnaryInterceptor(map[string]model.Scope{
+		readMethod:  model.ScopeCompaniesRead,
+		writeMethod: model.ScopeCompaniesWrite,
+	})
+	signWith := func(secretKey string, claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secretKey))
+		assert.NoError(t, err)
+		return token
+	}
+	sign := func(claims jwt.MapClaims) string {
+		return signWith(secretKey, claims)
+	}
+	// handler answers with the tenant the call acts for
+	handler := func(ctx context.Context, req any) (any, error) {
+		return tenant.FromContext(ctx)
+	}
+
+	tests := []struct {
+		name     string
+		md       metadata.MD
+		method   string
+		code     codes.Code
+		tenantID string
+	}{
+		{name: "token", md: metadata.Pairs("authorization", "Bearer "+sign(jwt.MapClaims{"username": "admin", tenant.Claim: "tenant-a"})), method: writeMethod, tenantID: "tenant-a"},
+		{name: "token without tenant", md: metadata.Pairs("authorization", "Bearer "+sign(jwt.MapClaims{"username": "admin"})), method: readMethod, code: codes.Unauthenticated},
+		{name: "token of another secret", md: metadata.Pairs("authorization", "Bearer "+signWith("other", jwt.MapClaims{tenant.Claim: "tenant-a"})), method: readMethod, code: codes.Unauthenticated},
+		{name: "malformed token", md: metadata.Pairs("authorization", "Bearer not-a-token"), method: readMethod, code: codes.Unauthenticated},
+		{name: "no credentials", md: metadata.MD{}, method: readMethod, code: codes.Unauthenticated},
+		{name: "api key", md: metadata.Pairs("x-api-key", "valid"), method: readMethod, tenantID: "tenant-b"},
+		{name: "api key lacking scope", md: metadata.Pairs("x-api-key", "valid"), method: writeMethod, code: codes.PermissionDenied},
+		{name: "api key on unknown method", md: metadata.Pairs("x-api-key", "valid"), method: "/company.v1.CompanyService/Unknown", code: codes.PermissionDenied},
+		{name: "revoked api key", md: metadata.Pairs("x-api-key", "revoked"), method: readMethod, code: codes.Unauthenticated},
+		{name: "failing api key check", md: metadata.Pairs("x-api-key", "failing"), method: readMethod, code: codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+
+			tenantID, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+
+			assert.Equal(t, tt.code, status.Code(err), err)
+			if tt.code == codes.OK {
+				assert.Equal(t, tt.tenantID, tenantID)
+			}
+		})
+	}
+}
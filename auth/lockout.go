@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+type loginAttempts struct {
+	failures    int
+	lockouts    int
+	lockedUntil time.Time
+	lastFailure time.Time
+}
+
+// loginLockout locks a username out after maxFailures consecutive failed logins. Every further lockout
+// doubles the lockout duration up to maxLockout, a successful login starts over.
+type loginLockout struct {
+	mu          sync.Mutex
+	maxFailures int
+	lockout     time.Duration
+	maxLockout  time.Duration
+	attempts    map[string]*loginAttempts
+	lastSweep   time.Time
+	now         func() time.Time
+}
+
+func newLoginLockout(maxFailures int, lockout, maxLockout time.Duration) *loginLockout {
+	if maxLockout < lockout {
+		maxLockout = lockout
+	}
+	return &loginLockout{
+		maxFailures: maxFailures,
+		lockout:     lockout,
+		maxLockout:  maxLockout,
+		attempts:    make(map[string]*loginAttempts),
+		now:         time.Now,
+	}
+}
+
+// lockedFor returns how long username is still locked out, zero when it may log in.
+func (l *loginLockout) lockedFor(username string) time.Duration {
+	if l.maxFailures <= 0 {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	attempts, ok := l.attempts[username]
+	if !ok {
+		return 0
+	}
+	if remaining := attempts.lockedUntil.Sub(l.now()); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// failed records a failed login of username and returns the lockout it caused, zero if none.
+func (l *loginLockout) failed(username string) time.Duration {
+	if l.maxFailures <= 0 {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.sweep(now)
+	attempts, ok := l.attempts[username]
+	if !ok {
+		attempts = &loginAttempts{}
+		l.attempts[username] = attempts
+	}
+	attempts.lastFailure = now
+	attempts.failures++
+	if attempts.failures < l.maxFailures {
+		return 0
+	}
+	attempts.failures = 0
+	attempts.lockouts++
+	duration := l.lockout
+	for i := 1; i < attempts.lockouts && duration < l.maxLockout; i++ {
+		duration *= 2
+	}
+	if duration > l.maxLockout {
+		duration = l.maxLockout
+	}
+	attempts.lockedUntil = now.Add(duration)
+	return duration
+}
+
+// succeeded forgets the failed logins of username
+func (l *loginLockout) succeeded(username string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.attempts, username)
+}
+
+// sweep forgets usernames without failures for longer than the longest lockout
+func (l *loginLockout) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.maxLockout {
+		return
+	}
+	for username, attempts := range l.attempts {
+		if now.Sub(attempts.lastFailure) >= l.maxLockout && !now.Before(attempts.lockedUntil) {
+			delete(l.attempts, username)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/ngereci/xm_interview/env"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoginLockout(t *testing.T) {
+	now := time.Now()
+	lockout := newLoginLockout(3, time.Minute, 3*time.Minute)
+	lockout.now = func() time.Time { return now }
+
+	// the first lockout after three failures
+	assert.Zero(t, lockout.failed("admin"))
+	assert.Zero(t, lockout.failed("admin"))
+	assert.Equal(t, time.Minute, lockout.failed("admin"))
+	assert.Equal(t, time.Minute, lockout.lockedFor("admin"))
+	assert.Zero(t, lockout.lockedFor("other"))
+
+	// every further lockout doubles up to the maximum
+	now = now.Add(time.Minute)
+	assert.Zero(t, lockout.lockedFor("admin"))
+	lockout.failed("admin")
+	lockout.failed("admin")
+	assert.Equal(t, 2*time.Minute, lockout.failed("admin"))
+	now = now.Add(2 * time.Minute)
+	lockout.failed("admin")
+	lockout.failed("admin")
+	assert.Equal(t, 3*time.Minute, lockout.failed("admin"))
+
+	// a successful login starts over
+	now = now.Add(3 * time.Minute)
+	lockout.succeeded("admin")
+	lockout.failed("admin")
+	lockout.failed("admin")
+	assert.Equal(t, time.Minute, lockout.failed("admin"))
+}
+
+func TestLoginLockout_Disabled(t *testing.T) {
+	lockout := newLoginLockout(0, time.Minute, time.Hour)
+
+	for i := 0; i < 10; i++ {
+		assert.Zero(t, lockout.failed("admin"))
+	}
+	assert.Zero(t, lockout.lockedFor("admin"))
+}
+
+func TestController_Login_Lockout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	viper.Set(env.COMPANY_JWT_SECRET_KEY, "test-key")
+	viper.Set(env.COMPANY_JWT_EXPIRE_TIME, 3600)
+	viper.Set(env.COMPANY_LOGIN_MAX_FAILURES, 2)
+	viper.Set(env.COMPANY_LOGIN_LOCKOUT, time.Minute)
+	viper.Set(env.COMPANY_LOGIN_MAX_LOCKOUT, time.Hour)
+	defer viper.Set(env.COMPANY_LOGIN_MAX_FAILURES, 0)
+	r := gin.New()
+	r.POST("/login", NewAuthController().Login)
+	login := func(password string) *httptest.ResponseRecorder {
+		requestBody, _ := json.Marshal(LoginRequest{Username: "admin", Password: password})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(string(requestBody))))
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, login("wrong").Code)
+	locked := login("wrong")
+	assert.Equal(t, http.StatusTooManyRequests, locked.Code)
+	assert.Equal(t, "60", locked.Header().Get("Retry-After"))
+	// the correct password is rejected as well while locked out
+	assert.Equal(t, http.StatusTooManyRequests, login("admin").Code)
+}
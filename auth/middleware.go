@@ -1,61 +1,208 @@
 package auth
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/ngereci/xm_interview/tenant"
 )
 
+const (
+	APIKeyHeader = "X-API-Key"
+	// apiKeyContextKey holds the API key a request was authenticated with, it is not set for users with a token
+	apiKeyContextKey = "apiKey"
+)
+
+// APIKeyAuthenticator resolves the key of the X-API-Key header, apikey.Service implements it.
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, plaintext string) (*model.APIKey, error)
+}
+
+var (
+	errMissingAuthorization = errors.New("Authorization header is missing")
+	errInvalidTokenFormat   = errors.New("Invalid token format")
+	errInvalidToken         = errors.New("Invalid token")
+	errTokenClaims          = errors.New("Failed to parse token claims")
+	errTokenWithoutTenant   = errors.New("Token has no valid tenant")
+)
+
+// ErrMissingScope is returned by CheckScope for requests authenticated with an API key lacking Scope
+type ErrMissingScope struct {
+	Scope model.Scope
+}
+
+func (e ErrMissingScope) Error() string {
+	return fmt.Sprintf("API key lacks scope %v", e.Scope)
+}
+
+type apiKeyKey struct{}
+
+type usernameKey struct{}
+
 type AuthMiddleware struct {
 	secretKey string
+	apiKeys   APIKeyAuthenticator
 }
 
-func NewAuthMiddleware(secretKey string) *AuthMiddleware {
-	return &AuthMiddleware{secretKey: secretKey}
+// NewAuthMiddleware creates the middleware accepting Bearer tokens signed with secretKey and,
+// unless apiKeys is nil, API keys in the X-API-Key header.
+func NewAuthMiddleware(secretKey string, apiKeys APIKeyAuthenticator) *AuthMiddleware {
+	return &AuthMiddleware{secretKey: secretKey, apiKeys: apiKeys}
 }
 
 func (a *AuthMiddleware) Authenticate() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		tokenString := c.Request.Header.Get("Authorization")
-		if tokenString == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is missing"})
+		if plaintext := c.Request.Header.Get(APIKeyHeader); plaintext != "" && a.apiKeys != nil {
+			a.authenticateAPIKey(c, plaintext)
 			return
 		}
 
-		splitToken := strings.Split(tokenString, "Bearer ")
-		if len(splitToken) != 2 {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token format"})
+		claims, tenantID, err := a.verifyToken(c.Request.Header.Get("Authorization"))
+		if err != nil {
+			status := http.StatusUnauthorized
+			if errors.Is(err, errTokenClaims) {
+				status = http.StatusInternalServerError
+			}
+			c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
 			return
 		}
 
-		tokenString = splitToken[1]
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, errors.New("Unexpected signing method")
-			}
-			return []byte(a.secretKey), nil
-		})
+		c.Set("userId", claims["userId"])
+		if isOperatorToken(claims) {
+			c.Request = c.Request.WithContext(withOperator(c.Request.Context()))
+		}
+		authenticated(c, claims["username"], tenantID)
+	}
+}
 
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
-			return
+// verifyToken verifies the "Bearer <token>" value of an Authorization header and returns the claims and the tenant of the token.
+// All errors but errTokenClaims mean the token is missing or not acceptable. Stream tickets are not accepted as tokens.
+func (a *AuthMiddleware) verifyToken(authorization string) (jwt.MapClaims, string, error) {
+	if authorization == "" {
+		return nil, "", errMissingAuthorization
+	}
+
+	splitToken := strings.Split(authorization, "Bearer ")
+	if len(splitToken) != 2 {
+		return nil, "", errInvalidTokenFormat
+	}
+
+	claims, tenantID, err := a.parseToken(splitToken[1])
+	if err != nil {
+		return nil, "", err
+	}
+	if isStreamTicket(claims) {
+		return nil, "", errInvalidToken
+	}
+	return claims, tenantID, nil
+}
+
+// parseToken verifies the signature, expiry and tenant of a signed token and returns its claims and tenant
+func (a *AuthMiddleware) parseToken(tokenString string, options ...jwt.ParserOption) (jwt.MapClaims, string, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("Unexpected signing method")
 		}
+		return []byte(a.secretKey), nil
+	}, options...)
 
-		if !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !token.Valid {
+		return nil, "", errInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, "", errTokenClaims
+	}
+
+	tenantID, _ := claims[tenant.Claim].(string)
+	if !tenant.Valid(tenantID) {
+		return nil, "", errTokenWithoutTenant
+	}
+	return claims, tenantID, nil
+}
+
+func (a *AuthMiddleware) authenticateAPIKey(c *gin.Context, plaintext string) {
+	key, err := a.apiKeys.Authenticate(c.Request.Context(), plaintext)
+	if err != nil {
+		if errors.Is(err, model.ErrInvalidAPIKey) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
 			return
 		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify API key"})
+		return
+	}
+	c.Set(apiKeyContextKey, key)
+	c.Request = c.Request.WithContext(WithAPIKey(c.Request.Context(), key))
+	authenticated(c, "apikey:"+key.ID.String(), key.TenantID)
+}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse token claims"})
+// authenticated continues the request as username acting for the tenant tenantID.
+func authenticated(c *gin.Context, username any, tenantID string) {
+	c.Set("username", username)
+	c.Request = c.Request.WithContext(authenticatedContext(c.Request.Context(), username, tenantID))
+	c.Next()
+}
+
+// authenticatedContext returns a copy of ctx acting for the tenant tenantID, whose log lines name username and the tenant.
+func authenticatedContext(ctx context.Context, username any, tenantID string) context.Context {
+	if name, ok := username.(string); ok {
+		ctx = context.WithValue(ctx, usernameKey{}, name)
+	}
+	ctx = logging.WithField(ctx, logging.FieldUser, username)
+	ctx = logging.WithField(ctx, logging.FieldTenant, tenantID)
+	return tenant.WithID(ctx, tenantID)
+}
+
+// Username returns the user ctx was authenticated as, API keys are named like apikey:<id>. It is empty before authentication.
+func Username(ctx context.Context) string {
+	username, _ := ctx.Value(usernameKey{}).(string)
+	return username
+}
+
+// WithAPIKey returns a copy of ctx authenticated with key, which CheckScope checks scopes against
+func WithAPIKey(ctx context.Context, key *model.APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyKey{}, key)
+}
+
+// CheckScope returns ErrMissingScope if ctx was authenticated with an API key lacking scope, for handlers checking
+// scopes themselves instead of using RequireScope. Users with a token have every scope, ScopeOperator is only granted
+// to operator tokens.
+func CheckScope(ctx context.Context, scope model.Scope) error {
+	if scope == model.ScopeOperator && !IsOperator(ctx) {
+		return ErrMissingScope{Scope: scope}
+	}
+	if key, ok := ctx.Value(apiKeyKey{}).(*model.APIKey); ok && !key.HasScope(scope) {
+		return ErrMissingScope{Scope: scope}
+	}
+	return nil
+}
+
+// RequireScope rejects requests authenticated with an API key lacking scope, users with a token have every scope.
+// ScopeOperator is only granted to operator tokens, see CreateOperatorToken.
+func RequireScope(scope model.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if scope == model.ScopeOperator && !IsOperator(c.Request.Context()) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": errNotOperator.Error()})
 			return
 		}
-
-		c.Set("userId", claims["userId"])
+		if value, ok := c.Get(apiKeyContextKey); ok {
+			if key, ok := value.(*model.APIKey); !ok || !key.HasScope(scope) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": ErrMissingScope{Scope: scope}.Error()})
+				return
+			}
+		}
 		c.Next()
 	}
 }
@@ -1,22 +1,31 @@
 package auth
 
 import (
+	"context"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/ngereci/xm_interview/tenant"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestAuthMiddleware_Authenticate_Success(t *testing.T) {
 	secretKey := "secret"
-	middleware := NewAuthMiddleware(secretKey)
+	middleware := NewAuthMiddleware(secretKey, nil)
 
 	// Create a test JWT token
 	token := jwt.New(jwt.SigningMethodHS256)
 	claims := token.Claims.(jwt.MapClaims)
 	claims["userId"] = "123"
+	claims["username"] = "admin"
+	claims[tenant.Claim] = "tenant-a"
 	tokenString, err := token.SignedString([]byte(secretKey))
 	if err != nil {
 		t.Fatal(err)
@@ -43,15 +52,50 @@ func TestAuthMiddleware_Authenticate_Success(t *testing.T) {
 		t.Errorf("Authenticate middleware did not set userId in context")
 	}
 
+	// Verify that the middleware attached the user to the log lines of the request
+	assert.Equal(t, "admin", logging.FromContext(c.Request.Context()).Data[logging.FieldUser])
+
+	// Verify that the request acts for the tenant of the token
+	tenantID, err := tenant.FromContext(c.Request.Context())
+	assert.NoError(t, err)
+	assert.Equal(t, "tenant-a", tenantID)
+
 	// Verify that the middleware called the next handler
 	if res.Code != http.StatusOK {
 		t.Errorf("Authenticate middleware did not call next handler")
 	}
 }
 
+func TestAuthMiddleware_Authenticate_MissingTenant(t *testing.T) {
+	secretKey := "secret"
+	middleware := NewAuthMiddleware(secretKey, nil)
+
+	for name, tenantID := range map[string]any{"missing": nil, "empty": "", "invalid": "tenant/a", "not a string": 42} {
+		t.Run(name, func(t *testing.T) {
+			claims := jwt.MapClaims{"username": "admin"}
+			if tenantID != nil {
+				claims[tenant.Claim] = tenantID
+			}
+			tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secretKey))
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(res)
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			c.Request.Header.Set("Authorization", "Bearer "+tokenString)
+
+			middleware.Authenticate()(c)
+
+			assert.Equal(t, http.StatusUnauthorized, res.Code)
+			assert.JSONEq(t, `{"error":"Token has no valid tenant"}`, res.Body.String())
+		})
+	}
+}
+
 func TestAuthMiddleware_Authenticate_MissingAuthorizationHeader(t *testing.T) {
 	secretKey := "secret"
-	middleware := NewAuthMiddleware(secretKey)
+	middleware := NewAuthMiddleware(secretKey, nil)
 
 	// Create a test request without an Authorization header
 	req, err := http.NewRequest("GET", "/", nil)
@@ -81,7 +125,7 @@ func TestAuthMiddleware_Authenticate_MissingAuthorizationHeader(t *testing.T) {
 
 func TestAuthMiddleware_Authenticate_InvalidTokenFormat(t *testing.T) {
 	secretKey := "secret"
-	middleware := NewAuthMiddleware(secretKey)
+	middleware := NewAuthMiddleware(secretKey, nil)
 
 	// Create a test request with an invalid Authorization header format
 	req, err := http.NewRequest("GET", "/", nil)
@@ -112,7 +156,7 @@ func TestAuthMiddleware_Authenticate_InvalidTokenFormat(t *testing.T) {
 
 func TestAuthMiddleware_Authenticate_InvalidToken(t *testing.T) {
 	secretKey := "secret"
-	middleware := NewAuthMiddleware(secretKey)
+	middleware := NewAuthMiddleware(secretKey, nil)
 
 	// Create a mock Gin context
 	router := gin.New()
@@ -135,3 +179,104 @@ func TestAuthMiddleware_Authenticate_InvalidToken(t *testing.T) {
 	expectedBody := `{"error":"token is malformed: token contains an invalid number of segments"}`
 	assert.JSONEq(t, expectedBody, resp.Body.String())
 }
+
+type apiKeyAuthenticatorFunc func(ctx context.Context, plaintext string) (*model.APIKey, error)
+
+func (f apiKeyAuthenticatorFunc) Authenticate(ctx context.Context, plaintext string) (*model.APIKey, error) {
+	return f(ctx, plaintext)
+}
+
+func TestAuthMiddleware_Authenticate_APIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	key := &model.APIKey{ID: uuid.MustParse("56f86115-a58f-43db-8a1b-9aa2908f7a18"), TenantID: "tenant-a", Name: "batch", Scopes: []model.Scope{model.ScopeCompaniesRead}}
+	middleware := NewAuthMiddleware("secret", apiKeyAuthenticatorFunc(func(ctx context.Context, plaintext string) (*model.APIKey, error) {
+		switch plaintext {
+		case "valid":
+			return key, nil
+		case "failing":
+			return nil, errors.New("unavailable")
+		default:
+			return nil, model.ErrInvalidAPIKey
+		}
+	}))
+	router := gin.New()
+	router.Use(middleware.Authenticate())
+	router.GET("/companies", RequireScope(model.ScopeCompaniesRead), func(c *gin.Context) {
+		username, _ := c.Get("username")
+		tenantID, _ := tenant.FromContext(c.Request.Context())
+		c.JSON(http.StatusOK, gin.H{"username": username, "tenant": tenantID})
+	})
+	router.POST("/companies", RequireScope(model.ScopeCompaniesWrite), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+	request := func(method, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/companies", nil)
+		req.Header.Set(APIKeyHeader, apiKey)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := request(http.MethodGet, "valid")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"username":"apikey:56f86115-a58f-43db-8a1b-9aa2908f7a18","tenant":"tenant-a"}`, resp.Body.String())
+	resp = request(http.MethodPost, "valid")
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.JSONEq(t, `{"error":"API key lacks scope companies:write"}`, resp.Body.String())
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "revoked").Code)
+	assert.Equal(t, http.StatusInternalServerError, request(http.MethodGet, "failing").Code)
+}
+
+func TestRequireScope_Token(t *testing.T) {
+	// users authenticated with a token have every scope
+	res := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(res)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Set("username", "admin")
+
+	RequireScope(model.ScopeAdmin)(c)
+
+	assert.False(t, c.IsAborted())
+}
+
+func TestRequireScope_Operator(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	secretKey := "secret"
+	key := &model.APIKey{ID: uuid.MustParse("56f86115-a58f-43db-8a1b-9aa2908f7a18"), TenantID: "tenant-a", Scopes: model.Scopes}
+	middleware := NewAuthMiddleware(secretKey, apiKeyAuthenticatorFunc(func(ctx context.Context, plaintext string) (*model.APIKey, error) {
+		return key, nil
+	}))
+	router := gin.New()
+	router.Use(middleware.Authenticate())
+	router.POST("/company-types", RequireScope(model.ScopeOperator), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+	request := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/company-types", nil)
+		req.Header.Set(header, value)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+	tenantToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"username": "admin", tenant.Claim: "tenant-a"}).SignedString([]byte(secretKey))
+	assert.NoError(t, err)
+	operatorToken, err := CreateOperatorToken(secretKey, "operator", time.Minute)
+	assert.NoError(t, err)
+
+	// tenant users and API keys have every other scope, but never the operator scope
+	resp := request("Authorization", "Bearer "+tenantToken)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.JSONEq(t, `{"error":"Requires an operator token"}`, resp.Body.String())
+	assert.Equal(t, http.StatusForbidden, request(APIKeyHeader, "valid").Code)
+	assert.Equal(t, http.StatusCreated, request("Authorization", "Bearer "+operatorToken).Code)
+}
+
+func TestCheckScope(t *testing.T) {
+	key := &model.APIKey{Scopes: []model.Scope{model.ScopeCompaniesRead}}
+
+	assert.NoError(t, CheckScope(context.Background(), model.ScopeAdmin))
+	assert.NoError(t, CheckScope(WithAPIKey(context.Background(), key), model.ScopeCompaniesRead))
+	assert.Equal(t, ErrMissingScope{Scope: model.ScopeCompaniesWrite}, CheckScope(WithAPIKey(context.Background(), key), model.ScopeCompaniesWrite))
+	assert.Equal(t, ErrMissingScope{Scope: model.ScopeOperator}, CheckScope(context.Background(), model.ScopeOperator))
+	assert.NoError(t, CheckScope(withOperator(context.Background()), model.ScopeOperator))
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ngereci/xm_interview/tenant"
+)
+
+// operatorClaim marks the tokens of operators, who change data shared by all tenants. Login never issues it, operator
+// tokens are only created with CreateOperatorToken by someone holding the signing key.
+const operatorClaim = "operator"
+
+var errNotOperator = errors.New("Requires an operator token")
+
+type operatorKey struct{}
+
+// CreateOperatorToken returns a token signed with secretKey granting ScopeOperator to username for ttl
+func CreateOperatorToken(secretKey, username string, ttl time.Duration) (string, error) {
+	claims := jwt.MapClaims{
+		"username":    username,
+		tenant.Claim:  tenant.Default,
+		operatorClaim: true,
+		"exp":         time.Now().Add(ttl).Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secretKey))
+}
+
+// IsOperator reports whether ctx was authenticated with an operator token
+func IsOperator(ctx context.Context) bool {
+	operator, _ := ctx.Value(operatorKey{}).(bool)
+	return operator
+}
+
+func withOperator(ctx context.Context) context.Context {
+	return context.WithValue(ctx, operatorKey{}, true)
+}
+
+func isOperatorToken(claims jwt.MapClaims) bool {
+	operator, _ := claims[operatorClaim].(bool)
+	return operator
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ngereci/xm_interview/env"
+	"github.com/ngereci/xm_interview/tenant"
+	"github.com/spf13/viper"
+	"net/http"
+	"time"
+)
+
+const (
+	// StreamTicketParam is the query parameter streams take a ticket in, browsers can not set headers on
+	// EventSource and WebSocket requests
+	StreamTicketParam = "ticket"
+	// streamTicketAudience tells tickets apart from tokens, tickets only open streams and tokens are never sent in a URL
+	streamTicketAudience = "stream"
+	// streamTicketTTL is how long a ticket can be used to open a stream, open streams are not closed when it expires
+	streamTicketTTL = 30 * time.Second
+)
+
+var errInvalidStreamTicket = errors.New("Invalid stream ticket")
+
+type StreamTicketResponse struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// StreamTicket returns a ticket the authenticated caller can open a stream with for streamTicketTTL, see AuthenticateStream
+func (a *Controller) StreamTicket(c *gin.Context) {
+	tenantID, err := tenant.FromContext(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	expiresAt := time.Now().Add(streamTicketTTL).UTC().Truncate(time.Second)
+	claims := jwt.MapClaims{
+		"username":   Username(c.Request.Context()),
+		tenant.Claim: tenantID,
+		"aud":        streamTicketAudience,
+		"exp":        expiresAt.Unix(),
+	}
+	ticket, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(viper.GetString(env.COMPANY_JWT_SECRET_KEY)))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create stream ticket"})
+		return
+	}
+	c.JSON(http.StatusOK, StreamTicketResponse{Ticket: ticket, ExpiresAt: expiresAt})
+}
+
+// AuthenticateStream authenticates like Authenticate, or with a ticket of StreamTicket in the ticket parameter for
+// browsers, which can not send headers with EventSource and WebSocket requests. Tokens and API keys are never accepted
+// as parameters, URLs end up in logs and browser histories; the access log leaves the query out for the tickets.
+func (a *AuthMiddleware) AuthenticateStream() gin.HandlerFunc {
+	authenticate := a.Authenticate()
+	return func(c *gin.Context) {
+		ticket := c.Query(StreamTicketParam)
+		if ticket == "" {
+			authenticate(c)
+			return
+		}
+		claims, tenantID, err := a.parseToken(ticket, jwt.WithAudience(streamTicketAudience))
+		if err != nil {
+			status := http.StatusUnauthorized
+			if errors.Is(err, errTokenClaims) {
+				status = http.StatusInternalServerError
+			}
+			c.AbortWithStatusJSON(status, gin.H{"error": errInvalidStreamTicket.Error()})
+			return
+		}
+		authenticated(c, claims["username"], tenantID)
+	}
+}
+
+// isStreamTicket returns whether the claims are those of a stream ticket
+func isStreamTicket(claims jwt.MapClaims) bool {
+	audience, _ := claims.GetAudience()
+	for _, aud := range audience {
+		if aud == streamTicketAudience {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ngereci/xm_interview/env"
+	"github.com/ngereci/xm_interview/tenant"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newStreamRouter serves a stream route answering with the user and tenant it was opened for, and the ticket route
+func newStreamRouter(secretKey string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	viper.Set(env.COMPANY_JWT_SECRET_KEY, secretKey)
+	middleware := NewAuthMiddleware(secretKey, nil)
+	router := gin.New()
+	router.POST("/tickets", middleware.Authenticate(), NewAuthController().StreamTicket)
+	router.GET("/stream", middleware.AuthenticateStream(), func(c *gin.Context) {
+		tenantID, _ := tenant.FromContext(c.Request.Context())
+		c.JSON(http.StatusOK, gin.H{"username": Username(c.Request.Context()), "tenant": tenantID})
+	})
+	return router
+}
+
+func TestStreamTicket(t *testing.T) {
+	secretKey := "secret"
+	router := newStreamRouter(secretKey)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"username": "admin", tenant.Claim: "tenant-a"}).SignedString([]byte(secretKey))
+	require.NoError(t, err)
+	serve := func(method, path, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(http.MethodPost, "/tickets", "Bearer "+token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response StreamTicketResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.WithinDuration(t, time.Now().Add(streamTicketTTL), response.ExpiresAt, 2*time.Second)
+
+	w = serve(http.MethodGet, "/stream?ticket="+response.Ticket, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"username":"admin","tenant":"tenant-a"}`, w.Body.String())
+	// streams still accept the header
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/stream", "Bearer "+token).Code)
+
+	// tokens are not accepted as tickets, tickets are not accepted as tokens
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/stream?ticket="+token, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodPost, "/tickets", "Bearer "+response.Ticket).Code)
+}
+
+func TestStreamTicket_Expired(t *testing.T) {
+	secretKey := "secret"
+	router := newStreamRouter(secretKey)
+	ticket, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"username": "admin", tenant.Claim: "tenant-a", "aud": streamTicketAudience, "exp": time.Now().Add(-time.Minute).Unix(),
+	}).SignedString([]byte(secretKey))
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream?ticket="+ticket, nil))
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.JSONEq(t, `{"error":"Invalid stream ticket"}`, w.Body.String())
+}
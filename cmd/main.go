@@ -1,73 +1,348 @@
 package main
 
 import (
-	"github.com/gocql/gocql"
+	"context"
+	"flag"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/ngereci/xm_interview/apikey"
 	"github.com/ngereci/xm_interview/auth"
 	"github.com/ngereci/xm_interview/company"
+	"github.com/ngereci/xm_interview/companypb"
+	"github.com/ngereci/xm_interview/companytype"
 	"github.com/ngereci/xm_interview/env"
 	"github.com/ngereci/xm_interview/event"
-	"log"
+	"github.com/ngereci/xm_interview/health"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/metrics"
+	"github.com/ngereci/xm_interview/middleware"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/ngereci/xm_interview/openapi"
+	"github.com/ngereci/xm_interview/relationship"
+	"github.com/ngereci/xm_interview/search"
+	"github.com/ngereci/xm_interview/snapshot"
+	"github.com/ngereci/xm_interview/stream"
+	"github.com/ngereci/xm_interview/tracing"
+	log "github.com/sirupsen/logrus"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
 )
 
+// eventSinkMemory keeps events in memory instead of sending them to Kafka
+const eventSinkMemory = "memory"
+
 func main() {
 	viper.SetConfigFile("config/config.env")
 	if err := viper.ReadInConfig(); err != nil {
 		log.Fatalf("Error reading config file: %v", err)
 	}
 	viper.AutomaticEnv()
-	// Initialize the Cassandra session
-	cluster := gocql.NewCluster(viper.GetString(env.COMPANY_CASSANDRA_HOST))
-	cluster.Keyspace = viper.GetString(env.COMPANY_CASSANDRA_KEYSPACE)
-	cluster.Consistency = gocql.Quorum
-	session, err := cluster.CreateSession()
+	if err := logging.Setup(viper.GetString(env.COMPANY_LOG_LEVEL), viper.GetString(env.COMPANY_LOG_FORMAT)); err != nil {
+		log.Fatalf("Error configuring logging: %v", err)
+	}
+	// names are normalized the same way by the server, the subcommands and the data migrations
+	model.SetStripLegalForms(viper.GetBool(env.COMPANY_NAME_STRIP_LEGAL_FORMS))
 
-	if err != nil {
-		log.Fatal("Failed to create Cassandra session: ", err)
+	storage := flag.String("storage", viper.GetString(env.COMPANY_STORAGE), "company storage: cassandra, postgres or embedded")
+	flag.Parse()
+	viper.Set(env.COMPANY_STORAGE, *storage)
+
+	if args := flag.Args(); len(args) > 0 {
+		switch args[0] {
+		case "snapshot":
+			runSnapshot(args[1:])
+			return
+		case "migrate":
+			runMigrate()
+			return
+		case "operator-token":
+			runOperatorToken(args[1:])
+			return
+		default:
+			log.Fatalf("Unknown command %v", args[0])
+		}
 	}
+	runServer()
+}
 
-	kafkaProducer, err := event.NewKafkaAdapter([]string{viper.GetString(env.COMPANY_BROKER_URL)}, viper.GetString(env.COMPANY_BROKER_TOPIC))
+func runServer() {
+	shutdownTracing := setupTracing()
+	store := newStorage(true)
+	broker := stream.NewBroker(viper.GetInt(env.COMPANY_STREAM_BUFFER_SIZE))
+	searchIndex, err := search.NewIndex()
 	if err != nil {
-		log.Fatalf("Error creating Kafka producer: %v", err)
+		log.Fatalf("Error creating search index: %v", err)
+	}
+	kafkaProducer := event.NewNotifyingAdapter(newEventAdapter(), broker, searchIndex)
+
+	checkers := []health.Checker{health.NewKafkaChecker(kafkaProducer)}
+	if store.checker != nil {
+		checkers = append(checkers, store.checker)
 	}
+	healthController := health.NewController(viper.GetDuration(env.COMPANY_HEALTH_CHECK_TIMEOUT), checkers...)
 
-	companyRepo := company.NewRepository(session)
-	companyService := company.NewService(companyRepo, kafkaProducer)
+	companyTypeService := companytype.NewService(store.companyTypes, store.companies)
+	relationshipService := relationship.NewService(store.relationships, store.companies, kafkaProducer)
+	companyService := company.NewTracingService(company.NewService(store.companies, kafkaProducer, companyTypeService, relationshipService))
 	companyController := company.NewController(companyService)
+	snapshotController := snapshot.NewController(snapshot.NewService(store.companies, kafkaProducer))
+	searchController := search.NewController(search.NewService(searchIndex, store.companies, companyService))
+	apiKeyService := apikey.NewService(store.apiKeys)
+	apiKeyController := apikey.NewController(apiKeyService)
+
+	doc, err := openapi.Load()
+	if err != nil {
+		log.Fatalf("Error loading OpenAPI document: %v", err)
+	}
+	openapiController, err := openapi.NewController(doc)
+	if err != nil {
+		log.Fatalf("Error loading OpenAPI document: %v", err)
+	}
+
+	authMiddleware := auth.NewAuthMiddleware(viper.GetString(env.COMPANY_JWT_SECRET_KEY), apiKeyService)
+	router := newRouter(handlers{
+		health:            healthController,
+		auth:              auth.NewAuthController(),
+		authMiddleware:    authMiddleware,
+		companies:         companyController,
+		companyTypes:      companytype.NewController(companyTypeService),
+		relationships:     relationship.NewController(relationshipService),
+		graphql:           company.NewGraphQLController(companyService, middleware.NewLimiter(viper.GetFloat64(env.COMPANY_RATE_LIMIT_WRITE), viper.GetInt(env.COMPANY_RATE_LIMIT_WRITE_BURST))),
+		streams:           stream.NewController(broker, viper.GetDuration(env.COMPANY_STREAM_HEARTBEAT_INTERVAL)),
+		search:            searchController,
+		snapshots:         snapshotController,
+		apiKeys:           apiKeyController,
+		openapi:           openapiController,
+		doc:               doc,
+		validateResponses: viper.GetBool(env.COMPANY_OPENAPI_VALIDATE_RESPONSES) || gin.Mode() == gin.TestMode,
+	})
+
+	port := viper.GetString(env.COMPANY_SERVER_PORT)
+	server := &http.Server{
+		Addr:         ":" + port,
+		Handler:      router,
+		ReadTimeout:  viper.GetDuration(env.COMPANY_SERVER_READ_TIMEOUT),
+		WriteTimeout: viper.GetDuration(env.COMPANY_SERVER_WRITE_TIMEOUT),
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go company.RefreshCompaniesByType(ctx, store.companies, store.companyTypes, viper.GetDuration(env.COMPANY_METRICS_REFRESH_INTERVAL))
+	go search.RebuildPeriodically(ctx, searchIndex, store.companies, viper.GetDuration(env.COMPANY_SEARCH_REBUILD_INTERVAL))
+	serverErr := make(chan error, 2)
+	go func() {
+		log.Printf("Server listening on port %s", port)
+		serverErr <- server.ListenAndServe()
+	}()
+	grpcServer := newGRPCServer(companyService, authMiddleware)
+	grpcPort := viper.GetString(env.COMPANY_GRPC_PORT)
+	go func() {
+		listener, err := net.Listen("tcp", ":"+grpcPort)
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		log.Printf("gRPC server listening on port %s", grpcPort)
+		serverErr <- grpcServer.Serve(listener)
+	}()
+
+	select {
+	case err := <-serverErr:
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Error starting server: %v", err)
+		}
+	case <-ctx.Done():
+		// a second signal terminates immediately
+		stop()
+		healthController.SetReady(false)
+		shutdown(server, grpcServer, broker, kafkaProducer, searchIndex, store.close, shutdownTracing)
+	}
+}
 
-	authController := auth.NewAuthController()
-	authMiddleware := auth.NewAuthMiddleware(viper.GetString(env.COMPANY_JWT_SECRET_KEY))
+// handlers are the controllers and middleware the routes are served with
+type handlers struct {
+	health         health.Controller
+	auth           *auth.Controller
+	authMiddleware *auth.AuthMiddleware
+	companies      company.Controller
+	companyTypes   companytype.Controller
+	relationships  relationship.Controller
+	graphql        company.GraphQLController
+	streams        stream.Controller
+	search         search.Controller
+	snapshots      snapshot.Controller
+	apiKeys        apikey.Controller
+	openapi        openapi.Controller
+	// doc is the OpenAPI document requests, and with validateResponses responses, are validated against
+	doc               *openapi3.T
+	validateResponses bool
+}
 
-	router := gin.Default()
+// newRouter registers the routes of the API, every one of them is described in the OpenAPI document.
+func newRouter(h handlers) *gin.Engine {
+	router := gin.New()
+	// otelgin restores the request when it returns, the access log has to run inside it to see the user and the trace
+	router.Use(gin.Recovery(), otelgin.Middleware(viper.GetString(env.COMPANY_TRACING_SERVICE_NAME)))
+	router.Use(middleware.RequestID(), middleware.AccessLog(), middleware.Metrics(), openapi.Validate(h.doc, h.validateResponses))
+	router.GET("/healthz", h.health.Liveness)
+	router.GET("/readyz", h.health.Readiness)
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	router.GET(openapi.SpecPath, h.openapi.Spec)
+	router.GET(openapi.DocsPath, h.openapi.Docs)
 
 	loginRouter := router.Group("/api/v1")
-	loginRouter.POST("/login", authController.Login)
+	loginRouter.Use(middleware.RateLimit(viper.GetFloat64(env.COMPANY_RATE_LIMIT_LOGIN), viper.GetInt(env.COMPANY_RATE_LIMIT_LOGIN_BURST), middleware.ClientIPKey))
+	loginRouter.POST("/login", h.auth.Login)
 
 	apiRouter := router.Group("/api/v1")
-	apiRouter.Use(authMiddleware.Authenticate())
+	apiRouter.Use(h.authMiddleware.Authenticate())
 	// Company routes
-	apiRouter.POST("/companies", companyController.CreateCompany)
-	apiRouter.PATCH("/companies/:id", companyController.UpdateCompany)
-	apiRouter.DELETE("/companies/:id", companyController.DeleteCompany)
-	apiRouter.GET("/companies/:id", companyController.GetCompany)
+	companyRouter := apiRouter.Group("/companies")
+	companyRouter.Use(middleware.Timeout(viper.GetDuration(env.COMPANY_REQUEST_TIMEOUT)))
+	writeLimit := middleware.RateLimit(viper.GetFloat64(env.COMPANY_RATE_LIMIT_WRITE), viper.GetInt(env.COMPANY_RATE_LIMIT_WRITE_BURST), middleware.UserKey)
+	readScope := auth.RequireScope(model.ScopeCompaniesRead)
+	writeScope := auth.RequireScope(model.ScopeCompaniesWrite)
+	companyRouter.POST("", writeScope, writeLimit, h.companies.CreateCompany)
+	companyRouter.PATCH("/:id", writeScope, writeLimit, h.companies.UpdateCompany)
+	companyRouter.DELETE("/:id", writeScope, writeLimit, h.companies.DeleteCompany)
+	companyRouter.GET("/search", readScope, h.search.Search)
+	companyRouter.GET("/:id", readScope, h.companies.GetCompany)
+	companyRouter.PUT("/:id/tags/:key", writeScope, writeLimit, h.companies.SetTag)
+	companyRouter.DELETE("/:id/tags/:key", writeScope, writeLimit, h.companies.RemoveTag)
+	companyRouter.PUT("/:id/parent", writeScope, writeLimit, h.relationships.SetParent)
+	companyRouter.DELETE("/:id/parent", writeScope, writeLimit, h.relationships.RemoveParent)
+	companyRouter.GET("/:id/ancestors", readScope, h.relationships.Ancestors)
+	companyRouter.GET("/:id/descendants", readScope, h.relationships.Descendants)
+	apiRouter.GET("/company-types", readScope, h.companyTypes.ListCompanyTypes)
+	apiRouter.GET("/company-types/:name", readScope, h.companyTypes.GetCompanyType)
+	apiRouter.POST("/companies/stream/tickets", readScope, h.auth.StreamTicket)
+	// streams are long-lived and left out of the request timeout of the other company routes, browsers authenticate
+	// them with a ticket in the query
+	streamRouter := router.Group("/api/v1/companies/stream")
+	streamRouter.Use(h.authMiddleware.AuthenticateStream())
+	streamRouter.GET("", readScope, h.streams.Events)
+	streamRouter.GET("/ws", readScope, h.streams.WebSocket)
+	// GraphQL checks the scopes of API keys itself, queries and mutations share the route
+	router.POST("/graphql", h.authMiddleware.Authenticate(), middleware.Timeout(viper.GetDuration(env.COMPANY_REQUEST_TIMEOUT)), h.graphql.Query)
+	// Admin routes
+	adminRouter := apiRouter.Group("/admin")
+	adminRouter.Use(auth.RequireScope(model.ScopeAdmin))
+	adminRouter.POST("/snapshots", h.snapshots.PublishSnapshot)
+	adminRouter.POST("/search-index/rebuild", h.search.RebuildIndex)
+	adminRouter.POST("/api-keys", h.apiKeys.CreateAPIKey)
+	adminRouter.GET("/api-keys", h.apiKeys.ListAPIKeys)
+	adminRouter.DELETE("/api-keys/:id", h.apiKeys.RevokeAPIKey)
+	// company types are shared by all tenants, only operators change them
+	operatorRouter := apiRouter.Group("/admin/company-types")
+	operatorRouter.Use(auth.RequireScope(model.ScopeOperator))
+	operatorRouter.POST("", h.companyTypes.CreateCompanyType)
+	operatorRouter.PUT("/:name", h.companyTypes.UpdateCompanyType)
+	operatorRouter.DELETE("/:name", h.companyTypes.DeleteCompanyType)
 
-	port := viper.GetString(env.COMPANY_SERVER_PORT)
-	server := &http.Server{
-		Addr:    ":" + port,
-		Handler: router,
+	return router
+}
+
+// shutdown stops the server in order: after the drain period, during which the server keeps serving but reports
+// not ready so that load balancers stop routing to it, new connections are refused and in-flight requests and calls are completed.
+// Only then the event producer is flushed and closed, the storage connections and the search index are closed and the pending spans are exported.
+func shutdown(server *http.Server, grpcServer *grpc.Server, broker stream.Broker, kafkaProducer event.KafkaAdapter, searchIndex search.Index, closeRepo func(), shutdownTracing func(context.Context) error) {
+	drainPeriod := viper.GetDuration(env.COMPANY_SERVER_DRAIN_PERIOD)
+	log.Printf("Shutting down, draining for %v", drainPeriod)
+	time.Sleep(drainPeriod)
+
+	ctx, cancel := context.WithTimeout(context.Background(), viper.GetDuration(env.COMPANY_SERVER_SHUTDOWN_TIMEOUT))
+	defer cancel()
+	// change streams never complete on their own
+	broker.Close()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Error completing in-flight requests: %v", err)
+	}
+	stopGRPCServer(ctx, grpcServer)
+	if err := kafkaProducer.Close(); err != nil {
+		log.Printf("Error closing Kafka producer: %v", err)
 	}
-	log.Printf("Server listening on port %s", port)
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("Error starting server: %v", err)
+	closeRepo()
+	if err := searchIndex.Close(); err != nil {
+		log.Printf("Error closing search index: %v", err)
 	}
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("Error exporting pending spans: %v", err)
+	}
+	log.Printf("Shutdown complete")
+}
 
-	defer func() {
-		session.Close()
-		if err := kafkaProducer.Close(); err != nil {
-			log.Fatalf("Error closing Kafka producer: %v", err)
-		}
+// stopGRPCServer completes the in-flight calls of grpcServer, the calls still running when ctx is done are cancelled
+func stopGRPCServer(ctx context.Context, grpcServer *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
 	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		log.Printf("Error completing in-flight gRPC calls: %v", ctx.Err())
+		grpcServer.Stop()
+	}
+}
+
+// newGRPCServer serves the gRPC API of companies, the calls are traced, authenticated, bounded by the request timeout
+// and the writes rate limited like the REST requests.
+func newGRPCServer(companies company.Service, authMiddleware *auth.AuthMiddleware) *grpc.Server {
+	writeMethods := make(map[string]bool)
+	for method, scope := range company.GRPCScopes {
+		if scope == model.ScopeCompaniesWrite {
+			writeMethods[method] = true
+		}
+	}
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		otelgrpc.UnaryServerInterceptor(),
+		authMiddleware.UnaryInterceptor(company.GRPCScopes),
+		middleware.UnaryTimeout(viper.GetDuration(env.COMPANY_REQUEST_TIMEOUT)),
+		middleware.UnaryRateLimit(viper.GetFloat64(env.COMPANY_RATE_LIMIT_WRITE), viper.GetInt(env.COMPANY_RATE_LIMIT_WRITE_BURST), writeMethods, middleware.ContextUserKey),
+	))
+	companypb.RegisterCompanyServiceServer(grpcServer, company.NewGRPCServer(companies))
+	reflection.Register(grpcServer)
+	return grpcServer
+}
+
+// newEventAdapter creates the adapter events are published with, COMPANY_EVENT_SINK selects between
+// kafka and memory. It defaults to memory for the embedded storage and to kafka otherwise.
+func newEventAdapter() event.KafkaAdapter {
+	sink := viper.GetString(env.COMPANY_EVENT_SINK)
+	if sink == "" && viper.GetString(env.COMPANY_STORAGE) == storageEmbedded {
+		sink = eventSinkMemory
+	}
+	if sink == eventSinkMemory {
+		return event.NewMemoryAdapter(viper.GetString(env.COMPANY_BROKER_TOPIC), viper.GetInt(env.COMPANY_EVENT_MEMORY_CAPACITY))
+	}
+	kafkaProducer, err := event.NewKafkaAdapter([]string{viper.GetString(env.COMPANY_BROKER_URL)}, viper.GetString(env.COMPANY_BROKER_TOPIC))
+	if err != nil {
+		log.Fatalf("Error creating Kafka producer: %v", err)
+	}
+	return kafkaProducer
+}
+
+// setupTracing installs the tracer provider configured by the COMPANY_TRACING_* variables
+func setupTracing() func(context.Context) error {
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:     viper.GetString(env.COMPANY_TRACING_EXPORTER),
+		ServiceName:  viper.GetString(env.COMPANY_TRACING_SERVICE_NAME),
+		OTLPEndpoint: viper.GetString(env.COMPANY_TRACING_OTLP_ENDPOINT),
+		OTLPInsecure: viper.GetBool(env.COMPANY_TRACING_OTLP_INSECURE),
+		SampleRatio:  viper.GetFloat64(env.COMPANY_TRACING_SAMPLE_RATIO),
+	})
+	if err != nil {
+		log.Fatalf("Error setting up tracing: %v", err)
+	}
+	return shutdownTracing
 }
@@ -7,13 +7,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/gin-gonic/gin"
-	"github.com/gocql/gocql"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/ngereci/xm_interview/auth"
 	"github.com/ngereci/xm_interview/company"
+	"github.com/ngereci/xm_interview/companytype"
 	"github.com/ngereci/xm_interview/env"
 	"github.com/ngereci/xm_interview/event"
+	"github.com/ngereci/xm_interview/middleware"
 	"github.com/ngereci/xm_interview/model"
+	"github.com/ngereci/xm_interview/relationship"
+	"github.com/ngereci/xm_interview/tenant"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"io"
@@ -21,6 +25,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func Setup(t *testing.T) *httptest.Server {
@@ -29,10 +34,9 @@ func Setup(t *testing.T) *httptest.Server {
 		t.Error(err)
 	}
 	viper.AutomaticEnv()
-	// Initialize the Cassandra session
-	cluster := gocql.NewCluster(viper.GetString(env.COMPANY_CASSANDRA_HOST))
-	cluster.Keyspace = viper.GetString(env.COMPANY_CASSANDRA_KEYSPACE)
-	cluster.Consistency = gocql.Quorum
+	// Initialize the Cassandra session, the test keyspace is created by the migrations
+	cluster := newCassandraCluster()
+	migrateCassandra(cluster)
 	session, err := cluster.CreateSession()
 
 	if err != nil {
@@ -46,16 +50,17 @@ func Setup(t *testing.T) *httptest.Server {
 
 	companyRepo := company.NewRepository(session)
 	// empty test keyspace
-	query := session.Query(`TRUNCATE companies_test.company;`)
+	query := session.Query(`TRUNCATE companies_test.company_by_tenant;`)
 	err = query.Exec()
 	if err != nil {
 		t.Error(err)
 	}
-	companyService := company.NewService(companyRepo, kafkaProducer)
+	relationships := relationship.NewService(relationship.NewRepository(session), companyRepo, kafkaProducer)
+	companyService := company.NewService(companyRepo, kafkaProducer, companytype.NewService(companytype.NewRepository(session), companyRepo), relationships)
 	companyController := company.NewController(companyService)
 
 	authController := auth.NewAuthController()
-	authMiddleware := auth.NewAuthMiddleware(viper.GetString(env.COMPANY_JWT_SECRET_KEY))
+	authMiddleware := auth.NewAuthMiddleware(viper.GetString(env.COMPANY_JWT_SECRET_KEY), nil)
 
 	router := gin.Default()
 
@@ -65,10 +70,12 @@ func Setup(t *testing.T) *httptest.Server {
 	apiRouter := router.Group("/api/v1")
 	apiRouter.Use(authMiddleware.Authenticate())
 	// Company routes
-	apiRouter.POST("/companies", companyController.CreateCompany)
-	apiRouter.PATCH("/companies/:id", companyController.UpdateCompany)
-	apiRouter.DELETE("/companies/:id", companyController.DeleteCompany)
-	apiRouter.GET("/companies/:id", companyController.GetCompany)
+	companyRouter := apiRouter.Group("/companies")
+	companyRouter.Use(middleware.Timeout(viper.GetDuration(env.COMPANY_REQUEST_TIMEOUT)))
+	companyRouter.POST("", companyController.CreateCompany)
+	companyRouter.PATCH("/:id", companyController.UpdateCompany)
+	companyRouter.DELETE("/:id", companyController.DeleteCompany)
+	companyRouter.GET("/:id", companyController.GetCompany)
 
 	return httptest.NewServer(router)
 }
@@ -152,6 +159,19 @@ func Test_Companies_CRUD(t *testing.T) {
 		assert.Equal(t, newCompany.Registered, responseCompany.Registered)
 		assert.Equal(t, companyUUID, responseCompany.ID)
 	})
+	t.Run("inserted item should NOT be available to another tenant", func(t *testing.T) {
+		otherToken, err := tenantToken("other-tenant")
+		assert.NoError(t, err)
+		for _, method := range []string{"GET", "PATCH", "DELETE"} {
+			req, err := http.NewRequest(method, fmt.Sprintf("%s/api/v1/companies/%v", server.URL, companyUUID), strings.NewReader(`{"name":"Stolen","employees":1,"type":"Corporation"}`))
+			assert.NoError(t, err)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", otherToken))
+			resp, err := http.DefaultClient.Do(req)
+			assert.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusNotFound, resp.StatusCode, method)
+		}
+	})
 	t.Run("it should successfully update", func(t *testing.T) {
 		requestBody, _ := json.Marshal(updatedCompany)
 		req, err := http.NewRequest("PATCH", fmt.Sprintf("%s/api/v1/companies/%v", server.URL, companyUUID), strings.NewReader(string(requestBody)))
@@ -250,3 +270,13 @@ func login(server *httptest.Server) (token string, err error) {
 	token = response.Token
 	return
 }
+
+// tenantToken signs a token of a user of tenantID, the login only knows users of the default tenant.
+func tenantToken(tenantID string) (string, error) {
+	claims := jwt.MapClaims{
+		"username":   "tenant-user",
+		tenant.Claim: tenantID,
+		"exp":        time.Now().Add(time.Minute).Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(viper.GetString(env.COMPANY_JWT_SECRET_KEY)))
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/ngereci/xm_interview/auth"
+	"github.com/ngereci/xm_interview/env"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"time"
+)
+
+// runOperatorToken implements the "operator-token" subcommand, which prints a token allowed to change the data shared
+// by all tenants, like the company types. It is signed with the configured key, tenant users can not obtain one.
+func runOperatorToken(args []string) {
+	flags := flag.NewFlagSet("operator-token", flag.ExitOnError)
+	username := flags.String("user", "operator", "name of the operator the token is issued to")
+	ttl := flags.Duration("ttl", time.Hour, "how long the token is valid")
+	if err := flags.Parse(args); err != nil {
+		log.Fatalf("Error parsing operator-token flags: %v", err)
+	}
+
+	token, err := auth.CreateOperatorToken(viper.GetString(env.COMPANY_JWT_SECRET_KEY), *username, *ttl)
+	if err != nil {
+		log.Fatalf("Error creating operator token: %v", err)
+	}
+	fmt.Println(token)
+}
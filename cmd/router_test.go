@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/ngereci/xm_interview/apikey"
+	"github.com/ngereci/xm_interview/auth"
+	"github.com/ngereci/xm_interview/company"
+	"github.com/ngereci/xm_interview/companytype"
+	"github.com/ngereci/xm_interview/env"
+	"github.com/ngereci/xm_interview/event"
+	"github.com/ngereci/xm_interview/health"
+	"github.com/ngereci/xm_interview/middleware"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/ngereci/xm_interview/openapi"
+	"github.com/ngereci/xm_interview/relationship"
+	"github.com/ngereci/xm_interview/search"
+	"github.com/ngereci/xm_interview/snapshot"
+	"github.com/ngereci/xm_interview/stream"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestRouter serves the API from memory with response validation on, so every response checked below
+// is also checked against the OpenAPI document.
+func newTestRouter(t *testing.T) *gin.Engine {
+	viper.SetConfigFile("../config/config_test.env")
+	require.NoError(t, viper.ReadInConfig())
+	viper.Set(env.COMPANY_RATE_LIMIT_LOGIN, 0)
+	viper.Set(env.COMPANY_RATE_LIMIT_WRITE, 0)
+	gin.SetMode(gin.TestMode)
+
+	doc, err := openapi.Load()
+	require.NoError(t, err)
+	openapiController, err := openapi.NewController(doc)
+	require.NoError(t, err)
+	companies := company.NewMemoryRepository()
+	broker := stream.NewBroker(100)
+	t.Cleanup(broker.Close)
+	searchIndex, err := search.NewIndex()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = searchIndex.Close() })
+	events := event.NewNotifyingAdapter(event.NewMemoryAdapter("companies", 100), broker, searchIndex)
+	apiKeyService := apikey.NewService(apikey.NewMemoryRepository())
+	companyTypeService := companytype.NewService(companytype.NewMemoryRepository(model.DefaultCompanyTypes()...), companies)
+	relationshipService := relationship.NewService(relationship.NewMemoryRepository(), companies, events)
+	companyService := company.NewService(companies, events, companyTypeService, relationshipService)
+	return newRouter(handlers{
+		health:            health.NewController(viper.GetDuration(env.COMPANY_HEALTH_CHECK_TIMEOUT), health.NewKafkaChecker(events)),
+		auth:              auth.NewAuthController(),
+		authMiddleware:    auth.NewAuthMiddleware(viper.GetString(env.COMPANY_JWT_SECRET_KEY), apiKeyService),
+		companies:         company.NewController(companyService),
+		companyTypes:      companytype.NewController(companyTypeService),
+		relationships:     relationship.NewController(relationshipService),
+		graphql:           company.NewGraphQLController(companyService, middleware.NewLimiter(viper.GetFloat64(env.COMPANY_RATE_LIMIT_WRITE), viper.GetInt(env.COMPANY_RATE_LIMIT_WRITE_BURST))),
+		streams:           stream.NewController(broker, time.Second),
+		search:            search.NewController(search.NewService(searchIndex, companies, companyService)),
+		snapshots:         snapshot.NewController(snapshot.NewService(companies, events)),
+		apiKeys:           apikey.NewController(apiKeyService),
+		openapi:           openapiController,
+		doc:               doc,
+		validateResponses: true,
+	})
+}
+
+// pathParam matches the parameters of gin paths like :id, which the OpenAPI document writes as {id}
+var pathParam = regexp.MustCompile(`:(\w+)`)
+
+func TestRouter_RoutesAreDocumented(t *testing.T) {
+	router := newTestRouter(t)
+	doc, err := openapi.Load()
+	require.NoError(t, err)
+
+	registered := map[string]bool{}
+	for _, route := range router.Routes() {
+		path := pathParam.ReplaceAllString(route.Path, "{$1}")
+		registered[route.Method+" "+path] = true
+		pathItem := doc.Paths.Find(path)
+		if assert.NotNil(t, pathItem, "route %v %v is not documented", route.Method, route.Path) {
+			assert.NotNil(t, pathItem.GetOperation(route.Method), "route %v %v is not documented", route.Method, route.Path)
+		}
+	}
+	for path, pathItem := range doc.Paths {
+		for method := range pathItem.Operations() {
+			assert.True(t, registered[method+" "+path], "documented operation %v %v is not registered", method, path)
+		}
+	}
+}
+
+func TestRouter_ResponsesMatchDocument(t *testing.T) {
+	router := newTestRouter(t)
+	serve := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) map[string]any {
+		var response map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), w.Body.String())
+		return response
+	}
+
+	for _, path := range []string{"/healthz", "/readyz", "/openapi.json", "/docs", "/metrics"} {
+		assert.Equal(t, http.StatusOK, serve("GET", path, "", "").Code, path)
+	}
+
+	w := serve("POST", "/api/v1/login", "", `{"username":"admin","password":"admin"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	token := decode(w)["token"].(string)
+	assert.Equal(t, http.StatusUnauthorized, serve("POST", "/api/v1/login", "", `{"username":"admin","password":"wrong"}`).Code)
+
+	w = serve("POST", "/api/v1/companies", token, `{"name":"Acme","employees":10,"registered":true,"type":"Corporation","country":"DE",`+
+		`"addresses":[{"type":"Headquarters","street":"Hauptstraße 1","city":"Berlin","country":"DE"}],"contacts":[{"name":"Jane Doe","phone":"+4930123456"}]}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	companyPath := fmt.Sprintf("/api/v1/companies/%v", decode(w)["id"])
+	assert.Equal(t, http.StatusOK, serve("GET", companyPath, token, "").Code)
+	assert.Equal(t, http.StatusOK, serve("PATCH", companyPath, token, `{"name":"Acme Ltd","employees":20,"type":"Cooperative"}`).Code)
+	assert.Equal(t, http.StatusConflict, serve("POST", "/api/v1/companies", token, `{"name":" ACME  ltd. ","employees":10,"type":"Corporation"}`).Code)
+	w = serve("POST", "/api/v1/companies", token, `{"name":"<b>Acme</b>","employees":10,"type":"Corporation"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "name", decode(w)["fields"].([]any)[0].(map[string]any)["field"])
+	assert.Equal(t, http.StatusUnauthorized, serve("GET", companyPath, "", "").Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, serve("GET", "/api/v1/companies/not-a-uuid", token, "").Code)
+
+	w = serve("GET", "/api/v1/companies/search?q=akme+LTD", token, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Len(t, decode(w)["results"], 1)
+	assert.Equal(t, http.StatusBadRequest, serve("GET", "/api/v1/companies/search", token, "").Code)
+	w = serve("POST", "/api/v1/admin/search-index/rebuild", token, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, float64(1), decode(w)["companies"])
+
+	companyID := strings.TrimPrefix(companyPath, "/api/v1/companies/")
+	query, err := json.Marshal(map[string]string{"query": `{ company(id: "` + companyID + `") { name } companyList { companies { id } hasNextPage } }`})
+	require.NoError(t, err)
+	w = serve("POST", "/graphql", token, string(query))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"data":{"company":{"name":"Acme Ltd"},"companyList":{"companies":[{"id":"`+companyID+`"}],"hasNextPage":false}}}`, w.Body.String())
+	assert.Equal(t, http.StatusUnauthorized, serve("POST", "/graphql", "", `{"query":"{ companyList { hasNextPage } }"}`).Code)
+
+	w = serve("POST", "/api/v1/admin/snapshots", token, `{"filter":{"types":["Cooperative"]}}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, true, decode(w)["done"])
+
+	w = serve("GET", "/api/v1/company-types", token, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"name":"SoleProprietorship"`)
+	assert.Equal(t, http.StatusBadRequest, serve("POST", "/api/v1/companies", token, `{"name":"Widgets","employees":10,"type":"Partnership"}`).Code)
+	// company types are shared by all tenants, tenant users can not change them
+	w = serve("POST", "/api/v1/admin/company-types", token, `{"name":"Partnership","requires_registration_number":true}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, http.StatusForbidden, serve("DELETE", "/api/v1/admin/company-types/Cooperative", token, "").Code)
+	operatorToken, err := auth.CreateOperatorToken(viper.GetString(env.COMPANY_JWT_SECRET_KEY), "operator", time.Minute)
+	require.NoError(t, err)
+	w = serve("POST", "/api/v1/admin/company-types", operatorToken, `{"name":"Partnership","requires_registration_number":true}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = serve("POST", "/api/v1/companies", token, `{"name":"Widgets","employees":10,"type":"Partnership"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "registration_number", decode(w)["fields"].([]any)[0].(map[string]any)["field"])
+	w = serve("PUT", "/api/v1/admin/company-types/Cooperative", operatorToken, `{"description":"Member owned","deprecated":true}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, http.StatusOK, serve("PATCH", companyPath, token, `{"name":"Acme Ltd","employees":30,"type":"Cooperative"}`).Code)
+	assert.Equal(t, http.StatusConflict, serve("DELETE", "/api/v1/admin/company-types/Cooperative", operatorToken, "").Code)
+	assert.Equal(t, http.StatusOK, serve("GET", "/api/v1/company-types/Cooperative", token, "").Code)
+
+	w = serve("POST", "/api/v1/companies", token, `{"name":"Acme Berlin","employees":5,"type":"Corporation"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	branchPath := fmt.Sprintf("/api/v1/companies/%v", decode(w)["id"])
+	w = serve("PUT", branchPath+"/parent", token, `{"parent_id":"`+companyID+`","type":"Branch"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, http.StatusBadRequest, serve("PUT", companyPath+"/parent", token, `{"parent_id":"`+strings.TrimPrefix(branchPath, "/api/v1/companies/")+`","type":"Subsidiary"}`).Code)
+	w = serve("GET", branchPath+"/ancestors?depth=3", token, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"name":"Acme Ltd"`)
+	w = serve("GET", companyPath+"/descendants", token, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"name":"Acme Berlin"`)
+	assert.Equal(t, http.StatusConflict, serve("DELETE", companyPath, token, "").Code)
+	assert.Equal(t, http.StatusOK, serve("DELETE", branchPath+"/parent", token, "").Code)
+	assert.Equal(t, http.StatusNotFound, serve("DELETE", branchPath+"/parent", token, "").Code)
+
+	w = serve("PUT", companyPath+"/tags/env", token, `{"value":"prod"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, map[string]any{"env": "prod"}, decode(w)["tags"])
+	w = serve("PUT", companyPath+"/tags/Env", token, `{"value":"prod"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "tags.Env", decode(w)["fields"].([]any)[0].(map[string]any)["field"])
+	assert.Equal(t, http.StatusOK, serve("PATCH", companyPath, token, `{"name":"Acme Ltd","employees":30,"type":"Corporation"}`).Code)
+	assert.Contains(t, serve("GET", companyPath, token, "").Body.String(), `"tags":{"env":"prod"}`)
+	query, err = json.Marshal(map[string]string{"query": `{ companyList(filter: {tags: [{key: "env", value: "prod"}]}) { companies { id } } }`})
+	require.NoError(t, err)
+	w = serve("POST", "/graphql", token, string(query))
+	assert.JSONEq(t, `{"data":{"companyList":{"companies":[{"id":"`+companyID+`"}]}}}`, w.Body.String())
+	w = serve("DELETE", companyPath+"/tags/env", token, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Nil(t, decode(w)["tags"])
+	assert.Equal(t, http.StatusNotFound, serve("DELETE", companyPath+"/tags/env", token, "").Code)
+
+	assert.Equal(t, http.StatusOK, serve("DELETE", companyPath, token, "").Code)
+	assert.Equal(t, http.StatusNotFound, serve("GET", companyPath, token, "").Code)
+	assert.Equal(t, http.StatusOK, serve("DELETE", "/api/v1/admin/company-types/Cooperative", operatorToken, "").Code)
+	assert.Equal(t, http.StatusNotFound, serve("GET", "/api/v1/company-types/Cooperative", token, "").Code)
+
+	w = serve("POST", "/api/v1/admin/api-keys", token, `{"name":"ci","scopes":["companies:read"]}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	keyPath := fmt.Sprintf("/api/v1/admin/api-keys/%v", decode(w)["id"])
+	assert.Equal(t, http.StatusOK, serve("GET", "/api/v1/admin/api-keys", token, "").Code)
+	assert.Equal(t, http.StatusOK, serve("DELETE", keyPath, token, "").Code)
+}
+
+func TestRouter_RejectsInvalidRequests(t *testing.T) {
+	router := newTestRouter(t)
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{name: "malformed company type", path: "/api/v1/companies", body: `{"name":"Acme","employees":10,"type":"non-profit"}`},
+		{name: "missing name", path: "/api/v1/companies", body: `{"employees":10,"type":"Corporation"}`},
+		{name: "employees not a number", path: "/api/v1/companies", body: `{"name":"Acme","employees":"ten","type":"Corporation"}`},
+		{name: "malformed JSON", path: "/api/v1/companies", body: `{"name":`},
+		{name: "login without password", path: "/api/v1/login", body: `{"username":"admin"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the body is checked before the credentials, a request without any is enough
+			req := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			body, err := io.ReadAll(w.Body)
+			require.NoError(t, err)
+			assert.Contains(t, string(body), `"error":"request body has an error`)
+		})
+	}
+}
+
+func TestRouter_StreamsChanges(t *testing.T) {
+	server := httptest.NewServer(newTestRouter(t))
+	defer server.Close()
+	login, err := http.Post(server.URL+"/api/v1/login", "application/json", strings.NewReader(`{"username":"admin","password":"admin"}`))
+	require.NoError(t, err)
+	var credentials map[string]string
+	require.NoError(t, json.NewDecoder(login.Body).Decode(&credentials))
+	_ = login.Body.Close()
+	send := func(ctx context.Context, method, path, body string) *http.Response {
+		req, err := http.NewRequestWithContext(ctx, method, server.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+credentials["token"])
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	// browsers open the stream with a ticket instead of the header
+	ticket := send(context.Background(), "POST", "/api/v1/companies/stream/tickets", "")
+	require.Equal(t, http.StatusOK, ticket.StatusCode)
+	var issued auth.StreamTicketResponse
+	require.NoError(t, json.NewDecoder(ticket.Body).Decode(&issued))
+	_ = ticket.Body.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	streamRequest, err := http.NewRequestWithContext(ctx, "GET", server.URL+"/api/v1/companies/stream?type=Corporation&ticket="+issued.Ticket, nil)
+	require.NoError(t, err)
+	events, err := http.DefaultClient.Do(streamRequest)
+	require.NoError(t, err)
+	defer events.Body.Close()
+	require.Equal(t, http.StatusOK, events.StatusCode)
+	assert.Equal(t, "text/event-stream", events.Header.Get("Content-Type"))
+
+	created := send(context.Background(), "POST", "/api/v1/companies", `{"name":"Acme","employees":10,"type":"Corporation"}`)
+	require.Equal(t, http.StatusCreated, created.StatusCode)
+	_ = created.Body.Close()
+
+	scanner := bufio.NewScanner(events.Body)
+	var lines []string
+	for len(lines) < 2 && scanner.Scan() {
+		if line := scanner.Text(); line != "" && !strings.HasPrefix(line, ":") {
+			lines = append(lines, line)
+		}
+	}
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], "id: "), lines[0])
+	var change stream.Change
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(lines[1], "data: ")), &change))
+	assert.Equal(t, event.EVENT_CREATE, change.Type)
+	assert.Equal(t, "Acme", change.Company.Name)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/env"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/ngereci/xm_interview/snapshot"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// runSnapshot implements the "snapshot" subcommand, which publishes a SNAPSHOT event for every
+// (filtered) company. Progress is checkpointed to a file after every page, so an interrupted
+// run continues where it stopped when started again with the same progress file, topic and filter.
+// The file is removed once the run is done.
+func runSnapshot(args []string) {
+	flags := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	topic := flags.String("topic", viper.GetString(env.COMPANY_SNAPSHOT_TOPIC), "topic the snapshot events are published to")
+	rate := flags.Float64("rate", viper.GetFloat64(env.COMPANY_SNAPSHOT_RATE), "maximum events per second, 0 for unlimited")
+	pageSize := flags.Int("page-size", viper.GetInt(env.COMPANY_SNAPSHOT_PAGE_SIZE), "number of companies read per page")
+	types := flags.String("types", "", "comma separated company types to publish, all types if empty")
+	ids := flags.String("ids", "", "comma separated company ids to publish, all companies if empty, requires -tenant")
+	tenantID := flags.String("tenant", "", "tenant whose companies are published, all tenants if empty")
+	progressFile := flags.String("progress", "snapshot.progress", "file the progress is checkpointed to")
+	if err := flags.Parse(args); err != nil {
+		log.Fatalf("Error parsing snapshot flags: %v", err)
+	}
+
+	request := &snapshot.Request{
+		Topic:    *topic,
+		Rate:     *rate,
+		PageSize: *pageSize,
+	}
+	request.Filter.TenantID = *tenantID
+	for _, t := range splitList(*types) {
+		request.Filter.Types = append(request.Filter.Types, model.CompanyType(t))
+	}
+	for _, id := range splitList(*ids) {
+		companyUuid, err := uuid.Parse(id)
+		if err != nil {
+			log.Fatalf("Invalid company id %v: %v", id, err)
+		}
+		request.Filter.IDs = append(request.Filter.IDs, companyUuid)
+	}
+	resume, err := snapshot.LoadProgress(*progressFile)
+	if err != nil {
+		log.Fatalf("Error reading snapshot progress: %v", err)
+	}
+	switch {
+	case resume != nil && resume.Done:
+		// left behind by a run that finished before the file was removed on completion
+		log.Printf("Previous snapshot in %s is done, starting a new one", *progressFile)
+	case resume != nil:
+		log.Printf("Resuming snapshot, %d companies already scanned", resume.Scanned)
+		request.Resume = resume
+	}
+
+	store := newStorage(false)
+	kafkaProducer := newEventAdapter()
+
+	service := snapshot.NewService(store.companies, kafkaProducer)
+	// an interrupted run stops at the next event, the progress up to the last page is kept
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	progress, err := service.Publish(ctx, request, snapshot.FileCheckpoint(*progressFile))
+	stop()
+	store.close()
+	if closeErr := kafkaProducer.Close(); closeErr != nil {
+		log.Printf("Error closing Kafka producer: %v", closeErr)
+	}
+	if errors.Is(err, snapshot.ErrProgressMismatch) {
+		log.Fatalf("Snapshot not resumed: %v, delete %s to start a new one", err, *progressFile)
+	}
+	if err != nil {
+		log.Fatalf("Snapshot interrupted after %d companies, rerun to resume: %v", progress.Scanned, err)
+	}
+	if err = snapshot.RemoveProgress(*progressFile); err != nil {
+		log.Printf("Error removing snapshot progress: %v", err)
+	}
+	log.Printf("Snapshot finished, %d companies scanned, %d events published to %s", progress.Scanned, progress.Published, request.Topic)
+}
+
+func splitList(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"database/sql"
+	"github.com/gocql/gocql"
+	_ "github.com/jackc/pgx/v5/stdlib" // registers the "pgx" database/sql driver
+	"github.com/ngereci/xm_interview/apikey"
+	"github.com/ngereci/xm_interview/company"
+	"github.com/ngereci/xm_interview/companytype"
+	"github.com/ngereci/xm_interview/db"
+	"github.com/ngereci/xm_interview/env"
+	"github.com/ngereci/xm_interview/health"
+	"github.com/ngereci/xm_interview/relationship"
+	"github.com/ngereci/xm_interview/tracing"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	bolt "go.etcd.io/bbolt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Storage backends selectable with COMPANY_STORAGE
+const (
+	storageCassandra = "cassandra"
+	storagePostgres  = "postgres"
+	storageEmbedded  = "embedded"
+)
+
+// storage holds the repositories of the configured storage backend
+type storage struct {
+	companies     company.Repository
+	companyTypes  companytype.Repository
+	relationships relationship.Repository
+	apiKeys       apikey.Repository
+	// checker is the readiness check of the storage, nil if it has none
+	checker health.Checker
+	// close closes the underlying connections
+	close func()
+}
+
+// newStorage creates the repositories on the configured storage, applying the pending
+// migrations first if migrate is set.
+func newStorage(migrate bool) *storage {
+	switch backend := viper.GetString(env.COMPANY_STORAGE); backend {
+	case storageCassandra, "":
+		cluster := newCassandraCluster()
+		if migrate && viper.GetBool(env.COMPANY_CASSANDRA_MIGRATE) {
+			migrateCassandra(cluster)
+		}
+		session := newCassandraSession(cluster)
+		return &storage{
+			companies:     company.NewRepository(session),
+			companyTypes:  companytype.NewRepository(session),
+			relationships: relationship.NewRepository(session),
+			apiKeys:       apikey.NewRepository(session),
+			checker:       health.NewCassandraChecker(session),
+			close:         session.Close,
+		}
+	case storagePostgres:
+		database := newPostgresDB()
+		if migrate && viper.GetBool(env.COMPANY_POSTGRES_MIGRATE) {
+			migratePostgres(database)
+		}
+		return &storage{
+			companies:     company.NewPostgresRepository(database),
+			companyTypes:  companytype.NewPostgresRepository(database),
+			relationships: relationship.NewPostgresRepository(database),
+			apiKeys:       apikey.NewPostgresRepository(database),
+			checker:       health.NewPostgresChecker(database),
+			close: func() {
+				if err := database.Close(); err != nil {
+					log.Printf("Error closing Postgres connection: %v", err)
+				}
+			},
+		}
+	case storageEmbedded:
+		database := newBoltDB()
+		companies, err := company.NewBoltRepository(database)
+		if err != nil {
+			log.Fatalf("Error initializing embedded storage: %v", err)
+		}
+		companyTypes, err := companytype.NewBoltRepository(database)
+		if err != nil {
+			log.Fatalf("Error initializing embedded storage: %v", err)
+		}
+		relationships, err := relationship.NewBoltRepository(database)
+		if err != nil {
+			log.Fatalf("Error initializing embedded storage: %v", err)
+		}
+		apiKeys, err := apikey.NewBoltRepository(database)
+		if err != nil {
+			log.Fatalf("Error initializing embedded storage: %v", err)
+		}
+		return &storage{
+			companies:     companies,
+			companyTypes:  companyTypes,
+			relationships: relationships,
+			apiKeys:       apiKeys,
+			close: func() {
+				if err := database.Close(); err != nil {
+					log.Printf("Error closing embedded storage: %v", err)
+				}
+			},
+		}
+	default:
+		log.Fatalf("Unknown storage %v", backend)
+		return nil
+	}
+}
+
+// runMigrate implements the "migrate" subcommand for the configured storage
+func runMigrate() {
+	switch storage := viper.GetString(env.COMPANY_STORAGE); storage {
+	case storageCassandra, "":
+		migrateCassandra(newCassandraCluster())
+	case storagePostgres:
+		database := newPostgresDB()
+		defer database.Close()
+		migratePostgres(database)
+	case storageEmbedded:
+		log.Printf("Embedded storage needs no migrations")
+	default:
+		log.Fatalf("Unknown storage %v", storage)
+	}
+}
+
+func newCassandraCluster() *gocql.ClusterConfig {
+	cluster := gocql.NewCluster(viper.GetString(env.COMPANY_CASSANDRA_HOST))
+	cluster.Keyspace = viper.GetString(env.COMPANY_CASSANDRA_KEYSPACE)
+	cluster.Consistency = gocql.Quorum
+	cluster.QueryObserver = tracing.NewCassandraObserver()
+	return cluster
+}
+
+// newCassandraSession initializes the Cassandra session
+func newCassandraSession(cluster *gocql.ClusterConfig) *gocql.Session {
+	session, err := cluster.CreateSession()
+
+	if err != nil {
+		log.Fatal("Failed to create Cassandra session: ", err)
+	}
+	return session
+}
+
+// migrateCassandra creates the keyspace and applies the pending schema migrations
+func migrateCassandra(cluster *gocql.ClusterConfig) {
+	if err := db.CreateKeyspace(cluster, viper.GetInt(env.COMPANY_CASSANDRA_REPLICATION_FACTOR)); err != nil {
+		log.Fatalf("Error creating Cassandra keyspace: %v", err)
+	}
+	session := newCassandraSession(cluster)
+	defer session.Close()
+	migrator, err := db.NewCassandraMigrator(session, viper.GetDuration(env.COMPANY_CASSANDRA_MIGRATION_LOCK_TIMEOUT))
+	if err != nil {
+		log.Fatalf("Error loading Cassandra migrations: %v", err)
+	}
+	if err = migrator.Migrate(); err != nil {
+		log.Fatalf("Error migrating Cassandra: %v", err)
+	}
+}
+
+func newPostgresDB() *sql.DB {
+	database, err := sql.Open("pgx", viper.GetString(env.COMPANY_POSTGRES_DSN))
+	if err != nil {
+		log.Fatalf("Error opening Postgres connection: %v", err)
+	}
+	database.SetMaxOpenConns(viper.GetInt(env.COMPANY_POSTGRES_MAX_OPEN_CONNS))
+	if err = database.Ping(); err != nil {
+		log.Fatalf("Error connecting to Postgres: %v", err)
+	}
+	return database
+}
+
+// migratePostgres applies the pending schema migrations
+func migratePostgres(database *sql.DB) {
+	migrator, err := db.NewPostgresMigrator(database)
+	if err != nil {
+		log.Fatalf("Error loading Postgres migrations: %v", err)
+	}
+	if err = migrator.Migrate(); err != nil {
+		log.Fatalf("Error migrating Postgres: %v", err)
+	}
+}
+
+// newBoltDB opens the embedded database file, creating it if needed
+func newBoltDB() *bolt.DB {
+	path := viper.GetString(env.COMPANY_EMBEDDED_PATH)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		log.Fatalf("Error creating embedded storage directory: %v", err)
+	}
+	database, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		log.Fatalf("Error opening embedded storage %v: %v", path, err)
+	}
+	log.Printf("Using embedded storage %s", path)
+	return database
+}
@@ -1,18 +1,30 @@
 package company
 
 import (
+	"context"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/logging"
 	"github.com/ngereci/xm_interview/model"
-	log "github.com/sirupsen/logrus"
 	"net/http"
 )
 
+// StatusClientClosedRequest is the non standard status of requests the client cancelled.
+const StatusClientClosedRequest = 499
+
+// TagRequest is the body of setting a tag, the key is the last segment of the path. Values may be empty.
+type TagRequest struct {
+	Value *string `json:"value" binding:"required"`
+}
+
 type Controller interface {
 	CreateCompany(ctx *gin.Context)
 	GetCompany(ctx *gin.Context)
 	UpdateCompany(ctx *gin.Context)
 	DeleteCompany(ctx *gin.Context)
+	SetTag(ctx *gin.Context)
+	RemoveTag(ctx *gin.Context)
 }
 
 type controller struct {
@@ -26,14 +38,13 @@ func NewController(service Service) Controller {
 func (c *controller) CreateCompany(ctx *gin.Context) {
 	var company model.Company
 
-	if err := ctx.ShouldBindJSON(&company); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindCompany(ctx, &company) {
 		return
 	}
-	createdCompany, err := c.service.CreateCompany(&company)
+	createdCompany, err := c.service.CreateCompany(ctx.Request.Context(), &company)
 
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(errorStatus(err), errorBody(err))
 		return
 	}
 	ctx.JSON(http.StatusCreated, createdCompany)
@@ -44,10 +55,10 @@ func (c *controller) GetCompany(ctx *gin.Context) {
 	if err != nil {
 		return
 	}
-	company, err := c.service.GetCompanyByID(*companyUuid)
+	company, err := c.service.GetCompanyByID(ctx.Request.Context(), *companyUuid)
 
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -65,14 +76,13 @@ func (c *controller) UpdateCompany(ctx *gin.Context) {
 		return
 	}
 	var company model.Company
-	if err := ctx.ShouldBindJSON(&company); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindCompany(ctx, &company) {
 		return
 	}
-	updatedCompany, err := c.service.UpdateCompany(*companyUuid, &company)
+	updatedCompany, err := c.service.UpdateCompany(ctx.Request.Context(), *companyUuid, &company)
 
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(errorStatus(err), errorBody(err))
 		return
 	}
 
@@ -89,21 +99,102 @@ func (c *controller) DeleteCompany(ctx *gin.Context) {
 	if err != nil {
 		return
 	}
-	err = c.service.DeleteCompany(*companyUuid)
+	err = c.service.DeleteCompany(ctx.Request.Context(), *companyUuid)
 
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{})
 }
 
+func (c *controller) SetTag(ctx *gin.Context) {
+	companyUuid, err := processUuid(ctx)
+	if err != nil {
+		return
+	}
+	var request TagRequest
+	if err = ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	company, err := c.service.SetTag(ctx.Request.Context(), *companyUuid, ctx.Param("key"), *request.Value)
+	if err != nil {
+		ctx.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, company)
+}
+
+func (c *controller) RemoveTag(ctx *gin.Context) {
+	companyUuid, err := processUuid(ctx)
+	if err != nil {
+		return
+	}
+	company, err := c.service.RemoveTag(ctx.Request.Context(), *companyUuid, ctx.Param("key"))
+	if err != nil {
+		ctx.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, company)
+}
+
+// bindCompany decodes the company of the request body and validates it, answering the request if that fails
+func bindCompany(ctx *gin.Context, company *model.Company) bool {
+	if err := ctx.ShouldBindJSON(company); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if err := company.Validate(); err != nil {
+		ctx.JSON(errorStatus(err), errorBody(err))
+		return false
+	}
+	return true
+}
+
+// errorBody is the response body of an error, invalid companies list their invalid fields
+func errorBody(err error) gin.H {
+	var invalid model.ErrInvalidCompany
+	if errors.As(err, &invalid) {
+		return gin.H{"error": err.Error(), "fields": invalid.Fields}
+	}
+	return gin.H{"error": err.Error()}
+}
+
+// errorStatus maps a service error to the response status. Requests cancelled by the client are
+// answered with 499 (client closed request, as introduced by nginx), requests running out of time with 504.
+// Companies of other tenants are missing as well, which answers them with 404 too. Companies with branches
+// can not be deleted, which conflicts with the state of the company like an existing name.
+func errorStatus(err error) int {
+	var (
+		notFound    model.ErrCompanyNotFound
+		tagNotFound model.ErrTagNotFound
+		exists      model.ErrCompanyExists
+		invalid     model.ErrInvalidCompany
+		branches    model.ErrCompanyHasBranches
+	)
+	switch {
+	case errors.As(err, &invalid):
+		return http.StatusBadRequest
+	case errors.As(err, &notFound), errors.As(err, &tagNotFound):
+		return http.StatusNotFound
+	case errors.As(err, &exists), errors.As(err, &branches):
+		return http.StatusConflict
+	case errors.Is(err, context.Canceled):
+		return StatusClientClosedRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 func processUuid(ctx *gin.Context) (*uuid.UUID, error) {
 	id := ctx.Param("id")
 	companyUuid, err := uuid.Parse(id)
 	if err != nil {
-		log.Warnf("id:%v UUID parse error:%v", id, err)
+		logging.FromContext(ctx.Request.Context()).Warnf("id:%v UUID parse error:%v", id, err)
 		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
 		return nil, err
 	}
@@ -1,8 +1,10 @@
 package company
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	mock_company_service "github.com/ngereci/xm_interview/mocks/mock_company/service"
 	"github.com/ngereci/xm_interview/model"
 	"net/http"
@@ -26,7 +28,7 @@ func TestController_CreateCompany(t *testing.T) {
 	// Test case: Successful creation
 	newCompany := &model.Company{Name: "Test Company", Employees: 100, Type: model.Corporation}
 	expectedCompany := &model.Company{Name: "Test Company", ID: uuid.New(), Type: model.Corporation}
-	mockService.EXPECT().CreateCompany(newCompany).Return(expectedCompany, nil)
+	mockService.EXPECT().CreateCompany(gomock.Any(), newCompany).Return(expectedCompany, nil)
 	// Create a test user
 	requestBody, _ := json.Marshal(newCompany)
 	w := httptest.NewRecorder()
@@ -49,7 +51,7 @@ func TestController_CreateCompany_InternalError(t *testing.T) {
 
 	newCompany := &model.Company{Name: "Test Company", Employees: 100, Type: model.Corporation}
 	// Test case: Failed creation due to service error
-	mockService.EXPECT().CreateCompany(newCompany).Return(nil, errors.New("Test error"))
+	mockService.EXPECT().CreateCompany(gomock.Any(), newCompany).Return(nil, errors.New("Test error"))
 	requestBody, _ := json.Marshal(newCompany)
 	w := httptest.NewRecorder()
 	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(requestBody)))
@@ -62,6 +64,27 @@ func TestController_CreateCompany_InternalError(t *testing.T) {
 	assert.JSONEq(t, `{"error":"Test error"}`, w.Body.String())
 }
 
+func TestController_CreateCompany_Exists(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mock_company_service.NewMockService(ctrl)
+	mockController := NewController(mockService)
+
+	newCompany := &model.Company{Name: "acme ltd ", Employees: 100, Type: model.Corporation}
+	mockService.EXPECT().CreateCompany(gomock.Any(), newCompany).Return(nil, model.ErrCompanyExists{Name: newCompany.Name})
+	requestBody, _ := json.Marshal(newCompany)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(requestBody)))
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = r
+
+	mockController.CreateCompany(ctx)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"error":"company acme ltd  exists"}`, w.Body.String())
+}
+
 func TestController_CreateCompany_ValidationError(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -80,10 +103,10 @@ func TestController_CreateCompany_ValidationError(t *testing.T) {
 	mockController.CreateCompany(ctx)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.JSONEq(t, `{"error":"Key: 'Company.Type' Error:Field validation for 'Type' failed on the 'required' tag"}`, w.Body.String())
+	assert.JSONEq(t, `{"error":"invalid company: type is required","fields":[{"field":"type","message":"is required"}]}`, w.Body.String())
 
 	// Test case 2: validation fail on employees
-	newCompany = &model.Company{Name: "Test Company", Type: model.Corporation}
+	newCompany = &model.Company{Name: "Test Company", Employees: -1, Type: model.Corporation}
 	requestBody, _ = json.Marshal(newCompany)
 	w = httptest.NewRecorder()
 	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(requestBody)))
@@ -93,7 +116,7 @@ func TestController_CreateCompany_ValidationError(t *testing.T) {
 	mockController.CreateCompany(ctx)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.JSONEq(t, `{"error":"Key: 'Company.Employees' Error:Field validation for 'Employees' failed on the 'required' tag"}`, w.Body.String())
+	assert.JSONEq(t, `{"error":"invalid company: employees must not be negative","fields":[{"field":"employees","message":"must not be negative"}]}`, w.Body.String())
 	// Test case 3: validation fail on name
 	newCompany = &model.Company{Employees: 100, Type: model.Corporation}
 	requestBody, _ = json.Marshal(newCompany)
@@ -105,7 +128,29 @@ func TestController_CreateCompany_ValidationError(t *testing.T) {
 	mockController.CreateCompany(ctx)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.JSONEq(t, `{"error":"Key: 'Company.Name' Error:Field validation for 'Name' failed on the 'required' tag"}`, w.Body.String())
+	assert.JSONEq(t, `{"error":"invalid company: name is required","fields":[{"field":"name","message":"is required"}]}`, w.Body.String())
+}
+
+func TestController_CreateCompany_InvalidDetails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mock_company_service.NewMockService(ctrl)
+	mockController := NewController(mockService)
+
+	newCompany := &model.Company{Name: "Test Company", Employees: 100, Type: model.Corporation,
+		Addresses: []model.Address{{Type: model.AddressBilling, Street: "Hauptstraße 1", City: "Berlin", Country: "XX"}}}
+	requestBody, _ := json.Marshal(newCompany)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(requestBody)))
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = r
+
+	mockController.CreateCompany(ctx)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"invalid company: addresses[0].country must be an ISO 3166-1 alpha-2 country code",`+
+		`"fields":[{"field":"addresses[0].country","message":"must be an ISO 3166-1 alpha-2 country code"}]}`, w.Body.String())
 }
 
 func TestController_GetCompany(t *testing.T) {
@@ -126,7 +171,7 @@ func TestController_GetCompany(t *testing.T) {
 	ctx, _ := gin.CreateTestContext(w)
 	ctx.Request = r
 	ctx.Params = gin.Params{{Key: "id", Value: companyID.String()}}
-	mockService.EXPECT().GetCompanyByID(companyID).Return(dummyCompany, nil)
+	mockService.EXPECT().GetCompanyByID(gomock.Any(), companyID).Return(dummyCompany, nil)
 	controller.GetCompany(ctx)
 	expectedJsonString, _ := json.Marshal(dummyCompany)
 	assert.Equal(t, http.StatusOK, w.Code)
@@ -147,7 +192,7 @@ func TestController_GetCompany_NotFound(t *testing.T) {
 	ctx, _ := gin.CreateTestContext(w)
 	ctx.Request = r
 	ctx.Params = gin.Params{{Key: "id", Value: companyID.String()}}
-	mockService.EXPECT().GetCompanyByID(companyID).Return(nil, nil)
+	mockService.EXPECT().GetCompanyByID(gomock.Any(), companyID).Return(nil, nil)
 	controller.GetCompany(ctx)
 	assert.Equal(t, http.StatusNotFound, w.Code)
 	assert.Contains(t, w.Body.String(), "company not found")
@@ -167,7 +212,7 @@ func TestController_GetCompany_Error(t *testing.T) {
 	ctx, _ := gin.CreateTestContext(w)
 	ctx.Request = r
 	ctx.Params = gin.Params{{Key: "id", Value: companyID.String()}}
-	mockService.EXPECT().GetCompanyByID(companyID).Return(nil, errors.New("something went wrong"))
+	mockService.EXPECT().GetCompanyByID(gomock.Any(), companyID).Return(nil, errors.New("something went wrong"))
 	controller.GetCompany(ctx)
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 	assert.Contains(t, w.Body.String(), "something went wrong")
@@ -184,7 +229,7 @@ func TestUpdateCompany(t *testing.T) {
 	companyID := uuid.New()
 	newCompany := &model.Company{Name: "Test Company", Employees: 100, Type: model.Corporation}
 	expectedCompany := &model.Company{Name: "Test Company", ID: companyID, Type: model.Corporation}
-	mockService.EXPECT().UpdateCompany(companyID, gomock.Any()).Return(&model.Company{ID: companyID, Name: "Test Company", Type: model.Corporation}, nil).Times(1)
+	mockService.EXPECT().UpdateCompany(gomock.Any(), companyID, gomock.Any()).Return(&model.Company{ID: companyID, Name: "Test Company", Type: model.Corporation}, nil).Times(1)
 	// Create a test user
 	requestBody, _ := json.Marshal(newCompany)
 	w := httptest.NewRecorder()
@@ -235,7 +280,7 @@ func TestUpdateCompany_ServiceError(t *testing.T) {
 	// Test case: Successful update
 	companyID := uuid.New()
 	newCompany := &model.Company{Name: "Test Company", Employees: 100, Type: model.Corporation}
-	mockService.EXPECT().UpdateCompany(companyID, gomock.Any()).Return(nil, errors.New("something went wrong")).Times(1)
+	mockService.EXPECT().UpdateCompany(gomock.Any(), companyID, gomock.Any()).Return(nil, errors.New("something went wrong")).Times(1)
 	// Create a test user
 	requestBody, _ := json.Marshal(newCompany)
 	w := httptest.NewRecorder()
@@ -259,7 +304,7 @@ func TestUpdateCompany_CompanyNotFound(t *testing.T) {
 	// Test case: Successful update
 	companyID := uuid.New()
 	newCompany := &model.Company{Name: "Test Company", Employees: 100, Type: model.Corporation}
-	mockService.EXPECT().UpdateCompany(companyID, gomock.Any()).Return(nil, nil).Times(1)
+	mockService.EXPECT().UpdateCompany(gomock.Any(), companyID, gomock.Any()).Return(nil, nil).Times(1)
 	// Create a test user
 	requestBody, _ := json.Marshal(newCompany)
 	w := httptest.NewRecorder()
@@ -283,7 +328,7 @@ func TestDeleteCompany(t *testing.T) {
 
 	// Test case: Successful update
 	companyID := uuid.New()
-	mockService.EXPECT().DeleteCompany(companyID).Return(nil).Times(1)
+	mockService.EXPECT().DeleteCompany(gomock.Any(), companyID).Return(nil).Times(1)
 	// Create a test user
 	w := httptest.NewRecorder()
 	r := httptest.NewRequest(http.MethodPatch, "/", nil)
@@ -305,7 +350,7 @@ func TestDeleteCompany_ServiceError(t *testing.T) {
 
 	// Test case: Successful update
 	companyID := uuid.New()
-	mockService.EXPECT().DeleteCompany(companyID).Return(errors.New("something went wrong")).Times(1)
+	mockService.EXPECT().DeleteCompany(gomock.Any(), companyID).Return(errors.New("something went wrong")).Times(1)
 	// Create a test user
 	w := httptest.NewRecorder()
 	r := httptest.NewRequest(http.MethodPatch, "/", nil)
@@ -319,6 +364,101 @@ func TestDeleteCompany_ServiceError(t *testing.T) {
 
 }
 
+func TestDeleteCompany_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mock_company_service.NewMockService(ctrl)
+	mockController := NewController(mockService)
+
+	// a company of another tenant is not found either
+	companyID := uuid.New()
+	mockService.EXPECT().DeleteCompany(gomock.Any(), companyID).Return(model.ErrCompanyNotFound{Id: companyID}).Times(1)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodDelete, "/", nil)
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = r
+	ctx.Params = gin.Params{{Key: "id", Value: companyID.String()}}
+
+	mockController.DeleteCompany(ctx)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"error":"company `+companyID.String()+` not found"}`, w.Body.String())
+}
+
+func TestDeleteCompany_HasBranches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mock_company_service.NewMockService(ctrl)
+	mockController := NewController(mockService)
+
+	companyID := uuid.New()
+	mockService.EXPECT().DeleteCompany(gomock.Any(), companyID).Return(model.ErrCompanyHasBranches{Id: companyID, Branches: 2}).Times(1)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodDelete, "/", nil)
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = r
+	ctx.Params = gin.Params{{Key: "id", Value: companyID.String()}}
+
+	mockController.DeleteCompany(ctx)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"error":"company `+companyID.String()+` has 2 branches, delete them first"}`, w.Body.String())
+}
+
+func TestController_SetTag(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mock_company_service.NewMockService(ctrl)
+	mockController := NewController(mockService)
+
+	companyID := uuid.New()
+	tagged := &model.Company{ID: companyID, Name: "Acme", Tags: map[string]string{"env": "prod"}}
+	mockService.EXPECT().SetTag(gomock.Any(), companyID, "env", "prod").Return(tagged, nil).Times(1)
+	invalid := model.ErrInvalidCompany{Fields: []model.FieldError{{Field: "tags.Env", Message: "key is invalid"}}}
+	mockService.EXPECT().SetTag(gomock.Any(), companyID, "Env", "").Return(nil, invalid).Times(1)
+
+	tests := []struct {
+		key    string
+		body   string
+		status int
+		want   string
+	}{
+		{key: "env", body: `{"value":"prod"}`, status: http.StatusOK, want: `"tags":{"env":"prod"}`},
+		{key: "Env", body: `{"value":""}`, status: http.StatusBadRequest, want: `"fields":[{"field":"tags.Env"`},
+		{key: "env", body: `{}`, status: http.StatusBadRequest, want: `"error"`},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(http.MethodPut, "/", strings.NewReader(tt.body))
+		ctx.Params = gin.Params{{Key: "id", Value: companyID.String()}, {Key: "key", Value: tt.key}}
+
+		mockController.SetTag(ctx)
+		assert.Equal(t, tt.status, w.Code, tt.body)
+		assert.Contains(t, w.Body.String(), tt.want)
+	}
+}
+
+func TestController_RemoveTag(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mock_company_service.NewMockService(ctrl)
+	mockController := NewController(mockService)
+
+	companyID := uuid.New()
+	mockService.EXPECT().RemoveTag(gomock.Any(), companyID, "env").Return(nil, model.ErrTagNotFound{Id: companyID, Key: "env"}).Times(1)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodDelete, "/", nil)
+	ctx.Params = gin.Params{{Key: "id", Value: companyID.String()}, {Key: "key", Value: "env"}}
+
+	mockController.RemoveTag(ctx)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"error":"company `+companyID.String()+` has no tag env"}`, w.Body.String())
+}
+
 func TestProcessUuid_Success(t *testing.T) {
 	// Prepare test case
 	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
@@ -348,3 +488,26 @@ func TestProcessUuid_Error(t *testing.T) {
 	assert.Error(t, err)
 	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 }
+
+func TestController_GetCompany_ContextErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mock_company_service.NewMockService(ctrl)
+	controller := NewController(mockService)
+
+	companyID := uuid.New()
+	for err, status := range map[error]int{
+		context.DeadlineExceeded: http.StatusGatewayTimeout,
+		context.Canceled:         StatusClientClosedRequest,
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = r
+		ctx.Params = gin.Params{{Key: "id", Value: companyID.String()}}
+		mockService.EXPECT().GetCompanyByID(gomock.Any(), companyID).Return(nil, fmt.Errorf("query failed: %w", err))
+		controller.GetCompany(ctx)
+		assert.Equal(t, status, w.Code)
+	}
+}
@@ -0,0 +1,686 @@
+package company
+
+import (
+	"context"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/graph-gophers/dataloader/v7"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/ngereci/xm_interview/auth"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/middleware"
+	"github.com/ngereci/xm_interview/model"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	maxGraphQLPageSize = 1000
+	// maxGraphQLDepth and maxGraphQLParallelism bound the work a single query can cause
+	maxGraphQLDepth       = 10
+	maxGraphQLParallelism = 10
+)
+
+// GraphQL error codes, returned in the code extension of the errors
+const (
+	graphqlNotFound      = "NOT_FOUND"
+	graphqlAlreadyExists = "ALREADY_EXISTS"
+	graphqlBadUserInput  = "BAD_USER_INPUT"
+	graphqlForbidden     = "FORBIDDEN"
+	graphqlConflict      = "CONFLICT"
+	graphqlTimeout       = "TIMEOUT"
+	graphqlRateLimited   = "RATE_LIMITED"
+	graphqlInternal      = "INTERNAL"
+)
+
+//go:embed schema.graphql
+var graphqlSchema string
+
+type GraphQLController interface {
+	Query(ctx *gin.Context)
+}
+
+type graphqlController struct {
+	service Service
+	schema  *graphql.Schema
+}
+
+// NewGraphQLController serves the GraphQL API of service, see schema.graphql. Companies looked up by id
+// during a request are loaded in batches with a single call to Service.GetCompaniesByIDs. Every mutation
+// takes a token of the user from writeLimit like a write of the REST API, a nil writeLimit does not limit them.
+func NewGraphQLController(service Service, writeLimit *middleware.Limiter) GraphQLController {
+	schema := graphql.MustParseSchema(graphqlSchema, &graphqlResolver{service: service, writeLimit: writeLimit},
+		graphql.MaxDepth(maxGraphQLDepth), graphql.MaxParallelism(maxGraphQLParallelism))
+	return &graphqlController{service: service, schema: schema}
+}
+
+type graphqlRequest struct {
+	Query         string         `json:"query" binding:"required"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// Query executes a GraphQL request. Like other GraphQL servers it answers 200 for every request it could execute,
+// errors of the query are returned in the errors of the response.
+func (c *graphqlController) Query(ctx *gin.Context) {
+	var request graphqlRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	requestCtx := withCompanyLoader(ctx.Request.Context(), c.service)
+	response := c.schema.Exec(requestCtx, request.Query, request.OperationName, request.Variables)
+	if len(response.Errors) > 0 {
+		logging.FromContext(requestCtx).Debugf("GraphQL errors:%v", response.Errors)
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+type loaderKey struct{}
+
+// withCompanyLoader returns a copy of ctx with a loader batching the company lookups by id of a request, it caches
+// the companies for the request only so that no request sees companies changed since it started.
+func withCompanyLoader(ctx context.Context, service Service) context.Context {
+	loader := dataloader.NewBatchedLoader(func(ctx context.Context, ids []uuid.UUID) []*dataloader.Result[*model.Company] {
+		results := make([]*dataloader.Result[*model.Company], len(ids))
+		companies, err := service.GetCompaniesByIDs(ctx, ids)
+		if err != nil {
+			for i := range results {
+				results[i] = &dataloader.Result[*model.Company]{Error: err}
+			}
+			return results
+		}
+		byID := make(map[uuid.UUID]*model.Company, len(companies))
+		for _, company := range companies {
+			byID[company.ID] = company
+		}
+		for i, id := range ids {
+			results[i] = &dataloader.Result[*model.Company]{Data: byID[id]}
+		}
+		return results
+	}, dataloader.WithBatchCapacity[uuid.UUID, *model.Company](maxGraphQLPageSize))
+	return context.WithValue(ctx, loaderKey{}, loader)
+}
+
+func companyLoader(ctx context.Context) *dataloader.Loader[uuid.UUID, *model.Company] {
+	return ctx.Value(loaderKey{}).(*dataloader.Loader[uuid.UUID, *model.Company])
+}
+
+// graphqlError is an error of a GraphQL response with a code extension clients can tell errors apart by,
+// errors of invalid companies list the invalid fields in a fields extension and rate limited mutations
+// the seconds to wait in a retryAfter extension
+type graphqlError struct {
+	message    string
+	code       string
+	fields     []model.FieldError
+	retryAfter int
+}
+
+func (e graphqlError) Error() string {
+	return e.message
+}
+
+func (e graphqlError) Extensions() map[string]any {
+	if e.fields != nil {
+		return map[string]any{"code": e.code, "fields": e.fields}
+	}
+	if e.retryAfter > 0 {
+		return map[string]any{"code": e.code, "retryAfter": e.retryAfter}
+	}
+	return map[string]any{"code": e.code}
+}
+
+func badUserInput(format string, args ...any) error {
+	return graphqlError{message: fmt.Sprintf(format, args...), code: graphqlBadUserInput}
+}
+
+// toGraphQLError maps a service error to the code of the error like errorStatus does to the status of the REST API
+func toGraphQLError(err error) error {
+	var (
+		notFound     model.ErrCompanyNotFound
+		tagNotFound  model.ErrTagNotFound
+		exists       model.ErrCompanyExists
+		missingScope auth.ErrMissingScope
+		invalid      model.ErrInvalidCompany
+		branches     model.ErrCompanyHasBranches
+	)
+	switch {
+	case errors.As(err, &invalid):
+		return graphqlError{message: err.Error(), code: graphqlBadUserInput, fields: invalid.Fields}
+	case errors.As(err, &notFound), errors.As(err, &tagNotFound):
+		return graphqlError{message: err.Error(), code: graphqlNotFound}
+	case errors.As(err, &exists):
+		return graphqlError{message: err.Error(), code: graphqlAlreadyExists}
+	case errors.As(err, &missingScope):
+		return graphqlError{message: err.Error(), code: graphqlForbidden}
+	case errors.As(err, &branches):
+		return graphqlError{message: err.Error(), code: graphqlConflict}
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return graphqlError{message: err.Error(), code: graphqlTimeout}
+	default:
+		return graphqlError{message: err.Error(), code: graphqlInternal}
+	}
+}
+
+func parseGraphQLID(ctx context.Context, id graphql.ID) (uuid.UUID, error) {
+	companyUuid, err := uuid.Parse(string(id))
+	if err != nil {
+		logging.FromContext(ctx).Warnf("id:%v UUID parse error:%v", id, err)
+		return uuid.Nil, badUserInput("invalid id %v", id)
+	}
+	return companyUuid, nil
+}
+
+type graphqlResolver struct {
+	service    Service
+	writeLimit *middleware.Limiter
+}
+
+// checkWrite checks the scope of a mutation and takes a token of the user from the write limit
+func (r *graphqlResolver) checkWrite(ctx context.Context) error {
+	if err := auth.CheckScope(ctx, model.ScopeCompaniesWrite); err != nil {
+		return toGraphQLError(err)
+	}
+	if delay := r.writeLimit.Reserve(middleware.ContextUserKey(ctx)); delay > 0 {
+		return graphqlError{message: "rate limit exceeded", code: graphqlRateLimited, retryAfter: int(math.Ceil(delay.Seconds()))}
+	}
+	return nil
+}
+
+func (r *graphqlResolver) Company(ctx context.Context, args struct{ ID graphql.ID }) (*companyResolver, error) {
+	if err := auth.CheckScope(ctx, model.ScopeCompaniesRead); err != nil {
+		return nil, toGraphQLError(err)
+	}
+	id, err := parseGraphQLID(ctx, args.ID)
+	if err != nil {
+		return nil, err
+	}
+	company, err := companyLoader(ctx).Load(ctx, id)()
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+	if company == nil {
+		return nil, nil
+	}
+	return &companyResolver{company: company}, nil
+}
+
+func (r *graphqlResolver) Companies(ctx context.Context, args struct{ IDs []graphql.ID }) ([]*companyResolver, error) {
+	if err := auth.CheckScope(ctx, model.ScopeCompaniesRead); err != nil {
+		return nil, toGraphQLError(err)
+	}
+	if len(args.IDs) > maxGraphQLPageSize {
+		return nil, badUserInput("at most %v ids can be looked up at once", maxGraphQLPageSize)
+	}
+	ids := make([]uuid.UUID, 0, len(args.IDs))
+	for _, arg := range args.IDs {
+		id, err := parseGraphQLID(ctx, arg)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	companies, errs := companyLoader(ctx).LoadMany(ctx, ids)()
+	for _, err := range errs {
+		if err != nil {
+			return nil, toGraphQLError(err)
+		}
+	}
+	resolvers := make([]*companyResolver, len(companies))
+	for i, company := range companies {
+		if company != nil {
+			resolvers[i] = &companyResolver{company: company}
+		}
+	}
+	return resolvers, nil
+}
+
+type companyFilter struct {
+	Types        *[]string
+	Registered   *bool
+	NameContains *string
+	Tags         *[]tagFilter
+}
+
+type tagFilter struct {
+	Key   string
+	Value *string
+}
+
+func (f *companyFilter) matches(company *model.Company) bool {
+	if f == nil {
+		return true
+	}
+	if f.Types != nil && !containsType(*f.Types, company.Type) {
+		return false
+	}
+	if f.Registered != nil && company.Registered != *f.Registered {
+		return false
+	}
+	if f.NameContains != nil && !strings.Contains(strings.ToLower(company.Name), strings.ToLower(*f.NameContains)) {
+		return false
+	}
+	if f.Tags != nil {
+		for _, tag := range *f.Tags {
+			if !company.HasTag(tag.Key, tag.Value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func containsType(types []string, companyType model.CompanyType) bool {
+	for _, t := range types {
+		if model.CompanyType(t) == companyType {
+			return true
+		}
+	}
+	return false
+}
+
+func (c listCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeListCursor(cursor string) (listCursor, error) {
+	var decoded listCursor
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		err = json.Unmarshal(data, &decoded)
+	}
+	if err != nil || decoded.Skip < 0 || decoded.PageSize < 1 || decoded.PageSize > maxGraphQLPageSize {
+		return listCursor{}, badUserInput("invalid cursor")
+	}
+	return decoded, nil
+}
+
+type companyListArgs struct {
+	First  int32
+	After  *string
+	Filter *companyFilter
+}
+
+// CompanyList pages through the companies with Service.ListCompanies, filtering them on the way, see listMatching.
+// Without a filter every page of the service is a page of the list.
+func (r *graphqlResolver) CompanyList(ctx context.Context, args companyListArgs) (*companyPageResolver, error) {
+	if err := auth.CheckScope(ctx, model.ScopeCompaniesRead); err != nil {
+		return nil, toGraphQLError(err)
+	}
+	if args.First < 1 || args.First > maxGraphQLPageSize {
+		return nil, badUserInput("first must be between 1 and %v", maxGraphQLPageSize)
+	}
+	cursor := listCursor{PageSize: int(args.First)}
+	if args.After != nil {
+		var err error
+		if cursor, err = decodeListCursor(*args.After); err != nil {
+			return nil, err
+		}
+	}
+
+	companies, next, err := listMatching(ctx, r.service, cursor, int(args.First), args.Filter.matches)
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+	page := &companyPageResolver{companies: make([]*companyResolver, 0, len(companies))}
+	for _, company := range companies {
+		page.companies = append(page.companies, &companyResolver{company: company})
+	}
+	if next != nil {
+		endCursor := next.encode()
+		page.endCursor = &endCursor
+	}
+	return page, nil
+}
+
+type companyInput struct {
+	Name               string
+	Description        *string
+	Employees          int32
+	Registered         *bool
+	Type               string
+	RegistrationNumber *string
+	Country            *string
+	VatNumber          *string
+	Lei                *string
+	Website            *string
+	Addresses          *[]addressInput
+	Contacts           *[]contactInput
+	Tags               *[]tagInput
+}
+
+type addressInput struct {
+	Type       string
+	Street     string
+	City       string
+	PostalCode *string
+	Region     *string
+	Country    string
+}
+
+type tagInput struct {
+	Key   string
+	Value string
+}
+
+type contactInput struct {
+	Name  string
+	Role  *string
+	Email *string
+	Phone *string
+}
+
+func (i companyInput) company() (*model.Company, error) {
+	company := &model.Company{
+		Name:               i.Name,
+		Description:        valueOf(i.Description),
+		Employees:          int(i.Employees),
+		Type:               model.CompanyType(i.Type),
+		RegistrationNumber: valueOf(i.RegistrationNumber),
+		Country:            valueOf(i.Country),
+		VATNumber:          valueOf(i.VatNumber),
+		LEI:                valueOf(i.Lei),
+		Website:            valueOf(i.Website),
+	}
+	if i.Registered != nil {
+		company.Registered = *i.Registered
+	}
+	if i.Addresses != nil {
+		for _, address := range *i.Addresses {
+			company.Addresses = append(company.Addresses, model.Address{
+				Type:       model.AddressType(address.Type),
+				Street:     address.Street,
+				City:       address.City,
+				PostalCode: valueOf(address.PostalCode),
+				Region:     valueOf(address.Region),
+				Country:    address.Country,
+			})
+		}
+	}
+	if i.Contacts != nil {
+		for _, contact := range *i.Contacts {
+			company.Contacts = append(company.Contacts, model.Contact{
+				Name:  contact.Name,
+				Role:  valueOf(contact.Role),
+				Email: valueOf(contact.Email),
+				Phone: valueOf(contact.Phone),
+			})
+		}
+	}
+	if i.Tags != nil && len(*i.Tags) > 0 {
+		company.Tags = make(map[string]string, len(*i.Tags))
+		for _, tag := range *i.Tags {
+			company.Tags[tag.Key] = tag.Value
+		}
+	}
+	if err := company.Validate(); err != nil {
+		return nil, toGraphQLError(err)
+	}
+	return company, nil
+}
+
+// valueOf returns the value of an optional string argument, an omitted argument is empty
+func valueOf(argument *string) string {
+	if argument == nil {
+		return ""
+	}
+	return *argument
+}
+
+func (r *graphqlResolver) CreateCompany(ctx context.Context, args struct{ Input companyInput }) (*companyResolver, error) {
+	if err := r.checkWrite(ctx); err != nil {
+		return nil, err
+	}
+	company, err := args.Input.company()
+	if err != nil {
+		return nil, err
+	}
+	createdCompany, err := r.service.CreateCompany(ctx, company)
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+	return &companyResolver{company: createdCompany}, nil
+}
+
+func (r *graphqlResolver) UpdateCompany(ctx context.Context, args struct {
+	ID    graphql.ID
+	Input companyInput
+}) (*companyResolver, error) {
+	if err := r.checkWrite(ctx); err != nil {
+		return nil, err
+	}
+	id, err := parseGraphQLID(ctx, args.ID)
+	if err != nil {
+		return nil, err
+	}
+	company, err := args.Input.company()
+	if err != nil {
+		return nil, err
+	}
+	updatedCompany, err := r.service.UpdateCompany(ctx, id, company)
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+	if updatedCompany == nil {
+		return nil, toGraphQLError(model.ErrCompanyNotFound{Id: id})
+	}
+	return &companyResolver{company: updatedCompany}, nil
+}
+
+func (r *graphqlResolver) DeleteCompany(ctx context.Context, args struct{ ID graphql.ID }) (graphql.ID, error) {
+	if err := r.checkWrite(ctx); err != nil {
+		return "", err
+	}
+	id, err := parseGraphQLID(ctx, args.ID)
+	if err != nil {
+		return "", err
+	}
+	if err = r.service.DeleteCompany(ctx, id); err != nil {
+		return "", toGraphQLError(err)
+	}
+	return args.ID, nil
+}
+
+func (r *graphqlResolver) SetCompanyTag(ctx context.Context, args struct {
+	ID    graphql.ID
+	Key   string
+	Value string
+}) (*companyResolver, error) {
+	if err := r.checkWrite(ctx); err != nil {
+		return nil, err
+	}
+	id, err := parseGraphQLID(ctx, args.ID)
+	if err != nil {
+		return nil, err
+	}
+	company, err := r.service.SetTag(ctx, id, args.Key, args.Value)
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+	return &companyResolver{company: company}, nil
+}
+
+func (r *graphqlResolver) RemoveCompanyTag(ctx context.Context, args struct {
+	ID  graphql.ID
+	Key string
+}) (*companyResolver, error) {
+	if err := r.checkWrite(ctx); err != nil {
+		return nil, err
+	}
+	id, err := parseGraphQLID(ctx, args.ID)
+	if err != nil {
+		return nil, err
+	}
+	company, err := r.service.RemoveTag(ctx, id, args.Key)
+	if err != nil {
+		return nil, toGraphQLError(err)
+	}
+	return &companyResolver{company: company}, nil
+}
+
+type companyPageResolver struct {
+	companies []*companyResolver
+	endCursor *string
+}
+
+func (p *companyPageResolver) Companies() []*companyResolver {
+	return p.companies
+}
+
+func (p *companyPageResolver) EndCursor() *string {
+	return p.endCursor
+}
+
+func (p *companyPageResolver) HasNextPage() bool {
+	return p.endCursor != nil
+}
+
+type companyResolver struct {
+	company *model.Company
+}
+
+func (c *companyResolver) ID() graphql.ID {
+	return graphql.ID(c.company.ID.String())
+}
+
+func (c *companyResolver) TenantID() string {
+	return c.company.TenantID
+}
+
+func (c *companyResolver) Name() string {
+	return c.company.Name
+}
+
+func (c *companyResolver) Description() string {
+	return c.company.Description
+}
+
+func (c *companyResolver) Employees() int32 {
+	return int32(c.company.Employees)
+}
+
+func (c *companyResolver) Registered() bool {
+	return c.company.Registered
+}
+
+func (c *companyResolver) Type() string {
+	return string(c.company.Type)
+}
+
+func (c *companyResolver) RegistrationNumber() string {
+	return c.company.RegistrationNumber
+}
+
+func (c *companyResolver) Country() string {
+	return c.company.Country
+}
+
+func (c *companyResolver) VatNumber() string {
+	return c.company.VATNumber
+}
+
+func (c *companyResolver) Lei() string {
+	return c.company.LEI
+}
+
+func (c *companyResolver) Website() string {
+	return c.company.Website
+}
+
+func (c *companyResolver) Addresses() []*addressResolver {
+	resolvers := make([]*addressResolver, 0, len(c.company.Addresses))
+	for i := range c.company.Addresses {
+		resolvers = append(resolvers, &addressResolver{address: &c.company.Addresses[i]})
+	}
+	return resolvers
+}
+
+func (c *companyResolver) Contacts() []*contactResolver {
+	resolvers := make([]*contactResolver, 0, len(c.company.Contacts))
+	for i := range c.company.Contacts {
+		resolvers = append(resolvers, &contactResolver{contact: &c.company.Contacts[i]})
+	}
+	return resolvers
+}
+
+func (c *companyResolver) Tags() []*tagResolver {
+	keys := make([]string, 0, len(c.company.Tags))
+	for key := range c.company.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	resolvers := make([]*tagResolver, 0, len(keys))
+	for _, key := range keys {
+		resolvers = append(resolvers, &tagResolver{key: key, value: c.company.Tags[key]})
+	}
+	return resolvers
+}
+
+type addressResolver struct {
+	address *model.Address
+}
+
+func (a *addressResolver) Type() string {
+	return string(a.address.Type)
+}
+
+func (a *addressResolver) Street() string {
+	return a.address.Street
+}
+
+func (a *addressResolver) City() string {
+	return a.address.City
+}
+
+func (a *addressResolver) PostalCode() string {
+	return a.address.PostalCode
+}
+
+func (a *addressResolver) Region() string {
+	return a.address.Region
+}
+
+func (a *addressResolver) Country() string {
+	return a.address.Country
+}
+
+type contactResolver struct {
+	contact *model.Contact
+}
+
+func (c *contactResolver) Name() string {
+	return c.contact.Name
+}
+
+func (c *contactResolver) Role() string {
+	return c.contact.Role
+}
+
+func (c *contactResolver) Email() string {
+	return c.contact.Email
+}
+
+func (c *contactResolver) Phone() string {
+	return c.contact.Phone
+}
+
+type tagResolver struct {
+	key   string
+	value string
+}
+
+func (t *tagResolver) Key() string {
+	return t.key
+}
+
+func (t *tagResolver) Value() string {
+	return t.value
+}
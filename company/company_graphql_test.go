@@ -0,0 +1,347 @@
+package company
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/auth"
+	"github.com/ngereci/xm_interview/middleware"
+	mock_company_service "github.com/ngereci/xm_interview/mocks/mock_company/service"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type graphqlResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string `json:"message"`
+		Extensions struct {
+			Code       string             `json:"code"`
+			Fields     []model.FieldError `json:"fields"`
+			RetryAfter int                `json:"retryAfter"`
+		} `json:"extensions"`
+	} `json:"errors"`
+}
+
+// queryGraphQL executes query with variables against a controller of mockService in the context ctx
+func queryGraphQL(t *testing.T, ctx context.Context, mockService Service, query string, variables map[string]any) graphqlResponse {
+	return queryController(t, ctx, NewGraphQLController(mockService, nil), query, variables)
+}
+
+// queryController executes query with variables against controller in the context ctx
+func queryController(t *testing.T, ctx context.Context, controller GraphQLController, query string, variables map[string]any) graphqlResponse {
+	body, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))).WithContext(ctx)
+
+	controller.Query(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response graphqlResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return response
+}
+
+func errorCodes(response graphqlResponse) []string {
+	var codes []string
+	for _, err := range response.Errors {
+		codes = append(codes, err.Extensions.Code)
+	}
+	return codes
+}
+
+func TestGraphQLController_Company(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockService := mock_company_service.NewMockService(ctrl)
+	mockService.EXPECT().GetCompaniesByIDs(gomock.Any(), []uuid.UUID{testCompany.ID}).Return([]*model.Company{testCompany}, nil)
+
+	response := queryGraphQL(t, context.Background(), mockService, `query($id: ID!) { company(id: $id) { id tenantId name employees type } }`,
+		map[string]any{"id": testCompany.ID.String()})
+
+	assert.Empty(t, response.Errors)
+	assert.JSONEq(t, `{"id":"`+testCompany.ID.String()+`","tenantId":"`+testTenant+`","name":"Test Company","employees":100,"type":"Corporation"}`,
+		string(response.Data["company"]))
+}
+
+func TestGraphQLController_Company_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockService := mock_company_service.NewMockService(ctrl)
+	mockService.EXPECT().GetCompaniesByIDs(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+	response := queryGraphQL(t, context.Background(), mockService, `{ company(id: "`+testCompany.ID.String()+`") { id } }`, nil)
+
+	assert.Empty(t, response.Errors)
+	assert.Equal(t, "null", string(response.Data["company"]))
+}
+
+func TestGraphQLController_Company_InvalidID(t *testing.T) {
+	response := queryGraphQL(t, context.Background(), mock_company_service.NewMockService(gomock.NewController(t)), `{ company(id: "not-a-uuid") { id } }`, nil)
+
+	assert.Equal(t, []string{graphqlBadUserInput}, errorCodes(response))
+}
+
+func TestGraphQLController_Batching(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockService := mock_company_service.NewMockService(ctrl)
+	missingID := uuid.New()
+	// all the lookups of a request are loaded with a single call, each id once
+	mockService.EXPECT().GetCompaniesByIDs(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, ids []uuid.UUID) ([]*model.Company, error) {
+		assert.ElementsMatch(t, []uuid.UUID{testCompany.ID, missingID}, ids)
+		return []*model.Company{testCompany}, nil
+	})
+
+	response := queryGraphQL(t, context.Background(), mockService, `query($id: ID!, $ids: [ID!]!) {
+		a: company(id: $id) { name }
+		b: company(id: $id) { name }
+		companies(ids: $ids) { id }
+	}`, map[string]any{"id": testCompany.ID.String(), "ids": []string{missingID.String(), testCompany.ID.String()}})
+
+	assert.Empty(t, response.Errors)
+	assert.JSONEq(t, `{"name":"Test Company"}`, string(response.Data["a"]))
+	assert.JSONEq(t, `{"name":"Test Company"}`, string(response.Data["b"]))
+	assert.JSONEq(t, `[null,{"id":"`+testCompany.ID.String()+`"}]`, string(response.Data["companies"]))
+}
+
+func TestGraphQLController_Batching_Error(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockService := mock_company_service.NewMockService(ctrl)
+	mockService.EXPECT().GetCompaniesByIDs(gomock.Any(), gomock.Any()).Return(nil, testErr)
+
+	response := queryGraphQL(t, context.Background(), mockService, `{ company(id: "`+testCompany.ID.String()+`") { id } }`, nil)
+
+	assert.Equal(t, []string{graphqlInternal}, errorCodes(response))
+}
+
+func TestGraphQLController_CompanyList(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockService := mock_company_service.NewMockService(ctrl)
+	corporation := func(name string) *model.Company {
+		return &model.Company{ID: uuid.New(), Name: name, Employees: 1, Type: model.Corporation}
+	}
+	nonProfit := &model.Company{ID: uuid.New(), Name: "Charity", Employees: 1, Type: model.NonProfit}
+	firstPage := []*model.Company{corporation("Acme"), corporation("Initech"), nonProfit}
+	secondPage := []*model.Company{corporation("Globex")}
+	// the first list ends in the middle of the first page, which the second list reads again
+	mockService.EXPECT().ListCompanies(gomock.Any(), []byte(nil), 2).Return(firstPage, []byte("second"), nil).Times(2)
+	mockService.EXPECT().ListCompanies(gomock.Any(), []byte("second"), 2).Return(secondPage, nil, nil)
+	query := `query($after: String) {
+		companyList(first: 2, after: $after, filter: {types: ["Corporation"]}) { companies { name } endCursor hasNextPage }
+	}`
+	type page struct {
+		Companies   []struct{ Name string }
+		EndCursor   *string
+		HasNextPage bool
+	}
+	names := func(p page) []string {
+		var names []string
+		for _, company := range p.Companies {
+			names = append(names, company.Name)
+		}
+		return names
+	}
+
+	response := queryGraphQL(t, context.Background(), mockService, query, nil)
+	require.Empty(t, response.Errors)
+	var first page
+	require.NoError(t, json.Unmarshal(response.Data["companyList"], &first))
+	assert.Equal(t, []string{"Acme", "Initech"}, names(first))
+	assert.True(t, first.HasNextPage)
+	require.NotNil(t, first.EndCursor)
+
+	response = queryGraphQL(t, context.Background(), mockService, query, map[string]any{"after": *first.EndCursor})
+	require.Empty(t, response.Errors)
+	var second page
+	require.NoError(t, json.Unmarshal(response.Data["companyList"], &second))
+	assert.Equal(t, []string{"Globex"}, names(second))
+	assert.False(t, second.HasNextPage)
+	assert.Nil(t, second.EndCursor)
+}
+
+func TestGraphQLController_CompanyList_ScanLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockService := mock_company_service.NewMockService(ctrl)
+	nonProfit := []*model.Company{{ID: uuid.New(), Name: "Charity", Employees: 1, Type: model.NonProfit}}
+	mockService.EXPECT().ListCompanies(gomock.Any(), gomock.Any(), 5).Return(nonProfit, []byte("next"), nil).Times(maxListPages)
+
+	response := queryGraphQL(t, context.Background(), mockService,
+		`{ companyList(first: 5, filter: {types: ["Corporation"]}) { companies { name } endCursor hasNextPage } }`, nil)
+
+	require.Empty(t, response.Errors)
+	var page struct {
+		Companies   []struct{ Name string }
+		EndCursor   *string
+		HasNextPage bool
+	}
+	require.NoError(t, json.Unmarshal(response.Data["companyList"], &page))
+	assert.Empty(t, page.Companies)
+	assert.True(t, page.HasNextPage)
+	require.NotNil(t, page.EndCursor)
+	cursor, err := decodeListCursor(*page.EndCursor)
+	require.NoError(t, err)
+	assert.Equal(t, listCursor{PageState: []byte("next"), PageSize: 5}, cursor)
+}
+
+func TestGraphQLController_CompanyList_Invalid(t *testing.T) {
+	mockService := mock_company_service.NewMockService(gomock.NewController(t))
+
+	response := queryGraphQL(t, context.Background(), mockService, `{ companyList(first: 0) { hasNextPage } }`, nil)
+	assert.Equal(t, []string{graphqlBadUserInput}, errorCodes(response))
+
+	response = queryGraphQL(t, context.Background(), mockService, `{ companyList(after: "not-a-cursor") { hasNextPage } }`, nil)
+	assert.Equal(t, []string{graphqlBadUserInput}, errorCodes(response))
+}
+
+func TestGraphQLController_Mutations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockService := mock_company_service.NewMockService(ctrl)
+	mockService.EXPECT().CreateCompany(gomock.Any(), &model.Company{Name: "Test Company", Employees: 100, Type: model.Corporation}).Return(testCompany, nil)
+	mockService.EXPECT().UpdateCompany(gomock.Any(), testCompany.ID, &model.Company{Name: "Test Company", Description: "Updated", Employees: 100, Registered: true, Type: model.NonProfit}).
+		Return(nil, model.ErrCompanyNotFound{Id: testCompany.ID})
+	mockService.EXPECT().DeleteCompany(gomock.Any(), testCompany.ID).Return(nil)
+
+	response := queryGraphQL(t, context.Background(), mockService, `mutation { createCompany(input: {name: "Test Company", employees: 100, type: "Corporation"}) { id } }`, nil)
+	assert.Empty(t, response.Errors)
+	assert.JSONEq(t, `{"id":"`+testCompany.ID.String()+`"}`, string(response.Data["createCompany"]))
+
+	response = queryGraphQL(t, context.Background(), mockService, `mutation($id: ID!) {
+		updateCompany(id: $id, input: {name: "Test Company", description: "Updated", employees: 100, registered: true, type: "NonProfit"}) { id }
+	}`, map[string]any{"id": testCompany.ID.String()})
+	assert.Equal(t, []string{graphqlNotFound}, errorCodes(response))
+
+	response = queryGraphQL(t, context.Background(), mockService, `mutation($id: ID!) { deleteCompany(id: $id) }`, map[string]any{"id": testCompany.ID.String()})
+	assert.Empty(t, response.Errors)
+	assert.Equal(t, `"`+testCompany.ID.String()+`"`, string(response.Data["deleteCompany"]))
+
+	response = queryGraphQL(t, context.Background(), mockService, `mutation { createCompany(input: {name: "Test Company", employees: 100, type: "non-profit"}) { id } }`, nil)
+	assert.Equal(t, []string{graphqlBadUserInput}, errorCodes(response))
+}
+
+func TestGraphQLController_Mutations_RateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockService := mock_company_service.NewMockService(ctrl)
+	mockService.EXPECT().DeleteCompany(gomock.Any(), testCompany.ID).Return(nil)
+	controller := NewGraphQLController(mockService, middleware.NewLimiter(0.5, 1))
+	mutation := `mutation { deleteCompany(id: "` + testCompany.ID.String() + `") }`
+
+	response := queryController(t, context.Background(), controller, mutation, nil)
+	assert.Empty(t, response.Errors)
+	response = queryController(t, context.Background(), controller, mutation, nil)
+	assert.Equal(t, []string{graphqlRateLimited}, errorCodes(response))
+	assert.Equal(t, 2, response.Errors[0].Extensions.RetryAfter)
+}
+
+func TestGraphQLController_Details(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockService := mock_company_service.NewMockService(ctrl)
+	company := &model.Company{
+		Name:      "Test Company",
+		Employees: 100,
+		Type:      model.Corporation,
+		Country:   "DE",
+		LEI:       "5299000J2N45DDNE4Y28",
+		Addresses: []model.Address{{Type: model.AddressRegistered, Street: "Unter den Linden 1", City: "Berlin", Country: "DE"}},
+		Contacts:  []model.Contact{{Name: "Jane Doe", Email: "jane@example.com"}},
+	}
+	created := *company
+	created.ID = testCompany.ID
+	mockService.EXPECT().CreateCompany(gomock.Any(), company).Return(&created, nil)
+
+	response := queryGraphQL(t, context.Background(), mockService, `mutation {
+		createCompany(input: {name: "Test Company", employees: 100, type: "Corporation", country: "DE", lei: "5299000J2N45DDNE4Y28",
+			addresses: [{type: "Registered", street: "Unter den Linden 1", city: "Berlin", country: "DE"}],
+			contacts: [{name: "Jane Doe", email: "jane@example.com"}]}) {
+			country lei addresses { type city postalCode } contacts { name email phone }
+		}
+	}`, nil)
+	assert.Empty(t, response.Errors)
+	assert.JSONEq(t, `{"country":"DE","lei":"5299000J2N45DDNE4Y28","addresses":[{"type":"Registered","city":"Berlin","postalCode":""}],`+
+		`"contacts":[{"name":"Jane Doe","email":"jane@example.com","phone":""}]}`, string(response.Data["createCompany"]))
+
+	response = queryGraphQL(t, context.Background(), mockService, `mutation {
+		createCompany(input: {name: "Test Company", employees: 100, type: "Corporation", contacts: [{name: "Jane Doe", phone: "030 123456"}]}) { id }
+	}`, nil)
+	assert.Equal(t, []string{graphqlBadUserInput}, errorCodes(response))
+	assert.Equal(t, []model.FieldError{{Field: "contacts[0].phone", Message: "must be a phone number in E.164 format like +4930123456"}},
+		response.Errors[0].Extensions.Fields)
+}
+
+func TestGraphQLController_Tags(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockService := mock_company_service.NewMockService(ctrl)
+	company := &model.Company{Name: "Test Company", Employees: 100, Type: model.Corporation, Tags: map[string]string{"team": "payments", "env": "prod"}}
+	created := *company
+	created.ID = testCompany.ID
+	mockService.EXPECT().CreateCompany(gomock.Any(), company).Return(&created, nil)
+	mockService.EXPECT().SetTag(gomock.Any(), testCompany.ID, "vip", "").Return(&created, nil)
+	mockService.EXPECT().RemoveTag(gomock.Any(), testCompany.ID, "owner").Return(nil, model.ErrTagNotFound{Id: testCompany.ID, Key: "owner"})
+
+	response := queryGraphQL(t, context.Background(), mockService, `mutation {
+		createCompany(input: {name: "Test Company", employees: 100, type: "Corporation",
+			tags: [{key: "team", value: "payments"}, {key: "env", value: "prod"}]}) { tags { key value } }
+	}`, nil)
+	assert.Empty(t, response.Errors)
+	assert.JSONEq(t, `{"tags":[{"key":"env","value":"prod"},{"key":"team","value":"payments"}]}`, string(response.Data["createCompany"]))
+
+	variables := map[string]any{"id": testCompany.ID.String()}
+	response = queryGraphQL(t, context.Background(), mockService, `mutation($id: ID!) { setCompanyTag(id: $id, key: "vip", value: "") { id } }`, variables)
+	assert.Empty(t, response.Errors)
+	response = queryGraphQL(t, context.Background(), mockService, `mutation($id: ID!) { removeCompanyTag(id: $id, key: "owner") { id } }`, variables)
+	assert.Equal(t, []string{graphqlNotFound}, errorCodes(response))
+}
+
+func TestGraphQLController_CompanyList_TagFilter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockService := mock_company_service.NewMockService(ctrl)
+	tagged := func(name string, tags map[string]string) *model.Company {
+		return &model.Company{ID: uuid.New(), Name: name, Employees: 1, Type: model.Corporation, Tags: tags}
+	}
+	companies := []*model.Company{
+		tagged("Acme", map[string]string{"env": "prod", "team": "payments"}),
+		tagged("Initech", map[string]string{"env": "staging", "team": "payments"}),
+		tagged("Globex", map[string]string{"env": "prod"}),
+		tagged("Umbrella", nil),
+	}
+	mockService.EXPECT().ListCompanies(gomock.Any(), []byte(nil), 10).Return(companies, nil, nil).Times(2)
+	query := `query($filter: CompanyFilter) { companyList(first: 10, filter: $filter) { companies { name } } }`
+
+	response := queryGraphQL(t, context.Background(), mockService, query,
+		map[string]any{"filter": map[string]any{"tags": []any{map[string]any{"key": "env", "value": "prod"}, map[string]any{"key": "team"}}}})
+	assert.Empty(t, response.Errors)
+	assert.JSONEq(t, `{"companies":[{"name":"Acme"}]}`, string(response.Data["companyList"]))
+
+	response = queryGraphQL(t, context.Background(), mockService, query,
+		map[string]any{"filter": map[string]any{"tags": []any{map[string]any{"key": "team"}}}})
+	assert.Empty(t, response.Errors)
+	assert.JSONEq(t, `{"companies":[{"name":"Acme"},{"name":"Initech"}]}`, string(response.Data["companyList"]))
+}
+
+func TestGraphQLController_Scopes(t *testing.T) {
+	mockService := mock_company_service.NewMockService(gomock.NewController(t))
+	ctx := auth.WithAPIKey(context.Background(), &model.APIKey{Scopes: []model.Scope{model.ScopeCompaniesRead}})
+	mockService.EXPECT().ListCompanies(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil, nil)
+
+	response := queryGraphQL(t, ctx, mockService, `{ companyList { hasNextPage } }`, nil)
+	assert.Empty(t, response.Errors)
+
+	response = queryGraphQL(t, ctx, mockService, `mutation { deleteCompany(id: "`+testCompany.ID.String()+`") }`, nil)
+	assert.Equal(t, []string{graphqlForbidden}, errorCodes(response))
+}
+
+func TestGraphQLController_InvalidRequest(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"variables":{}}`))
+
+	NewGraphQLController(mock_company_service.NewMockService(gomock.NewController(t)), nil).Query(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,320 @@
+package company
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/companypb"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/model"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultGRPCPageSize = 100
+	maxGRPCPageSize     = 1000
+)
+
+// GRPCScopes are the scopes API keys need for the methods of the gRPC service, see auth.AuthMiddleware.UnaryInterceptor
+var GRPCScopes = map[string]model.Scope{
+	companypb.CompanyService_CreateCompany_FullMethodName:    model.ScopeCompaniesWrite,
+	companypb.CompanyService_GetCompany_FullMethodName:       model.ScopeCompaniesRead,
+	companypb.CompanyService_UpdateCompany_FullMethodName:    model.ScopeCompaniesWrite,
+	companypb.CompanyService_DeleteCompany_FullMethodName:    model.ScopeCompaniesWrite,
+	companypb.CompanyService_ListCompanies_FullMethodName:    model.ScopeCompaniesRead,
+	companypb.CompanyService_SetCompanyTag_FullMethodName:    model.ScopeCompaniesWrite,
+	companypb.CompanyService_RemoveCompanyTag_FullMethodName: model.ScopeCompaniesWrite,
+}
+
+type grpcServer struct {
+	companypb.UnimplementedCompanyServiceServer
+	service Service
+}
+
+// NewGRPCServer serves the gRPC API of service, it is the counterpart of the Controller of the REST API.
+func NewGRPCServer(service Service) companypb.CompanyServiceServer {
+	return &grpcServer{service: service}
+}
+
+func (s *grpcServer) CreateCompany(ctx context.Context, request *companypb.CreateCompanyRequest) (*companypb.Company, error) {
+	company, err := companyFromInput(request.GetCompany())
+	if err != nil {
+		return nil, err
+	}
+	createdCompany, err := s.service.CreateCompany(ctx, company)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return companyToProto(createdCompany), nil
+}
+
+func (s *grpcServer) GetCompany(ctx context.Context, request *companypb.GetCompanyRequest) (*companypb.Company, error) {
+	id, err := parseGRPCID(ctx, request.GetId())
+	if err != nil {
+		return nil, err
+	}
+	company, err := s.service.GetCompanyByID(ctx, id)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if company == nil {
+		return nil, status.Error(codes.NotFound, "company not found")
+	}
+	return companyToProto(company), nil
+}
+
+func (s *grpcServer) UpdateCompany(ctx context.Context, request *companypb.UpdateCompanyRequest) (*companypb.Company, error) {
+	id, err := parseGRPCID(ctx, request.GetId())
+	if err != nil {
+		return nil, err
+	}
+	company, err := companyFromInput(request.GetCompany())
+	if err != nil {
+		return nil, err
+	}
+	updatedCompany, err := s.service.UpdateCompany(ctx, id, company)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if updatedCompany == nil {
+		return nil, status.Error(codes.NotFound, "company not found")
+	}
+	return companyToProto(updatedCompany), nil
+}
+
+func (s *grpcServer) DeleteCompany(ctx context.Context, request *companypb.DeleteCompanyRequest) (*companypb.DeleteCompanyResponse, error) {
+	id, err := parseGRPCID(ctx, request.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if err = s.service.DeleteCompany(ctx, id); err != nil {
+		return nil, grpcError(err)
+	}
+	return &companypb.DeleteCompanyResponse{}, nil
+}
+
+func (s *grpcServer) ListCompanies(ctx context.Context, request *companypb.ListCompaniesRequest) (*companypb.ListCompaniesResponse, error) {
+	pageSize := int(request.GetPageSize())
+	switch {
+	case pageSize < 0:
+		return nil, status.Error(codes.InvalidArgument, "page_size must not be negative")
+	case pageSize == 0:
+		pageSize = defaultGRPCPageSize
+	case pageSize > maxGRPCPageSize:
+		pageSize = maxGRPCPageSize
+	}
+	if len(request.GetTags()) > 0 {
+		return s.listTagged(ctx, request, pageSize)
+	}
+	companies, nextPageState, err := s.service.ListCompanies(ctx, request.GetPageToken(), pageSize)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	response := &companypb.ListCompaniesResponse{
+		Companies:     make([]*companypb.Company, 0, len(companies)),
+		NextPageToken: nextPageState,
+	}
+	for _, company := range companies {
+		response.Companies = append(response.Companies, companyToProto(company))
+	}
+	return response, nil
+}
+
+// listTagged lists the companies having the tags of the request with listMatching, its page tokens are listCursors
+// in JSON since pages can end in the middle of a page of the service
+func (s *grpcServer) listTagged(ctx context.Context, request *companypb.ListCompaniesRequest, pageSize int) (*companypb.ListCompaniesResponse, error) {
+	cursor := listCursor{PageSize: pageSize}
+	if len(request.GetPageToken()) > 0 {
+		err := json.Unmarshal(request.GetPageToken(), &cursor)
+		if err != nil || cursor.Skip < 0 || cursor.PageSize < 1 || cursor.PageSize > maxGRPCPageSize {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+	}
+	companies, next, err := listMatching(ctx, s.service, cursor, pageSize, func(company *model.Company) bool {
+		return hasTags(company, request.GetTags())
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	response := &companypb.ListCompaniesResponse{Companies: make([]*companypb.Company, 0, len(companies))}
+	for _, company := range companies {
+		response.Companies = append(response.Companies, companyToProto(company))
+	}
+	if next != nil {
+		response.NextPageToken, _ = json.Marshal(next)
+	}
+	return response, nil
+}
+
+// hasTags returns whether the company matches all of the tag filters
+func hasTags(company *model.Company, tags []*companypb.TagFilter) bool {
+	for _, tag := range tags {
+		if !company.HasTag(tag.GetKey(), tag.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *grpcServer) SetCompanyTag(ctx context.Context, request *companypb.SetCompanyTagRequest) (*companypb.Company, error) {
+	id, err := parseGRPCID(ctx, request.GetId())
+	if err != nil {
+		return nil, err
+	}
+	company, err := s.service.SetTag(ctx, id, request.GetKey(), request.GetValue())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return companyToProto(company), nil
+}
+
+func (s *grpcServer) RemoveCompanyTag(ctx context.Context, request *companypb.RemoveCompanyTagRequest) (*companypb.Company, error) {
+	id, err := parseGRPCID(ctx, request.GetId())
+	if err != nil {
+		return nil, err
+	}
+	company, err := s.service.RemoveTag(ctx, id, request.GetKey())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return companyToProto(company), nil
+}
+
+// grpcError maps a service error to the status of the call like errorStatus does for the REST API
+func grpcError(err error) error {
+	var (
+		notFound    model.ErrCompanyNotFound
+		tagNotFound model.ErrTagNotFound
+		exists      model.ErrCompanyExists
+		invalid     model.ErrInvalidCompany
+		branches    model.ErrCompanyHasBranches
+	)
+	switch {
+	case errors.As(err, &invalid):
+		return invalidArgument(invalid)
+	case errors.As(err, &notFound), errors.As(err, &tagNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.As(err, &exists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.As(err, &branches):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// invalidArgument answers an invalid company with its invalid fields as BadRequest details
+func invalidArgument(invalid model.ErrInvalidCompany) error {
+	badRequest := &errdetails.BadRequest{}
+	for _, field := range invalid.Fields {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       field.Field,
+			Description: field.Message,
+		})
+	}
+	withDetails, err := status.New(codes.InvalidArgument, invalid.Error()).WithDetails(badRequest)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, invalid.Error())
+	}
+	return withDetails.Err()
+}
+
+// parseGRPCID parses the id of a company, the REST API answers malformed ids with 422
+func parseGRPCID(ctx context.Context, id string) (uuid.UUID, error) {
+	companyUuid, err := uuid.Parse(id)
+	if err != nil {
+		logging.FromContext(ctx).Warnf("id:%v UUID parse error:%v", id, err)
+		return uuid.Nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return companyUuid, nil
+}
+
+// companyFromInput converts the input of a call to a company and validates it
+func companyFromInput(input *companypb.CompanyInput) (*model.Company, error) {
+	if input == nil {
+		return nil, status.Error(codes.InvalidArgument, "company is required")
+	}
+	company := &model.Company{
+		Name:               input.GetName(),
+		Description:        input.GetDescription(),
+		Employees:          int(input.GetEmployees()),
+		Registered:         input.GetRegistered(),
+		Type:               model.CompanyType(input.GetType()),
+		RegistrationNumber: input.GetRegistrationNumber(),
+		Country:            input.GetCountry(),
+		VATNumber:          input.GetVatNumber(),
+		LEI:                input.GetLei(),
+		Website:            input.GetWebsite(),
+	}
+	if len(input.GetTags()) > 0 {
+		company.Tags = input.GetTags()
+	}
+	for _, address := range input.GetAddresses() {
+		company.Addresses = append(company.Addresses, model.Address{
+			Type:       model.AddressType(address.GetType()),
+			Street:     address.GetStreet(),
+			City:       address.GetCity(),
+			PostalCode: address.GetPostalCode(),
+			Region:     address.GetRegion(),
+			Country:    address.GetCountry(),
+		})
+	}
+	for _, contact := range input.GetContacts() {
+		company.Contacts = append(company.Contacts, model.Contact{
+			Name:  contact.GetName(),
+			Role:  contact.GetRole(),
+			Email: contact.GetEmail(),
+			Phone: contact.GetPhone(),
+		})
+	}
+	if err := company.Validate(); err != nil {
+		return nil, grpcError(err)
+	}
+	return company, nil
+}
+
+func companyToProto(company *model.Company) *companypb.Company {
+	protoCompany := &companypb.Company{
+		Id:                 company.ID.String(),
+		TenantId:           company.TenantID,
+		Name:               company.Name,
+		Description:        company.Description,
+		Employees:          int64(company.Employees),
+		Registered:         company.Registered,
+		Type:               string(company.Type),
+		RegistrationNumber: company.RegistrationNumber,
+		Country:            company.Country,
+		VatNumber:          company.VATNumber,
+		Lei:                company.LEI,
+		Website:            company.Website,
+		Addresses:          make([]*companypb.Address, 0, len(company.Addresses)),
+		Contacts:           make([]*companypb.Contact, 0, len(company.Contacts)),
+		Tags:               company.Tags,
+	}
+	for _, address := range company.Addresses {
+		protoCompany.Addresses = append(protoCompany.Addresses, &companypb.Address{
+			Type:       string(address.Type),
+			Street:     address.Street,
+			City:       address.City,
+			PostalCode: address.PostalCode,
+			Region:     address.Region,
+			Country:    address.Country,
+		})
+	}
+	for _, contact := range company.Contacts {
+		protoCompany.Contacts = append(protoCompany.Contacts, &companypb.Contact{
+			Name:  contact.Name,
+			Role:  contact.Role,
+			Email: contact.Email,
+			Phone: contact.Phone,
+		})
+	}
+	return protoCompany
+}
@@ -0,0 +1,282 @@
+package company
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/companypb"
+	mock_company_service "github.com/ngereci/xm_interview/mocks/mock_company/service"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"testing"
+)
+
+// newTestGRPCClient serves the gRPC API of a mocked service in memory
+func newTestGRPCClient(t *testing.T) (*mock_company_service.MockService, companypb.CompanyServiceClient) {
+	ctrl := gomock.NewController(t)
+	mockService := mock_company_service.NewMockService(ctrl)
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	companypb.RegisterCompanyServiceServer(server, NewGRPCServer(mockService))
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return mockService, companypb.NewCompanyServiceClient(conn)
+}
+
+func TestGRPCServer_CreateCompany(t *testing.T) {
+	mockService, client := newTestGRPCClient(t)
+	input := &companypb.CompanyInput{Name: "Test Company", Description: "Test Description", Employees: 100, Type: "Corporation"}
+	mockService.EXPECT().CreateCompany(gomock.Any(), &model.Company{Name: "Test Company", Description: "Test Description", Employees: 100, Type: model.Corporation}).Return(testCompany, nil)
+
+	company, err := client.CreateCompany(context.Background(), &companypb.CreateCompanyRequest{Company: input})
+
+	require.NoError(t, err)
+	assert.Equal(t, testCompany.ID.String(), company.Id)
+	assert.Equal(t, testTenant, company.TenantId)
+	assert.Equal(t, testCompany.Name, company.Name)
+	assert.Equal(t, int64(testCompany.Employees), company.Employees)
+	assert.Equal(t, string(testCompany.Type), company.Type)
+}
+
+func TestGRPCServer_CreateCompany_Details(t *testing.T) {
+	mockService, client := newTestGRPCClient(t)
+	input := &companypb.CompanyInput{
+		Name: "Test Company", Employees: 100, Type: "Corporation",
+		RegistrationNumber: "HRB 12345", Country: "DE", VatNumber: "DE123456789", Lei: "5299000J2N45DDNE4Y28", Website: "https://example.com",
+		Addresses: []*companypb.Address{{Type: "Registered", Street: "Hauptstraße 1", City: "Berlin", PostalCode: "10115", Country: "DE"}},
+		Contacts:  []*companypb.Contact{{Name: "Jane Doe", Role: "CFO", Email: "jane@example.com", Phone: "+4930123456"}},
+	}
+	detailed := &model.Company{
+		Name: "Test Company", Employees: 100, Type: model.Corporation,
+		RegistrationNumber: "HRB 12345", Country: "DE", VATNumber: "DE123456789", LEI: "5299000J2N45DDNE4Y28", Website: "https://example.com",
+		Addresses: []model.Address{{Type: model.AddressRegistered, Street: "Hauptstraße 1", City: "Berlin", PostalCode: "10115", Country: "DE"}},
+		Contacts:  []model.Contact{{Name: "Jane Doe", Role: "CFO", Email: "jane@example.com", Phone: "+4930123456"}},
+	}
+	mockService.EXPECT().CreateCompany(gomock.Any(), detailed).DoAndReturn(func(_ context.Context, company *model.Company) (*model.Company, error) {
+		company.ID = testCompany.ID
+		return company, nil
+	})
+
+	company, err := client.CreateCompany(context.Background(), &companypb.CreateCompanyRequest{Company: input})
+
+	require.NoError(t, err)
+	assert.Equal(t, input.VatNumber, company.VatNumber)
+	assert.Equal(t, input.Lei, company.Lei)
+	assert.Equal(t, input.Website, company.Website)
+	require.Len(t, company.Addresses, 1)
+	assert.Equal(t, "Berlin", company.Addresses[0].City)
+	require.Len(t, company.Contacts, 1)
+	assert.Equal(t, "+4930123456", company.Contacts[0].Phone)
+}
+
+func TestGRPCServer_CreateCompany_InvalidArgument(t *testing.T) {
+	_, client := newTestGRPCClient(t)
+	tests := []struct {
+		name  string
+		input *companypb.CompanyInput
+	}{
+		{name: "missing company"},
+		{name: "missing name", input: &companypb.CompanyInput{Employees: 1, Type: "Corporation"}},
+		{name: "missing type", input: &companypb.CompanyInput{Name: "Test Company", Employees: 1}},
+		{name: "malformed type", input: &companypb.CompanyInput{Name: "Test Company", Employees: 1, Type: "non-profit"}},
+		{name: "unknown country", input: &companypb.CompanyInput{Name: "Test Company", Employees: 1, Type: "Corporation", Country: "XX"}},
+		{name: "malformed LEI", input: &companypb.CompanyInput{Name: "Test Company", Employees: 1, Type: "Corporation", Lei: "lei"}},
+		{name: "address without city", input: &companypb.CompanyInput{Name: "Test Company", Employees: 1, Type: "Corporation",
+			Addresses: []*companypb.Address{{Type: "Registered", Street: "Hauptstraße 1", Country: "DE"}}}},
+		{name: "malformed email", input: &companypb.CompanyInput{Name: "Test Company", Employees: 1, Type: "Corporation",
+			Contacts: []*companypb.Contact{{Name: "Jane Doe", Email: "jane"}}}},
+		{name: "negative employees", input: &companypb.CompanyInput{Name: "Test Company", Employees: -1, Type: "Corporation"}},
+		{name: "markup in name", input: &companypb.CompanyInput{Name: "<script>", Employees: 1, Type: "Corporation"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.CreateCompany(context.Background(), &companypb.CreateCompanyRequest{Company: tt.input})
+
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+}
+
+func TestGRPCServer_CreateCompany_FieldViolations(t *testing.T) {
+	_, client := newTestGRPCClient(t)
+
+	_, err := client.CreateCompany(context.Background(), &companypb.CreateCompanyRequest{
+		Company: &companypb.CompanyInput{Name: "Test Company", Employees: -1, Type: "Corporation", Country: "XX"}})
+
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	details := status.Convert(err).Details()
+	require.Len(t, details, 1)
+	badRequest, ok := details[0].(*errdetails.BadRequest)
+	require.True(t, ok)
+	var fields []string
+	for _, violation := range badRequest.GetFieldViolations() {
+		fields = append(fields, violation.GetField())
+	}
+	assert.Equal(t, []string{"country", "employees"}, fields)
+}
+
+func TestGRPCServer_CreateCompany_AlreadyExists(t *testing.T) {
+	mockService, client := newTestGRPCClient(t)
+	mockService.EXPECT().CreateCompany(gomock.Any(), gomock.Any()).Return(nil, model.ErrCompanyExists{Name: "Test Company"})
+
+	_, err := client.CreateCompany(context.Background(), &companypb.CreateCompanyRequest{Company: &companypb.CompanyInput{Name: "Test Company", Employees: 1, Type: "Corporation"}})
+
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+}
+
+func TestGRPCServer_GetCompany(t *testing.T) {
+	mockService, client := newTestGRPCClient(t)
+	mockService.EXPECT().GetCompanyByID(gomock.Any(), testCompany.ID).Return(testCompany, nil)
+
+	company, err := client.GetCompany(context.Background(), &companypb.GetCompanyRequest{Id: testCompany.ID.String()})
+
+	require.NoError(t, err)
+	assert.Equal(t, testCompany.ID.String(), company.Id)
+}
+
+func TestGRPCServer_GetCompany_Errors(t *testing.T) {
+	mockService, client := newTestGRPCClient(t)
+
+	_, err := client.GetCompany(context.Background(), &companypb.GetCompanyRequest{Id: "not-a-uuid"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	mockService.EXPECT().GetCompanyByID(gomock.Any(), testCompany.ID).Return(nil, nil)
+	_, err = client.GetCompany(context.Background(), &companypb.GetCompanyRequest{Id: testCompany.ID.String()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	mockService.EXPECT().GetCompanyByID(gomock.Any(), testCompany.ID).Return(nil, context.DeadlineExceeded)
+	_, err = client.GetCompany(context.Background(), &companypb.GetCompanyRequest{Id: testCompany.ID.String()})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+
+	mockService.EXPECT().GetCompanyByID(gomock.Any(), testCompany.ID).Return(nil, testErr)
+	_, err = client.GetCompany(context.Background(), &companypb.GetCompanyRequest{Id: testCompany.ID.String()})
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestGRPCServer_UpdateCompany_NotFound(t *testing.T) {
+	mockService, client := newTestGRPCClient(t)
+	mockService.EXPECT().UpdateCompany(gomock.Any(), testCompany.ID, gomock.Any()).Return(nil, model.ErrCompanyNotFound{Id: testCompany.ID})
+
+	_, err := client.UpdateCompany(context.Background(), &companypb.UpdateCompanyRequest{
+		Id:      testCompany.ID.String(),
+		Company: &companypb.CompanyInput{Name: "Test Company", Employees: 1, Type: "NonProfit"},
+	})
+
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGRPCServer_DeleteCompany(t *testing.T) {
+	mockService, client := newTestGRPCClient(t)
+	mockService.EXPECT().DeleteCompany(gomock.Any(), testCompany.ID).Return(nil)
+
+	_, err := client.DeleteCompany(context.Background(), &companypb.DeleteCompanyRequest{Id: testCompany.ID.String()})
+
+	assert.NoError(t, err)
+}
+
+func TestGRPCServer_DeleteCompany_HasBranches(t *testing.T) {
+	mockService, client := newTestGRPCClient(t)
+	mockService.EXPECT().DeleteCompany(gomock.Any(), testCompany.ID).Return(model.ErrCompanyHasBranches{Id: testCompany.ID, Branches: 1})
+
+	_, err := client.DeleteCompany(context.Background(), &companypb.DeleteCompanyRequest{Id: testCompany.ID.String()})
+
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestGRPCServer_ListCompanies(t *testing.T) {
+	mockService, client := newTestGRPCClient(t)
+	mockService.EXPECT().ListCompanies(gomock.Any(), []byte(nil), defaultGRPCPageSize).Return([]*model.Company{testCompany}, []byte("next"), nil)
+	mockService.EXPECT().ListCompanies(gomock.Any(), []byte("next"), maxGRPCPageSize).Return(nil, nil, nil)
+
+	response, err := client.ListCompanies(context.Background(), &companypb.ListCompaniesRequest{})
+	require.NoError(t, err)
+	require.Len(t, response.Companies, 1)
+	assert.Equal(t, testCompany.ID.String(), response.Companies[0].Id)
+	assert.Equal(t, []byte("next"), response.NextPageToken)
+
+	response, err = client.ListCompanies(context.Background(), &companypb.ListCompaniesRequest{PageSize: 5000, PageToken: response.NextPageToken})
+	require.NoError(t, err)
+	assert.Empty(t, response.Companies)
+	assert.Empty(t, response.NextPageToken)
+
+	_, err = client.ListCompanies(context.Background(), &companypb.ListCompaniesRequest{PageSize: -1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGRPCServer_ListCompanies_Tags(t *testing.T) {
+	mockService, client := newTestGRPCClient(t)
+	prod := &model.Company{ID: uuid.New(), Name: "Acme", Tags: map[string]string{"env": "prod", "team": "payments"}}
+	staging := &model.Company{ID: uuid.New(), Name: "Initech", Tags: map[string]string{"env": "staging"}}
+	mockService.EXPECT().ListCompanies(gomock.Any(), []byte(nil), 1).Return([]*model.Company{staging}, []byte("second"), nil)
+	mockService.EXPECT().ListCompanies(gomock.Any(), []byte("second"), 1).Return([]*model.Company{prod}, []byte("third"), nil)
+	mockService.EXPECT().ListCompanies(gomock.Any(), []byte("third"), 1).Return([]*model.Company{testCompany}, nil, nil)
+
+	value := "prod"
+	tags := []*companypb.TagFilter{{Key: "env", Value: &value}, {Key: "team"}}
+	// the pages of the service are scanned until the page is full
+	response, err := client.ListCompanies(context.Background(), &companypb.ListCompaniesRequest{PageSize: 1, Tags: tags})
+	require.NoError(t, err)
+	require.Len(t, response.Companies, 1)
+	assert.Equal(t, map[string]string{"env": "prod", "team": "payments"}, response.Companies[0].Tags)
+	require.NotEmpty(t, response.NextPageToken)
+
+	response, err = client.ListCompanies(context.Background(), &companypb.ListCompaniesRequest{PageSize: 1, Tags: tags, PageToken: response.NextPageToken})
+	require.NoError(t, err)
+	assert.Empty(t, response.Companies)
+	assert.Empty(t, response.NextPageToken)
+}
+
+func TestGRPCServer_ListCompanies_TagsScanLimit(t *testing.T) {
+	mockService, client := newTestGRPCClient(t)
+	untagged := []*model.Company{testCompany}
+	mockService.EXPECT().ListCompanies(gomock.Any(), gomock.Any(), 5).Return(untagged, []byte("next"), nil).Times(maxListPages)
+
+	response, err := client.ListCompanies(context.Background(), &companypb.ListCompaniesRequest{
+		PageSize: 5,
+		Tags:     []*companypb.TagFilter{{Key: "env"}},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, response.Companies)
+	var cursor listCursor
+	require.NoError(t, json.Unmarshal(response.NextPageToken, &cursor))
+	assert.Equal(t, listCursor{PageState: []byte("next"), PageSize: 5}, cursor)
+
+	_, err = client.ListCompanies(context.Background(), &companypb.ListCompaniesRequest{
+		Tags:      []*companypb.TagFilter{{Key: "env"}},
+		PageToken: []byte("next"),
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGRPCServer_CompanyTags(t *testing.T) {
+	mockService, client := newTestGRPCClient(t)
+	tagged := *testCompany
+	tagged.Tags = map[string]string{"env": "prod"}
+	mockService.EXPECT().SetTag(gomock.Any(), testCompany.ID, "env", "prod").Return(&tagged, nil)
+	mockService.EXPECT().RemoveTag(gomock.Any(), testCompany.ID, "env").Return(nil, model.ErrTagNotFound{Id: testCompany.ID, Key: "env"})
+
+	company, err := client.SetCompanyTag(context.Background(), &companypb.SetCompanyTagRequest{Id: testCompany.ID.String(), Key: "env", Value: "prod"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod"}, company.Tags)
+
+	_, err = client.RemoveCompanyTag(context.Background(), &companypb.RemoveCompanyTagRequest{Id: testCompany.ID.String(), Key: "env"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = client.RemoveCompanyTag(context.Background(), &companypb.RemoveCompanyTagRequest{Id: "acme", Key: "env"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
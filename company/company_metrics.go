@@ -0,0 +1,53 @@
+package company
+
+import (
+	"context"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/metrics"
+	"github.com/ngereci/xm_interview/model"
+	"time"
+)
+
+// TypeLister lists the company types, see companytype.Repository
+type TypeLister interface {
+	List(ctx context.Context) ([]*model.CompanyTypeDefinition, error)
+}
+
+// RefreshCompaniesByType keeps the companies by type gauge up to date by counting the companies of every stored type
+// every interval until ctx is done. Types are replaced as a whole, so deleted types are dropped from the gauge.
+func RefreshCompaniesByType(ctx context.Context, repo Repository, types TypeLister, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if counts, err := countByType(ctx, repo, types); err != nil {
+			logging.FromContext(ctx).Errorf("RefreshCompaniesByType error:%v", err)
+		} else {
+			metrics.CompaniesByType.Reset()
+			for companyType, count := range counts {
+				metrics.CompaniesByType.WithLabelValues(string(companyType)).Set(float64(count))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// countByType counts the stored companies of every type of types, types without companies are counted as 0.
+func countByType(ctx context.Context, repo Repository, types TypeLister) (map[model.CompanyType]int, error) {
+	definitions, err := types.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[model.CompanyType]int, len(definitions))
+	for _, definition := range definitions {
+		count, err := repo.CountByType(ctx, definition.Name)
+		if err != nil {
+			return nil, err
+		}
+		counts[definition.Name] = count
+	}
+	return counts, nil
+}
@@ -0,0 +1,38 @@
+package company
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// typeList is a TypeLister of fixed types
+type typeList []*model.CompanyTypeDefinition
+
+func (l typeList) List(ctx context.Context) ([]*model.CompanyTypeDefinition, error) {
+	return l, nil
+}
+
+func TestCountByType(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	types := []model.CompanyType{model.Corporation, model.Corporation, model.NonProfit}
+	for i := 0; i < 501; i++ {
+		companyType := types[i%len(types)]
+		err := repo.Create(ctx, &model.Company{ID: uuid.New(), Name: fmt.Sprintf("Company %d", i), Employees: 1, Type: companyType})
+		assert.NoError(t, err)
+	}
+
+	// NonProfit was deleted, it is no longer counted
+	counts, err := countByType(ctx, repo, typeList{{Name: model.Corporation}, {Name: model.Cooperative}, {Name: "Partnership"}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[model.CompanyType]int{
+		model.Corporation: 334,
+		model.Cooperative: 0,
+		"Partnership":     0,
+	}, counts)
+}
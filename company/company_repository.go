@@ -1,18 +1,41 @@
 package company
 
 import (
+	"context"
 	"github.com/gocql/gocql"
 	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/metrics"
 	"github.com/ngereci/xm_interview/model"
-	log "github.com/sirupsen/logrus"
+	"time"
 )
 
+// Repository stores the companies of all tenants. Every operation on a single company or name is scoped to
+// a tenant, a company of another tenant is treated as missing.
 type Repository interface {
-	Create(company *model.Company) error
-	GetByID(id uuid.UUID) (*model.Company, error)
-	Update(company *model.Company) (*model.Company, error)
-	Delete(id uuid.UUID) error
-	CountByName(name string) (int, error)
+	// Create stores the company for its TenantID
+	Create(ctx context.Context, company *model.Company) error
+	GetByID(ctx context.Context, tenantID string, id uuid.UUID) (*model.Company, error)
+	// GetByIDs returns the companies of tenantID with one of the ids in no particular order, missing ones are left out
+	GetByIDs(ctx context.Context, tenantID string, ids []uuid.UUID) ([]*model.Company, error)
+	// Update replaces the company of its TenantID except for its tags, which are changed with SetTag and DeleteTag
+	Update(ctx context.Context, company *model.Company) (*model.Company, error)
+	// SetTag sets the tag key of a company to value in place, leaving its other fields and tags as they are.
+	// It returns the changed company, or nil if the company does not exist.
+	SetTag(ctx context.Context, tenantID string, id uuid.UUID, key string, value string) (*model.Company, error)
+	// DeleteTag removes the tag key of a company in place like SetTag, it returns model.ErrTagNotFound if the
+	// company exists without the tag.
+	DeleteTag(ctx context.Context, tenantID string, id uuid.UUID, key string) (*model.Company, error)
+	Delete(ctx context.Context, tenantID string, id uuid.UUID) error
+	// CountByName counts the companies of tenantID whose name has the normalized form of name, see model.NormalizeName
+	CountByName(ctx context.Context, tenantID string, name string) (int, error)
+	// CountByType counts the companies of all tenants that have the type companyType
+	CountByType(ctx context.Context, companyType model.CompanyType) (int, error)
+	// List returns a single page of the companies of all tenants starting at pageState (nil for the first page)
+	// together with the page state of the next page, which is empty once the table is exhausted.
+	List(ctx context.Context, pageState []byte, pageSize int) ([]*model.Company, []byte, error)
+	// ListByTenant is List restricted to the companies of tenantID
+	ListByTenant(ctx context.Context, tenantID string, pageState []byte, pageSize int) ([]*model.Company, []byte, error)
 }
 
 type companyRepository struct {
@@ -23,89 +46,248 @@ func NewRepository(session *gocql.Session) Repository {
 	return &companyRepository{session: session}
 }
 
-func (r *companyRepository) Create(company *model.Company) error {
+func (r *companyRepository) Create(ctx context.Context, company *model.Company) error {
+	start := time.Now()
 	query := r.session.Query(`
-		INSERT INTO company (id, name, description, employees, registered, type)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`, company.ID.String(), company.Name, company.Description, company.Employees, company.Registered, company.Type)
+		INSERT INTO company_by_tenant (tenant_id, id, name, normalized_name, description, employees, registered, type,
+			registration_number, country, vat_number, lei, website, addresses, contacts, tags)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, company.TenantID, company.ID.String(), company.Name, model.NormalizeName(company.Name), company.Description, company.Employees, company.Registered, company.Type,
+		company.RegistrationNumber, company.Country, company.VATNumber, company.LEI, company.Website, company.Addresses, company.Contacts, company.Tags).WithContext(ctx)
 
-	return query.Exec()
+	err := query.Exec()
+	observeQuery("create", start, err)
+	return err
 }
 
-func (r *companyRepository) GetByID(id uuid.UUID) (*model.Company, error) {
-
+func (r *companyRepository) GetByID(ctx context.Context, tenantID string, id uuid.UUID) (*model.Company, error) {
+	start := time.Now()
 	query := r.session.Query(`
-		SELECT id, name, description, employees, registered, type
-		FROM company
-		WHERE id = ?
-	`, id.String())
-	resultMap := make(map[string]any)
-	err := query.MapScan(resultMap)
+		SELECT tenant_id, id, name, description, employees, registered, type,
+			registration_number, country, vat_number, lei, website, addresses, contacts, tags
+		FROM company_by_tenant
+		WHERE tenant_id = ? AND id = ?
+	`, tenantID, id.String()).WithContext(ctx)
+	company, err := scanCompany(query.Scan)
+	observeQuery("get_by_id", start, err)
 	if err != nil {
 		if err == gocql.ErrNotFound {
-			log.Warnf("id:%v GetByID not found", id)
+			logging.FromContext(ctx).Warnf("id:%v GetByID not found", id)
 			return nil, nil
 		}
-		log.Errorf("id:%v GetByID error:%v", id, err)
+		logging.FromContext(ctx).Errorf("id:%v GetByID error:%v", id, err)
 		return nil, err
 	}
-	company := model.Company{
-		ID:          id,
-		Name:        resultMap["name"].(string),
-		Description: resultMap["description"].(string),
-		Employees:   resultMap["employees"].(int),
-		Registered:  resultMap["registered"].(bool),
-		Type:        model.CompanyType(resultMap["type"].(string)),
+	return company, nil
+}
+
+func (r *companyRepository) GetByIDs(ctx context.Context, tenantID string, ids []uuid.UUID) ([]*model.Company, error) {
+	if len(ids) == 0 {
+		return nil, nil
 	}
+	idStrings := make([]string, 0, len(ids))
+	for _, id := range ids {
+		idStrings = append(idStrings, id.String())
+	}
+	query := r.session.Query(`
+		SELECT tenant_id, id, name, description, employees, registered, type,
+			registration_number, country, vat_number, lei, website, addresses, contacts, tags
+		FROM company_by_tenant
+		WHERE tenant_id = ? AND id IN ?
+	`, tenantID, idStrings)
+	// a single page holds all the companies, there is at most one per id
+	companies, _, err := r.list(ctx, "get_by_ids", query, nil, len(ids))
+	return companies, err
+}
 
-	return &company, nil
+// CountByName looks the normalized name up in the normalized name index, which spans all tenants, and counts the
+// companies of tenantID. Names are unique per tenant, so only few rows are read.
+func (r *companyRepository) CountByName(ctx context.Context, tenantID string, name string) (count int, err error) {
+	start := time.Now()
+	iter := r.session.Query(`
+		SELECT tenant_id
+		FROM company_by_tenant
+		WHERE normalized_name = ?
+	`, model.NormalizeName(name)).WithContext(ctx).Iter()
+	var owner string
+	for iter.Scan(&owner) {
+		if owner == tenantID {
+			count++
+		}
+	}
+	err = iter.Close()
+	observeQuery("count_by_name", start, err)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("name:%v CountByName error:%v", name, err)
+		return 0, err
+	}
+	return count, nil
 }
-func (r *companyRepository) CountByName(name string) (count int, err error) {
 
-	query := r.session.Query(`
+// CountByType counts the companies in the type index, which spans all tenants
+func (r *companyRepository) CountByType(ctx context.Context, companyType model.CompanyType) (count int, err error) {
+	start := time.Now()
+	err = r.session.Query(`
 		SELECT COUNT(*)
-		FROM company
-		WHERE name = ?
-	`, name)
-	err = query.Scan(&count)
+		FROM company_by_tenant
+		WHERE type = ?
+	`, companyType).WithContext(ctx).Scan(&count)
+	observeQuery("count_by_type", start, err)
 	if err != nil {
-		if err == gocql.ErrNotFound {
-			log.Warnf("name:%v CountByName not found", name)
-			return count, nil
+		logging.FromContext(ctx).Errorf("type:%v CountByType error:%v", companyType, err)
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *companyRepository) List(ctx context.Context, pageState []byte, pageSize int) ([]*model.Company, []byte, error) {
+	query := r.session.Query(`
+		SELECT tenant_id, id, name, description, employees, registered, type,
+			registration_number, country, vat_number, lei, website, addresses, contacts, tags
+		FROM company_by_tenant
+	`)
+	return r.list(ctx, "list", query, pageState, pageSize)
+}
+
+func (r *companyRepository) ListByTenant(ctx context.Context, tenantID string, pageState []byte, pageSize int) ([]*model.Company, []byte, error) {
+	query := r.session.Query(`
+		SELECT tenant_id, id, name, description, employees, registered, type,
+			registration_number, country, vat_number, lei, website, addresses, contacts, tags
+		FROM company_by_tenant
+		WHERE tenant_id = ?
+	`, tenantID)
+	return r.list(ctx, "list_by_tenant", query, pageState, pageSize)
+}
+
+// list reads a single page of the companies selected by query, operation names it in the metrics
+func (r *companyRepository) list(ctx context.Context, operation string, query *gocql.Query, pageState []byte, pageSize int) ([]*model.Company, []byte, error) {
+	start := time.Now()
+	iter := query.WithContext(ctx).PageSize(pageSize).PageState(pageState).Iter()
+	nextPageState := iter.PageState()
+
+	companies := make([]*model.Company, 0, iter.NumRows())
+	scanner := iter.Scanner()
+	for scanner.Next() {
+		company, err := scanCompany(scanner.Scan)
+		if err != nil {
+			observeQuery(operation, start, err)
+			logging.FromContext(ctx).Errorf("List scan error:%v", err)
+			return nil, nil, err
 		}
-		log.Errorf("name:%v CountByName error:%v", name, err)
-		return count, err
+		companies = append(companies, company)
+	}
+	err := scanner.Err()
+	observeQuery(operation, start, err)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("List error:%v", err)
+		return nil, nil, err
 	}
-	return
+	return companies, nextPageState, nil
+}
+
+// scanCompany scans a row of the columns the queries of the repository select into a company
+func scanCompany(scan func(dest ...any) error) (*model.Company, error) {
+	var (
+		id          gocql.UUID
+		companyType string
+		company     model.Company
+	)
+	err := scan(&company.TenantID, &id, &company.Name, &company.Description, &company.Employees, &company.Registered, &companyType,
+		&company.RegistrationNumber, &company.Country, &company.VATNumber, &company.LEI, &company.Website, &company.Addresses, &company.Contacts, &company.Tags)
+	if err != nil {
+		return nil, err
+	}
+	company.ID = uuid.UUID(id)
+	company.Type = model.CompanyType(companyType)
+	return &company, nil
 }
 
-func (r *companyRepository) Update(company *model.Company) (*model.Company, error) {
+func (r *companyRepository) Update(ctx context.Context, company *model.Company) (*model.Company, error) {
+	start := time.Now()
 	query := r.session.Query(`
-		UPDATE company
-		SET name = ?, description = ?, employees = ?, registered = ?, type = ?
-		WHERE id = ?
-	`, company.Name, company.Description, company.Employees, company.Registered, company.Type, company.ID.String())
+		UPDATE company_by_tenant
+		SET name = ?, normalized_name = ?, description = ?, employees = ?, registered = ?, type = ?,
+			registration_number = ?, country = ?, vat_number = ?, lei = ?, website = ?, addresses = ?, contacts = ?
+		WHERE tenant_id = ? AND id = ?
+	`, company.Name, model.NormalizeName(company.Name), company.Description, company.Employees, company.Registered, company.Type,
+		company.RegistrationNumber, company.Country, company.VATNumber, company.LEI, company.Website, company.Addresses, company.Contacts,
+		company.TenantID, company.ID.String()).WithContext(ctx)
 
 	err := query.Exec()
-
+	observeQuery("update", start, err)
 	if err != nil {
-		log.Errorf("id:%v Update error:%v", company.ID, err)
+		logging.FromContext(ctx).Errorf("id:%v Update error:%v", company.ID, err)
 		return nil, err
 	}
 
-	return r.GetByID(company.ID)
+	return r.GetByID(ctx, company.TenantID, company.ID)
 }
 
-func (r *companyRepository) Delete(id uuid.UUID) error {
+func (r *companyRepository) Delete(ctx context.Context, tenantID string, id uuid.UUID) error {
+	start := time.Now()
 	query := r.session.Query(`
-		DELETE FROM company
-		WHERE id = ?
-	`, id.String())
+		DELETE FROM company_by_tenant
+		WHERE tenant_id = ? AND id = ?
+	`, tenantID, id.String()).WithContext(ctx)
 
 	err := query.Exec()
+	observeQuery("delete", start, err)
 	if err != nil {
-		log.Errorf("id:%v Delete error:%v", id, err)
+		logging.FromContext(ctx).Errorf("id:%v Delete error:%v", id, err)
 		return err
 	}
 	return nil
 }
+
+// SetTag writes the single entry of the tags map, the condition keeps it from creating a row of a deleted company
+func (r *companyRepository) SetTag(ctx context.Context, tenantID string, id uuid.UUID, key string, value string) (*model.Company, error) {
+	start := time.Now()
+	applied, err := r.session.Query(`
+		UPDATE company_by_tenant
+		SET tags[?] = ?
+		WHERE tenant_id = ? AND id = ?
+		IF EXISTS
+	`, key, value, tenantID, id.String()).WithContext(ctx).MapScanCAS(map[string]any{})
+	observeQuery("set_tag", start, err)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("id:%v SetTag error:%v", id, err)
+		return nil, err
+	}
+	if !applied {
+		return nil, nil
+	}
+	return r.GetByID(ctx, tenantID, id)
+}
+
+// DeleteTag removes the single entry of the tags map if it is there, a company the condition does not apply to
+// either has no such tag or does not exist
+func (r *companyRepository) DeleteTag(ctx context.Context, tenantID string, id uuid.UUID, key string) (*model.Company, error) {
+	start := time.Now()
+	applied, err := r.session.Query(`
+		DELETE tags[?]
+		FROM company_by_tenant
+		WHERE tenant_id = ? AND id = ?
+		IF tags[?] != null
+	`, key, tenantID, id.String(), key).WithContext(ctx).MapScanCAS(map[string]any{})
+	observeQuery("delete_tag", start, err)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("id:%v DeleteTag error:%v", id, err)
+		return nil, err
+	}
+	company, err := r.GetByID(ctx, tenantID, id)
+	if err != nil || company == nil {
+		return nil, err
+	}
+	if !applied {
+		return nil, model.ErrTagNotFound{Id: id, Key: key}
+	}
+	return company, nil
+}
+
+// observeQuery records a query of the Cassandra repository, a missing row is a result and not a failure.
+func observeQuery(operation string, start time.Time, err error) {
+	if err == gocql.ErrNotFound {
+		err = nil
+	}
+	metrics.ObserveCassandraQuery(operation, start, err)
+}
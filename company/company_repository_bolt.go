@@ -0,0 +1,402 @@
+package company
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/ngereci/xm_interview/tenant"
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	boltCompanyBucket = []byte("company")
+	// boltNameBucket maps the tenant and normalized name of a company to its id and enforces the uniqueness of
+	// names per tenant
+	boltNameBucket = []byte("company_tenant_normalized_name")
+	// boltTenantNameBucket mapped the tenant and name as it was entered to ids, see migrateBoltNormalizedNames
+	boltTenantNameBucket = []byte("company_tenant_name")
+	// boltLegacyNameBucket mapped the names to ids before there were tenants, see migrateBoltTenants
+	boltLegacyNameBucket = []byte("company_name")
+)
+
+type boltCompanyRepository struct {
+	db *bolt.DB
+}
+
+// NewBoltRepository creates a Repository on an embedded bbolt database file, for single node and local use.
+// bbolt transactions can not be interrupted, the context is only checked before a transaction starts.
+func NewBoltRepository(db *bolt.DB) (Repository, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{boltCompanyBucket, boltNameBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		if err := migrateBoltTenants(tx); err != nil {
+			return err
+		}
+		return migrateBoltNormalizedNames(tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &boltCompanyRepository{db: db}, nil
+}
+
+func (r *boltCompanyRepository) Create(ctx context.Context, company *model.Company) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		names := tx.Bucket(boltNameBucket)
+		if names.Get(boltNameKey(company.TenantID, company.Name)) != nil {
+			return model.ErrCompanyExists{Name: company.Name}
+		}
+		if err := names.Put(boltNameKey(company.TenantID, company.Name), company.ID[:]); err != nil {
+			return err
+		}
+		return putBoltCompany(tx, company)
+	})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("id:%v Create error:%v", company.ID, err)
+	}
+	return err
+}
+
+func (r *boltCompanyRepository) GetByID(ctx context.Context, tenantID string, id uuid.UUID) (company *model.Company, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	err = r.db.View(func(tx *bolt.Tx) error {
+		company, err = getBoltCompany(tx, tenantID, id)
+		return err
+	})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("id:%v GetByID error:%v", id, err)
+		return nil, err
+	}
+	if company == nil {
+		logging.FromContext(ctx).Warnf("id:%v GetByID not found", id)
+	}
+	return company, nil
+}
+
+func (r *boltCompanyRepository) GetByIDs(ctx context.Context, tenantID string, ids []uuid.UUID) (companies []*model.Company, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	err = r.db.View(func(tx *bolt.Tx) error {
+		companies = make([]*model.Company, 0, len(ids))
+		seen := make(map[uuid.UUID]bool, len(ids))
+		for _, id := range ids {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			company, err := getBoltCompany(tx, tenantID, id)
+			if err != nil {
+				return err
+			}
+			if company != nil {
+				companies = append(companies, company)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("ids:%v GetByIDs error:%v", ids, err)
+		return nil, err
+	}
+	return companies, nil
+}
+
+func (r *boltCompanyRepository) CountByName(ctx context.Context, tenantID string, name string) (count int, err error) {
+	if err = ctx.Err(); err != nil {
+		return 0, err
+	}
+	err = r.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(boltNameBucket).Get(boltNameKey(tenantID, name)) != nil {
+			count = 1
+		}
+		return nil
+	})
+	return
+}
+
+// CountByType reads the companies of all tenants, there is no index of their types in the single node storage
+func (r *boltCompanyRepository) CountByType(ctx context.Context, companyType model.CompanyType) (count int, err error) {
+	if err = ctx.Err(); err != nil {
+		return 0, err
+	}
+	err = r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltCompanyBucket).ForEach(func(key, value []byte) error {
+			var company model.Company
+			if err := json.Unmarshal(value, &company); err != nil {
+				return err
+			}
+			if company.Type == companyType {
+				count++
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("type:%v CountByType error:%v", companyType, err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// List pages through the companies in key order, the page state is the id of the last company of the page.
+func (r *boltCompanyRepository) List(ctx context.Context, pageState []byte, pageSize int) ([]*model.Company, []byte, error) {
+	return r.list(ctx, pageState, pageSize, func(*model.Company) bool { return true })
+}
+
+// ListByTenant pages through the companies of tenantID like List. The companies are keyed by id only,
+// so it reads the companies of all tenants, which is fine for the single node the storage is meant for.
+func (r *boltCompanyRepository) ListByTenant(ctx context.Context, tenantID string, pageState []byte, pageSize int) ([]*model.Company, []byte, error) {
+	return r.list(ctx, pageState, pageSize, func(company *model.Company) bool { return company.TenantID == tenantID })
+}
+
+// list pages through the companies matching match
+func (r *boltCompanyRepository) list(ctx context.Context, pageState []byte, pageSize int, match func(*model.Company) bool) (companies []*model.Company, nextPageState []byte, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	err = r.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(boltCompanyBucket).Cursor()
+		key, value := cursor.First()
+		if len(pageState) > 0 {
+			key, value = cursor.Seek(pageState)
+			if bytes.Equal(key, pageState) {
+				key, value = cursor.Next()
+			}
+		}
+		companies = make([]*model.Company, 0, pageSize)
+		for ; key != nil && len(companies) < pageSize; key, value = cursor.Next() {
+			var company model.Company
+			if err := json.Unmarshal(value, &company); err != nil {
+				return err
+			}
+			if match(&company) {
+				companies = append(companies, &company)
+			}
+		}
+		if key != nil {
+			nextPageState = append([]byte(nil), companies[len(companies)-1].ID[:]...)
+		}
+		return nil
+	})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("List error:%v", err)
+		return nil, nil, err
+	}
+	return companies, nextPageState, nil
+}
+
+// Update replaces the company but its tags, it returns nil if the company does not exist.
+func (r *boltCompanyRepository) Update(ctx context.Context, company *model.Company) (updated *model.Company, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	err = r.db.Update(func(tx *bolt.Tx) error {
+		existing, err := getBoltCompany(tx, company.TenantID, company.ID)
+		if err != nil || existing == nil {
+			return err
+		}
+		if model.NormalizeName(existing.Name) != model.NormalizeName(company.Name) {
+			names := tx.Bucket(boltNameBucket)
+			if names.Get(boltNameKey(company.TenantID, company.Name)) != nil {
+				return model.ErrCompanyExists{Name: company.Name}
+			}
+			if err = deleteBoltName(names, existing); err != nil {
+				return err
+			}
+			if err = names.Put(boltNameKey(company.TenantID, company.Name), company.ID[:]); err != nil {
+				return err
+			}
+		}
+		stored := *company
+		stored.Tags = existing.Tags
+		if err = putBoltCompany(tx, &stored); err != nil {
+			return err
+		}
+		updated = &stored
+		return nil
+	})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("id:%v Update error:%v", company.ID, err)
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (r *boltCompanyRepository) SetTag(ctx context.Context, tenantID string, id uuid.UUID, key string, value string) (*model.Company, error) {
+	return r.changeTags(ctx, tenantID, id, func(tags map[string]string) error {
+		tags[key] = value
+		return nil
+	})
+}
+
+func (r *boltCompanyRepository) DeleteTag(ctx context.Context, tenantID string, id uuid.UUID, key string) (*model.Company, error) {
+	return r.changeTags(ctx, tenantID, id, func(tags map[string]string) error {
+		if _, ok := tags[key]; !ok {
+			return model.ErrTagNotFound{Id: id, Key: key}
+		}
+		delete(tags, key)
+		return nil
+	})
+}
+
+// changeTags applies change to the tags of the company within the transaction that stores it
+func (r *boltCompanyRepository) changeTags(ctx context.Context, tenantID string, id uuid.UUID, change func(tags map[string]string) error) (changed *model.Company, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	err = r.db.Update(func(tx *bolt.Tx) error {
+		company, err := getBoltCompany(tx, tenantID, id)
+		if err != nil || company == nil {
+			return err
+		}
+		if company.Tags == nil {
+			company.Tags = make(map[string]string)
+		}
+		if err = change(company.Tags); err != nil {
+			return err
+		}
+		if len(company.Tags) == 0 {
+			company.Tags = nil
+		}
+		if err = putBoltCompany(tx, company); err != nil {
+			return err
+		}
+		changed = company
+		return nil
+	})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("id:%v tags error:%v", id, err)
+		return nil, err
+	}
+	return changed, nil
+}
+
+func (r *boltCompanyRepository) Delete(ctx context.Context, tenantID string, id uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		existing, err := getBoltCompany(tx, tenantID, id)
+		if err != nil || existing == nil {
+			return err
+		}
+		if err = deleteBoltName(tx.Bucket(boltNameBucket), existing); err != nil {
+			return err
+		}
+		return tx.Bucket(boltCompanyBucket).Delete(id[:])
+	})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("id:%v Delete error:%v", id, err)
+	}
+	return err
+}
+
+// getBoltCompany returns the company id of tenantID, or nil if it does not exist or belongs to another tenant.
+func getBoltCompany(tx *bolt.Tx, tenantID string, id uuid.UUID) (*model.Company, error) {
+	value := tx.Bucket(boltCompanyBucket).Get(id[:])
+	if value == nil {
+		return nil, nil
+	}
+	var company model.Company
+	if err := json.Unmarshal(value, &company); err != nil {
+		return nil, err
+	}
+	if company.TenantID != tenantID {
+		return nil, nil
+	}
+	return &company, nil
+}
+
+func putBoltCompany(tx *bolt.Tx, company *model.Company) error {
+	value, err := json.Marshal(company)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(boltCompanyBucket).Put(company.ID[:], value)
+}
+
+// boltNameKey is the key of a name in boltNameBucket, tenant ids can not contain the separator.
+func boltNameKey(tenantID, name string) []byte {
+	return []byte(tenantID + "/" + model.NormalizeName(name))
+}
+
+// deleteBoltName removes the name of company from names, unless it is the name of another company that was stored
+// before names were normalized, see migrateBoltNormalizedNames.
+func deleteBoltName(names *bolt.Bucket, company *model.Company) error {
+	key := boltNameKey(company.TenantID, company.Name)
+	if !bytes.Equal(names.Get(key), company.ID[:]) {
+		return nil
+	}
+	return names.Delete(key)
+}
+
+// migrateBoltTenants moves the companies stored before there were tenants to the default tenant and
+// replaces the legacy name bucket. It does nothing once the legacy bucket is gone.
+func migrateBoltTenants(tx *bolt.Tx) error {
+	if tx.Bucket(boltLegacyNameBucket) == nil {
+		return nil
+	}
+	companies := tx.Bucket(boltCompanyBucket)
+	names := tx.Bucket(boltNameBucket)
+	var migrated []*model.Company
+	err := companies.ForEach(func(key, value []byte) error {
+		var company model.Company
+		if err := json.Unmarshal(value, &company); err != nil {
+			return err
+		}
+		if company.TenantID == "" {
+			company.TenantID = tenant.Default
+			migrated = append(migrated, &company)
+		}
+		return names.Put(boltNameKey(company.TenantID, company.Name), company.ID[:])
+	})
+	if err != nil {
+		return err
+	}
+	// buckets must not be changed while iterating them
+	for _, company := range migrated {
+		if err = putBoltCompany(tx, company); err != nil {
+			return err
+		}
+	}
+	return tx.DeleteBucket(boltLegacyNameBucket)
+}
+
+// migrateBoltNormalizedNames indexes the companies stored before names were normalized by their normalized name and
+// drops the bucket of the names as they were entered. Of companies whose names normalize to the same name only the
+// first is indexed, the others have to be renamed to be found by CountByName. It does nothing once the bucket is gone.
+func migrateBoltNormalizedNames(tx *bolt.Tx) error {
+	if tx.Bucket(boltTenantNameBucket) == nil {
+		return nil
+	}
+	names := tx.Bucket(boltNameBucket)
+	err := tx.Bucket(boltCompanyBucket).ForEach(func(key, value []byte) error {
+		var company model.Company
+		if err := json.Unmarshal(value, &company); err != nil {
+			return err
+		}
+		nameKey := boltNameKey(company.TenantID, company.Name)
+		if names.Get(nameKey) != nil {
+			log.Warnf("company:%v name:%v normalizes to the name of another company of tenant:%v", company.ID, company.Name, company.TenantID)
+			return nil
+		}
+		return names.Put(nameKey, company.ID[:])
+	})
+	if err != nil {
+		return err
+	}
+	return tx.DeleteBucket(boltTenantNameBucket)
+}
@@ -0,0 +1,118 @@
+package company_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/company"
+	"github.com/ngereci/xm_interview/company/companytest"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/ngereci/xm_interview/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+	"path/filepath"
+	"testing"
+)
+
+func TestBoltRepository(t *testing.T) {
+	companytest.RunRepositorySuite(t, func(t *testing.T) company.Repository {
+		database, err := bolt.Open(filepath.Join(t.TempDir(), "companies.db"), 0o600, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { database.Close() })
+		repo, err := company.NewBoltRepository(database)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repo
+	}, companytest.Options{UniqueNames: true})
+}
+
+func TestBoltRepository_MigratesLegacyCompanies(t *testing.T) {
+	database, err := bolt.Open(filepath.Join(t.TempDir(), "companies.db"), 0o600, nil)
+	require.NoError(t, err)
+	defer database.Close()
+	// a company as stored before there were tenants
+	legacy := &model.Company{ID: uuid.New(), Name: "Legacy Company", Employees: 3, Type: model.Cooperative}
+	err = database.Update(func(tx *bolt.Tx) error {
+		value, err := json.Marshal(legacy)
+		if err != nil {
+			return err
+		}
+		companies, err := tx.CreateBucket([]byte("company"))
+		if err != nil {
+			return err
+		}
+		names, err := tx.CreateBucket([]byte("company_name"))
+		if err != nil {
+			return err
+		}
+		if err = companies.Put(legacy.ID[:], value); err != nil {
+			return err
+		}
+		return names.Put([]byte(legacy.Name), legacy.ID[:])
+	})
+	require.NoError(t, err)
+
+	repo, err := company.NewBoltRepository(database)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	found, err := repo.GetByID(ctx, tenant.Default, legacy.ID)
+	require.NoError(t, err)
+	legacy.TenantID = tenant.Default
+	assert.Equal(t, legacy, found)
+	count, err := repo.CountByName(ctx, tenant.Default, legacy.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestBoltRepository_MigratesNamesToNormalizedNames(t *testing.T) {
+	database, err := bolt.Open(filepath.Join(t.TempDir(), "companies.db"), 0o600, nil)
+	require.NoError(t, err)
+	defer database.Close()
+	// companies as stored before names were normalized, the names of both normalize to "acme"
+	acme := &model.Company{ID: uuid.New(), TenantID: "tenant-a", Name: "Acme", Employees: 3, Type: model.Cooperative}
+	acmeLtd := &model.Company{ID: uuid.New(), TenantID: "tenant-a", Name: "ACME Ltd", Employees: 3, Type: model.Cooperative}
+	err = database.Update(func(tx *bolt.Tx) error {
+		companies, err := tx.CreateBucket([]byte("company"))
+		if err != nil {
+			return err
+		}
+		names, err := tx.CreateBucket([]byte("company_tenant_name"))
+		if err != nil {
+			return err
+		}
+		for _, c := range []*model.Company{acme, acmeLtd} {
+			value, err := json.Marshal(c)
+			if err != nil {
+				return err
+			}
+			if err = companies.Put(c.ID[:], value); err != nil {
+				return err
+			}
+			if err = names.Put([]byte(c.TenantID+"/"+c.Name), c.ID[:]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	repo, err := company.NewBoltRepository(database)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	count, err := repo.CountByName(ctx, "tenant-a", "acme ltd.")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	// once both clashing companies are deleted the name is free again
+	require.NoError(t, repo.Delete(ctx, "tenant-a", acme.ID))
+	require.NoError(t, repo.Delete(ctx, "tenant-a", acmeLtd.ID))
+	count, err = repo.CountByName(ctx, "tenant-a", "Acme")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+	require.NoError(t, repo.Create(ctx, &model.Company{ID: uuid.New(), TenantID: "tenant-a", Name: "Acme", Employees: 1, Type: model.Corporation}))
+}
@@ -0,0 +1,204 @@
+package company
+
+import (
+	"bytes"
+	"context"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/model"
+	"sort"
+	"sync"
+)
+
+type memoryCompanyRepository struct {
+	mu        sync.RWMutex
+	companies map[uuid.UUID]model.Company
+	names     map[tenantName]uuid.UUID
+}
+
+// tenantName is the normalized name of a company, which is unique per tenant
+type tenantName struct {
+	tenantID string
+	name     string
+}
+
+func newTenantName(tenantID, name string) tenantName {
+	return tenantName{tenantID: tenantID, name: model.NormalizeName(name)}
+}
+
+// NewMemoryRepository creates a Repository keeping the companies in memory, for tests and as a reference implementation.
+// Its operations never block, so the context is ignored.
+func NewMemoryRepository() Repository {
+	return &memoryCompanyRepository{
+		companies: make(map[uuid.UUID]model.Company),
+		names:     make(map[tenantName]uuid.UUID),
+	}
+}
+
+func (r *memoryCompanyRepository) Create(ctx context.Context, company *model.Company) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name := newTenantName(company.TenantID, company.Name)
+	if _, exists := r.names[name]; exists {
+		return model.ErrCompanyExists{Name: company.Name}
+	}
+	r.companies[company.ID] = *company
+	r.names[name] = company.ID
+	return nil
+}
+
+func (r *memoryCompanyRepository) GetByID(ctx context.Context, tenantID string, id uuid.UUID) (*model.Company, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	company, exists := r.companies[id]
+	if !exists || company.TenantID != tenantID {
+		return nil, nil
+	}
+	return &company, nil
+}
+
+func (r *memoryCompanyRepository) GetByIDs(ctx context.Context, tenantID string, ids []uuid.UUID) ([]*model.Company, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	companies := make([]*model.Company, 0, len(ids))
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		if company, exists := r.companies[id]; exists && company.TenantID == tenantID && !seen[id] {
+			seen[id] = true
+			companies = append(companies, &company)
+		}
+	}
+	return companies, nil
+}
+
+func (r *memoryCompanyRepository) CountByName(ctx context.Context, tenantID string, name string) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if _, exists := r.names[newTenantName(tenantID, name)]; exists {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (r *memoryCompanyRepository) CountByType(ctx context.Context, companyType model.CompanyType) (count int, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, company := range r.companies {
+		if company.Type == companyType {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// List pages through the companies ordered by id, the page state is the id of the last company of the page.
+func (r *memoryCompanyRepository) List(ctx context.Context, pageState []byte, pageSize int) ([]*model.Company, []byte, error) {
+	return r.list(pageState, pageSize, func(*model.Company) bool { return true })
+}
+
+func (r *memoryCompanyRepository) ListByTenant(ctx context.Context, tenantID string, pageState []byte, pageSize int) ([]*model.Company, []byte, error) {
+	return r.list(pageState, pageSize, func(company *model.Company) bool { return company.TenantID == tenantID })
+}
+
+// list pages through the companies matching match
+func (r *memoryCompanyRepository) list(pageState []byte, pageSize int, match func(*model.Company) bool) ([]*model.Company, []byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ids := make([]uuid.UUID, 0, len(r.companies))
+	for id, company := range r.companies {
+		if (len(pageState) == 0 || bytes.Compare(id[:], pageState) > 0) && match(&company) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return bytes.Compare(ids[i][:], ids[j][:]) < 0
+	})
+
+	var nextPageState []byte
+	if len(ids) > pageSize {
+		ids = ids[:pageSize]
+		lastID := ids[pageSize-1]
+		nextPageState = lastID[:]
+	}
+	companies := make([]*model.Company, 0, len(ids))
+	for _, id := range ids {
+		company := r.companies[id]
+		companies = append(companies, &company)
+	}
+	return companies, nextPageState, nil
+}
+
+// Update replaces the company but its tags, it returns nil if the company does not exist.
+func (r *memoryCompanyRepository) Update(ctx context.Context, company *model.Company) (*model.Company, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	existing, exists := r.companies[company.ID]
+	if !exists || existing.TenantID != company.TenantID {
+		return nil, nil
+	}
+	if name, existingName := newTenantName(company.TenantID, company.Name), newTenantName(existing.TenantID, existing.Name); name != existingName {
+		if _, taken := r.names[name]; taken {
+			return nil, model.ErrCompanyExists{Name: company.Name}
+		}
+		delete(r.names, existingName)
+		r.names[name] = company.ID
+	}
+	updated := *company
+	updated.Tags = existing.Tags
+	r.companies[company.ID] = updated
+	return &updated, nil
+}
+
+func (r *memoryCompanyRepository) SetTag(ctx context.Context, tenantID string, id uuid.UUID, key string, value string) (*model.Company, error) {
+	return r.changeTags(tenantID, id, func(tags map[string]string) error {
+		tags[key] = value
+		return nil
+	})
+}
+
+func (r *memoryCompanyRepository) DeleteTag(ctx context.Context, tenantID string, id uuid.UUID, key string) (*model.Company, error) {
+	return r.changeTags(tenantID, id, func(tags map[string]string) error {
+		if _, ok := tags[key]; !ok {
+			return model.ErrTagNotFound{Id: id, Key: key}
+		}
+		delete(tags, key)
+		return nil
+	})
+}
+
+// changeTags applies change to a copy of the tags of the company, the maps of the companies handed out are never changed
+func (r *memoryCompanyRepository) changeTags(tenantID string, id uuid.UUID, change func(tags map[string]string) error) (*model.Company, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	company, exists := r.companies[id]
+	if !exists || company.TenantID != tenantID {
+		return nil, nil
+	}
+	company.Tags = copyTags(company.Tags)
+	if err := change(company.Tags); err != nil {
+		return nil, err
+	}
+	if len(company.Tags) == 0 {
+		company.Tags = nil
+	}
+	r.companies[id] = company
+	return &company, nil
+}
+
+// copyTags returns a copy of tags which can be changed, it is never nil
+func copyTags(tags map[string]string) map[string]string {
+	copied := make(map[string]string, len(tags)+1)
+	for key, value := range tags {
+		copied[key] = value
+	}
+	return copied
+}
+
+func (r *memoryCompanyRepository) Delete(ctx context.Context, tenantID string, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, exists := r.companies[id]; exists && existing.TenantID == tenantID {
+		delete(r.names, newTenantName(existing.TenantID, existing.Name))
+		delete(r.companies, id)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package company_test
+
+import (
+	"github.com/ngereci/xm_interview/company"
+	"github.com/ngereci/xm_interview/company/companytest"
+	"testing"
+)
+
+func TestMemoryRepository(t *testing.T) {
+	companytest.RunRepositorySuite(t, func(t *testing.T) company.Repository {
+		return company.NewMemoryRepository()
+	}, companytest.Options{UniqueNames: true})
+}
@@ -0,0 +1,347 @@
+package company
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/model"
+)
+
+const (
+	pgUniqueViolation = "23505"
+	pgCompanyNameKey  = "company_tenant_normalized_name_key"
+)
+
+type postgresCompanyRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository creates a Repository on the company table of the
+// Postgres database, see db/migrations/postgres for the schema.
+func NewPostgresRepository(db *sql.DB) Repository {
+	return &postgresCompanyRepository{db: db}
+}
+
+func (r *postgresCompanyRepository) Create(ctx context.Context, company *model.Company) error {
+	addresses, contacts, tags, err := marshalPostgresDetails(company)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO company (tenant_id, id, name, normalized_name, description, employees, registered, type,
+			registration_number, country, vat_number, lei, website, addresses, contacts, tags)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	`, company.TenantID, company.ID, company.Name, model.NormalizeName(company.Name), company.Description, company.Employees, company.Registered, company.Type,
+		company.RegistrationNumber, company.Country, company.VATNumber, company.LEI, company.Website, addresses, contacts, tags)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("id:%v Create error:%v", company.ID, err)
+		return mapPostgresError(company, err)
+	}
+	return nil
+}
+
+func (r *postgresCompanyRepository) GetByID(ctx context.Context, tenantID string, id uuid.UUID) (*model.Company, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT tenant_id, id, name, description, employees, registered, type,
+			registration_number, country, vat_number, lei, website, addresses, contacts, tags
+		FROM company
+		WHERE tenant_id = $1 AND id = $2
+	`, tenantID, id)
+	company, err := scanPostgresCompany(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logging.FromContext(ctx).Warnf("id:%v GetByID not found", id)
+			return nil, nil
+		}
+		logging.FromContext(ctx).Errorf("id:%v GetByID error:%v", id, err)
+		return nil, err
+	}
+	return company, nil
+}
+
+func (r *postgresCompanyRepository) GetByIDs(ctx context.Context, tenantID string, ids []uuid.UUID) ([]*model.Company, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	idStrings := make([]string, 0, len(ids))
+	for _, id := range ids {
+		idStrings = append(idStrings, id.String())
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT tenant_id, id, name, description, employees, registered, type,
+			registration_number, country, vat_number, lei, website, addresses, contacts, tags
+		FROM company
+		WHERE tenant_id = $1 AND id = ANY($2::uuid[])
+	`, tenantID, idStrings)
+	companies, _, err := r.list(ctx, rows, err, len(ids))
+	return companies, err
+}
+
+func (r *postgresCompanyRepository) CountByName(ctx context.Context, tenantID string, name string) (count int, err error) {
+	err = r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM company
+		WHERE tenant_id = $1 AND normalized_name = $2
+	`, tenantID, model.NormalizeName(name)).Scan(&count)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("name:%v CountByName error:%v", name, err)
+	}
+	return
+}
+
+func (r *postgresCompanyRepository) CountByType(ctx context.Context, companyType model.CompanyType) (count int, err error) {
+	err = r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM company
+		WHERE type = $1
+	`, companyType).Scan(&count)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("type:%v CountByType error:%v", companyType, err)
+	}
+	return
+}
+
+// List pages through the companies ordered by id, the page state is the id of the last company of the page.
+func (r *postgresCompanyRepository) List(ctx context.Context, pageState []byte, pageSize int) ([]*model.Company, []byte, error) {
+	after, err := postgresPageStart(pageState)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT tenant_id, id, name, description, employees, registered, type,
+			registration_number, country, vat_number, lei, website, addresses, contacts, tags
+		FROM company
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2
+	`, after, pageSize)
+	return r.list(ctx, rows, err, pageSize)
+}
+
+// ListByTenant pages through the companies of tenantID like List, on the index company_tenant_id_idx.
+func (r *postgresCompanyRepository) ListByTenant(ctx context.Context, tenantID string, pageState []byte, pageSize int) ([]*model.Company, []byte, error) {
+	after, err := postgresPageStart(pageState)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT tenant_id, id, name, description, employees, registered, type,
+			registration_number, country, vat_number, lei, website, addresses, contacts, tags
+		FROM company
+		WHERE tenant_id = $1 AND id > $2
+		ORDER BY id
+		LIMIT $3
+	`, tenantID, after, pageSize)
+	return r.list(ctx, rows, err, pageSize)
+}
+
+// postgresPageStart returns the id the page of pageState starts after
+func postgresPageStart(pageState []byte) (uuid.UUID, error) {
+	if len(pageState) == 0 {
+		return uuid.Nil, nil
+	}
+	return uuid.FromBytes(pageState)
+}
+
+// list reads a page of companies from the result of a list query
+func (r *postgresCompanyRepository) list(ctx context.Context, rows *sql.Rows, err error, pageSize int) ([]*model.Company, []byte, error) {
+	if err != nil {
+		logging.FromContext(ctx).Errorf("List error:%v", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	companies := make([]*model.Company, 0, pageSize)
+	for rows.Next() {
+		company, err := scanPostgresCompany(rows)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("List scan error:%v", err)
+			return nil, nil, err
+		}
+		companies = append(companies, company)
+	}
+	if err = rows.Err(); err != nil {
+		logging.FromContext(ctx).Errorf("List error:%v", err)
+		return nil, nil, err
+	}
+	var nextPageState []byte
+	if len(companies) == pageSize {
+		lastID := companies[len(companies)-1].ID
+		nextPageState = lastID[:]
+	}
+	return companies, nextPageState, nil
+}
+
+// Update changes the company and reads it back in one transaction, so the returned company is
+// exactly the one written. It returns nil if the company does not exist.
+func (r *postgresCompanyRepository) Update(ctx context.Context, company *model.Company) (*model.Company, error) {
+	addresses, contacts, _, err := marshalPostgresDetails(company)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() //nolint:errcheck // no-op after commit
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE company
+		SET name = $1, normalized_name = $2, description = $3, employees = $4, registered = $5, type = $6,
+			registration_number = $7, country = $8, vat_number = $9, lei = $10, website = $11, addresses = $12, contacts = $13
+		WHERE tenant_id = $14 AND id = $15
+	`, company.Name, model.NormalizeName(company.Name), company.Description, company.Employees, company.Registered, company.Type,
+		company.RegistrationNumber, company.Country, company.VATNumber, company.LEI, company.Website, addresses, contacts,
+		company.TenantID, company.ID)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("id:%v Update error:%v", company.ID, err)
+		return nil, mapPostgresError(company, err)
+	}
+	if updated, err := result.RowsAffected(); err != nil || updated == 0 {
+		return nil, err
+	}
+	updatedCompany, err := scanPostgresCompany(tx.QueryRowContext(ctx, `
+		SELECT tenant_id, id, name, description, employees, registered, type,
+			registration_number, country, vat_number, lei, website, addresses, contacts, tags
+		FROM company
+		WHERE tenant_id = $1 AND id = $2
+	`, company.TenantID, company.ID))
+	if err != nil {
+		logging.FromContext(ctx).Errorf("id:%v Update error:%v", company.ID, err)
+		return nil, err
+	}
+	return updatedCompany, tx.Commit()
+}
+
+func (r *postgresCompanyRepository) SetTag(ctx context.Context, tenantID string, id uuid.UUID, key string, value string) (*model.Company, error) {
+	company, err := scanPostgresCompany(r.db.QueryRowContext(ctx, `
+		UPDATE company
+		SET tags = tags || jsonb_build_object($1::text, $2::text)
+		WHERE tenant_id = $3 AND id = $4
+		RETURNING tenant_id, id, name, description, employees, registered, type,
+			registration_number, country, vat_number, lei, website, addresses, contacts, tags
+	`, key, value, tenantID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		logging.FromContext(ctx).Errorf("id:%v SetTag error:%v", id, err)
+		return nil, err
+	}
+	return company, nil
+}
+
+// DeleteTag only changes companies having the tag, a company it does not change either has no such tag or does not exist
+func (r *postgresCompanyRepository) DeleteTag(ctx context.Context, tenantID string, id uuid.UUID, key string) (*model.Company, error) {
+	company, err := scanPostgresCompany(r.db.QueryRowContext(ctx, `
+		UPDATE company
+		SET tags = tags - $1::text
+		WHERE tenant_id = $2 AND id = $3 AND tags ? $1::text
+		RETURNING tenant_id, id, name, description, employees, registered, type,
+			registration_number, country, vat_number, lei, website, addresses, contacts, tags
+	`, key, tenantID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		existing, err := r.GetByID(ctx, tenantID, id)
+		if err != nil || existing == nil {
+			return nil, err
+		}
+		return nil, model.ErrTagNotFound{Id: id, Key: key}
+	}
+	if err != nil {
+		logging.FromContext(ctx).Errorf("id:%v DeleteTag error:%v", id, err)
+		return nil, err
+	}
+	return company, nil
+}
+
+func (r *postgresCompanyRepository) Delete(ctx context.Context, tenantID string, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `
+		DELETE FROM company
+		WHERE tenant_id = $1 AND id = $2
+	`, tenantID, id)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("id:%v Delete error:%v", id, err)
+		return err
+	}
+	return nil
+}
+
+type postgresScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPostgresCompany(row postgresScanner) (*model.Company, error) {
+	var (
+		company             model.Company
+		companyType         string
+		addresses, contacts []byte
+		tags                []byte
+	)
+	err := row.Scan(&company.TenantID, &company.ID, &company.Name, &company.Description, &company.Employees, &company.Registered, &companyType,
+		&company.RegistrationNumber, &company.Country, &company.VATNumber, &company.LEI, &company.Website, &addresses, &contacts, &tags)
+	if err != nil {
+		return nil, err
+	}
+	company.Type = model.CompanyType(companyType)
+	if err = json.Unmarshal(addresses, &company.Addresses); err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(contacts, &company.Contacts); err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(tags, &company.Tags); err != nil {
+		return nil, err
+	}
+	// companies without addresses, contacts or tags have none, like in the other repositories
+	if len(company.Addresses) == 0 {
+		company.Addresses = nil
+	}
+	if len(company.Contacts) == 0 {
+		company.Contacts = nil
+	}
+	if len(company.Tags) == 0 {
+		company.Tags = nil
+	}
+	return &company, nil
+}
+
+// marshalPostgresDetails returns the addresses and contacts of company as the JSON arrays of their columns
+// and the tags as the JSON object of theirs
+func marshalPostgresDetails(company *model.Company) ([]byte, []byte, []byte, error) {
+	addresses, contacts, tags := company.Addresses, company.Contacts, company.Tags
+	if addresses == nil {
+		addresses = []model.Address{}
+	}
+	if contacts == nil {
+		contacts = []model.Contact{}
+	}
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	addressesJSON, err := json.Marshal(addresses)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	contactsJSON, err := json.Marshal(contacts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	tagsJSON, err := json.Marshal(tags)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return addressesJSON, contactsJSON, tagsJSON, nil
+}
+
+// mapPostgresError turns the violation of the unique name per tenant constraint into model.ErrCompanyExists.
+func mapPostgresError(company *model.Company, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation && pgErr.ConstraintName == pgCompanyNameKey {
+		return model.ErrCompanyExists{Name: company.Name}
+	}
+	return err
+}
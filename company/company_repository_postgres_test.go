@@ -0,0 +1,38 @@
+//go:build integration
+// +build integration
+
+package company_test
+
+import (
+	"database/sql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/ngereci/xm_interview/company"
+	"github.com/ngereci/xm_interview/company/companytest"
+	"github.com/ngereci/xm_interview/db"
+	"github.com/ngereci/xm_interview/env"
+	"github.com/spf13/viper"
+	"testing"
+)
+
+func TestPostgresRepository(t *testing.T) {
+	readTestConfig(t)
+	database, err := sql.Open("pgx", viper.GetString(env.COMPANY_POSTGRES_DSN))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	migrator, err := db.NewPostgresMigrator(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = migrator.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	companytest.RunRepositorySuite(t, func(t *testing.T) company.Repository {
+		if _, err := database.Exec(`TRUNCATE company`); err != nil {
+			t.Fatal(err)
+		}
+		return company.NewPostgresRepository(database)
+	}, companytest.Options{UniqueNames: true})
+}
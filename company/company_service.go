@@ -1,62 +1,127 @@
 package company
 
 import (
+	"context"
+	"fmt"
 	"github.com/google/uuid"
 	"github.com/ngereci/xm_interview/event"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/metrics"
 	"github.com/ngereci/xm_interview/model"
-	log "github.com/sirupsen/logrus"
+	"github.com/ngereci/xm_interview/tenant"
+	"time"
 )
 
+// Service manages the companies of the tenant in the context of each call, see tenant.WithID.
+// The companies of other tenants are treated as missing.
 type Service interface {
-	CreateCompany(newCompany *model.Company) (*model.Company, error)
-	GetCompanyByID(id uuid.UUID) (*model.Company, error)
-	UpdateCompany(id uuid.UUID, forUpdateCompany *model.Company) (*model.Company, error)
-	DeleteCompany(id uuid.UUID) error
+	CreateCompany(ctx context.Context, newCompany *model.Company) (*model.Company, error)
+	GetCompanyByID(ctx context.Context, id uuid.UUID) (*model.Company, error)
+	// GetCompaniesByIDs returns the companies with one of the ids in no particular order, missing ones are left out
+	GetCompaniesByIDs(ctx context.Context, ids []uuid.UUID) ([]*model.Company, error)
+	UpdateCompany(ctx context.Context, id uuid.UUID, forUpdateCompany *model.Company) (*model.Company, error)
+	DeleteCompany(ctx context.Context, id uuid.UUID) error
+	// SetTag adds the tag key to the company or replaces its value, RemoveTag returns model.ErrTagNotFound
+	// if the company has no tag key. Both send an update event of the company.
+	SetTag(ctx context.Context, id uuid.UUID, key string, value string) (*model.Company, error)
+	RemoveTag(ctx context.Context, id uuid.UUID, key string) (*model.Company, error)
+	// ListCompanies returns a single page of the companies starting at pageState (nil for the first page)
+	// together with the page state of the next page, which is empty after the last page.
+	ListCompanies(ctx context.Context, pageState []byte, pageSize int) ([]*model.Company, []byte, error)
+}
+
+// TypeChecker checks a company against its type, which is managed at runtime, see the companytype package.
+// previous is the stored company on updates and nil on creates. Companies breaking the rules of their type
+// are rejected with model.ErrInvalidCompany.
+type TypeChecker interface {
+	CheckCompany(ctx context.Context, company *model.Company, previous *model.Company) error
+}
+
+// Relationships applies the rules of the relationships between companies to their deletion, see the relationship package
+type Relationships interface {
+	// CheckDelete returns model.ErrCompanyHasBranches while the company has branches. Otherwise no branches are added
+	// to the company until unlock is called, after it is deleted and its relationships are removed.
+	CheckDelete(ctx context.Context, company *model.Company) (unlock func(), err error)
+	// RemoveCompany removes the relationships of a deleted company, its subsidiaries are left without parent
+	RemoveCompany(ctx context.Context, company *model.Company) error
 }
 
 type companyService struct {
 	repo          Repository
 	kafkaProducer event.KafkaAdapter
+	types         TypeChecker
+	relationships Relationships
 }
 
-func NewService(repo Repository, kafkaProducer event.KafkaAdapter) Service {
-	return &companyService{repo: repo, kafkaProducer: kafkaProducer}
+func NewService(repo Repository, kafkaProducer event.KafkaAdapter, types TypeChecker, relationships Relationships) Service {
+	return &companyService{repo: repo, kafkaProducer: kafkaProducer, types: types, relationships: relationships}
 }
 
-func (s *companyService) CreateCompany(newCompany *model.Company) (*model.Company, error) {
-	// Generate a new UUID for the company
-	newCompany.ID = uuid.New()
-	// determine new company name is unique
-	count, err := s.repo.CountByName(newCompany.Name)
+func (s *companyService) CreateCompany(ctx context.Context, newCompany *model.Company) (*model.Company, error) {
+	tenantID, err := tenant.FromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
-	if count > 0 {
-		return nil, model.ErrCompanyExists{Name: newCompany.Name}
+	if err = newCompany.Validate(); err != nil {
+		return nil, err
 	}
-	err = s.repo.Create(newCompany)
+	if err = s.types.CheckCompany(ctx, newCompany, nil); err != nil {
+		return nil, err
+	}
+	// Generate a new UUID for the company
+	newCompany.ID = uuid.New()
+	newCompany.TenantID = tenantID
+	ctx = logging.WithField(ctx, logging.FieldCompanyID, newCompany.ID)
+	// determine new company name is unique within the tenant
+	if err = s.checkNameIsFree(ctx, tenantID, newCompany.Name); err != nil {
+		return nil, err
+	}
+	err = s.repo.Create(ctx, newCompany)
 	if err != nil {
 		return nil, err
 	}
-	if kafkaErr := s.kafkaProducer.SendEventWithPayload(event.EVENT_CREATE, newCompany); kafkaErr != nil {
-		log.Errorf("company:%v created but send event failed, rolling back. error:%v", newCompany.ID, err)
+	if kafkaErr := s.kafkaProducer.SendEventWithPayload(ctx, event.EVENT_CREATE, newCompany); kafkaErr != nil {
+		logging.FromContext(ctx).Errorf("company:%v created but send event failed, rolling back. error:%v", newCompany.ID, kafkaErr)
 		//handle rollback
-		if err = s.repo.Delete(newCompany.ID); err != nil {
-			log.Errorf("company:%v rollback failed. error:%v", newCompany.ID, err)
+		err = s.repo.Delete(RollbackContext(ctx), tenantID, newCompany.ID)
+		metrics.ObserveRollback("create", err)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("company:%v rollback failed. error:%v", newCompany.ID, err)
 			return nil, err
 		}
-		log.Infof("company:%v rollback success", newCompany.ID)
+		logging.FromContext(ctx).Infof("company:%v rollback success", newCompany.ID)
 		return nil, kafkaErr
 	}
 	return newCompany, nil
 }
 
-func (s *companyService) GetCompanyByID(id uuid.UUID) (*model.Company, error) {
-	return s.repo.GetByID(id)
+func (s *companyService) GetCompanyByID(ctx context.Context, id uuid.UUID) (*model.Company, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx = logging.WithField(ctx, logging.FieldCompanyID, id)
+	return s.repo.GetByID(ctx, tenantID, id)
+}
+
+func (s *companyService) GetCompaniesByIDs(ctx context.Context, ids []uuid.UUID) ([]*model.Company, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetByIDs(ctx, tenantID, ids)
 }
 
-func (s *companyService) UpdateCompany(id uuid.UUID, forUpdateCompany *model.Company) (*model.Company, error) {
-	existingCompany, err := s.repo.GetByID(id)
+func (s *companyService) UpdateCompany(ctx context.Context, id uuid.UUID, forUpdateCompany *model.Company) (*model.Company, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx = logging.WithField(ctx, logging.FieldCompanyID, id)
+	if err = forUpdateCompany.Validate(); err != nil {
+		return nil, err
+	}
+	existingCompany, err := s.repo.GetByID(ctx, tenantID, id)
 
 	if err != nil {
 		return nil, err
@@ -65,53 +130,252 @@ func (s *companyService) UpdateCompany(id uuid.UUID, forUpdateCompany *model.Com
 	if existingCompany == nil {
 		return nil, model.ErrCompanyNotFound{Id: id}
 	}
+	if err = s.types.CheckCompany(ctx, forUpdateCompany, existingCompany); err != nil {
+		return nil, err
+	}
 
-	// Copy over the fields that can't be updated
+	// Copy over the fields that can't be updated, tags are changed with SetTag and RemoveTag and left as they are by Repository.Update
 	forUpdateCompany.ID = existingCompany.ID
+	forUpdateCompany.TenantID = existingCompany.TenantID
+	forUpdateCompany.Tags = existingCompany.Tags
+	// a company may change the spelling of its own name, see model.NormalizeName
+	if model.NormalizeName(forUpdateCompany.Name) != model.NormalizeName(existingCompany.Name) {
+		if err = s.checkNameIsFree(ctx, tenantID, forUpdateCompany.Name); err != nil {
+			return nil, err
+		}
+	}
 
-	updatedCompany, err := s.repo.Update(forUpdateCompany)
+	updatedCompany, err := s.repo.Update(ctx, forUpdateCompany)
 
 	if err != nil {
 		return nil, err
 	}
-	if kafkaErr := s.kafkaProducer.SendEventWithPayload(event.EVENT_UPDATE, forUpdateCompany); kafkaErr != nil {
-		log.Errorf("forUpdateCompany:%v updated but send event failed, rolling back. error:%v", forUpdateCompany.ID, err)
+	if kafkaErr := s.kafkaProducer.SendEventWithPayload(ctx, event.EVENT_UPDATE, forUpdateCompany); kafkaErr != nil {
+		logging.FromContext(ctx).Errorf("forUpdateCompany:%v updated but send event failed, rolling back. error:%v", forUpdateCompany.ID, kafkaErr)
 		//handle rollback
-		if _, err = s.repo.Update(existingCompany); err != nil {
-			log.Errorf("forUpdateCompany:%v rollback failed. error:%v", forUpdateCompany.ID, err)
+		_, err = s.repo.Update(RollbackContext(ctx), existingCompany)
+		metrics.ObserveRollback("update", err)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("forUpdateCompany:%v rollback failed. error:%v", forUpdateCompany.ID, err)
 			return nil, err
 		}
-		log.Infof("forUpdateCompany:%v rollback success", forUpdateCompany.ID)
+		logging.FromContext(ctx).Infof("forUpdateCompany:%v rollback success", forUpdateCompany.ID)
 		return nil, kafkaErr
 	}
 
 	return updatedCompany, nil
 }
 
-func (s *companyService) DeleteCompany(id uuid.UUID) error {
-	existingCompany, err := s.repo.GetByID(id)
+func (s *companyService) DeleteCompany(ctx context.Context, id uuid.UUID) error {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	ctx = logging.WithField(ctx, logging.FieldCompanyID, id)
+	existingCompany, err := s.repo.GetByID(ctx, tenantID, id)
 
 	if err != nil {
 		return err
 	}
 
 	if existingCompany == nil {
-		return model.ErrCompanyNotFound{id}
+		return model.ErrCompanyNotFound{Id: id}
+	}
+	unlock, err := s.relationships.CheckDelete(ctx, existingCompany)
+	if err != nil {
+		return err
 	}
+	defer unlock()
 
-	err = s.repo.Delete(id)
+	err = s.repo.Delete(ctx, tenantID, id)
 	if err != nil {
 		return err
 	}
-	if kafkaErr := s.kafkaProducer.SendEventWithPayload(event.EVENT_DELETE, existingCompany); kafkaErr != nil {
-		log.Errorf("company:%v deleted but send event failed, rolling back. error:%v", existingCompany.ID, err)
+	if kafkaErr := s.kafkaProducer.SendEventWithPayload(ctx, event.EVENT_DELETE, existingCompany); kafkaErr != nil {
+		logging.FromContext(ctx).Errorf("company:%v deleted but send event failed, rolling back. error:%v", existingCompany.ID, kafkaErr)
 		//handle rollback
-		if err = s.repo.Create(existingCompany); err != nil {
-			log.Errorf("company:%v rollback failed. error:%v", existingCompany.ID, err)
+		err = s.repo.Create(RollbackContext(ctx), existingCompany)
+		metrics.ObserveRollback("delete", err)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("company:%v rollback failed. error:%v", existingCompany.ID, err)
 			return err
 		}
-		log.Infof("company:%v rollback success", existingCompany.ID)
+		logging.FromContext(ctx).Infof("company:%v rollback success", existingCompany.ID)
 		return kafkaErr
 	}
+	// the company is gone either way, relationships left behind are skipped when they are traversed
+	if err = s.relationships.RemoveCompany(ctx, existingCompany); err != nil {
+		logging.FromContext(ctx).Errorf("company:%v deleted but removing its relationships failed. error:%v", existingCompany.ID, err)
+	}
 	return nil
 }
+
+func (s *companyService) SetTag(ctx context.Context, id uuid.UUID, key string, value string) (*model.Company, error) {
+	if err := model.ValidateTag(key, value); err != nil {
+		return nil, err
+	}
+	ctx = logging.WithField(ctx, logging.FieldCompanyID, id)
+	existingCompany, err := s.getTagged(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	previous, existed := existingCompany.Tags[key]
+	if !existed && len(existingCompany.Tags) >= model.MaxTags {
+		return nil, model.ErrInvalidCompany{Fields: []model.FieldError{{Field: "tags", Message: fmt.Sprintf("must have at most %v entries", model.MaxTags)}}}
+	}
+	// only the tag is written, concurrent changes of other tags or fields of the company are kept
+	updatedCompany, err := s.repo.SetTag(ctx, existingCompany.TenantID, id, key, value)
+	if err != nil {
+		return nil, err
+	}
+	if updatedCompany == nil {
+		return nil, model.ErrCompanyNotFound{Id: id}
+	}
+	return s.sendTagsChanged(ctx, updatedCompany, func(ctx context.Context) error {
+		if existed {
+			_, err := s.repo.SetTag(ctx, existingCompany.TenantID, id, key, previous)
+			return err
+		}
+		_, err := s.repo.DeleteTag(ctx, existingCompany.TenantID, id, key)
+		return err
+	})
+}
+
+func (s *companyService) RemoveTag(ctx context.Context, id uuid.UUID, key string) (*model.Company, error) {
+	ctx = logging.WithField(ctx, logging.FieldCompanyID, id)
+	existingCompany, err := s.getTagged(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	previous, existed := existingCompany.Tags[key]
+	if !existed {
+		return nil, model.ErrTagNotFound{Id: id, Key: key}
+	}
+	updatedCompany, err := s.repo.DeleteTag(ctx, existingCompany.TenantID, id, key)
+	if err != nil {
+		return nil, err
+	}
+	if updatedCompany == nil {
+		return nil, model.ErrCompanyNotFound{Id: id}
+	}
+	return s.sendTagsChanged(ctx, updatedCompany, func(ctx context.Context) error {
+		_, err := s.repo.SetTag(ctx, existingCompany.TenantID, id, key, previous)
+		return err
+	})
+}
+
+// getTagged returns the stored company whose tags are about to change
+func (s *companyService) getTagged(ctx context.Context, id uuid.UUID) (*model.Company, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	existingCompany, err := s.repo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if existingCompany == nil {
+		return nil, model.ErrCompanyNotFound{Id: id}
+	}
+	return existingCompany, nil
+}
+
+// sendTagsChanged sends the update event of the company as stored after a change of its tags, rollback undoes the
+// change of the single tag if the event could not be sent
+func (s *companyService) sendTagsChanged(ctx context.Context, updatedCompany *model.Company, rollback func(ctx context.Context) error) (*model.Company, error) {
+	if kafkaErr := s.kafkaProducer.SendEventWithPayload(ctx, event.EVENT_UPDATE, updatedCompany); kafkaErr != nil {
+		logging.FromContext(ctx).Errorf("company:%v tags changed but send event failed, rolling back. error:%v", updatedCompany.ID, kafkaErr)
+		//handle rollback
+		err := rollback(RollbackContext(ctx))
+		metrics.ObserveRollback("tags", err)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("company:%v rollback failed. error:%v", updatedCompany.ID, err)
+			return nil, err
+		}
+		logging.FromContext(ctx).Infof("company:%v rollback success", updatedCompany.ID)
+		return nil, kafkaErr
+	}
+	return updatedCompany, nil
+}
+
+func (s *companyService) ListCompanies(ctx context.Context, pageState []byte, pageSize int) ([]*model.Company, []byte, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.repo.ListByTenant(ctx, tenantID, pageState, pageSize)
+}
+
+// maxListPages bounds the pages of the service a filtered list scans per call
+const maxListPages = 10
+
+// listCursor is the position of a company list, the company at Skip of the page Service.ListCompanies returns
+// for PageState and PageSize. Filtered pages can end in the middle of a page of the service.
+type listCursor struct {
+	PageState []byte `json:"s,omitempty"`
+	Skip      int    `json:"o,omitempty"`
+	PageSize  int    `json:"n"`
+}
+
+// listMatching pages through the companies of service from cursor until limit of them match. A filter that matches
+// few companies ends the list after maxListPages pages of the service, with fewer companies or none. It returns the
+// cursor of the company after the last one it returns, nil at the end of the companies.
+func listMatching(ctx context.Context, service Service, cursor listCursor, limit int, matches func(*model.Company) bool) ([]*model.Company, *listCursor, error) {
+	matching := make([]*model.Company, 0, limit)
+	for pages := 1; ; pages++ {
+		companies, nextPageState, err := service.ListCompanies(ctx, cursor.PageState, cursor.PageSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i := cursor.Skip; i < len(companies); i++ {
+			if !matches(companies[i]) {
+				continue
+			}
+			matching = append(matching, companies[i])
+			if len(matching) == limit {
+				if i+1 < len(companies) {
+					return matching, &listCursor{PageState: cursor.PageState, Skip: i + 1, PageSize: cursor.PageSize}, nil
+				}
+				if len(nextPageState) == 0 {
+					return matching, nil, nil
+				}
+				return matching, &listCursor{PageState: nextPageState, PageSize: cursor.PageSize}, nil
+			}
+		}
+		if len(nextPageState) == 0 {
+			return matching, nil, nil
+		}
+		cursor = listCursor{PageState: nextPageState, PageSize: cursor.PageSize}
+		if pages == maxListPages {
+			return matching, &cursor, nil
+		}
+	}
+}
+
+// checkNameIsFree returns model.ErrCompanyExists if a company of tenantID has a name with the normalized form of name
+func (s *companyService) checkNameIsFree(ctx context.Context, tenantID string, name string) error {
+	count, err := s.repo.CountByName(ctx, tenantID, name)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return model.ErrCompanyExists{Name: name}
+	}
+	return nil
+}
+
+// RollbackContext detaches a rollback from the cancellation of the request, a rollback has
+// to run even when the event could not be sent because the request was cancelled.
+func RollbackContext(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+// detachedContext keeps the values of its parent but neither its deadline nor its cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (d detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (d detachedContext) Done() <-chan struct{}       { return nil }
+func (d detachedContext) Err() error                  { return nil }
+func (d detachedContext) Value(key any) any           { return d.parent.Value(key) }
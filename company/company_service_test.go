@@ -1,20 +1,27 @@
 package company
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/golang/mock/gomock"
 	"github.com/google/uuid"
 	"github.com/ngereci/xm_interview/event"
 	mock_kafka "github.com/ngereci/xm_interview/mocks/mock_company/event"
 	mock_company_repository "github.com/ngereci/xm_interview/mocks/mock_company/repository"
+	mock_company_service "github.com/ngereci/xm_interview/mocks/mock_company/service"
 	"github.com/ngereci/xm_interview/model"
+	"github.com/ngereci/xm_interview/tenant"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
 
+const testTenant = "tenant-a"
+
 var (
 	testCompany = &model.Company{
 		ID:          uuid.MustParse("56f86115-a58f-43db-8a1b-9aa2908f7a18"),
+		TenantID:    testTenant,
 		Name:        "Test Company",
 		Description: "Test Description",
 		Employees:   100,
@@ -32,6 +39,25 @@ var (
 	testErr = errors.New("test error")
 )
 
+func tenantContext() context.Context {
+	return tenant.WithID(context.Background(), testTenant)
+}
+
+// anyType returns a TypeChecker accepting every company
+func anyType(ctrl *gomock.Controller) TypeChecker {
+	types := mock_company_service.NewMockTypeChecker(ctrl)
+	types.EXPECT().CheckCompany(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	return types
+}
+
+// noRelationships returns Relationships of companies without any relationships
+func noRelationships(ctrl *gomock.Controller) Relationships {
+	relationships := mock_company_service.NewMockRelationships(ctrl)
+	relationships.EXPECT().CheckDelete(gomock.Any(), gomock.Any()).Return(func() {}, nil).AnyTimes()
+	relationships.EXPECT().RemoveCompany(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	return relationships
+}
+
 func TestCompanyService_CreateCompany(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -40,20 +66,23 @@ func TestCompanyService_CreateCompany(t *testing.T) {
 	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
 
 	newCompany := &model.Company{
-		Name: "Test Company",
+		Name:      "Test Company",
+		Employees: 10,
+		Type:      model.Corporation,
 	}
 
-	mockRepo.EXPECT().CountByName(newCompany.Name).Return(0, nil)
-	mockRepo.EXPECT().Create(gomock.Any()).DoAndReturn(func(company *model.Company) error {
+	mockRepo.EXPECT().CountByName(gomock.Any(), testTenant, newCompany.Name).Return(0, nil)
+	mockRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, company *model.Company) error {
 		assert.Equal(t, newCompany.Name, company.Name)
+		assert.Equal(t, testTenant, company.TenantID)
 		assert.NotEqual(t, uuid.Nil, company.ID)
 		*testCompany = *company
 		return nil
 	})
-	mockKafka.EXPECT().SendEventWithPayload(event.EVENT_CREATE, testCompany).Return(nil)
+	mockKafka.EXPECT().SendEventWithPayload(gomock.Any(), event.EVENT_CREATE, testCompany).Return(nil)
 
-	svc := NewService(mockRepo, mockKafka)
-	company, err := svc.CreateCompany(newCompany)
+	svc := NewService(mockRepo, mockKafka, anyType(ctrl), noRelationships(ctrl))
+	company, err := svc.CreateCompany(tenantContext(), newCompany)
 
 	assert.NoError(t, err)
 	assert.Equal(t, testCompany, company)
@@ -67,18 +96,20 @@ func TestCompanyService_CreateCompany_AlreadyExists(t *testing.T) {
 	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
 
 	newCompany := &model.Company{
-		Name: "Test Company",
+		Name:      "Test Company",
+		Employees: 10,
+		Type:      model.Corporation,
 	}
 
-	mockRepo.EXPECT().CountByName(newCompany.Name).Return(1, nil)
-	svc := NewService(mockRepo, mockKafka)
-	_, err := svc.CreateCompany(newCompany)
+	mockRepo.EXPECT().CountByName(gomock.Any(), testTenant, newCompany.Name).Return(1, nil)
+	svc := NewService(mockRepo, mockKafka, anyType(ctrl), noRelationships(ctrl))
+	_, err := svc.CreateCompany(tenantContext(), newCompany)
 	assert.Error(t, err)
 	assert.IsType(t, model.ErrCompanyExists{}, err)
 
-	mockRepo.EXPECT().CountByName(newCompany.Name).Return(0, testErr)
+	mockRepo.EXPECT().CountByName(gomock.Any(), testTenant, newCompany.Name).Return(0, testErr)
 
-	_, err = svc.CreateCompany(newCompany)
+	_, err = svc.CreateCompany(tenantContext(), newCompany)
 	assert.Error(t, err)
 	assert.Equal(t, testErr, err)
 }
@@ -91,19 +122,21 @@ func TestCompanyService_CreateCompany_CreateFailed(t *testing.T) {
 	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
 
 	newCompany := &model.Company{
-		Name: "Test Company",
+		Name:      "Test Company",
+		Employees: 10,
+		Type:      model.Corporation,
 	}
 
-	mockRepo.EXPECT().CountByName(newCompany.Name).Return(0, nil)
-	mockRepo.EXPECT().Create(gomock.Any()).DoAndReturn(func(company *model.Company) error {
+	mockRepo.EXPECT().CountByName(gomock.Any(), testTenant, newCompany.Name).Return(0, nil)
+	mockRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, company *model.Company) error {
 		assert.Equal(t, newCompany.Name, company.Name)
 		assert.NotEqual(t, uuid.Nil, company.ID)
 		*testCompany = *company
 		return testErr
 	})
 	//mockKafka.EXPECT().SendEventWithPayload(event.EVENT_CREATE, testCompany).Return(testErr)
-	svc := NewService(mockRepo, mockKafka)
-	_, err := svc.CreateCompany(newCompany)
+	svc := NewService(mockRepo, mockKafka, anyType(ctrl), noRelationships(ctrl))
+	_, err := svc.CreateCompany(tenantContext(), newCompany)
 	assert.Error(t, err)
 	assert.Equal(t, testErr, err)
 }
@@ -116,24 +149,83 @@ func TestCompanyService_CreateCompany_KafkaFailed(t *testing.T) {
 	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
 
 	newCompany := &model.Company{
-		Name: "Test Company",
+		Name:      "Test Company",
+		Employees: 10,
+		Type:      model.Corporation,
 	}
 
-	mockRepo.EXPECT().CountByName(newCompany.Name).Return(0, nil)
-	mockRepo.EXPECT().Create(gomock.Any()).DoAndReturn(func(company *model.Company) error {
+	mockRepo.EXPECT().CountByName(gomock.Any(), testTenant, newCompany.Name).Return(0, nil)
+	mockRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, company *model.Company) error {
 		assert.Equal(t, newCompany.Name, company.Name)
 		assert.NotEqual(t, uuid.Nil, company.ID)
 		*testCompany = *company
 		return nil
 	})
-	mockRepo.EXPECT().Delete(gomock.Any()).Return(nil)
-	mockKafka.EXPECT().SendEventWithPayload(event.EVENT_CREATE, testCompany).Return(testErr)
-	svc := NewService(mockRepo, mockKafka)
-	_, err := svc.CreateCompany(newCompany)
+	mockRepo.EXPECT().Delete(gomock.Any(), testTenant, gomock.Any()).Return(nil)
+	mockKafka.EXPECT().SendEventWithPayload(gomock.Any(), event.EVENT_CREATE, testCompany).Return(testErr)
+	svc := NewService(mockRepo, mockKafka, anyType(ctrl), noRelationships(ctrl))
+	_, err := svc.CreateCompany(tenantContext(), newCompany)
 	assert.Error(t, err)
 	assert.Equal(t, testErr, err)
 }
 
+func TestCompanyService_InvalidCompany(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// the repository and producer are never called
+	svc := NewService(mock_company_repository.NewMockRepository(ctrl), mock_kafka.NewMockKafkaAdapter(ctrl), anyType(ctrl), noRelationships(ctrl))
+	invalidCompany := &model.Company{Name: "Test Company", Employees: -1, Type: "non-profit"}
+
+	_, err := svc.CreateCompany(tenantContext(), invalidCompany)
+	var invalid model.ErrInvalidCompany
+	assert.ErrorAs(t, err, &invalid)
+	assert.Equal(t, []string{"employees", "type"}, []string{invalid.Fields[0].Field, invalid.Fields[1].Field})
+
+	_, err = svc.UpdateCompany(tenantContext(), testCompany.ID, invalidCompany)
+	assert.ErrorAs(t, err, &invalid)
+}
+
+func TestCompanyService_TypeRulesBroken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_company_repository.NewMockRepository(ctrl)
+	types := mock_company_service.NewMockTypeChecker(ctrl)
+	svc := NewService(mockRepo, mock_kafka.NewMockKafkaAdapter(ctrl), types, noRelationships(ctrl))
+	broken := model.ErrInvalidCompany{Fields: []model.FieldError{{Field: "registered", Message: "must be true for type NonProfit"}}}
+	newCompany := &model.Company{Name: "Test Company", Employees: 10, Type: model.NonProfit}
+
+	types.EXPECT().CheckCompany(gomock.Any(), newCompany, nil).Return(broken)
+	_, err := svc.CreateCompany(tenantContext(), newCompany)
+	assert.Equal(t, broken, err)
+
+	// updates are checked against the stored company, which may keep a deprecated type
+	mockRepo.EXPECT().GetByID(gomock.Any(), testTenant, testCompany.ID).Return(testCompany, nil)
+	types.EXPECT().CheckCompany(gomock.Any(), newCompany, testCompany).Return(broken)
+	_, err = svc.UpdateCompany(tenantContext(), testCompany.ID, newCompany)
+	assert.Equal(t, broken, err)
+}
+
+func TestCompanyService_WithoutTenant(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc := NewService(mock_company_repository.NewMockRepository(ctrl), mock_kafka.NewMockKafkaAdapter(ctrl), anyType(ctrl), noRelationships(ctrl))
+
+	_, err := svc.CreateCompany(context.Background(), &model.Company{Name: "Test Company"})
+	assert.Equal(t, tenant.ErrMissing, err)
+	_, err = svc.GetCompanyByID(context.Background(), testCompany.ID)
+	assert.Equal(t, tenant.ErrMissing, err)
+	_, err = svc.UpdateCompany(context.Background(), testCompany.ID, testCompanyUpdate)
+	assert.Equal(t, tenant.ErrMissing, err)
+	assert.Equal(t, tenant.ErrMissing, svc.DeleteCompany(context.Background(), testCompany.ID))
+	_, _, err = svc.ListCompanies(context.Background(), nil, 10)
+	assert.Equal(t, tenant.ErrMissing, err)
+	_, err = svc.GetCompaniesByIDs(context.Background(), []uuid.UUID{testCompany.ID})
+	assert.Equal(t, tenant.ErrMissing, err)
+}
+
 func TestGetCompanyByID(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -141,10 +233,10 @@ func TestGetCompanyByID(t *testing.T) {
 	mockRepo := mock_company_repository.NewMockRepository(ctrl)
 	mockKafkaProducer := mock_kafka.NewMockKafkaAdapter(ctrl)
 
-	mockRepo.EXPECT().GetByID(testCompany.ID).Return(testCompany, nil)
+	mockRepo.EXPECT().GetByID(gomock.Any(), testTenant, testCompany.ID).Return(testCompany, nil)
 
-	companyService := NewService(mockRepo, mockKafkaProducer)
-	company, err := companyService.GetCompanyByID(testCompany.ID)
+	companyService := NewService(mockRepo, mockKafkaProducer, anyType(ctrl), noRelationships(ctrl))
+	company, err := companyService.GetCompanyByID(tenantContext(), testCompany.ID)
 
 	assert.NoError(t, err)
 	assert.Equal(t, testCompany, company)
@@ -157,15 +249,46 @@ func TestGetCompanyByID_Error(t *testing.T) {
 	mockRepo := mock_company_repository.NewMockRepository(ctrl)
 	mockKafkaProducer := mock_kafka.NewMockKafkaAdapter(ctrl)
 
-	mockRepo.EXPECT().GetByID(testCompany.ID).Return(nil, errors.New("something went wrong"))
+	mockRepo.EXPECT().GetByID(gomock.Any(), testTenant, testCompany.ID).Return(nil, errors.New("something went wrong"))
 
-	companyService := NewService(mockRepo, mockKafkaProducer)
-	company, err := companyService.GetCompanyByID(testCompany.ID)
+	companyService := NewService(mockRepo, mockKafkaProducer, anyType(ctrl), noRelationships(ctrl))
+	company, err := companyService.GetCompanyByID(tenantContext(), testCompany.ID)
 
 	assert.Error(t, err)
 	assert.Nil(t, company)
 }
 
+func TestCompanyService_ListCompanies(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_company_repository.NewMockRepository(ctrl)
+	pageState, nextPageState := []byte("page"), []byte("next")
+	mockRepo.EXPECT().ListByTenant(gomock.Any(), testTenant, pageState, 10).Return([]*model.Company{testCompany}, nextPageState, nil)
+
+	companyService := NewService(mockRepo, mock_kafka.NewMockKafkaAdapter(ctrl), anyType(ctrl), noRelationships(ctrl))
+	companies, next, err := companyService.ListCompanies(tenantContext(), pageState, 10)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*model.Company{testCompany}, companies)
+	assert.Equal(t, nextPageState, next)
+}
+
+func TestCompanyService_GetCompaniesByIDs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_company_repository.NewMockRepository(ctrl)
+	ids := []uuid.UUID{testCompany.ID, uuid.New()}
+	mockRepo.EXPECT().GetByIDs(gomock.Any(), testTenant, ids).Return([]*model.Company{testCompany}, nil)
+
+	companyService := NewService(mockRepo, mock_kafka.NewMockKafkaAdapter(ctrl), anyType(ctrl), noRelationships(ctrl))
+	companies, err := companyService.GetCompaniesByIDs(tenantContext(), ids)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*model.Company{testCompany}, companies)
+}
+
 func TestCompanyService_UpdateCompany(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -173,17 +296,42 @@ func TestCompanyService_UpdateCompany(t *testing.T) {
 	mockRepo := mock_company_repository.NewMockRepository(ctrl)
 	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
 
-	mockRepo.EXPECT().GetByID(testCompany.ID).Return(testCompany, nil)
-	mockRepo.EXPECT().Update(gomock.Any()).Return(testCompanyUpdate, nil)
-	mockKafka.EXPECT().SendEventWithPayload(event.EVENT_UPDATE, testCompanyUpdate).Return(nil)
+	mockRepo.EXPECT().GetByID(gomock.Any(), testTenant, testCompany.ID).Return(testCompany, nil)
+	mockRepo.EXPECT().CountByName(gomock.Any(), testTenant, testCompanyUpdate.Name).Return(0, nil)
+	mockRepo.EXPECT().Update(gomock.Any(), gomock.Any()).Return(testCompanyUpdate, nil)
+	mockKafka.EXPECT().SendEventWithPayload(gomock.Any(), event.EVENT_UPDATE, testCompanyUpdate).Return(nil)
 
-	svc := NewService(mockRepo, mockKafka)
-	company, err := svc.UpdateCompany(testCompany.ID, testCompanyUpdate)
+	svc := NewService(mockRepo, mockKafka, anyType(ctrl), noRelationships(ctrl))
+	company, err := svc.UpdateCompany(tenantContext(), testCompany.ID, testCompanyUpdate)
 
 	assert.NoError(t, err)
 	assert.Equal(t, testCompanyUpdate, company)
 }
 
+func TestCompanyService_UpdateCompany_KeepsTenant(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_company_repository.NewMockRepository(ctrl)
+	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
+
+	forUpdate := *testCompanyUpdate
+	forUpdate.TenantID = "tenant-b"
+	mockRepo.EXPECT().GetByID(gomock.Any(), testTenant, testCompany.ID).Return(testCompany, nil)
+	mockRepo.EXPECT().CountByName(gomock.Any(), testTenant, testCompanyUpdate.Name).Return(0, nil)
+	mockRepo.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, company *model.Company) (*model.Company, error) {
+		assert.Equal(t, testTenant, company.TenantID)
+		return company, nil
+	})
+	mockKafka.EXPECT().SendEventWithPayload(gomock.Any(), event.EVENT_UPDATE, gomock.Any()).Return(nil)
+
+	svc := NewService(mockRepo, mockKafka, anyType(ctrl), noRelationships(ctrl))
+	company, err := svc.UpdateCompany(tenantContext(), testCompany.ID, &forUpdate)
+
+	assert.NoError(t, err)
+	assert.Equal(t, testTenant, company.TenantID)
+}
+
 func TestCompanyService_UpdateCompany_UpdateFailed(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -191,11 +339,12 @@ func TestCompanyService_UpdateCompany_UpdateFailed(t *testing.T) {
 	mockRepo := mock_company_repository.NewMockRepository(ctrl)
 	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
 
-	mockRepo.EXPECT().GetByID(testCompany.ID).Return(testCompany, nil)
-	mockRepo.EXPECT().Update(gomock.Any()).Return(nil, errors.New("something went wrong"))
+	mockRepo.EXPECT().GetByID(gomock.Any(), testTenant, testCompany.ID).Return(testCompany, nil)
+	mockRepo.EXPECT().CountByName(gomock.Any(), testTenant, testCompanyUpdate.Name).Return(0, nil)
+	mockRepo.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil, errors.New("something went wrong"))
 
-	svc := NewService(mockRepo, mockKafka)
-	company, err := svc.UpdateCompany(testCompany.ID, testCompanyUpdate)
+	svc := NewService(mockRepo, mockKafka, anyType(ctrl), noRelationships(ctrl))
+	company, err := svc.UpdateCompany(tenantContext(), testCompany.ID, testCompanyUpdate)
 
 	assert.Error(t, err)
 	assert.Nil(t, company)
@@ -208,17 +357,234 @@ func TestCompanyService_UpdateCompany_KafkaFailed(t *testing.T) {
 	mockRepo := mock_company_repository.NewMockRepository(ctrl)
 	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
 
-	mockRepo.EXPECT().GetByID(testCompany.ID).Return(testCompany, nil)
-	//mockRepo.EXPECT().CountByName(testCompany.Name).Return(0, nil)
-	mockRepo.EXPECT().Update(gomock.Any()).Return(testCompanyUpdate, nil).Times(2)
-	mockKafka.EXPECT().SendEventWithPayload(event.EVENT_UPDATE, testCompanyUpdate).Return(errors.New("something went wrong"))
+	mockRepo.EXPECT().GetByID(gomock.Any(), testTenant, testCompany.ID).Return(testCompany, nil)
+	mockRepo.EXPECT().CountByName(gomock.Any(), testTenant, testCompanyUpdate.Name).Return(0, nil)
+	mockRepo.EXPECT().Update(gomock.Any(), gomock.Any()).Return(testCompanyUpdate, nil).Times(2)
+	mockKafka.EXPECT().SendEventWithPayload(gomock.Any(), event.EVENT_UPDATE, testCompanyUpdate).Return(errors.New("something went wrong"))
 
-	svc := NewService(mockRepo, mockKafka)
-	company, err := svc.UpdateCompany(testCompany.ID, testCompanyUpdate)
+	svc := NewService(mockRepo, mockKafka, anyType(ctrl), noRelationships(ctrl))
+	company, err := svc.UpdateCompany(tenantContext(), testCompany.ID, testCompanyUpdate)
 
 	assert.Error(t, err)
 	assert.Nil(t, company)
 }
+
+func TestCompanyService_UpdateCompany_NameTaken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_company_repository.NewMockRepository(ctrl)
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), testTenant, testCompany.ID).Return(testCompany, nil)
+	mockRepo.EXPECT().CountByName(gomock.Any(), testTenant, testCompanyUpdate.Name).Return(1, nil)
+
+	svc := NewService(mockRepo, mock_kafka.NewMockKafkaAdapter(ctrl), anyType(ctrl), noRelationships(ctrl))
+	company, err := svc.UpdateCompany(tenantContext(), testCompany.ID, testCompanyUpdate)
+
+	assert.Equal(t, model.ErrCompanyExists{Name: testCompanyUpdate.Name}, err)
+	assert.Nil(t, company)
+}
+
+func TestCompanyService_UpdateCompany_SameNormalizedName(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_company_repository.NewMockRepository(ctrl)
+	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
+
+	// the company itself holds the name, so it is not looked up
+	forUpdate := *testCompany
+	forUpdate.Name = " TEST  company ltd"
+	mockRepo.EXPECT().GetByID(gomock.Any(), testTenant, testCompany.ID).Return(testCompany, nil)
+	mockRepo.EXPECT().Update(gomock.Any(), &forUpdate).Return(&forUpdate, nil)
+	mockKafka.EXPECT().SendEventWithPayload(gomock.Any(), event.EVENT_UPDATE, &forUpdate).Return(nil)
+
+	svc := NewService(mockRepo, mockKafka, anyType(ctrl), noRelationships(ctrl))
+	company, err := svc.UpdateCompany(tenantContext(), testCompany.ID, &forUpdate)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &forUpdate, company)
+}
+
+func TestCompanyService_UpdateCompany_KeepsTags(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_company_repository.NewMockRepository(ctrl)
+	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
+
+	existing := *testCompany
+	existing.Tags = map[string]string{"env": "prod"}
+	forUpdate := *testCompanyUpdate
+	forUpdate.Tags = map[string]string{"env": "staging"}
+	mockRepo.EXPECT().GetByID(gomock.Any(), testTenant, testCompany.ID).Return(&existing, nil)
+	mockRepo.EXPECT().CountByName(gomock.Any(), testTenant, testCompanyUpdate.Name).Return(0, nil)
+	mockRepo.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, company *model.Company) (*model.Company, error) {
+		return company, nil
+	})
+	mockKafka.EXPECT().SendEventWithPayload(gomock.Any(), event.EVENT_UPDATE, gomock.Any()).Return(nil)
+
+	svc := NewService(mockRepo, mockKafka, anyType(ctrl), noRelationships(ctrl))
+	company, err := svc.UpdateCompany(tenantContext(), testCompany.ID, &forUpdate)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod"}, company.Tags)
+}
+
+func TestCompanyService_SetTag(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_company_repository.NewMockRepository(ctrl)
+	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
+
+	existing := *testCompany
+	existing.Tags = map[string]string{"env": "staging"}
+	// the event carries the company as stored, with the tags other calls set in the meantime
+	stored := existing
+	stored.Tags = map[string]string{"env": "prod", "team": "payments"}
+	mockRepo.EXPECT().GetByID(gomock.Any(), testTenant, testCompany.ID).Return(&existing, nil)
+	mockRepo.EXPECT().SetTag(gomock.Any(), testTenant, testCompany.ID, "env", "prod").Return(&stored, nil)
+	mockKafka.EXPECT().SendEventWithPayload(gomock.Any(), event.EVENT_UPDATE, &stored).Return(nil)
+
+	svc := NewService(mockRepo, mockKafka, anyType(ctrl), noRelationships(ctrl))
+	company, err := svc.SetTag(tenantContext(), testCompany.ID, "env", "prod")
+
+	assert.NoError(t, err)
+	assert.Equal(t, &stored, company)
+}
+
+func TestCompanyService_SetTag_Invalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_company_repository.NewMockRepository(ctrl)
+	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
+
+	full := *testCompany
+	full.Tags = make(map[string]string, model.MaxTags)
+	for i := 0; i < model.MaxTags; i++ {
+		full.Tags[fmt.Sprintf("tag-%v", i)] = "x"
+	}
+	mockRepo.EXPECT().GetByID(gomock.Any(), testTenant, testCompany.ID).Return(&full, nil).Times(2)
+	mockRepo.EXPECT().SetTag(gomock.Any(), testTenant, testCompany.ID, "tag-0", "y").Return(&full, nil)
+	mockKafka.EXPECT().SendEventWithPayload(gomock.Any(), event.EVENT_UPDATE, &full).Return(nil)
+
+	svc := NewService(mockRepo, mockKafka, anyType(ctrl), noRelationships(ctrl))
+	company, err := svc.SetTag(tenantContext(), testCompany.ID, "Env", "prod")
+	var invalid model.ErrInvalidCompany
+	assert.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "tags.Env", invalid.Fields[0].Field)
+	assert.Nil(t, company)
+
+	_, err = svc.SetTag(tenantContext(), testCompany.ID, "env", "prod")
+	assert.ErrorAs(t, err, &invalid)
+	assert.Equal(t, []model.FieldError{{Field: "tags", Message: "must have at most 50 entries"}}, invalid.Fields)
+	// replacing the value of a tag does not add one
+	_, err = svc.SetTag(tenantContext(), testCompany.ID, "tag-0", "y")
+	assert.NoError(t, err)
+}
+
+func TestCompanyService_SetTag_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_company_repository.NewMockRepository(ctrl)
+	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
+
+	gomock.InOrder(
+		mockRepo.EXPECT().GetByID(gomock.Any(), testTenant, testCompany.ID).Return(nil, nil),
+		// deleted between reading and tagging it
+		mockRepo.EXPECT().GetByID(gomock.Any(), testTenant, testCompany.ID).Return(testCompany, nil),
+	)
+	mockRepo.EXPECT().SetTag(gomock.Any(), testTenant, testCompany.ID, "env", "prod").Return(nil, nil)
+
+	svc := NewService(mockRepo, mockKafka, anyType(ctrl), noRelationships(ctrl))
+	for i := 0; i < 2; i++ {
+		company, err := svc.SetTag(tenantContext(), testCompany.ID, "env", "prod")
+		assert.Equal(t, model.ErrCompanyNotFound{Id: testCompany.ID}, err)
+		assert.Nil(t, company)
+	}
+}
+
+func TestCompanyService_SetTag_KafkaFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_company_repository.NewMockRepository(ctrl)
+	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
+
+	existing := *testCompany
+	existing.Tags = map[string]string{"env": "staging"}
+	mockRepo.EXPECT().GetByID(gomock.Any(), testTenant, testCompany.ID).Return(&existing, nil).Times(2)
+	mockKafka.EXPECT().SendEventWithPayload(gomock.Any(), event.EVENT_UPDATE, gomock.Any()).Return(testErr).Times(2)
+	// the rollback restores the previous value of the tag, or removes a tag that was new
+	gomock.InOrder(
+		mockRepo.EXPECT().SetTag(gomock.Any(), testTenant, testCompany.ID, "env", "prod").Return(&existing, nil),
+		mockRepo.EXPECT().SetTag(gomock.Any(), testTenant, testCompany.ID, "env", "staging").Return(&existing, nil),
+	)
+	gomock.InOrder(
+		mockRepo.EXPECT().SetTag(gomock.Any(), testTenant, testCompany.ID, "team", "payments").Return(&existing, nil),
+		mockRepo.EXPECT().DeleteTag(gomock.Any(), testTenant, testCompany.ID, "team").Return(&existing, nil),
+	)
+
+	svc := NewService(mockRepo, mockKafka, anyType(ctrl), noRelationships(ctrl))
+	company, err := svc.SetTag(tenantContext(), testCompany.ID, "env", "prod")
+	assert.Equal(t, testErr, err)
+	assert.Nil(t, company)
+	company, err = svc.SetTag(tenantContext(), testCompany.ID, "team", "payments")
+	assert.Equal(t, testErr, err)
+	assert.Nil(t, company)
+}
+
+func TestCompanyService_RemoveTag(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_company_repository.NewMockRepository(ctrl)
+	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
+
+	existing := *testCompany
+	existing.Tags = map[string]string{"env": "prod", "team": "payments"}
+	untagged := existing
+	untagged.Tags = map[string]string{"team": "payments"}
+	mockRepo.EXPECT().GetByID(gomock.Any(), testTenant, testCompany.ID).Return(&existing, nil).Times(2)
+	mockRepo.EXPECT().DeleteTag(gomock.Any(), testTenant, testCompany.ID, "env").Return(&untagged, nil)
+	mockKafka.EXPECT().SendEventWithPayload(gomock.Any(), event.EVENT_UPDATE, &untagged).Return(nil)
+
+	svc := NewService(mockRepo, mockKafka, anyType(ctrl), noRelationships(ctrl))
+	company, err := svc.RemoveTag(tenantContext(), testCompany.ID, "env")
+	assert.NoError(t, err)
+	assert.Equal(t, &untagged, company)
+
+	company, err = svc.RemoveTag(tenantContext(), testCompany.ID, "owner")
+	assert.Equal(t, model.ErrTagNotFound{Id: testCompany.ID, Key: "owner"}, err)
+	assert.Nil(t, company)
+}
+
+func TestCompanyService_RemoveTag_KafkaFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_company_repository.NewMockRepository(ctrl)
+	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
+
+	existing := *testCompany
+	existing.Tags = map[string]string{"env": "prod"}
+	mockRepo.EXPECT().GetByID(gomock.Any(), testTenant, testCompany.ID).Return(&existing, nil)
+	gomock.InOrder(
+		mockRepo.EXPECT().DeleteTag(gomock.Any(), testTenant, testCompany.ID, "env").Return(testCompany, nil),
+		mockRepo.EXPECT().SetTag(gomock.Any(), testTenant, testCompany.ID, "env", "prod").Return(&existing, nil),
+	)
+	mockKafka.EXPECT().SendEventWithPayload(gomock.Any(), event.EVENT_UPDATE, testCompany).Return(testErr)
+
+	svc := NewService(mockRepo, mockKafka, anyType(ctrl), noRelationships(ctrl))
+	company, err := svc.RemoveTag(tenantContext(), testCompany.ID, "env")
+
+	assert.Equal(t, testErr, err)
+	assert.Nil(t, company)
+}
+
 func TestCompanyService_DeleteCompany(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -226,15 +592,61 @@ func TestCompanyService_DeleteCompany(t *testing.T) {
 	mockRepo := mock_company_repository.NewMockRepository(ctrl)
 	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
 
-	mockRepo.EXPECT().GetByID(testCompany.ID).Return(testCompany, nil)
+	relationships := mock_company_service.NewMockRelationships(ctrl)
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), testTenant, testCompany.ID).Return(testCompany, nil)
 	//mockRepo.EXPECT().CountByName(testCompany.Name).Return(0, nil)
-	mockRepo.EXPECT().Delete(gomock.Any()).Return(nil)
-	mockKafka.EXPECT().SendEventWithPayload(event.EVENT_DELETE, testCompany).Return(nil)
+	unlocked := false
+	relationships.EXPECT().CheckDelete(gomock.Any(), testCompany).Return(func() { unlocked = true }, nil)
+	mockRepo.EXPECT().Delete(gomock.Any(), testTenant, gomock.Any()).Return(nil)
+	mockKafka.EXPECT().SendEventWithPayload(gomock.Any(), event.EVENT_DELETE, testCompany).Return(nil)
+	relationships.EXPECT().RemoveCompany(gomock.Any(), testCompany).DoAndReturn(func(context.Context, *model.Company) error {
+		// no branches are added until the relationships are removed
+		assert.False(t, unlocked)
+		return nil
+	})
 
-	svc := NewService(mockRepo, mockKafka)
-	err := svc.DeleteCompany(testCompany.ID)
+	svc := NewService(mockRepo, mockKafka, anyType(ctrl), relationships)
+	err := svc.DeleteCompany(tenantContext(), testCompany.ID)
 
 	assert.NoError(t, err)
+	assert.True(t, unlocked)
+}
+
+func TestCompanyService_DeleteCompany_HasBranches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_company_repository.NewMockRepository(ctrl)
+	relationships := mock_company_service.NewMockRelationships(ctrl)
+	hasBranches := model.ErrCompanyHasBranches{Id: testCompany.ID, Branches: 2}
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), testTenant, testCompany.ID).Return(testCompany, nil)
+	relationships.EXPECT().CheckDelete(gomock.Any(), testCompany).Return(nil, hasBranches)
+
+	svc := NewService(mockRepo, mock_kafka.NewMockKafkaAdapter(ctrl), anyType(ctrl), relationships)
+	err := svc.DeleteCompany(tenantContext(), testCompany.ID)
+
+	assert.Equal(t, hasBranches, err)
+}
+
+func TestCompanyService_DeleteCompany_RemoveRelationshipsFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_company_repository.NewMockRepository(ctrl)
+	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
+	relationships := mock_company_service.NewMockRelationships(ctrl)
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), testTenant, testCompany.ID).Return(testCompany, nil)
+	relationships.EXPECT().CheckDelete(gomock.Any(), testCompany).Return(func() {}, nil)
+	mockRepo.EXPECT().Delete(gomock.Any(), testTenant, testCompany.ID).Return(nil)
+	mockKafka.EXPECT().SendEventWithPayload(gomock.Any(), event.EVENT_DELETE, testCompany).Return(nil)
+	relationships.EXPECT().RemoveCompany(gomock.Any(), testCompany).Return(errors.New("something went wrong"))
+
+	svc := NewService(mockRepo, mockKafka, anyType(ctrl), relationships)
+	// the company is deleted all the same
+	assert.NoError(t, svc.DeleteCompany(tenantContext(), testCompany.ID))
 }
 
 func TestCompanyService_DeleteCompany_DeleteFailed(t *testing.T) {
@@ -244,11 +656,11 @@ func TestCompanyService_DeleteCompany_DeleteFailed(t *testing.T) {
 	mockRepo := mock_company_repository.NewMockRepository(ctrl)
 	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
 
-	mockRepo.EXPECT().GetByID(testCompany.ID).Return(testCompany, nil)
-	mockRepo.EXPECT().Delete(gomock.Any()).Return(errors.New("something went wrong"))
+	mockRepo.EXPECT().GetByID(gomock.Any(), testTenant, testCompany.ID).Return(testCompany, nil)
+	mockRepo.EXPECT().Delete(gomock.Any(), testTenant, gomock.Any()).Return(errors.New("something went wrong"))
 
-	svc := NewService(mockRepo, mockKafka)
-	err := svc.DeleteCompany(testCompany.ID)
+	svc := NewService(mockRepo, mockKafka, anyType(ctrl), noRelationships(ctrl))
+	err := svc.DeleteCompany(tenantContext(), testCompany.ID)
 
 	assert.Error(t, err)
 }
@@ -260,13 +672,37 @@ func TestCompanyService_DeleteCompany_KafkaFailed(t *testing.T) {
 	mockRepo := mock_company_repository.NewMockRepository(ctrl)
 	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
 
-	mockRepo.EXPECT().GetByID(testCompany.ID).Return(testCompany, nil)
-	mockRepo.EXPECT().Delete(gomock.Any()).Return(nil)
-	mockRepo.EXPECT().Create(testCompany).Return(nil)
-	mockKafka.EXPECT().SendEventWithPayload(event.EVENT_DELETE, testCompany).Return(errors.New("something went wrong"))
+	mockRepo.EXPECT().GetByID(gomock.Any(), testTenant, testCompany.ID).Return(testCompany, nil)
+	mockRepo.EXPECT().Delete(gomock.Any(), testTenant, gomock.Any()).Return(nil)
+	mockRepo.EXPECT().Create(gomock.Any(), testCompany).Return(nil)
+	mockKafka.EXPECT().SendEventWithPayload(gomock.Any(), event.EVENT_DELETE, testCompany).Return(errors.New("something went wrong"))
 
-	svc := NewService(mockRepo, mockKafka)
-	err := svc.DeleteCompany(testCompany.ID)
+	svc := NewService(mockRepo, mockKafka, anyType(ctrl), noRelationships(ctrl))
+	err := svc.DeleteCompany(tenantContext(), testCompany.ID)
 
 	assert.Error(t, err)
 }
+
+func TestCompanyService_DeleteCompany_RollbackAfterCancel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_company_repository.NewMockRepository(ctrl)
+	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
+
+	ctx, cancel := context.WithCancel(tenantContext())
+	mockRepo.EXPECT().GetByID(gomock.Any(), testTenant, testCompany.ID).Return(testCompany, nil)
+	mockRepo.EXPECT().Delete(gomock.Any(), testTenant, testCompany.ID).Return(nil)
+	mockKafka.EXPECT().SendEventWithPayload(gomock.Any(), event.EVENT_DELETE, testCompany).DoAndReturn(func(ctx context.Context, _ event.EventType, _ any) error {
+		cancel()
+		return ctx.Err()
+	})
+	// the rollback runs although the request was cancelled
+	mockRepo.EXPECT().Create(gomock.Any(), testCompany).DoAndReturn(func(ctx context.Context, _ *model.Company) error {
+		return ctx.Err()
+	})
+
+	svc := NewService(mockRepo, mockKafka, anyType(ctrl), noRelationships(ctrl))
+	err := svc.DeleteCompany(ctx, testCompany.ID)
+	assert.Equal(t, context.Canceled, err)
+}
@@ -0,0 +1,96 @@
+package company
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/ngereci/xm_interview/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const serviceInstrumentation = "github.com/ngereci/xm_interview/company"
+
+// companyIDKey is the span attribute of the company a service call is about
+const companyIDKey = attribute.Key("company.id")
+
+// tagKeyKey is the span attribute of the tag a service call changes, values are left out as they may be anything
+const tagKeyKey = attribute.Key("company.tag")
+
+type tracingService struct {
+	next   Service
+	tracer trace.Tracer
+}
+
+// NewTracingService wraps next in a span per call, the repository queries and events of the call are its children.
+func NewTracingService(next Service) Service {
+	return &tracingService{next: next, tracer: otel.Tracer(serviceInstrumentation)}
+}
+
+func (s *tracingService) CreateCompany(ctx context.Context, newCompany *model.Company) (*model.Company, error) {
+	ctx, span := s.tracer.Start(ctx, "CompanyService.CreateCompany")
+	defer span.End()
+	company, err := s.next.CreateCompany(ctx, newCompany)
+	if company != nil {
+		span.SetAttributes(companyIDKey.String(company.ID.String()))
+	}
+	tracing.RecordError(span, err)
+	return company, err
+}
+
+func (s *tracingService) GetCompanyByID(ctx context.Context, id uuid.UUID) (*model.Company, error) {
+	ctx, span := s.tracer.Start(ctx, "CompanyService.GetCompanyByID", trace.WithAttributes(companyIDKey.String(id.String())))
+	defer span.End()
+	company, err := s.next.GetCompanyByID(ctx, id)
+	tracing.RecordError(span, err)
+	return company, err
+}
+
+func (s *tracingService) GetCompaniesByIDs(ctx context.Context, ids []uuid.UUID) ([]*model.Company, error) {
+	ctx, span := s.tracer.Start(ctx, "CompanyService.GetCompaniesByIDs", trace.WithAttributes(attribute.Int("company.ids", len(ids))))
+	defer span.End()
+	companies, err := s.next.GetCompaniesByIDs(ctx, ids)
+	tracing.RecordError(span, err)
+	return companies, err
+}
+
+func (s *tracingService) UpdateCompany(ctx context.Context, id uuid.UUID, forUpdateCompany *model.Company) (*model.Company, error) {
+	ctx, span := s.tracer.Start(ctx, "CompanyService.UpdateCompany", trace.WithAttributes(companyIDKey.String(id.String())))
+	defer span.End()
+	company, err := s.next.UpdateCompany(ctx, id, forUpdateCompany)
+	tracing.RecordError(span, err)
+	return company, err
+}
+
+func (s *tracingService) DeleteCompany(ctx context.Context, id uuid.UUID) error {
+	ctx, span := s.tracer.Start(ctx, "CompanyService.DeleteCompany", trace.WithAttributes(companyIDKey.String(id.String())))
+	defer span.End()
+	err := s.next.DeleteCompany(ctx, id)
+	tracing.RecordError(span, err)
+	return err
+}
+
+func (s *tracingService) SetTag(ctx context.Context, id uuid.UUID, key string, value string) (*model.Company, error) {
+	ctx, span := s.tracer.Start(ctx, "CompanyService.SetTag", trace.WithAttributes(companyIDKey.String(id.String()), tagKeyKey.String(key)))
+	defer span.End()
+	company, err := s.next.SetTag(ctx, id, key, value)
+	tracing.RecordError(span, err)
+	return company, err
+}
+
+func (s *tracingService) RemoveTag(ctx context.Context, id uuid.UUID, key string) (*model.Company, error) {
+	ctx, span := s.tracer.Start(ctx, "CompanyService.RemoveTag", trace.WithAttributes(companyIDKey.String(id.String()), tagKeyKey.String(key)))
+	defer span.End()
+	company, err := s.next.RemoveTag(ctx, id, key)
+	tracing.RecordError(span, err)
+	return company, err
+}
+
+func (s *tracingService) ListCompanies(ctx context.Context, pageState []byte, pageSize int) ([]*model.Company, []byte, error) {
+	ctx, span := s.tracer.Start(ctx, "CompanyService.ListCompanies")
+	defer span.End()
+	companies, nextPageState, err := s.next.ListCompanies(ctx, pageState, pageSize)
+	tracing.RecordError(span, err)
+	return companies, nextPageState, err
+}
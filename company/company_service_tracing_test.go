@@ -0,0 +1,56 @@
+package company
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	mock_company_service "github.com/ngereci/xm_interview/mocks/mock_company/service"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"testing"
+)
+
+func newTestTracingService(t *testing.T) (*mock_company_service.MockService, Service, *tracetest.SpanRecorder) {
+	ctrl := gomock.NewController(t)
+	next := mock_company_service.NewMockService(ctrl)
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return next, &tracingService{next: next, tracer: provider.Tracer("test")}, recorder
+}
+
+func TestTracingService_GetCompanyByID(t *testing.T) {
+	next, service, recorder := newTestTracingService(t)
+	next.EXPECT().GetCompanyByID(gomock.Any(), testCompany.ID).DoAndReturn(func(ctx context.Context, _ any) (any, error) {
+		// the call continues in the span of the service method
+		assert.True(t, trace.SpanContextFromContext(ctx).IsValid())
+		return testCompany, nil
+	})
+
+	company, err := service.GetCompanyByID(context.Background(), testCompany.ID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, testCompany, company)
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "CompanyService.GetCompanyByID", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), companyIDKey.String(testCompany.ID.String()))
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+}
+
+func TestTracingService_DeleteCompany_Error(t *testing.T) {
+	next, service, recorder := newTestTracingService(t)
+	deleteErr := errors.New("unavailable")
+	next.EXPECT().DeleteCompany(gomock.Any(), testCompany.ID).Return(deleteErr)
+
+	err := service.DeleteCompany(context.Background(), testCompany.ID)
+
+	assert.Equal(t, deleteErr, err)
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "CompanyService.DeleteCompany", spans[0].Name())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, deleteErr.Error(), spans[0].Status().Description)
+}
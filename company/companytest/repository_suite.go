@@ -0,0 +1,448 @@
+// Package companytest provides a conformance test suite for company.Repository implementations.
+package companytest
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/company"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+)
+
+// Options describe the optional behaviour of the repository under test.
+type Options struct {
+	// UniqueNames is set for repositories that reject a company whose name is taken with
+	// model.ErrCompanyExists. The others leave uniqueness to the service and only report it through CountByName.
+	UniqueNames bool
+}
+
+const (
+	testTenant  = "tenant-a"
+	otherTenant = "tenant-b"
+)
+
+// NewRepository returns an empty repository, it is called once per test.
+type NewRepository func(t *testing.T) company.Repository
+
+// RunRepositorySuite runs the contract every company.Repository implementation has to fulfil.
+func RunRepositorySuite(t *testing.T, newRepository NewRepository, options Options) {
+	t.Run("CreateAndGet", func(t *testing.T) { testCreateAndGet(t, newRepository(t)) })
+	t.Run("GetNotFound", func(t *testing.T) { testGetNotFound(t, newRepository(t)) })
+	t.Run("GetByIDs", func(t *testing.T) { testGetByIDs(t, newRepository(t)) })
+	t.Run("Update", func(t *testing.T) { testUpdate(t, newRepository(t)) })
+	t.Run("Tags", func(t *testing.T) { testTags(t, newRepository(t)) })
+	t.Run("TagsNotFound", func(t *testing.T) { testTagsNotFound(t, newRepository(t)) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, newRepository(t)) })
+	t.Run("CountByName", func(t *testing.T) { testCountByName(t, newRepository(t)) })
+	t.Run("CountByType", func(t *testing.T) { testCountByType(t, newRepository(t)) })
+	t.Run("Uniqueness", func(t *testing.T) { testUniqueness(t, newRepository(t), options) })
+	t.Run("Pagination", func(t *testing.T) { testPagination(t, newRepository(t)) })
+	t.Run("ListByTenant", func(t *testing.T) { testListByTenant(t, newRepository(t)) })
+	t.Run("Concurrency", func(t *testing.T) { testConcurrency(t, newRepository(t), options) })
+	t.Run("TenantIsolation", func(t *testing.T) { testTenantIsolation(t, newRepository(t)) })
+}
+
+func newCompany(name string) *model.Company {
+	return &model.Company{
+		ID:          uuid.New(),
+		TenantID:    testTenant,
+		Name:        name,
+		Description: "Description of " + name,
+		Employees:   42,
+		Registered:  true,
+		Type:        model.Corporation,
+		// the details are stored and read back as well
+		RegistrationNumber: "HRB 12345",
+		Country:            "DE",
+		VATNumber:          "DE123456789",
+		LEI:                "5299000J2N45DDNE4Y28",
+		Website:            "https://example.com",
+		Addresses: []model.Address{
+			{Type: model.AddressRegistered, Street: "Hauptstraße 1", City: "Berlin", PostalCode: "10115", Country: "DE"},
+			{Type: model.AddressBilling, Street: "1 Main Street", City: "Springfield", Region: "IL", Country: "US"},
+		},
+		Contacts: []model.Contact{{Name: "Jane Doe", Role: "CFO", Email: "jane@example.com", Phone: "+4930123456"}},
+		Tags:     map[string]string{"env": "prod", "team:owner": "payments", "vip": ""},
+	}
+}
+
+func testCreateAndGet(t *testing.T, repo company.Repository) {
+	ctx := context.Background()
+	created := newCompany("Create Company")
+	require.NoError(t, repo.Create(ctx, created))
+
+	found, err := repo.GetByID(ctx, testTenant, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created, found)
+}
+
+func testGetNotFound(t *testing.T, repo company.Repository) {
+	ctx := context.Background()
+	found, err := repo.GetByID(ctx, testTenant, uuid.New())
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+}
+
+func testGetByIDs(t *testing.T, repo company.Repository) {
+	ctx := context.Background()
+	first, second := newCompany("First Company"), newCompany("Second Company")
+	other := newCompany("Other Tenant Company")
+	other.TenantID = otherTenant
+	for _, c := range []*model.Company{first, second, other} {
+		require.NoError(t, repo.Create(ctx, c))
+	}
+
+	found, err := repo.GetByIDs(ctx, testTenant, []uuid.UUID{second.ID, uuid.New(), other.ID, first.ID, second.ID})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []*model.Company{first, second}, found)
+
+	found, err = repo.GetByIDs(ctx, testTenant, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, found)
+}
+
+func testUpdate(t *testing.T, repo company.Repository) {
+	ctx := context.Background()
+	created := newCompany("Update Company")
+	require.NoError(t, repo.Create(ctx, created))
+
+	changed := &model.Company{
+		ID:          created.ID,
+		TenantID:    testTenant,
+		Name:        "Updated Company",
+		Description: "Updated description",
+		Employees:   7,
+		Registered:  false,
+		Type:        model.NonProfit,
+		Country:     "FR",
+		Addresses:   []model.Address{{Type: model.AddressHeadquarters, Street: "1 Rue de Rivoli", City: "Paris", Country: "FR"}},
+		Tags:        map[string]string{"env": "staging"},
+	}
+	updated, err := repo.Update(ctx, changed)
+	require.NoError(t, err)
+	// the tags are only changed by SetTag and DeleteTag
+	expected := *changed
+	expected.Tags = created.Tags
+	assert.Equal(t, &expected, updated)
+
+	found, err := repo.GetByID(ctx, testTenant, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &expected, found)
+}
+
+func testTags(t *testing.T, repo company.Repository) {
+	ctx := context.Background()
+	created := newCompany("Tagged Company")
+	created.Tags = nil
+	require.NoError(t, repo.Create(ctx, created))
+
+	tagged, err := repo.SetTag(ctx, testTenant, created.ID, "env", "prod")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod"}, tagged.Tags)
+	assert.Equal(t, created.Name, tagged.Name)
+	tagged, err = repo.SetTag(ctx, testTenant, created.ID, "team", "payments")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod", "team": "payments"}, tagged.Tags)
+	tagged, err = repo.SetTag(ctx, testTenant, created.ID, "env", "")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "", "team": "payments"}, tagged.Tags)
+
+	untagged, err := repo.DeleteTag(ctx, testTenant, created.ID, "env")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "payments"}, untagged.Tags)
+	_, err = repo.DeleteTag(ctx, testTenant, created.ID, "env")
+	assert.Equal(t, model.ErrTagNotFound{Id: created.ID, Key: "env"}, err)
+	untagged, err = repo.DeleteTag(ctx, testTenant, created.ID, "team")
+	require.NoError(t, err)
+	assert.Empty(t, untagged.Tags)
+
+	found, err := repo.GetByID(ctx, testTenant, created.ID)
+	require.NoError(t, err)
+	assert.Empty(t, found.Tags)
+	assert.Equal(t, created.Name, found.Name)
+}
+
+func testTagsNotFound(t *testing.T, repo company.Repository) {
+	ctx := context.Background()
+	created := newCompany("Other Tenant Tagged Company")
+	require.NoError(t, repo.Create(ctx, created))
+
+	// neither missing companies nor the companies of other tenants are created or changed
+	for _, tenantID := range []string{testTenant, otherTenant} {
+		id := created.ID
+		if tenantID == testTenant {
+			id = uuid.New()
+		}
+		tagged, err := repo.SetTag(ctx, tenantID, id, "env", "staging")
+		assert.NoError(t, err)
+		assert.Nil(t, tagged)
+		untagged, err := repo.DeleteTag(ctx, tenantID, id, "env")
+		assert.NoError(t, err)
+		assert.Nil(t, untagged)
+		found, err := repo.GetByID(ctx, tenantID, id)
+		assert.NoError(t, err)
+		assert.Nil(t, found)
+	}
+	found, err := repo.GetByID(ctx, testTenant, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created.Tags, found.Tags)
+}
+
+func testDelete(t *testing.T, repo company.Repository) {
+	ctx := context.Background()
+	created := newCompany("Delete Company")
+	require.NoError(t, repo.Create(ctx, created))
+
+	require.NoError(t, repo.Delete(ctx, testTenant, created.ID))
+	found, err := repo.GetByID(ctx, testTenant, created.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+
+	count, err := repo.CountByName(ctx, testTenant, created.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	// deleting a missing company is not an error
+	assert.NoError(t, repo.Delete(ctx, testTenant, uuid.New()))
+}
+
+func testCountByName(t *testing.T, repo company.Repository) {
+	ctx := context.Background()
+	created := newCompany("Count Company")
+	require.NoError(t, repo.Create(ctx, created))
+
+	count, err := repo.CountByName(ctx, testTenant, created.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// names are compared by their normalized form, see model.NormalizeName
+	count, err = repo.CountByName(ctx, testTenant, " count  COMPANY, Ltd. ")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	count, err = repo.CountByName(ctx, testTenant, "Unknown Company")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	renamed := *created
+	renamed.Name = "Renamed Count Company"
+	_, err = repo.Update(ctx, &renamed)
+	require.NoError(t, err)
+
+	count, err = repo.CountByName(ctx, testTenant, created.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+	count, err = repo.CountByName(ctx, testTenant, renamed.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func testCountByType(t *testing.T, repo company.Repository) {
+	ctx := context.Background()
+	// a type of its own, so that companies of other tests in the same database are not counted
+	companyType := model.CompanyType("Count-" + uuid.NewString())
+	first := newCompany("First Typed Company")
+	first.Type = companyType
+	require.NoError(t, repo.Create(ctx, first))
+	// types span all tenants
+	second := newCompany("Second Typed Company")
+	second.TenantID = otherTenant
+	second.Type = companyType
+	require.NoError(t, repo.Create(ctx, second))
+
+	count, err := repo.CountByType(ctx, companyType)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	changed := *first
+	changed.Type = model.Corporation
+	_, err = repo.Update(ctx, &changed)
+	require.NoError(t, err)
+
+	count, err = repo.CountByType(ctx, companyType)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func testUniqueness(t *testing.T, repo company.Repository, options Options) {
+	ctx := context.Background()
+	first := newCompany("Unique Company")
+	require.NoError(t, repo.Create(ctx, first))
+
+	duplicate := newCompany("UNIQUE company inc")
+	err := repo.Create(ctx, duplicate)
+	if !options.UniqueNames {
+		// without enforcement the name is still reported as taken, so the service can reject it
+		assert.NoError(t, err)
+		count, err := repo.CountByName(ctx, testTenant, first.Name)
+		assert.NoError(t, err)
+		assert.Positive(t, count)
+		return
+	}
+	assert.Equal(t, model.ErrCompanyExists{Name: duplicate.Name}, err)
+	found, err := repo.GetByID(ctx, testTenant, duplicate.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+
+	second := newCompany("Other Unique Company")
+	require.NoError(t, repo.Create(ctx, second))
+	renamed := *second
+	renamed.Name = "Unique Company Ltd"
+	_, err = repo.Update(ctx, &renamed)
+	assert.Equal(t, model.ErrCompanyExists{Name: renamed.Name}, err)
+	found, err = repo.GetByID(ctx, testTenant, second.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, second, found)
+
+	// a company can change the spelling of its own name
+	respelled := *first
+	respelled.Name = "UNIQUE COMPANY"
+	updated, err := repo.Update(ctx, &respelled)
+	assert.NoError(t, err)
+	assert.Equal(t, &respelled, updated)
+}
+
+func testPagination(t *testing.T, repo company.Repository) {
+	ctx := context.Background()
+	const (
+		companies = 7
+		pageSize  = 3
+	)
+	created := make(map[uuid.UUID]*model.Company, companies)
+	for i := 0; i < companies; i++ {
+		c := newCompany(fmt.Sprintf("Page Company %d", i))
+		require.NoError(t, repo.Create(ctx, c))
+		created[c.ID] = c
+	}
+
+	listed := make(map[uuid.UUID]*model.Company, companies)
+	var pageState []byte
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, companies, "pagination does not terminate")
+		page, next, err := repo.List(ctx, pageState, pageSize)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(page), pageSize)
+		for _, c := range page {
+			assert.NotContains(t, listed, c.ID, "company listed twice")
+			listed[c.ID] = c
+		}
+		if len(next) == 0 {
+			break
+		}
+		pageState = next
+	}
+	assert.Equal(t, created, listed)
+}
+
+func testListByTenant(t *testing.T, repo company.Repository) {
+	ctx := context.Background()
+	const pageSize = 2
+	created := make(map[uuid.UUID]*model.Company)
+	for i := 0; i < 5; i++ {
+		c := newCompany(fmt.Sprintf("Tenant Page Company %d", i))
+		require.NoError(t, repo.Create(ctx, c))
+		created[c.ID] = c
+		other := newCompany(c.Name)
+		other.TenantID = otherTenant
+		require.NoError(t, repo.Create(ctx, other))
+	}
+
+	listed := make(map[uuid.UUID]*model.Company, len(created))
+	var pageState []byte
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 2*len(created), "pagination does not terminate")
+		page, next, err := repo.ListByTenant(ctx, testTenant, pageState, pageSize)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(page), pageSize)
+		for _, c := range page {
+			assert.NotContains(t, listed, c.ID, "company listed twice")
+			listed[c.ID] = c
+		}
+		if len(next) == 0 {
+			break
+		}
+		pageState = next
+	}
+	assert.Equal(t, created, listed)
+
+	page, next, err := repo.ListByTenant(ctx, "tenant-without-companies", nil, pageSize)
+	require.NoError(t, err)
+	assert.Empty(t, page)
+	assert.Empty(t, next)
+}
+
+func testTenantIsolation(t *testing.T, repo company.Repository) {
+	ctx := context.Background()
+	created := newCompany("Tenant Company")
+	require.NoError(t, repo.Create(ctx, created))
+
+	// names are unique per tenant only
+	count, err := repo.CountByName(ctx, otherTenant, created.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+	other := newCompany(created.Name)
+	other.TenantID = otherTenant
+	require.NoError(t, repo.Create(ctx, other))
+
+	// the company of another tenant is missing
+	found, err := repo.GetByID(ctx, otherTenant, created.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+	require.NoError(t, repo.Delete(ctx, otherTenant, created.ID))
+	found, err = repo.GetByID(ctx, testTenant, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created, found)
+
+	found, err = repo.GetByID(ctx, otherTenant, other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, other, found)
+}
+
+func testConcurrency(t *testing.T, repo company.Repository, options Options) {
+	ctx := context.Background()
+	const workers = 16
+	var wg sync.WaitGroup
+	companies := make([]*model.Company, workers)
+	for i := range companies {
+		companies[i] = newCompany(fmt.Sprintf("Concurrent Company %d", i))
+	}
+	for _, c := range companies {
+		wg.Add(1)
+		go func(c *model.Company) {
+			defer wg.Done()
+			assert.NoError(t, repo.Create(ctx, c))
+			found, err := repo.GetByID(ctx, testTenant, c.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, c, found)
+		}(c)
+	}
+	wg.Wait()
+
+	if !options.UniqueNames {
+		return
+	}
+	// exactly one of the companies competing for the same name is created
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- repo.Create(ctx, newCompany("Contended Company"))
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	created := 0
+	for err := range errs {
+		if err == nil {
+			created++
+			continue
+		}
+		assert.IsType(t, model.ErrCompanyExists{}, err)
+	}
+	assert.Equal(t, 1, created)
+}
@@ -0,0 +1,110 @@
+package companytype
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/ngereci/xm_interview/model"
+	"net/http"
+)
+
+type Controller interface {
+	CreateCompanyType(ctx *gin.Context)
+	GetCompanyType(ctx *gin.Context)
+	ListCompanyTypes(ctx *gin.Context)
+	UpdateCompanyType(ctx *gin.Context)
+	DeleteCompanyType(ctx *gin.Context)
+}
+
+type controller struct {
+	service Service
+}
+
+func NewController(service Service) Controller {
+	return &controller{service: service}
+}
+
+func (c *controller) CreateCompanyType(ctx *gin.Context) {
+	var definition model.CompanyTypeDefinition
+	if err := ctx.ShouldBindJSON(&definition); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	created, err := c.service.CreateCompanyType(ctx.Request.Context(), &definition)
+	if err != nil {
+		ctx.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+	ctx.JSON(http.StatusCreated, created)
+}
+
+func (c *controller) GetCompanyType(ctx *gin.Context) {
+	definition, err := c.service.GetCompanyType(ctx.Request.Context(), model.CompanyType(ctx.Param("name")))
+	if err != nil {
+		ctx.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, definition)
+}
+
+func (c *controller) ListCompanyTypes(ctx *gin.Context) {
+	definitions, err := c.service.ListCompanyTypes(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+	if definitions == nil {
+		definitions = []*model.CompanyTypeDefinition{}
+	}
+	ctx.JSON(http.StatusOK, definitions)
+}
+
+func (c *controller) UpdateCompanyType(ctx *gin.Context) {
+	var definition model.CompanyTypeDefinition
+	if err := ctx.ShouldBindJSON(&definition); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	updated, err := c.service.UpdateCompanyType(ctx.Request.Context(), model.CompanyType(ctx.Param("name")), &definition)
+	if err != nil {
+		ctx.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, updated)
+}
+
+func (c *controller) DeleteCompanyType(ctx *gin.Context) {
+	if err := c.service.DeleteCompanyType(ctx.Request.Context(), model.CompanyType(ctx.Param("name"))); err != nil {
+		ctx.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// errorBody is the response body of an error, invalid types list their invalid fields
+func errorBody(err error) gin.H {
+	var invalid model.ErrInvalidCompanyType
+	if errors.As(err, &invalid) {
+		return gin.H{"error": err.Error(), "fields": invalid.Fields}
+	}
+	return gin.H{"error": err.Error()}
+}
+
+// errorStatus maps a service error to the response status, types still in use answer 409 like existing ones
+func errorStatus(err error) int {
+	var (
+		notFound model.ErrCompanyTypeNotFound
+		exists   model.ErrCompanyTypeExists
+		inUse    model.ErrCompanyTypeInUse
+		invalid  model.ErrInvalidCompanyType
+	)
+	switch {
+	case errors.As(err, &invalid):
+		return http.StatusBadRequest
+	case errors.As(err, &notFound):
+		return http.StatusNotFound
+	case errors.As(err, &exists), errors.As(err, &inUse):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
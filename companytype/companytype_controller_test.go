@@ -0,0 +1,72 @@
+package companytype
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serve calls handler with a request of body, name is the path parameter
+func serve(handler gin.HandlerFunc, name string, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	ctx.Params = gin.Params{{Key: "name", Value: name}}
+	handler(ctx)
+	return w
+}
+
+func TestController_CreateCompanyType(t *testing.T) {
+	controller := NewController(newTestService(t))
+
+	w := serve(controller.CreateCompanyType, "", `{"name":"Partnership","requires_registration_number":true}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"name":"Partnership","requires_registration":false,"requires_registration_number":true,"deprecated":false,`+
+		`"created_at":"2024-03-01T12:00:00Z","updated_at":"2024-03-01T12:00:00Z"}`, w.Body.String())
+
+	assert.Equal(t, http.StatusConflict, serve(controller.CreateCompanyType, "", `{"name":"Partnership"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(controller.CreateCompanyType, "", `{"name":`).Code)
+
+	w = serve(controller.CreateCompanyType, "", `{"name":"partnership"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"fields":[{"field":"name"`)
+}
+
+func TestController_GetAndListCompanyTypes(t *testing.T) {
+	controller := NewController(newTestService(t))
+
+	w := serve(controller.GetCompanyType, "NonProfit", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"requires_registration":true`)
+	assert.Equal(t, http.StatusNotFound, serve(controller.GetCompanyType, "Partnership", "").Code)
+
+	w = serve(controller.ListCompanyTypes, "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	for _, definition := range model.DefaultCompanyTypes() {
+		assert.Contains(t, w.Body.String(), `"name":"`+string(definition.Name)+`"`)
+	}
+}
+
+func TestController_UpdateAndDeleteCompanyType(t *testing.T) {
+	svc := newTestService(t, newCompany(model.Cooperative))
+	controller := NewController(svc)
+
+	w := serve(controller.UpdateCompanyType, "Cooperative", `{"description":"Member owned","deprecated":true}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	definition, err := svc.GetCompanyType(context.Background(), model.Cooperative)
+	require.NoError(t, err)
+	assert.True(t, definition.Deprecated)
+	assert.Equal(t, http.StatusNotFound, serve(controller.UpdateCompanyType, "Partnership", `{}`).Code)
+
+	w = serve(controller.DeleteCompanyType, "Cooperative", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"error":"company type Cooperative is used by 1 companies, deprecate it instead"}`, w.Body.String())
+	assert.Equal(t, http.StatusOK, serve(controller.DeleteCompanyType, "Corporation", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(controller.DeleteCompanyType, "Corporation", "").Code)
+}
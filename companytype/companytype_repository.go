@@ -0,0 +1,140 @@
+package companytype
+
+import (
+	"context"
+	"github.com/gocql/gocql"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/model"
+	"sort"
+)
+
+// Repository stores the company types, which are shared by all tenants
+type Repository interface {
+	// Create returns model.ErrCompanyTypeExists when a type of the same name exists
+	Create(ctx context.Context, definition *model.CompanyTypeDefinition) error
+	// Get returns nil without error when the type does not exist
+	Get(ctx context.Context, name model.CompanyType) (*model.CompanyTypeDefinition, error)
+	// List returns all types, deprecated ones included, by name
+	List(ctx context.Context) ([]*model.CompanyTypeDefinition, error)
+	// Update replaces the type of the same name, it returns model.ErrCompanyTypeNotFound when it does not exist
+	Update(ctx context.Context, definition *model.CompanyTypeDefinition) error
+	// Delete returns model.ErrCompanyTypeNotFound when the type does not exist
+	Delete(ctx context.Context, name model.CompanyType) error
+}
+
+type companyTypeRepository struct {
+	session *gocql.Session
+}
+
+// NewRepository creates a Repository on the company_type table of the Cassandra keyspace.
+func NewRepository(session *gocql.Session) Repository {
+	return &companyTypeRepository{session: session}
+}
+
+func (r *companyTypeRepository) Create(ctx context.Context, definition *model.CompanyTypeDefinition) error {
+	applied, err := r.session.Query(`
+		INSERT INTO company_type (name, description, requires_registration, requires_registration_number, max_employees,
+			deprecated, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		IF NOT EXISTS
+	`, definition.Name, definition.Description, definition.RequiresRegistration, definition.RequiresRegistrationNumber,
+		definition.MaxEmployees, definition.Deprecated, definition.CreatedAt, definition.UpdatedAt).WithContext(ctx).MapScanCAS(map[string]any{})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company type:%v Create error:%v", definition.Name, err)
+		return err
+	}
+	if !applied {
+		return model.ErrCompanyTypeExists{Name: definition.Name}
+	}
+	return nil
+}
+
+func (r *companyTypeRepository) Get(ctx context.Context, name model.CompanyType) (*model.CompanyTypeDefinition, error) {
+	scanner := r.session.Query(`
+		SELECT name, description, requires_registration, requires_registration_number, max_employees, deprecated, created_at, updated_at
+		FROM company_type
+		WHERE name = ?
+	`, name).WithContext(ctx).Iter().Scanner()
+	definitions, err := scanCompanyTypes(scanner)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company type:%v Get error:%v", name, err)
+		return nil, err
+	}
+	if len(definitions) == 0 {
+		return nil, nil
+	}
+	return definitions[0], nil
+}
+
+func (r *companyTypeRepository) List(ctx context.Context) ([]*model.CompanyTypeDefinition, error) {
+	scanner := r.session.Query(`
+		SELECT name, description, requires_registration, requires_registration_number, max_employees, deprecated, created_at, updated_at
+		FROM company_type
+	`).WithContext(ctx).Iter().Scanner()
+	definitions, err := scanCompanyTypes(scanner)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company type List error:%v", err)
+		return nil, err
+	}
+	sortCompanyTypes(definitions)
+	return definitions, nil
+}
+
+func (r *companyTypeRepository) Update(ctx context.Context, definition *model.CompanyTypeDefinition) error {
+	applied, err := r.session.Query(`
+		UPDATE company_type
+		SET description = ?, requires_registration = ?, requires_registration_number = ?, max_employees = ?, deprecated = ?,
+			created_at = ?, updated_at = ?
+		WHERE name = ?
+		IF EXISTS
+	`, definition.Description, definition.RequiresRegistration, definition.RequiresRegistrationNumber, definition.MaxEmployees,
+		definition.Deprecated, definition.CreatedAt, definition.UpdatedAt, definition.Name).WithContext(ctx).MapScanCAS(map[string]any{})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company type:%v Update error:%v", definition.Name, err)
+		return err
+	}
+	if !applied {
+		return model.ErrCompanyTypeNotFound{Name: definition.Name}
+	}
+	return nil
+}
+
+func (r *companyTypeRepository) Delete(ctx context.Context, name model.CompanyType) error {
+	applied, err := r.session.Query(`
+		DELETE FROM company_type
+		WHERE name = ?
+		IF EXISTS
+	`, name).WithContext(ctx).MapScanCAS(map[string]any{})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company type:%v Delete error:%v", name, err)
+		return err
+	}
+	if !applied {
+		return model.ErrCompanyTypeNotFound{Name: name}
+	}
+	return nil
+}
+
+func scanCompanyTypes(scanner gocql.Scanner) ([]*model.CompanyTypeDefinition, error) {
+	var definitions []*model.CompanyTypeDefinition
+	for scanner.Next() {
+		var (
+			name       string
+			definition model.CompanyTypeDefinition
+		)
+		err := scanner.Scan(&name, &definition.Description, &definition.RequiresRegistration, &definition.RequiresRegistrationNumber,
+			&definition.MaxEmployees, &definition.Deprecated, &definition.CreatedAt, &definition.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		definition.Name = model.CompanyType(name)
+		definitions = append(definitions, &definition)
+	}
+	return definitions, scanner.Err()
+}
+
+func sortCompanyTypes(definitions []*model.CompanyTypeDefinition) {
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Name < definitions[j].Name
+	})
+}
@@ -0,0 +1,148 @@
+package companytype
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/model"
+	bolt "go.etcd.io/bbolt"
+	"time"
+)
+
+var boltCompanyTypeBucket = []byte("company_type")
+
+type boltCompanyTypeRepository struct {
+	db *bolt.DB
+}
+
+// NewBoltRepository creates a Repository on an embedded bbolt database file, for single node and local use.
+// A new database is seeded with model.DefaultCompanyTypes.
+func NewBoltRepository(db *bolt.DB) (Repository, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(boltCompanyTypeBucket) != nil {
+			return nil
+		}
+		if _, err := tx.CreateBucket(boltCompanyTypeBucket); err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		for _, definition := range model.DefaultCompanyTypes() {
+			definition.CreatedAt, definition.UpdatedAt = now, now
+			if err := putBoltCompanyType(tx, &definition); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &boltCompanyTypeRepository{db: db}, nil
+}
+
+func (r *boltCompanyTypeRepository) Create(ctx context.Context, definition *model.CompanyTypeDefinition) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(boltCompanyTypeBucket).Get([]byte(definition.Name)) != nil {
+			return model.ErrCompanyTypeExists{Name: definition.Name}
+		}
+		return putBoltCompanyType(tx, definition)
+	})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company type:%v Create error:%v", definition.Name, err)
+	}
+	return err
+}
+
+func (r *boltCompanyTypeRepository) Get(ctx context.Context, name model.CompanyType) (definition *model.CompanyTypeDefinition, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	err = r.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(boltCompanyTypeBucket).Get([]byte(name))
+		if value == nil {
+			return nil
+		}
+		definition, err = unmarshalBoltCompanyType(value)
+		return err
+	})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company type:%v Get error:%v", name, err)
+		return nil, err
+	}
+	return definition, nil
+}
+
+func (r *boltCompanyTypeRepository) List(ctx context.Context) ([]*model.CompanyTypeDefinition, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var definitions []*model.CompanyTypeDefinition
+	// bbolt iterates the keys, which are the names, in order
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltCompanyTypeBucket).ForEach(func(_, value []byte) error {
+			definition, err := unmarshalBoltCompanyType(value)
+			if err != nil {
+				return err
+			}
+			definitions = append(definitions, definition)
+			return nil
+		})
+	})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company type List error:%v", err)
+		return nil, err
+	}
+	return definitions, nil
+}
+
+func (r *boltCompanyTypeRepository) Update(ctx context.Context, definition *model.CompanyTypeDefinition) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(boltCompanyTypeBucket).Get([]byte(definition.Name)) == nil {
+			return model.ErrCompanyTypeNotFound{Name: definition.Name}
+		}
+		return putBoltCompanyType(tx, definition)
+	})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company type:%v Update error:%v", definition.Name, err)
+	}
+	return err
+}
+
+func (r *boltCompanyTypeRepository) Delete(ctx context.Context, name model.CompanyType) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltCompanyTypeBucket)
+		if bucket.Get([]byte(name)) == nil {
+			return model.ErrCompanyTypeNotFound{Name: name}
+		}
+		return bucket.Delete([]byte(name))
+	})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company type:%v Delete error:%v", name, err)
+	}
+	return err
+}
+
+func putBoltCompanyType(tx *bolt.Tx, definition *model.CompanyTypeDefinition) error {
+	value, err := json.Marshal(definition)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(boltCompanyTypeBucket).Put([]byte(definition.Name), value)
+}
+
+func unmarshalBoltCompanyType(value []byte) (*model.CompanyTypeDefinition, error) {
+	var definition model.CompanyTypeDefinition
+	if err := json.Unmarshal(value, &definition); err != nil {
+		return nil, err
+	}
+	return &definition, nil
+}
@@ -0,0 +1,65 @@
+package companytype_test
+
+import (
+	"context"
+	"github.com/ngereci/xm_interview/companytype"
+	"github.com/ngereci/xm_interview/companytype/companytypetest"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+	"path/filepath"
+	"testing"
+)
+
+func openBoltDB(t *testing.T, path string) *bolt.DB {
+	database, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+func TestBoltRepository(t *testing.T) {
+	companytypetest.RunRepositorySuite(t, func(t *testing.T) companytype.Repository {
+		database := openBoltDB(t, filepath.Join(t.TempDir(), "companies.db"))
+		t.Cleanup(func() { database.Close() })
+		repo, err := companytype.NewBoltRepository(database)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the suite starts without the default types
+		for _, definition := range model.DefaultCompanyTypes() {
+			if err = repo.Delete(context.Background(), definition.Name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return repo
+	})
+}
+
+func TestBoltRepository_SeedsDefaultTypes(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "companies.db")
+	database := openBoltDB(t, path)
+	repo, err := companytype.NewBoltRepository(database)
+	require.NoError(t, err)
+	definitions, err := repo.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, definitions, len(model.DefaultCompanyTypes()))
+	nonProfit, err := repo.Get(ctx, model.NonProfit)
+	require.NoError(t, err)
+	assert.True(t, nonProfit.RequiresRegistration)
+	assert.False(t, nonProfit.CreatedAt.IsZero())
+
+	// a deleted default type stays deleted when the database is opened again
+	require.NoError(t, repo.Delete(ctx, model.Cooperative))
+	require.NoError(t, database.Close())
+	database = openBoltDB(t, path)
+	defer database.Close()
+	repo, err = companytype.NewBoltRepository(database)
+	require.NoError(t, err)
+	cooperative, err := repo.Get(ctx, model.Cooperative)
+	require.NoError(t, err)
+	assert.Nil(t, cooperative)
+}
@@ -0,0 +1,74 @@
+package companytype
+
+import (
+	"context"
+	"github.com/ngereci/xm_interview/model"
+	"sync"
+)
+
+type memoryCompanyTypeRepository struct {
+	mu          sync.RWMutex
+	definitions map[model.CompanyType]model.CompanyTypeDefinition
+}
+
+// NewMemoryRepository creates a Repository keeping the types in memory, for tests and as a reference implementation.
+// It starts with the given types, see model.DefaultCompanyTypes.
+func NewMemoryRepository(definitions ...model.CompanyTypeDefinition) Repository {
+	r := &memoryCompanyTypeRepository{definitions: make(map[model.CompanyType]model.CompanyTypeDefinition)}
+	for _, definition := range definitions {
+		r.definitions[definition.Name] = definition
+	}
+	return r
+}
+
+func (r *memoryCompanyTypeRepository) Create(ctx context.Context, definition *model.CompanyTypeDefinition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.definitions[definition.Name]; exists {
+		return model.ErrCompanyTypeExists{Name: definition.Name}
+	}
+	r.definitions[definition.Name] = *definition
+	return nil
+}
+
+func (r *memoryCompanyTypeRepository) Get(ctx context.Context, name model.CompanyType) (*model.CompanyTypeDefinition, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	definition, ok := r.definitions[name]
+	if !ok {
+		return nil, nil
+	}
+	return &definition, nil
+}
+
+func (r *memoryCompanyTypeRepository) List(ctx context.Context) ([]*model.CompanyTypeDefinition, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	definitions := make([]*model.CompanyTypeDefinition, 0, len(r.definitions))
+	for _, definition := range r.definitions {
+		stored := definition
+		definitions = append(definitions, &stored)
+	}
+	sortCompanyTypes(definitions)
+	return definitions, nil
+}
+
+func (r *memoryCompanyTypeRepository) Update(ctx context.Context, definition *model.CompanyTypeDefinition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.definitions[definition.Name]; !exists {
+		return model.ErrCompanyTypeNotFound{Name: definition.Name}
+	}
+	r.definitions[definition.Name] = *definition
+	return nil
+}
+
+func (r *memoryCompanyTypeRepository) Delete(ctx context.Context, name model.CompanyType) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.definitions[name]; !exists {
+		return model.ErrCompanyTypeNotFound{Name: name}
+	}
+	delete(r.definitions, name)
+	return nil
+}
@@ -0,0 +1,13 @@
+package companytype_test
+
+import (
+	"github.com/ngereci/xm_interview/companytype"
+	"github.com/ngereci/xm_interview/companytype/companytypetest"
+	"testing"
+)
+
+func TestMemoryRepository(t *testing.T) {
+	companytypetest.RunRepositorySuite(t, func(t *testing.T) companytype.Repository {
+		return companytype.NewMemoryRepository()
+	})
+}
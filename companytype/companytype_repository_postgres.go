@@ -0,0 +1,135 @@
+package companytype
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/model"
+)
+
+// pgUniqueViolation is the SQLSTATE of a unique constraint violation
+const pgUniqueViolation = "23505"
+
+type postgresCompanyTypeRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository creates a Repository on the company_type table of the
+// Postgres database, see db/migrations/postgres for the schema.
+func NewPostgresRepository(db *sql.DB) Repository {
+	return &postgresCompanyTypeRepository{db: db}
+}
+
+func (r *postgresCompanyTypeRepository) Create(ctx context.Context, definition *model.CompanyTypeDefinition) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO company_type (name, description, requires_registration, requires_registration_number, max_employees,
+			deprecated, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, definition.Name, definition.Description, definition.RequiresRegistration, definition.RequiresRegistrationNumber,
+		definition.MaxEmployees, definition.Deprecated, definition.CreatedAt, definition.UpdatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+		return model.ErrCompanyTypeExists{Name: definition.Name}
+	}
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company type:%v Create error:%v", definition.Name, err)
+	}
+	return err
+}
+
+func (r *postgresCompanyTypeRepository) Get(ctx context.Context, name model.CompanyType) (*model.CompanyTypeDefinition, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT name, description, requires_registration, requires_registration_number, max_employees, deprecated, created_at, updated_at
+		FROM company_type
+		WHERE name = $1
+	`, name)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company type:%v Get error:%v", name, err)
+		return nil, err
+	}
+	definitions, err := scanPostgresCompanyTypes(rows)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company type:%v Get error:%v", name, err)
+		return nil, err
+	}
+	if len(definitions) == 0 {
+		return nil, nil
+	}
+	return definitions[0], nil
+}
+
+func (r *postgresCompanyTypeRepository) List(ctx context.Context) ([]*model.CompanyTypeDefinition, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT name, description, requires_registration, requires_registration_number, max_employees, deprecated, created_at, updated_at
+		FROM company_type
+		ORDER BY name
+	`)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company type List error:%v", err)
+		return nil, err
+	}
+	definitions, err := scanPostgresCompanyTypes(rows)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company type List error:%v", err)
+		return nil, err
+	}
+	return definitions, nil
+}
+
+func (r *postgresCompanyTypeRepository) Update(ctx context.Context, definition *model.CompanyTypeDefinition) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE company_type
+		SET description = $1, requires_registration = $2, requires_registration_number = $3, max_employees = $4, deprecated = $5,
+			created_at = $6, updated_at = $7
+		WHERE name = $8
+	`, definition.Description, definition.RequiresRegistration, definition.RequiresRegistrationNumber, definition.MaxEmployees,
+		definition.Deprecated, definition.CreatedAt, definition.UpdatedAt, definition.Name)
+	return r.checkAffected(ctx, "Update", definition.Name, result, err)
+}
+
+func (r *postgresCompanyTypeRepository) Delete(ctx context.Context, name model.CompanyType) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM company_type
+		WHERE name = $1
+	`, name)
+	return r.checkAffected(ctx, "Delete", name, result, err)
+}
+
+// checkAffected returns model.ErrCompanyTypeNotFound when the statement of operation on the type name matched no row
+func (r *postgresCompanyTypeRepository) checkAffected(ctx context.Context, operation string, name model.CompanyType, result sql.Result, err error) error {
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company type:%v %v error:%v", name, operation, err)
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return model.ErrCompanyTypeNotFound{Name: name}
+	}
+	return nil
+}
+
+func scanPostgresCompanyTypes(rows *sql.Rows) ([]*model.CompanyTypeDefinition, error) {
+	defer rows.Close()
+	var definitions []*model.CompanyTypeDefinition
+	for rows.Next() {
+		var (
+			name       string
+			definition model.CompanyTypeDefinition
+		)
+		err := rows.Scan(&name, &definition.Description, &definition.RequiresRegistration, &definition.RequiresRegistrationNumber,
+			&definition.MaxEmployees, &definition.Deprecated, &definition.CreatedAt, &definition.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		definition.Name = model.CompanyType(name)
+		definition.CreatedAt = definition.CreatedAt.UTC()
+		definition.UpdatedAt = definition.UpdatedAt.UTC()
+		definitions = append(definitions, &definition)
+	}
+	return definitions, rows.Err()
+}
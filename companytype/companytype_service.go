@@ -0,0 +1,143 @@
+package companytype
+
+import (
+	"context"
+	"github.com/ngereci/xm_interview/company"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/model"
+	"time"
+)
+
+// Service manages the company types, which are reference data shared by all tenants, and checks companies
+// against the rules of their type, see company.TypeChecker.
+type Service interface {
+	CreateCompanyType(ctx context.Context, definition *model.CompanyTypeDefinition) (*model.CompanyTypeDefinition, error)
+	// GetCompanyType returns model.ErrCompanyTypeNotFound when the type does not exist
+	GetCompanyType(ctx context.Context, name model.CompanyType) (*model.CompanyTypeDefinition, error)
+	ListCompanyTypes(ctx context.Context) ([]*model.CompanyTypeDefinition, error)
+	// UpdateCompanyType replaces the description, rules and deprecation of the type name
+	UpdateCompanyType(ctx context.Context, name model.CompanyType, definition *model.CompanyTypeDefinition) (*model.CompanyTypeDefinition, error)
+	// DeleteCompanyType returns model.ErrCompanyTypeInUse while companies have the type, those types can only be deprecated
+	DeleteCompanyType(ctx context.Context, name model.CompanyType) error
+	company.TypeChecker
+}
+
+type companyTypeService struct {
+	repo      Repository
+	companies company.Repository
+	now       func() time.Time
+}
+
+// NewService creates a Service on repo, companies are counted by type before a type is deleted
+func NewService(repo Repository, companies company.Repository) Service {
+	return &companyTypeService{repo: repo, companies: companies, now: time.Now}
+}
+
+func (s *companyTypeService) CreateCompanyType(ctx context.Context, definition *model.CompanyTypeDefinition) (*model.CompanyTypeDefinition, error) {
+	if err := definition.Validate(); err != nil {
+		return nil, err
+	}
+	// Cassandra stores timestamps in milliseconds
+	definition.CreatedAt = s.now().UTC().Truncate(time.Millisecond)
+	definition.UpdatedAt = definition.CreatedAt
+	if err := s.repo.Create(ctx, definition); err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Infof("company type:%v created", definition.Name)
+	return definition, nil
+}
+
+func (s *companyTypeService) GetCompanyType(ctx context.Context, name model.CompanyType) (*model.CompanyTypeDefinition, error) {
+	definition, err := s.repo.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if definition == nil {
+		return nil, model.ErrCompanyTypeNotFound{Name: name}
+	}
+	return definition, nil
+}
+
+func (s *companyTypeService) ListCompanyTypes(ctx context.Context) ([]*model.CompanyTypeDefinition, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *companyTypeService) UpdateCompanyType(ctx context.Context, name model.CompanyType, definition *model.CompanyTypeDefinition) (*model.CompanyTypeDefinition, error) {
+	existing, err := s.GetCompanyType(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	// Copy over the fields that can't be updated
+	definition.Name = existing.Name
+	definition.CreatedAt = existing.CreatedAt
+	if err = definition.Validate(); err != nil {
+		return nil, err
+	}
+	definition.UpdatedAt = s.now().UTC().Truncate(time.Millisecond)
+	if err = s.repo.Update(ctx, definition); err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Infof("company type:%v updated, deprecated:%v", name, definition.Deprecated)
+	return definition, nil
+}
+
+func (s *companyTypeService) DeleteCompanyType(ctx context.Context, name model.CompanyType) error {
+	definition, err := s.GetCompanyType(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err = s.checkUnused(ctx, name); err != nil {
+		return err
+	}
+	if err = s.repo.Delete(ctx, name); err != nil {
+		return err
+	}
+	// A company may have taken the type between the count and the delete, the type is restored for it. Companies
+	// that take it later still keep it, see CheckCompany.
+	if err = s.checkUnused(ctx, name); err != nil {
+		if restoreErr := s.repo.Create(ctx, definition); restoreErr != nil {
+			logging.FromContext(ctx).Errorf("company type:%v restore error:%v", name, restoreErr)
+		}
+		return err
+	}
+	logging.FromContext(ctx).Infof("company type:%v deleted", name)
+	return nil
+}
+
+// checkUnused returns model.ErrCompanyTypeInUse if companies have the type name
+func (s *companyTypeService) checkUnused(ctx context.Context, name model.CompanyType) error {
+	count, err := s.companies.CountByType(ctx, name)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return model.ErrCompanyTypeInUse{Name: name, Companies: count}
+	}
+	return nil
+}
+
+// CheckCompany rejects unknown types and the rules of the type the company breaks. A deprecated or deleted type is only
+// accepted for a company that already has it.
+func (s *companyTypeService) CheckCompany(ctx context.Context, newCompany *model.Company, previous *model.Company) error {
+	definition, err := s.repo.Get(ctx, newCompany.Type)
+	if err != nil {
+		return err
+	}
+	if definition == nil {
+		// a company that took the type while it was deleted keeps it, like a deprecated type
+		if previous != nil && previous.Type == newCompany.Type {
+			logging.FromContext(ctx).Warnf("company:%v has the deleted type:%v", newCompany.ID, newCompany.Type)
+			return nil
+		}
+		return model.ErrInvalidCompany{Fields: []model.FieldError{{Field: "type", Message: "is not a known company type"}}}
+	}
+	var fields []model.FieldError
+	if definition.Deprecated && (previous == nil || previous.Type != newCompany.Type) {
+		fields = append(fields, model.FieldError{Field: "type", Message: "is deprecated"})
+	}
+	fields = append(fields, definition.Check(newCompany)...)
+	if len(fields) > 0 {
+		return model.ErrInvalidCompany{Fields: fields}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package companytype
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/company"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// newTestService returns a service on the default types and the companies of companies
+func newTestService(t *testing.T, companies ...*model.Company) Service {
+	companyRepo := company.NewMemoryRepository()
+	for _, stored := range companies {
+		require.NoError(t, companyRepo.Create(context.Background(), stored))
+	}
+	service := NewService(NewMemoryRepository(model.DefaultCompanyTypes()...), companyRepo).(*companyTypeService)
+	service.now = func() time.Time { return testNow }
+	return service
+}
+
+func newCompany(companyType model.CompanyType) *model.Company {
+	return &model.Company{ID: uuid.New(), TenantID: "tenant-a", Name: "Acme " + uuid.NewString(), Employees: 10, Type: companyType}
+}
+
+func TestCompanyTypeService_Create(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	created, err := svc.CreateCompanyType(ctx, &model.CompanyTypeDefinition{Name: "Partnership", MaxEmployees: 20, CreatedAt: testNow.Add(-time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, testNow, created.CreatedAt)
+	assert.Equal(t, testNow, created.UpdatedAt)
+	found, err := svc.GetCompanyType(ctx, "Partnership")
+	require.NoError(t, err)
+	assert.Equal(t, created, found)
+
+	_, err = svc.CreateCompanyType(ctx, &model.CompanyTypeDefinition{Name: model.Corporation})
+	assert.Equal(t, model.ErrCompanyTypeExists{Name: model.Corporation}, err)
+	_, err = svc.CreateCompanyType(ctx, &model.CompanyTypeDefinition{Name: "partnership"})
+	assert.ErrorAs(t, err, &model.ErrInvalidCompanyType{})
+}
+
+func TestCompanyTypeService_Update(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+
+	updated, err := svc.UpdateCompanyType(ctx, model.Cooperative, &model.CompanyTypeDefinition{Name: "Renamed", Description: "Member owned", Deprecated: true})
+	require.NoError(t, err)
+	assert.Equal(t, model.Cooperative, updated.Name)
+	assert.True(t, updated.Deprecated)
+	assert.Equal(t, testNow, updated.UpdatedAt)
+
+	_, err = svc.UpdateCompanyType(ctx, "Partnership", &model.CompanyTypeDefinition{})
+	assert.Equal(t, model.ErrCompanyTypeNotFound{Name: "Partnership"}, err)
+	_, err = svc.UpdateCompanyType(ctx, model.Cooperative, &model.CompanyTypeDefinition{MaxEmployees: -1})
+	assert.ErrorAs(t, err, &model.ErrInvalidCompanyType{})
+}
+
+func TestCompanyTypeService_Delete(t *testing.T) {
+	svc := newTestService(t, newCompany(model.Cooperative), newCompany(model.Cooperative))
+	ctx := context.Background()
+
+	assert.Equal(t, model.ErrCompanyTypeInUse{Name: model.Cooperative, Companies: 2}, svc.DeleteCompanyType(ctx, model.Cooperative))
+	assert.Equal(t, model.ErrCompanyTypeNotFound{Name: "Partnership"}, svc.DeleteCompanyType(ctx, "Partnership"))
+
+	require.NoError(t, svc.DeleteCompanyType(ctx, model.SoleProprietorship))
+	_, err := svc.GetCompanyType(ctx, model.SoleProprietorship)
+	assert.Equal(t, model.ErrCompanyTypeNotFound{Name: model.SoleProprietorship}, err)
+}
+
+// racingRepository creates a company of the type that is deleted right after it is deleted, as a concurrent
+// create that checked the type before the delete would
+type racingRepository struct {
+	Repository
+	companies company.Repository
+}
+
+func (r *racingRepository) Delete(ctx context.Context, name model.CompanyType) error {
+	if err := r.Repository.Delete(ctx, name); err != nil {
+		return err
+	}
+	return r.companies.Create(ctx, newCompany(name))
+}
+
+func TestCompanyTypeService_Delete_ConcurrentCreate(t *testing.T) {
+	ctx := context.Background()
+	companyRepo := company.NewMemoryRepository()
+	repo := NewMemoryRepository(model.DefaultCompanyTypes()...)
+	svc := NewService(&racingRepository{Repository: repo, companies: companyRepo}, companyRepo)
+
+	assert.Equal(t, model.ErrCompanyTypeInUse{Name: model.SoleProprietorship, Companies: 1}, svc.DeleteCompanyType(ctx, model.SoleProprietorship))
+	restored, err := svc.GetCompanyType(ctx, model.SoleProprietorship)
+	require.NoError(t, err)
+	assert.Equal(t, model.SoleProprietorship, restored.Name)
+}
+
+func TestCompanyTypeService_CheckCompany(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+	_, err := svc.UpdateCompanyType(ctx, model.Cooperative, &model.CompanyTypeDefinition{Deprecated: true})
+	require.NoError(t, err)
+
+	assert.NoError(t, svc.CheckCompany(ctx, newCompany(model.Corporation), nil))
+
+	err = svc.CheckCompany(ctx, newCompany("Partnership"), nil)
+	assert.Equal(t, model.ErrInvalidCompany{Fields: []model.FieldError{{Field: "type", Message: "is not a known company type"}}}, err)
+
+	err = svc.CheckCompany(ctx, newCompany(model.NonProfit), nil)
+	assert.Equal(t, model.ErrInvalidCompany{Fields: []model.FieldError{{Field: "registered", Message: "must be true for type NonProfit"}}}, err)
+
+	// companies can keep a deprecated type, but no other company can take it
+	cooperative := newCompany(model.Cooperative)
+	assert.NoError(t, svc.CheckCompany(ctx, cooperative, cooperative))
+	err = svc.CheckCompany(ctx, cooperative, nil)
+	assert.Equal(t, model.ErrInvalidCompany{Fields: []model.FieldError{{Field: "type", Message: "is deprecated"}}}, err)
+	err = svc.CheckCompany(ctx, cooperative, newCompany(model.Corporation))
+	assert.Equal(t, model.ErrInvalidCompany{Fields: []model.FieldError{{Field: "type", Message: "is deprecated"}}}, err)
+}
+
+func TestCompanyTypeService_CheckCompany_DeletedType(t *testing.T) {
+	svc := newTestService(t)
+	ctx := context.Background()
+	require.NoError(t, svc.DeleteCompanyType(ctx, model.SoleProprietorship))
+
+	// a company that took the type while it was deleted can still be changed, but no other company can take it
+	soleProprietorship := newCompany(model.SoleProprietorship)
+	assert.NoError(t, svc.CheckCompany(ctx, soleProprietorship, soleProprietorship))
+	err := svc.CheckCompany(ctx, soleProprietorship, nil)
+	assert.Equal(t, model.ErrInvalidCompany{Fields: []model.FieldError{{Field: "type", Message: "is not a known company type"}}}, err)
+}
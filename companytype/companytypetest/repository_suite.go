@@ -0,0 +1,123 @@
+// Package companytypetest provides a conformance test suite for companytype.Repository implementations.
+package companytypetest
+
+import (
+	"context"
+	"github.com/ngereci/xm_interview/companytype"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// NewRepository returns an empty repository, it is called once per test.
+type NewRepository func(t *testing.T) companytype.Repository
+
+// RunRepositorySuite runs the contract every companytype.Repository implementation has to fulfil.
+func RunRepositorySuite(t *testing.T, newRepository NewRepository) {
+	t.Run("CreateAndGet", func(t *testing.T) { testCreateAndGet(t, newRepository(t)) })
+	t.Run("CreateExisting", func(t *testing.T) { testCreateExisting(t, newRepository(t)) })
+	t.Run("GetNotFound", func(t *testing.T) { testGetNotFound(t, newRepository(t)) })
+	t.Run("List", func(t *testing.T) { testList(t, newRepository(t)) })
+	t.Run("Update", func(t *testing.T) { testUpdate(t, newRepository(t)) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, newRepository(t)) })
+}
+
+// newCompanyType returns a type created now, timestamps are stored in milliseconds
+func newCompanyType(name model.CompanyType) *model.CompanyTypeDefinition {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	return &model.CompanyTypeDefinition{
+		Name:                       name,
+		Description:                "A type named " + string(name),
+		RequiresRegistration:       true,
+		RequiresRegistrationNumber: true,
+		MaxEmployees:               50,
+		CreatedAt:                  now,
+		UpdatedAt:                  now,
+	}
+}
+
+// assertCompanyType compares the types field by field, timestamps may come back in another location
+func assertCompanyType(t *testing.T, expected, actual *model.CompanyTypeDefinition) {
+	require.NotNil(t, actual)
+	assert.Equal(t, expected.Name, actual.Name)
+	assert.Equal(t, expected.Description, actual.Description)
+	assert.Equal(t, expected.RequiresRegistration, actual.RequiresRegistration)
+	assert.Equal(t, expected.RequiresRegistrationNumber, actual.RequiresRegistrationNumber)
+	assert.Equal(t, expected.MaxEmployees, actual.MaxEmployees)
+	assert.Equal(t, expected.Deprecated, actual.Deprecated)
+	assert.True(t, expected.CreatedAt.Equal(actual.CreatedAt))
+	assert.True(t, expected.UpdatedAt.Equal(actual.UpdatedAt))
+}
+
+func testCreateAndGet(t *testing.T, repo companytype.Repository) {
+	ctx := context.Background()
+	created := newCompanyType("Partnership")
+	require.NoError(t, repo.Create(ctx, created))
+
+	found, err := repo.Get(ctx, created.Name)
+	require.NoError(t, err)
+	assertCompanyType(t, created, found)
+}
+
+func testCreateExisting(t *testing.T, repo companytype.Repository) {
+	ctx := context.Background()
+	require.NoError(t, repo.Create(ctx, newCompanyType("Partnership")))
+
+	err := repo.Create(ctx, newCompanyType("Partnership"))
+	assert.Equal(t, model.ErrCompanyTypeExists{Name: "Partnership"}, err)
+}
+
+func testGetNotFound(t *testing.T, repo companytype.Repository) {
+	found, err := repo.Get(context.Background(), "Partnership")
+	require.NoError(t, err)
+	assert.Nil(t, found)
+}
+
+func testList(t *testing.T, repo companytype.Repository) {
+	ctx := context.Background()
+	for _, name := range []model.CompanyType{"Trust", "Foundation", "Partnership"} {
+		require.NoError(t, repo.Create(ctx, newCompanyType(name)))
+	}
+
+	definitions, err := repo.List(ctx)
+	require.NoError(t, err)
+	names := make([]model.CompanyType, 0, len(definitions))
+	for _, definition := range definitions {
+		names = append(names, definition.Name)
+	}
+	assert.Equal(t, []model.CompanyType{"Foundation", "Partnership", "Trust"}, names)
+}
+
+func testUpdate(t *testing.T, repo companytype.Repository) {
+	ctx := context.Background()
+	definition := newCompanyType("Partnership")
+	require.NoError(t, repo.Create(ctx, definition))
+
+	definition.Description = "Deprecated in favour of Corporation"
+	definition.RequiresRegistration = false
+	definition.MaxEmployees = 0
+	definition.Deprecated = true
+	definition.UpdatedAt = definition.UpdatedAt.Add(time.Hour)
+	require.NoError(t, repo.Update(ctx, definition))
+
+	found, err := repo.Get(ctx, definition.Name)
+	require.NoError(t, err)
+	assertCompanyType(t, definition, found)
+
+	err = repo.Update(ctx, newCompanyType("Trust"))
+	assert.Equal(t, model.ErrCompanyTypeNotFound{Name: "Trust"}, err)
+}
+
+func testDelete(t *testing.T, repo companytype.Repository) {
+	ctx := context.Background()
+	require.NoError(t, repo.Create(ctx, newCompanyType("Partnership")))
+
+	require.NoError(t, repo.Delete(ctx, "Partnership"))
+
+	found, err := repo.Get(ctx, "Partnership")
+	require.NoError(t, err)
+	assert.Nil(t, found)
+	assert.Equal(t, model.ErrCompanyTypeNotFound{Name: "Partnership"}, repo.Delete(ctx, "Partnership"))
+}
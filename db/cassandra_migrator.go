@@ -0,0 +1,311 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"github.com/gocql/gocql"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/ngereci/xm_interview/tenant"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+//go:embed migrations/cassandra/*.cql
+var cassandraMigrations embed.FS
+
+const (
+	lockID           = "schema"
+	lockTTL          = time.Minute
+	lockRenewal      = lockTTL / 3
+	lockRetryBackoff = 2 * time.Second
+	copyPageSize     = 500
+)
+
+// errLockLost aborts the migrations once the lock is held by another migrator
+var errLockLost = errors.New("the schema migration lock was lost")
+
+// cassandraDataMigrations move data, which CQL statements can not do. They run after the statements
+// of the migration with the same version and have to be idempotent as well.
+var cassandraDataMigrations = map[int]func(ctx context.Context, session *gocql.Session) error{
+	3: copyCompaniesToTenants,
+	5: normalizeCassandraCompanyNames,
+}
+
+// Migrator applies the migrations shipped with the binary that are missing from the database.
+type Migrator interface {
+	Migrate() error
+}
+
+type cassandraMigrator struct {
+	session     *gocql.Session
+	migrations  []Migration
+	owner       string
+	lockTimeout time.Duration
+}
+
+// NewCassandraMigrator creates a Migrator for the keyspace of session.
+// Only one migrator applies migrations at a time, the others wait up to lockTimeout for the
+// lock, which is held in the schema_migrations_lock table using lightweight transactions.
+func NewCassandraMigrator(session *gocql.Session, lockTimeout time.Duration) (Migrator, error) {
+	migrations, err := loadMigrations(cassandraMigrations, "migrations/cassandra")
+	if err != nil {
+		return nil, err
+	}
+	return &cassandraMigrator{
+		session:     session,
+		migrations:  migrations,
+		owner:       uuid.New().String(),
+		lockTimeout: lockTimeout,
+	}, nil
+}
+
+// CreateKeyspace creates the keyspace configured on cluster if it does not exist yet.
+// It has to run before the session for the keyspace can be created.
+func CreateKeyspace(cluster *gocql.ClusterConfig, replicationFactor int) error {
+	keyspaceCluster := *cluster
+	keyspaceCluster.Keyspace = ""
+	session, err := keyspaceCluster.CreateSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	return session.Query(fmt.Sprintf(
+		`CREATE KEYSPACE IF NOT EXISTS %q WITH REPLICATION = { 'class' : 'SimpleStrategy', 'replication_factor' : %d }`,
+		cluster.Keyspace, replicationFactor,
+	)).Exec()
+}
+
+// Migrate applies all pending migrations in version order. Cassandra schema changes are not
+// transactional, so migrations have to be idempotent (IF NOT EXISTS) to be safely retried
+// after a migration failed halfway. The lock is renewed while the migrations run, they are
+// aborted if it can not be renewed, as another migrator may take it once it expired.
+func (m *cassandraMigrator) Migrate() error {
+	if err := m.createTables(); err != nil {
+		return err
+	}
+	if err := m.lock(); err != nil {
+		return err
+	}
+	defer m.unlock()
+	ctx, abort := context.WithCancelCause(context.Background())
+	defer abort(nil)
+	go m.renewLock(ctx, abort)
+
+	applied, err := m.appliedChecksums()
+	if err != nil {
+		return err
+	}
+	known := make(map[int]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+		checksum, isApplied := applied[migration.Version]
+		if isApplied {
+			if checksum != migration.Checksum {
+				return fmt.Errorf("migration %v_%v was modified after it was applied", migration.Version, migration.Name)
+			}
+			continue
+		}
+		if err = m.apply(ctx, migration); err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("migration %v_%v aborted: %w", migration.Version, migration.Name, context.Cause(ctx))
+			}
+			return err
+		}
+	}
+	for version := range applied {
+		if !known[version] {
+			log.Warnf("migration version:%v is applied but unknown to this binary", version)
+		}
+	}
+	return nil
+}
+
+func (m *cassandraMigrator) createTables() error {
+	err := m.session.Query(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version int PRIMARY KEY,
+			name text,
+			checksum text,
+			applied_at timestamp
+		)
+	`).Exec()
+	if err != nil {
+		return err
+	}
+	return m.session.Query(`
+		CREATE TABLE IF NOT EXISTS schema_migrations_lock (
+			id text PRIMARY KEY,
+			owner text
+		)
+	`).Exec()
+}
+
+func (m *cassandraMigrator) appliedChecksums() (map[int]string, error) {
+	applied := make(map[int]string)
+	iter := m.session.Query(`SELECT version, checksum FROM schema_migrations`).Iter()
+	var (
+		version  int
+		checksum string
+	)
+	for iter.Scan(&version, &checksum) {
+		applied[version] = checksum
+	}
+	return applied, iter.Close()
+}
+
+func (m *cassandraMigrator) apply(ctx context.Context, migration Migration) error {
+	log.Infof("applying migration %v_%v", migration.Version, migration.Name)
+	for _, statement := range migration.Statements {
+		if err := m.session.Query(statement).WithContext(ctx).Exec(); err != nil {
+			log.Errorf("migration %v_%v statement:%v error:%v", migration.Version, migration.Name, statement, err)
+			return fmt.Errorf("migration %v_%v failed: %w", migration.Version, migration.Name, err)
+		}
+	}
+	if migrateData, exists := cassandraDataMigrations[migration.Version]; exists {
+		if err := migrateData(ctx, m.session); err != nil {
+			log.Errorf("migration %v_%v data error:%v", migration.Version, migration.Name, err)
+			return fmt.Errorf("migration %v_%v failed: %w", migration.Version, migration.Name, err)
+		}
+	}
+	return m.session.Query(`
+		INSERT INTO schema_migrations (version, name, checksum, applied_at)
+		VALUES (?, ?, ?, ?)
+	`, migration.Version, migration.Name, migration.Checksum, time.Now().UTC()).WithContext(ctx).Exec()
+}
+
+// lock acquires the migration lock. The lock expires after lockTTL unless it is renewed, so
+// that a crashed migrator does not block the others forever.
+func (m *cassandraMigrator) lock() error {
+	deadline := time.Now().Add(m.lockTimeout)
+	for {
+		existing := make(map[string]interface{})
+		acquired, err := m.session.Query(`
+			INSERT INTO schema_migrations_lock (id, owner)
+			VALUES (?, ?)
+			IF NOT EXISTS
+			USING TTL ?
+		`, lockID, m.owner, int(lockTTL.Seconds())).MapScanCAS(existing)
+		if err != nil {
+			return err
+		}
+		if acquired {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errors.New("timed out waiting for the schema migration lock held by " + fmt.Sprint(existing["owner"]))
+		}
+		log.Infof("schema migration lock held by %v, waiting", existing["owner"])
+		time.Sleep(lockRetryBackoff)
+	}
+}
+
+// renewLock extends the lock to lockTTL every lockRenewal until ctx is done. A failed renewal
+// aborts the migrations with the error, the lock may expire before the next one.
+func (m *cassandraMigrator) renewLock(ctx context.Context, abort context.CancelCauseFunc) {
+	ticker := time.NewTicker(lockRenewal)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		renewed, err := m.session.Query(`
+			UPDATE schema_migrations_lock
+			USING TTL ?
+			SET owner = ?
+			WHERE id = ?
+			IF owner = ?
+		`, int(lockTTL.Seconds()), m.owner, lockID, m.owner).WithContext(ctx).MapScanCAS(make(map[string]interface{}))
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil && !renewed {
+			err = errLockLost
+		}
+		if err != nil {
+			log.Errorf("schema migration lock renewal error:%v", err)
+			abort(err)
+			return
+		}
+	}
+}
+
+func (m *cassandraMigrator) unlock() {
+	_, err := m.session.Query(`
+		DELETE FROM schema_migrations_lock
+		WHERE id = ?
+		IF owner = ?
+	`, lockID, m.owner).MapScanCAS(make(map[string]interface{}))
+	if err != nil {
+		log.Errorf("schema migration lock release error:%v", err)
+	}
+}
+
+// copyCompaniesToTenants copies the companies of the company table, which predates tenants, to the
+// default tenant of the company_by_tenant table. Copying again overwrites the copies with the same data.
+func copyCompaniesToTenants(ctx context.Context, session *gocql.Session) error {
+	iter := session.Query(`
+		SELECT id, name, description, employees, registered, type
+		FROM company
+	`).PageSize(copyPageSize).WithContext(ctx).Iter()
+	var (
+		id          gocql.UUID
+		name        string
+		description string
+		employees   int
+		registered  bool
+		companyType string
+		copied      int
+	)
+	for iter.Scan(&id, &name, &description, &employees, &registered, &companyType) {
+		err := session.Query(`
+			INSERT INTO company_by_tenant (tenant_id, id, name, description, employees, registered, type)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, tenant.Default, id, name, description, employees, registered, companyType).WithContext(ctx).Exec()
+		if err != nil {
+			iter.Close()
+			return err
+		}
+		copied++
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+	log.Infof("copied %v companies to tenant %v", copied, tenant.Default)
+	return nil
+}
+
+// normalizeCassandraCompanyNames sets the normalized name of every company, running it again sets the same names.
+func normalizeCassandraCompanyNames(ctx context.Context, session *gocql.Session) error {
+	iter := session.Query(`
+		SELECT tenant_id, id, name
+		FROM company_by_tenant
+	`).PageSize(copyPageSize).WithContext(ctx).Iter()
+	var (
+		tenantID   string
+		id         gocql.UUID
+		name       string
+		normalized int
+	)
+	for iter.Scan(&tenantID, &id, &name) {
+		err := session.Query(`
+			UPDATE company_by_tenant
+			SET normalized_name = ?
+			WHERE tenant_id = ? AND id = ?
+		`, model.NormalizeName(name), tenantID, id).WithContext(ctx).Exec()
+		if err != nil {
+			iter.Close()
+			return err
+		}
+		normalized++
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+	log.Infof("normalized the names of %v companies", normalized)
+	return nil
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Migration is a single versioned, up-only schema change.
+type Migration struct {
+	Version    int
+	Name       string
+	Statements []string
+	// Checksum of the migration file, used to detect edits of already applied migrations.
+	Checksum string
+}
+
+// loadMigrations reads all migrations from dir. File names have the form <version>_<name>.<ext>,
+// for example 0001_create_company.cql, versions have to be unique.
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	migrations := make([]Migration, 0, len(entries))
+	versions := make(map[int]string, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		fileName := entry.Name()
+		baseName := strings.TrimSuffix(fileName, path.Ext(fileName))
+		versionPart, name, found := strings.Cut(baseName, "_")
+		if !found {
+			return nil, fmt.Errorf("migration %v: file name must have the form <version>_<name>", fileName)
+		}
+		version, err := strconv.Atoi(versionPart)
+		if err != nil {
+			return nil, fmt.Errorf("migration %v: invalid version: %v", fileName, err)
+		}
+		if other, exists := versions[version]; exists {
+			return nil, fmt.Errorf("migration %v: version %v already used by %v", fileName, version, other)
+		}
+		versions[version] = fileName
+
+		body, err := fs.ReadFile(fsys, path.Join(dir, fileName))
+		if err != nil {
+			return nil, err
+		}
+		checksum := sha256.Sum256(body)
+		migrations = append(migrations, Migration{
+			Version:    version,
+			Name:       name,
+			Statements: splitStatements(string(body)),
+			Checksum:   hex.EncodeToString(checksum[:]),
+		})
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// splitStatements splits a script into its ';' terminated statements, dropping "--" and "//" comment lines.
+// Semicolons inside string literals are not supported.
+func splitStatements(script string) []string {
+	var cleaned strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "--") || strings.HasPrefix(trimmed, "//") {
+			continue
+		}
+		cleaned.WriteString(line)
+		cleaned.WriteString("\n")
+	}
+	var statements []string
+	for _, statement := range strings.Split(cleaned.String(), ";") {
+		if statement = strings.TrimSpace(statement); statement != "" {
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0002_add_index.cql": {Data: []byte("CREATE INDEX IF NOT EXISTS index_name ON company (name);")},
+		"migrations/0001_create.cql": {Data: []byte(`-- create the table
+CREATE TABLE IF NOT EXISTS company (
+   id uuid PRIMARY KEY
+);
+// second statement
+ALTER TABLE company ADD name text;
+`)},
+	}
+
+	migrations, err := loadMigrations(fsys, "migrations")
+
+	assert.NoError(t, err)
+	assert.Len(t, migrations, 2)
+	assert.Equal(t, 1, migrations[0].Version)
+	assert.Equal(t, "create", migrations[0].Name)
+	assert.Equal(t, []string{
+		"CREATE TABLE IF NOT EXISTS company (\n   id uuid PRIMARY KEY\n)",
+		"ALTER TABLE company ADD name text",
+	}, migrations[0].Statements)
+	assert.Equal(t, 2, migrations[1].Version)
+	assert.Equal(t, "add_index", migrations[1].Name)
+	assert.Len(t, migrations[1].Checksum, 64)
+	assert.NotEqual(t, migrations[0].Checksum, migrations[1].Checksum)
+}
+
+func TestLoadMigrations_InvalidFileName(t *testing.T) {
+	_, err := loadMigrations(fstest.MapFS{"migrations/create.cql": {Data: []byte("")}}, "migrations")
+	assert.Error(t, err)
+
+	_, err = loadMigrations(fstest.MapFS{"migrations/first_create.cql": {Data: []byte("")}}, "migrations")
+	assert.Error(t, err)
+}
+
+func TestLoadMigrations_DuplicateVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0001_create.cql": {Data: []byte("")},
+		"migrations/1_other.cql":     {Data: []byte("")},
+	}
+	_, err := loadMigrations(fsys, "migrations")
+	assert.Error(t, err)
+}
+
+func TestCassandraMigrations(t *testing.T) {
+	migrations, err := loadMigrations(cassandraMigrations, "migrations/cassandra")
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, migrations)
+	for i, migration := range migrations {
+		assert.Equal(t, i+1, migration.Version, "migration versions have to be consecutive")
+		assert.NotEmpty(t, migration.Statements)
+	}
+}
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS company (
+    id          uuid PRIMARY KEY,
+    name        text    NOT NULL,
+    description text    NOT NULL DEFAULT '',
+    employees   integer NOT NULL,
+    registered  boolean NOT NULL DEFAULT false,
+    type        text    NOT NULL,
+    CONSTRAINT company_name_key UNIQUE (name)
+);
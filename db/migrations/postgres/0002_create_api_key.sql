@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS api_key (
+    id           uuid        PRIMARY KEY,
+    name         text        NOT NULL,
+    hash         text        NOT NULL,
+    scopes       text        NOT NULL,
+    created_at   timestamptz NOT NULL,
+    last_used_at timestamptz,
+    revoked_at   timestamptz
+);
@@ -0,0 +1,9 @@
+-- Companies and API keys belong to a tenant, the existing ones to the default tenant.
+-- Company names are unique per tenant instead of globally.
+ALTER TABLE company ADD COLUMN tenant_id text NOT NULL DEFAULT 'default';
+ALTER TABLE company ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE company DROP CONSTRAINT company_name_key;
+ALTER TABLE company ADD CONSTRAINT company_tenant_name_key UNIQUE (tenant_id, name);
+
+ALTER TABLE api_key ADD COLUMN tenant_id text NOT NULL DEFAULT 'default';
+ALTER TABLE api_key ALTER COLUMN tenant_id DROP DEFAULT;
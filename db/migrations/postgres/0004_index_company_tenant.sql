@@ -0,0 +1,2 @@
+-- Pages through the companies of a tenant ordered by id
+CREATE INDEX IF NOT EXISTS company_tenant_id_idx ON company (tenant_id, id);
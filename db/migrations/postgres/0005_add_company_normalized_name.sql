@@ -0,0 +1,3 @@
+-- Company names are unique per tenant by their normalized form, see model.NormalizeName. The normalized names
+-- of the existing companies are filled in by normalizePostgresCompanyNames after this script.
+ALTER TABLE company ADD COLUMN normalized_name text;
@@ -0,0 +1,5 @@
+-- Replaces the uniqueness of names as they were entered. Fails if names of a tenant normalize to the same name,
+-- these companies have to be renamed before migrating.
+ALTER TABLE company ALTER COLUMN normalized_name SET NOT NULL;
+ALTER TABLE company DROP CONSTRAINT company_tenant_name_key;
+ALTER TABLE company ADD CONSTRAINT company_tenant_normalized_name_key UNIQUE (tenant_id, normalized_name);
@@ -0,0 +1,9 @@
+-- Identifiers, addresses and contacts of companies, existing companies have none.
+-- Addresses and contacts are JSON arrays of the model.Address and model.Contact objects.
+ALTER TABLE company ADD COLUMN registration_number text  NOT NULL DEFAULT '';
+ALTER TABLE company ADD COLUMN country             text  NOT NULL DEFAULT '';
+ALTER TABLE company ADD COLUMN vat_number          text  NOT NULL DEFAULT '';
+ALTER TABLE company ADD COLUMN lei                 text  NOT NULL DEFAULT '';
+ALTER TABLE company ADD COLUMN website             text  NOT NULL DEFAULT '';
+ALTER TABLE company ADD COLUMN addresses           jsonb NOT NULL DEFAULT '[]';
+ALTER TABLE company ADD COLUMN contacts            jsonb NOT NULL DEFAULT '[]';
@@ -0,0 +1,18 @@
+-- Company types with the rules of their companies, seeded with the types that used to be built in
+CREATE TABLE IF NOT EXISTS company_type (
+    name                         text        PRIMARY KEY,
+    description                  text        NOT NULL DEFAULT '',
+    requires_registration        boolean     NOT NULL DEFAULT false,
+    requires_registration_number boolean     NOT NULL DEFAULT false,
+    max_employees                integer     NOT NULL DEFAULT 0,
+    deprecated                   boolean     NOT NULL DEFAULT false,
+    created_at                   timestamptz NOT NULL DEFAULT now(),
+    updated_at                   timestamptz NOT NULL DEFAULT now()
+);
+
+INSERT INTO company_type (name, description, requires_registration) VALUES
+    ('Corporation', 'A company owned by its shareholders', false),
+    ('NonProfit', 'An organization that does not distribute its profits', true),
+    ('Cooperative', 'A company owned and run by its members', false),
+    ('SoleProprietorship', 'A business owned and run by a single person', false)
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,11 @@
+-- The parent of a company within its tenant, a company has at most one
+CREATE TABLE IF NOT EXISTS company_relationship (
+    tenant_id  text        NOT NULL,
+    company_id uuid        NOT NULL,
+    parent_id  uuid        NOT NULL,
+    type       text        NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, company_id)
+);
+
+CREATE INDEX IF NOT EXISTS company_relationship_parent_idx ON company_relationship (tenant_id, parent_id);
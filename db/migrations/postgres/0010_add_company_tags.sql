@@ -0,0 +1,2 @@
+-- Tags of companies, a JSON object of the keys to the values, existing companies have none.
+ALTER TABLE company ADD COLUMN tags jsonb NOT NULL DEFAULT '{}';
@@ -0,0 +1,2 @@
+-- Counts the companies of a type, which is checked before a type is deleted and exported as a gauge
+CREATE INDEX IF NOT EXISTS company_type_idx ON company (type);
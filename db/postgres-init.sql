@@ -0,0 +1,2 @@
+-- Executed once by the postgres container on an empty data directory, the schema itself is created by the migrations
+CREATE DATABASE companies_test OWNER companies;
@@ -0,0 +1,164 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"github.com/ngereci/xm_interview/model"
+	log "github.com/sirupsen/logrus"
+)
+
+//go:embed migrations/postgres/*.sql
+var postgresMigrations embed.FS
+
+// postgresLockKey identifies the advisory lock held while migrating, an arbitrary but fixed number.
+const postgresLockKey = 7_146_250_311
+
+// postgresDataMigrations move data, which SQL statements can not do. They run in the transaction of the migration
+// with the same version after its statements.
+var postgresDataMigrations = map[int]func(ctx context.Context, tx *sql.Tx) error{
+	5: normalizePostgresCompanyNames,
+}
+
+type postgresMigrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewPostgresMigrator creates a Migrator for the database of db.
+// Concurrent migrators are serialized with a session level advisory lock
+// and every migration is applied in its own transaction.
+func NewPostgresMigrator(db *sql.DB) (Migrator, error) {
+	migrations, err := loadMigrations(postgresMigrations, "migrations/postgres")
+	if err != nil {
+		return nil, err
+	}
+	return &postgresMigrator{db: db, migrations: migrations}, nil
+}
+
+func (m *postgresMigrator) Migrate() error {
+	ctx := context.Background()
+	// advisory locks belong to a connection, so all statements have to use the same one
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, postgresLockKey); err != nil {
+		return err
+	}
+	defer func() {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, postgresLockKey); err != nil {
+			log.Errorf("schema migration lock release error:%v", err)
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    integer PRIMARY KEY,
+			name       text        NOT NULL,
+			checksum   text        NOT NULL,
+			applied_at timestamptz NOT NULL DEFAULT now()
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	applied, err := m.appliedChecksums(ctx, conn)
+	if err != nil {
+		return err
+	}
+	for _, migration := range m.migrations {
+		checksum, isApplied := applied[migration.Version]
+		if isApplied {
+			if checksum != migration.Checksum {
+				return fmt.Errorf("migration %v_%v was modified after it was applied", migration.Version, migration.Name)
+			}
+			continue
+		}
+		if err = m.apply(ctx, conn, migration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *postgresMigrator) appliedChecksums(ctx context.Context, conn *sql.Conn) (map[int]string, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, checksum FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int]string)
+	for rows.Next() {
+		var (
+			version  int
+			checksum string
+		)
+		if err = rows.Scan(&version, &checksum); err != nil {
+			return nil, err
+		}
+		applied[version] = checksum
+	}
+	return applied, rows.Err()
+}
+
+func (m *postgresMigrator) apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	log.Infof("applying migration %v_%v", migration.Version, migration.Name)
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck // no-op after commit
+	for _, statement := range migration.Statements {
+		if _, err = tx.ExecContext(ctx, statement); err != nil {
+			log.Errorf("migration %v_%v statement:%v error:%v", migration.Version, migration.Name, statement, err)
+			return fmt.Errorf("migration %v_%v failed: %w", migration.Version, migration.Name, err)
+		}
+	}
+	if migrateData, exists := postgresDataMigrations[migration.Version]; exists {
+		if err = migrateData(ctx, tx); err != nil {
+			log.Errorf("migration %v_%v data error:%v", migration.Version, migration.Name, err)
+			return fmt.Errorf("migration %v_%v failed: %w", migration.Version, migration.Name, err)
+		}
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO schema_migrations (version, name, checksum)
+		VALUES ($1, $2, $3)
+	`, migration.Version, migration.Name, migration.Checksum)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// normalizePostgresCompanyNames sets the normalized name of every company
+func normalizePostgresCompanyNames(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id, name FROM company`)
+	if err != nil {
+		return err
+	}
+	names := make(map[string]string)
+	for rows.Next() {
+		var id, name string
+		if err = rows.Scan(&id, &name); err != nil {
+			rows.Close()
+			return err
+		}
+		names[id] = name
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	// the rows have to be read completely before the connection of the transaction can run other statements
+	for id, name := range names {
+		if _, err = tx.ExecContext(ctx, `UPDATE company SET normalized_name = $1 WHERE id = $2`, model.NormalizeName(name), id); err != nil {
+			return err
+		}
+	}
+	log.Infof("normalized the names of %v companies", len(names))
+	return nil
+}
@@ -0,0 +1,51 @@
+//go:build integration
+// +build integration
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/ngereci/xm_interview/env"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNormalizePostgresCompanyNames_KeepLegalForms(t *testing.T) {
+	viper.SetConfigFile("../config/config_test.env")
+	require.NoError(t, viper.ReadInConfig())
+	viper.AutomaticEnv()
+	database, err := sql.Open("pgx", viper.GetString(env.COMPANY_POSTGRES_DSN))
+	require.NoError(t, err)
+	defer database.Close()
+	model.SetStripLegalForms(false)
+	t.Cleanup(func() { model.SetStripLegalForms(true) })
+
+	ctx := context.Background()
+	tx, err := database.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	// the temporary table hides the company table from migration 5 until the transaction ends
+	_, err = tx.ExecContext(ctx, `CREATE TEMPORARY TABLE company (id text PRIMARY KEY, name text, normalized_name text) ON COMMIT DROP`)
+	require.NoError(t, err)
+	_, err = tx.ExecContext(ctx, `INSERT INTO company (id, name) VALUES ('1', 'Acme Ltd'), ('2', 'ACME  GmbH')`)
+	require.NoError(t, err)
+
+	require.NoError(t, normalizePostgresCompanyNames(ctx, tx))
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, normalized_name FROM company`)
+	require.NoError(t, err)
+	normalized := make(map[string]string)
+	for rows.Next() {
+		var id, name string
+		require.NoError(t, rows.Scan(&id, &name))
+		normalized[id] = name
+	}
+	require.NoError(t, rows.Err())
+	// the legal forms tell the companies apart, as they do when the server stores them
+	assert.Equal(t, map[string]string{"1": "acme ltd", "2": "acme gmbh"}, normalized)
+}
@@ -4,10 +4,85 @@ const (
 	COMPANY_SERVER_PORT          = "COMPANY_SERVER_PORT"
 	COMPANY_SERVER_READ_TIMEOUT  = "COMPANY_SERVER_READ_TIMEOUT"
 	COMPANY_SERVER_WRITE_TIMEOUT = "COMPANY_SERVER_WRITE_TIMEOUT"
+	COMPANY_REQUEST_TIMEOUT      = "COMPANY_REQUEST_TIMEOUT"
 	COMPANY_CASSANDRA_HOST       = "COMPANY_CASSANDRA_HOST"
 	COMPANY_CASSANDRA_KEYSPACE   = "COMPANY_CASSANDRA_KEYSPACE"
 	COMPANY_JWT_SECRET_KEY       = "COMPANY_JWT_SECRET_KEY"
 	COMPANY_JWT_EXPIRE_TIME      = "COMPANY_JWT_EXPIRE_TIME"
 	COMPANY_BROKER_URL           = "COMPANY_BROKER_URL"
 	COMPANY_BROKER_TOPIC         = "COMPANY_BROKER_TOPIC"
+
+	// COMPANY_LOG_LEVEL is the minimum level logged, such as debug, info or warn
+	COMPANY_LOG_LEVEL = "COMPANY_LOG_LEVEL"
+	// COMPANY_LOG_FORMAT selects the log format, json or text
+	COMPANY_LOG_FORMAT = "COMPANY_LOG_FORMAT"
+
+	// COMPANY_RATE_LIMIT_LOGIN is the rate of login requests allowed per client IP and second, 0 disables the limit
+	COMPANY_RATE_LIMIT_LOGIN       = "COMPANY_RATE_LIMIT_LOGIN"
+	COMPANY_RATE_LIMIT_LOGIN_BURST = "COMPANY_RATE_LIMIT_LOGIN_BURST"
+	// COMPANY_RATE_LIMIT_WRITE is the rate of company writes allowed per user and second, 0 disables the limit
+	COMPANY_RATE_LIMIT_WRITE       = "COMPANY_RATE_LIMIT_WRITE"
+	COMPANY_RATE_LIMIT_WRITE_BURST = "COMPANY_RATE_LIMIT_WRITE_BURST"
+	// COMPANY_LOGIN_MAX_FAILURES is the number of consecutive failed logins locking a username out, 0 disables the lockout
+	COMPANY_LOGIN_MAX_FAILURES = "COMPANY_LOGIN_MAX_FAILURES"
+	// COMPANY_LOGIN_LOCKOUT is the first lockout, it doubles with every further lockout up to COMPANY_LOGIN_MAX_LOCKOUT
+	COMPANY_LOGIN_LOCKOUT     = "COMPANY_LOGIN_LOCKOUT"
+	COMPANY_LOGIN_MAX_LOCKOUT = "COMPANY_LOGIN_MAX_LOCKOUT"
+
+	// COMPANY_SERVER_DRAIN_PERIOD is how long the server keeps serving after a shutdown signal
+	COMPANY_SERVER_DRAIN_PERIOD = "COMPANY_SERVER_DRAIN_PERIOD"
+	// COMPANY_SERVER_SHUTDOWN_TIMEOUT bounds the completion of in-flight requests on shutdown
+	COMPANY_SERVER_SHUTDOWN_TIMEOUT = "COMPANY_SERVER_SHUTDOWN_TIMEOUT"
+	// COMPANY_HEALTH_CHECK_TIMEOUT bounds the dependency checks of the readiness endpoint
+	COMPANY_HEALTH_CHECK_TIMEOUT = "COMPANY_HEALTH_CHECK_TIMEOUT"
+	// COMPANY_METRICS_REFRESH_INTERVAL is how often the companies by type gauge is recounted
+	COMPANY_METRICS_REFRESH_INTERVAL = "COMPANY_METRICS_REFRESH_INTERVAL"
+
+	// COMPANY_CASSANDRA_MIGRATE applies pending schema migrations at startup
+	COMPANY_CASSANDRA_MIGRATE                = "COMPANY_CASSANDRA_MIGRATE"
+	COMPANY_CASSANDRA_REPLICATION_FACTOR     = "COMPANY_CASSANDRA_REPLICATION_FACTOR"
+	COMPANY_CASSANDRA_MIGRATION_LOCK_TIMEOUT = "COMPANY_CASSANDRA_MIGRATION_LOCK_TIMEOUT"
+
+	// COMPANY_NAME_STRIP_LEGAL_FORMS strips trailing legal forms like Ltd from company names before they are compared
+	COMPANY_NAME_STRIP_LEGAL_FORMS = "COMPANY_NAME_STRIP_LEGAL_FORMS"
+
+	// COMPANY_STORAGE selects the company storage, cassandra, postgres or embedded
+	COMPANY_STORAGE                 = "COMPANY_STORAGE"
+	COMPANY_POSTGRES_DSN            = "COMPANY_POSTGRES_DSN"
+	COMPANY_POSTGRES_MAX_OPEN_CONNS = "COMPANY_POSTGRES_MAX_OPEN_CONNS"
+	// COMPANY_POSTGRES_MIGRATE applies pending schema migrations at startup
+	COMPANY_POSTGRES_MIGRATE = "COMPANY_POSTGRES_MIGRATE"
+	// COMPANY_EMBEDDED_PATH is the database file of the embedded storage
+	COMPANY_EMBEDDED_PATH = "COMPANY_EMBEDDED_PATH"
+
+	// COMPANY_EVENT_SINK selects where events are sent, kafka or memory
+	COMPANY_EVENT_SINK            = "COMPANY_EVENT_SINK"
+	COMPANY_EVENT_MEMORY_CAPACITY = "COMPANY_EVENT_MEMORY_CAPACITY"
+
+	// COMPANY_TRACING_EXPORTER selects where spans are exported, none, stdout or otlp
+	COMPANY_TRACING_EXPORTER      = "COMPANY_TRACING_EXPORTER"
+	COMPANY_TRACING_SERVICE_NAME  = "COMPANY_TRACING_SERVICE_NAME"
+	COMPANY_TRACING_OTLP_ENDPOINT = "COMPANY_TRACING_OTLP_ENDPOINT"
+	COMPANY_TRACING_OTLP_INSECURE = "COMPANY_TRACING_OTLP_INSECURE"
+	COMPANY_TRACING_SAMPLE_RATIO  = "COMPANY_TRACING_SAMPLE_RATIO"
+
+	// COMPANY_GRPC_PORT is the port of the gRPC API, which is served next to the REST API
+	COMPANY_GRPC_PORT = "COMPANY_GRPC_PORT"
+
+	// COMPANY_STREAM_BUFFER_SIZE is the number of changes kept for streams resuming from a Last-Event-ID
+	COMPANY_STREAM_BUFFER_SIZE = "COMPANY_STREAM_BUFFER_SIZE"
+	// COMPANY_STREAM_HEARTBEAT_INTERVAL is how often idle change streams are kept alive
+	COMPANY_STREAM_HEARTBEAT_INTERVAL = "COMPANY_STREAM_HEARTBEAT_INTERVAL"
+
+	// COMPANY_SEARCH_REBUILD_INTERVAL is how often the search index is rebuilt from the table to pick up the changes
+	// made through other instances, 0 rebuilds it only on startup
+	COMPANY_SEARCH_REBUILD_INTERVAL = "COMPANY_SEARCH_REBUILD_INTERVAL"
+
+	// COMPANY_OPENAPI_VALIDATE_RESPONSES checks responses against the OpenAPI document as well, it is always on in gin test mode
+	COMPANY_OPENAPI_VALIDATE_RESPONSES = "COMPANY_OPENAPI_VALIDATE_RESPONSES"
+
+	COMPANY_SNAPSHOT_TOPIC           = "COMPANY_SNAPSHOT_TOPIC"
+	COMPANY_SNAPSHOT_RATE            = "COMPANY_SNAPSHOT_RATE"
+	COMPANY_SNAPSHOT_PAGE_SIZE       = "COMPANY_SNAPSHOT_PAGE_SIZE"
+	COMPANY_SNAPSHOT_MAX_PER_REQUEST = "COMPANY_SNAPSHOT_MAX_PER_REQUEST"
 )
@@ -13,6 +13,14 @@ const (
 	EVENT_CREATE EventType = "Create"
 	EVENT_UPDATE EventType = "Update"
 	EVENT_DELETE EventType = "Delete"
+	// EVENT_SNAPSHOT carries the full current state of a company and is used
+	// to rebuild downstream consumers, see the snapshot package.
+	EVENT_SNAPSHOT EventType = "Snapshot"
+	// The relationship events carry a model.CompanyRelationship, an update links a company to another parent.
+	// They are sent to the topic of the company events, consumers of companies skip them by their type.
+	EVENT_RELATIONSHIP_CREATE EventType = "RelationshipCreate"
+	EVENT_RELATIONSHIP_UPDATE EventType = "RelationshipUpdate"
+	EVENT_RELATIONSHIP_DELETE EventType = "RelationshipDelete"
 )
 
 type Event struct {
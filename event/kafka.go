@@ -1,18 +1,30 @@
 package event
 
 import (
+	"context"
 	"fmt"
 	"github.com/Shopify/sarama"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/metrics"
+	"github.com/ngereci/xm_interview/tracing"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"time"
 )
 
 type KafkaAdapter interface {
-	SendEvent(event *Event) error
-	SendEventWithPayload(eventType EventType, payload any) error
+	SendEvent(ctx context.Context, event *Event) error
+	SendEventToTopic(ctx context.Context, topic string, event *Event) error
+	SendEventWithPayload(ctx context.Context, eventType EventType, payload any) error
+	// Ping checks that events can be sent, it is used by the readiness check.
+	Ping(ctx context.Context) error
 	Close() error
 }
 
 type kafkaAdapter struct {
+	client   sarama.Client
 	producer sarama.SyncProducer
 	topic    string
 }
@@ -25,45 +37,92 @@ func NewKafkaAdapter(brokers []string, topic string) (KafkaAdapter, error) {
 	config.Producer.Retry.Max = 10
 	config.Producer.Return.Successes = true
 
-	producer, err := sarama.NewSyncProducer(brokers, config)
+	client, err := sarama.NewClient(brokers, config)
 	if err != nil {
+		return nil, fmt.Errorf("failed to create Kafka client: %v", err)
+	}
+	producer, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		client.Close()
 		return nil, fmt.Errorf("failed to create Kafka producer: %v", err)
 	}
 
 	return &kafkaAdapter{
+		client:   client,
 		producer: producer,
 		topic:    topic,
 	}, nil
 }
 
-func (kp *kafkaAdapter) SendEvent(event *Event) error {
+func (kp *kafkaAdapter) SendEvent(ctx context.Context, event *Event) error {
+	return kp.SendEventToTopic(ctx, kp.topic, event)
+}
+
+// SendEventToTopic sends the event to the given topic instead of the adapter's default one.
+// The context is only checked before sending: a send that was started is not abandoned, because the
+// event could still be delivered after the caller gave up on it. It is bounded by the producer timeout instead.
+func (kp *kafkaAdapter) SendEventToTopic(ctx context.Context, topic string, event *Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	ctx, span := otel.Tracer(kafkaInstrumentation).Start(ctx, topic+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystem("kafka"),
+			semconv.MessagingDestinationName(topic),
+			semconv.MessagingOperationPublish,
+		),
+	)
+	defer span.End()
 	message := &sarama.ProducerMessage{
-		Topic: kp.topic,
+		Topic: topic,
 		Value: sarama.StringEncoder(event.String()),
 	}
+	otel.GetTextMapPropagator().Inject(ctx, messageCarrier{message: message})
 
+	start := time.Now()
 	_, _, err := kp.producer.SendMessage(message)
+	metrics.ObserveKafkaSend(topic, start, err)
+	tracing.RecordError(span, err)
 	if err != nil {
-		log.Errorf("event:%v sending error:%v", event, err)
+		logging.FromContext(ctx).Errorf("event:%v topic:%v sending error:%v", event, topic, err)
 		return err
 	}
 
 	return nil
 }
 
-func (kp *kafkaAdapter) SendEventWithPayload(eventType EventType, payload any) error {
+func (kp *kafkaAdapter) SendEventWithPayload(ctx context.Context, eventType EventType, payload any) error {
 	event, err := NewEvent(eventType, payload)
 	if err != nil {
 		return err
 	}
-	err = kp.SendEvent(event)
+	err = kp.SendEvent(ctx, event)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-// Close closes the KafkaAdapter.
+// Ping refreshes the metadata of the topic, which fails when no broker serving it is reachable.
+func (kp *kafkaAdapter) Ping(ctx context.Context) error {
+	result := make(chan error, 1)
+	go func() {
+		result <- kp.client.RefreshMetadata(kp.topic)
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close flushes the pending messages and closes the KafkaAdapter.
 func (kp *kafkaAdapter) Close() error {
-	return kp.producer.Close()
+	if err := kp.producer.Close(); err != nil {
+		kp.client.Close()
+		return err
+	}
+	return kp.client.Close()
 }
@@ -0,0 +1,40 @@
+package event
+
+import (
+	"context"
+)
+
+// Listener is notified of the events sent to the default topic of a KafkaAdapter, see NewNotifyingAdapter.
+type Listener interface {
+	// OnEvent is called after event was sent, it must not block.
+	OnEvent(ctx context.Context, event *Event)
+}
+
+type notifyingAdapter struct {
+	KafkaAdapter
+	listeners []Listener
+}
+
+// NewNotifyingAdapter returns a KafkaAdapter sending events with next and notifying listeners of the events that were
+// sent to the default topic successfully. Events sent to other topics, like snapshots, are not passed on.
+func NewNotifyingAdapter(next KafkaAdapter, listeners ...Listener) KafkaAdapter {
+	return &notifyingAdapter{KafkaAdapter: next, listeners: listeners}
+}
+
+func (n *notifyingAdapter) SendEvent(ctx context.Context, event *Event) error {
+	if err := n.KafkaAdapter.SendEvent(ctx, event); err != nil {
+		return err
+	}
+	for _, listener := range n.listeners {
+		listener.OnEvent(ctx, event)
+	}
+	return nil
+}
+
+func (n *notifyingAdapter) SendEventWithPayload(ctx context.Context, eventType EventType, payload any) error {
+	event, err := NewEvent(eventType, payload)
+	if err != nil {
+		return err
+	}
+	return n.SendEvent(ctx, event)
+}
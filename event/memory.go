@@ -0,0 +1,70 @@
+package event
+
+import (
+	"context"
+	"github.com/ngereci/xm_interview/logging"
+	log "github.com/sirupsen/logrus"
+	"sync"
+)
+
+// Message is an event together with the topic it was sent to.
+type Message struct {
+	Topic string
+	Event *Event
+}
+
+// MemoryAdapter is a KafkaAdapter keeping the last events in memory instead of sending them to a broker.
+// It is used with the embedded storage, so that the service runs without any external dependency.
+type MemoryAdapter struct {
+	mu       sync.Mutex
+	topic    string
+	capacity int
+	messages []Message
+}
+
+// NewMemoryAdapter creates a MemoryAdapter retaining at most capacity messages.
+func NewMemoryAdapter(topic string, capacity int) *MemoryAdapter {
+	log.Infof("creating in-memory event adapter for topic %v", topic)
+	return &MemoryAdapter{topic: topic, capacity: capacity}
+}
+
+func (m *MemoryAdapter) SendEvent(ctx context.Context, event *Event) error {
+	return m.SendEventToTopic(ctx, m.topic, event)
+}
+
+func (m *MemoryAdapter) SendEventToTopic(ctx context.Context, topic string, event *Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Debugf("event:%v sent to in-memory topic:%v", event, topic)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.capacity > 0 && len(m.messages) >= m.capacity {
+		m.messages = m.messages[1:]
+	}
+	m.messages = append(m.messages, Message{Topic: topic, Event: event})
+	return nil
+}
+
+func (m *MemoryAdapter) SendEventWithPayload(ctx context.Context, eventType EventType, payload any) error {
+	event, err := NewEvent(eventType, payload)
+	if err != nil {
+		return err
+	}
+	return m.SendEvent(ctx, event)
+}
+
+// Messages returns the retained messages, oldest first.
+func (m *MemoryAdapter) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
+
+func (m *MemoryAdapter) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (m *MemoryAdapter) Close() error {
+	return nil
+}
@@ -0,0 +1,43 @@
+package event
+
+import (
+	"github.com/Shopify/sarama"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const kafkaInstrumentation = "github.com/ngereci/xm_interview/event"
+
+// messageCarrier carries the trace context of an event in the headers of its Kafka message,
+// so that consumers can continue the trace of the request that produced the event.
+type messageCarrier struct {
+	message *sarama.ProducerMessage
+}
+
+var _ propagation.TextMapCarrier = messageCarrier{}
+
+func (c messageCarrier) Get(key string) string {
+	for _, header := range c.message.Headers {
+		if string(header.Key) == key {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
+
+func (c messageCarrier) Set(key, value string) {
+	for i, header := range c.message.Headers {
+		if string(header.Key) == key {
+			c.message.Headers[i].Value = []byte(value)
+			return
+		}
+	}
+	c.message.Headers = append(c.message.Headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+}
+
+func (c messageCarrier) Keys() []string {
+	keys := make([]string, 0, len(c.message.Headers))
+	for _, header := range c.message.Headers {
+		keys = append(keys, string(header.Key))
+	}
+	return keys
+}
@@ -0,0 +1,44 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"github.com/gocql/gocql"
+	"github.com/ngereci/xm_interview/event"
+)
+
+type checker struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// NewChecker creates a Checker from a check function.
+func NewChecker(name string, check func(ctx context.Context) error) Checker {
+	return &checker{name: name, check: check}
+}
+
+func (c *checker) Name() string {
+	return c.name
+}
+
+func (c *checker) Check(ctx context.Context) error {
+	return c.check(ctx)
+}
+
+// NewCassandraChecker checks Cassandra with a cheap query against the local node.
+func NewCassandraChecker(session *gocql.Session) Checker {
+	return NewChecker("cassandra", func(ctx context.Context) error {
+		var releaseVersion string
+		return session.Query(`SELECT release_version FROM system.local`).WithContext(ctx).Scan(&releaseVersion)
+	})
+}
+
+// NewPostgresChecker checks Postgres with a ping.
+func NewPostgresChecker(db *sql.DB) Checker {
+	return NewChecker("postgres", db.PingContext)
+}
+
+// NewKafkaChecker checks that the broker metadata of the event topic can be fetched.
+func NewKafkaChecker(kafkaProducer event.KafkaAdapter) Checker {
+	return NewChecker("kafka", kafkaProducer.Ping)
+}
@@ -0,0 +1,119 @@
+package health
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Checker checks the availability of a single dependency.
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+// DependencyStatus is the result of a single Checker.
+type DependencyStatus struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the body of the readiness endpoint.
+type Report struct {
+	Status       string                      `json:"status"`
+	ShuttingDown bool                        `json:"shuttingDown,omitempty"`
+	Dependencies map[string]DependencyStatus `json:"dependencies"`
+}
+
+type Controller interface {
+	// Liveness reports that the process is alive, it never checks dependencies.
+	Liveness(ctx *gin.Context)
+	// Readiness reports whether the service can serve requests: all dependencies are up and it is not shutting down.
+	Readiness(ctx *gin.Context)
+	// SetReady marks the service as (not) ready, it is set to false when the shutdown starts.
+	SetReady(ready bool)
+}
+
+type controller struct {
+	checkers []Checker
+	timeout  time.Duration
+	ready    atomic.Bool
+}
+
+// NewController creates a Controller checking the given dependencies, each check is bounded by timeout.
+func NewController(timeout time.Duration, checkers ...Checker) Controller {
+	c := &controller{checkers: checkers, timeout: timeout}
+	c.ready.Store(true)
+	return c
+}
+
+func (c *controller) Liveness(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": StatusUp})
+}
+
+func (c *controller) Readiness(ctx *gin.Context) {
+	report := Report{
+		Status:       StatusUp,
+		ShuttingDown: !c.ready.Load(),
+		Dependencies: c.check(ctx.Request.Context()),
+	}
+	for _, dependency := range report.Dependencies {
+		if dependency.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	if report.ShuttingDown {
+		report.Status = StatusDown
+	}
+	status := http.StatusOK
+	if report.Status != StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	ctx.JSON(status, report)
+}
+
+func (c *controller) SetReady(ready bool) {
+	c.ready.Store(ready)
+}
+
+// check runs all checkers concurrently.
+func (c *controller) check(ctx context.Context) map[string]DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		statuses = make(map[string]DependencyStatus, len(c.checkers))
+	)
+	for _, checker := range c.checkers {
+		wg.Add(1)
+		go func(checker Checker) {
+			defer wg.Done()
+			start := time.Now()
+			err := checker.Check(ctx)
+			status := DependencyStatus{
+				Status:    StatusUp,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				status.Status = StatusDown
+				status.Error = err.Error()
+			}
+			mu.Lock()
+			statuses[checker.Name()] = status
+			mu.Unlock()
+		}(checker)
+	}
+	wg.Wait()
+	return statuses
+}
@@ -0,0 +1,80 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func serve(handler gin.HandlerFunc) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	handler(ctx)
+	return w
+}
+
+func TestController_Liveness(t *testing.T) {
+	controller := NewController(time.Second, NewChecker("broken", func(ctx context.Context) error {
+		return errors.New("unreachable")
+	}))
+
+	w := serve(controller.Liveness)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"up"}`, w.Body.String())
+}
+
+func TestController_Readiness(t *testing.T) {
+	controller := NewController(time.Second,
+		NewChecker("cassandra", func(ctx context.Context) error { return nil }),
+		NewChecker("kafka", func(ctx context.Context) error { return nil }),
+	)
+
+	w := serve(controller.Readiness)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var report Report
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, StatusUp, report.Status)
+	assert.Equal(t, StatusUp, report.Dependencies["cassandra"].Status)
+	assert.Equal(t, StatusUp, report.Dependencies["kafka"].Status)
+}
+
+func TestController_Readiness_DependencyDown(t *testing.T) {
+	controller := NewController(10*time.Millisecond,
+		NewChecker("cassandra", func(ctx context.Context) error { return nil }),
+		NewChecker("kafka", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+	)
+
+	w := serve(controller.Readiness)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var report Report
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, StatusUp, report.Dependencies["cassandra"].Status)
+	assert.Equal(t, DependencyStatus{Status: StatusDown, LatencyMs: report.Dependencies["kafka"].LatencyMs, Error: context.DeadlineExceeded.Error()}, report.Dependencies["kafka"])
+}
+
+func TestController_Readiness_ShuttingDown(t *testing.T) {
+	controller := NewController(time.Second, NewChecker("cassandra", func(ctx context.Context) error { return nil }))
+	controller.SetReady(false)
+
+	w := serve(controller.Readiness)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var report Report
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, StatusDown, report.Status)
+	assert.True(t, report.ShuttingDown)
+}
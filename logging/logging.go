@@ -0,0 +1,74 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Formats selectable with COMPANY_LOG_FORMAT
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Fields attached to the log lines of a request
+const (
+	FieldRequestID = "request_id"
+	FieldUser      = "user"
+	FieldTenant    = "tenant"
+	FieldCompanyID = "company_id"
+	FieldTraceID   = "trace_id"
+)
+
+type fieldsKey struct{}
+
+// Setup configures the standard logrus logger, which every package logs with.
+func Setup(level, format string) error {
+	parsedLevel, err := log.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	log.SetLevel(parsedLevel)
+	switch format {
+	case FormatJSON, "":
+		log.SetFormatter(&log.JSONFormatter{})
+	case FormatText:
+		log.SetFormatter(&log.TextFormatter{FullTimestamp: true})
+	default:
+		return fmt.Errorf("unknown log format %v", format)
+	}
+	return nil
+}
+
+// WithFields returns a copy of ctx whose log lines carry fields in addition to the fields already in ctx.
+func WithFields(ctx context.Context, fields log.Fields) context.Context {
+	merged := make(log.Fields, len(fields))
+	if existing, ok := ctx.Value(fieldsKey{}).(log.Fields); ok {
+		for key, value := range existing {
+			merged[key] = value
+		}
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// WithField returns a copy of ctx whose log lines carry the field key in addition to the fields already in ctx.
+func WithField(ctx context.Context, key string, value any) context.Context {
+	return WithFields(ctx, log.Fields{key: value})
+}
+
+// FromContext returns the logger of ctx, it carries the fields added with WithFields and the trace id of the current span.
+func FromContext(ctx context.Context) *log.Entry {
+	entry := log.NewEntry(log.StandardLogger())
+	if fields, ok := ctx.Value(fieldsKey{}).(log.Fields); ok {
+		entry = entry.WithFields(fields)
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		entry = entry.WithField(FieldTraceID, spanContext.TraceID().String())
+	}
+	return entry
+}
@@ -0,0 +1,37 @@
+package logging
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+	"testing"
+)
+
+func TestWithFields(t *testing.T) {
+	ctx := WithFields(context.Background(), log.Fields{FieldRequestID: "request", FieldUser: "admin"})
+	child := WithFields(ctx, log.Fields{FieldCompanyID: "company", FieldUser: "other"})
+
+	assert.Equal(t, log.Fields{FieldRequestID: "request", FieldUser: "admin"}, FromContext(ctx).Data)
+	assert.Equal(t, log.Fields{FieldRequestID: "request", FieldUser: "other", FieldCompanyID: "company"}, FromContext(child).Data)
+}
+
+func TestFromContext_TraceID(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+
+	assert.Equal(t, log.Fields{FieldTraceID: "4bf92f3577b34da6a3ce929d0e0e4736"}, FromContext(ctx).Data)
+	assert.Empty(t, FromContext(context.Background()).Data)
+}
+
+func TestSetup(t *testing.T) {
+	defer log.SetFormatter(log.StandardLogger().Formatter)
+	defer log.SetLevel(log.GetLevel())
+
+	assert.NoError(t, Setup("debug", FormatJSON))
+	assert.Equal(t, log.DebugLevel, log.GetLevel())
+	assert.IsType(t, &log.JSONFormatter{}, log.StandardLogger().Formatter)
+	assert.Error(t, Setup("verbose", FormatJSON))
+	assert.Error(t, Setup("info", "xml"))
+}
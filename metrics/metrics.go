@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"time"
+)
+
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Number of HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of HTTP requests by method, route and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	CassandraQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cassandra_query_duration_seconds",
+		Help:    "Latency of the Cassandra queries of the company repository by operation.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation"})
+	CassandraQueryErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cassandra_query_errors_total",
+		Help: "Number of failed Cassandra queries of the company repository by operation.",
+	}, []string{"operation"})
+
+	KafkaSendDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kafka_send_duration_seconds",
+		Help:    "Latency of sending events to Kafka by topic.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"topic"})
+	KafkaSendFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_send_failures_total",
+		Help: "Number of events that could not be sent to Kafka by topic.",
+	}, []string{"topic"})
+
+	Rollbacks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "company_rollbacks_total",
+		Help: "Number of changes rolled back because their event could not be sent, by operation and result.",
+	}, []string{"operation", "result"})
+
+	CompaniesByType = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "companies_by_type",
+		Help: "Number of stored companies by type, refreshed periodically.",
+	}, []string{"type"})
+)
+
+// Handler serves the metrics of the default registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveCassandraQuery records the latency of a query started at start and counts it as failed when err is set.
+func ObserveCassandraQuery(operation string, start time.Time, err error) {
+	CassandraQueryDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		CassandraQueryErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+// ObserveKafkaSend records the latency of a send started at start and counts it as failed when err is set.
+func ObserveKafkaSend(topic string, start time.Time, err error) {
+	KafkaSendDuration.WithLabelValues(topic).Observe(time.Since(start).Seconds())
+	if err != nil {
+		KafkaSendFailures.WithLabelValues(topic).Inc()
+	}
+}
+
+// ObserveRollback counts a rollback of operation, err is the error of the rollback itself.
+func ObserveRollback(operation string, err error) {
+	result := ResultSuccess
+	if err != nil {
+		result = ResultFailure
+	}
+	Rollbacks.WithLabelValues(operation, result).Inc()
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"math"
+	"strconv"
+	"time"
+)
+
+// UnaryRateLimit limits the calls of the full method names of methods like RateLimit does requests, calls over the
+// limit fail with ResourceExhausted and a retry-after header. Other methods are not limited.
+func UnaryRateLimit(requestsPerSecond float64, burst int, methods map[string]bool, key ContextKeyFunc) grpc.UnaryServerInterceptor {
+	if requestsPerSecond <= 0 {
+		return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			return handler(ctx, req)
+		}
+	}
+	limiter := newRateLimiter(requestsPerSecond, burst, nil)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !methods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		if delay := limiter.reserve(key(ctx)); delay > 0 {
+			// fails outside of a server call, which only matters to tests
+			_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(int(math.Ceil(delay.Seconds())))))
+			return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// UnaryTimeout bounds the context of every gRPC call to timeout like Timeout does requests, a non positive timeout
+// leaves it unbounded.
+func UnaryTimeout(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if timeout <= 0 {
+			return handler(ctx, req)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
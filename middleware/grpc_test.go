@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+const (
+	readMethod  = "/company.v1.CompanyService/GetCompany"
+	writeMethod = "/company.v1.CompanyService/CreateCompany"
+)
+
+type userKey struct{}
+
+func TestUnaryRateLimit(t *testing.T) {
+	interceptor := UnaryRateLimit(0.5, 1, map[string]bool{writeMethod: true}, func(ctx context.Context) string {
+		return ctx.Value(userKey{}).(string)
+	})
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+	call := func(user string, method string) error {
+		ctx := context.WithValue(context.Background(), userKey{}, user)
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	assert.NoError(t, call("alice", writeMethod))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(call("alice", writeMethod)))
+	// other users have their own bucket and reads are not limited
+	assert.NoError(t, call("bob", writeMethod))
+	assert.NoError(t, call("alice", readMethod))
+}
+
+func TestUnaryRateLimit_Disabled(t *testing.T) {
+	interceptor := UnaryRateLimit(0, 0, map[string]bool{writeMethod: true}, ContextUserKey)
+	for i := 0; i < 10; i++ {
+		_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: writeMethod}, func(ctx context.Context, req any) (any, error) {
+			return nil, nil
+		})
+		assert.NoError(t, err)
+	}
+}
+
+func TestUnaryTimeout(t *testing.T) {
+	_, err := UnaryTimeout(time.Minute)(context.Background(), nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req any) (any, error) {
+		deadline, ok := ctx.Deadline()
+		assert.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+		return nil, nil
+	})
+	assert.NoError(t, err)
+
+	_, err = UnaryTimeout(0)(context.Background(), nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req any) (any, error) {
+		_, ok := ctx.Deadline()
+		assert.False(t, ok)
+		return nil, nil
+	})
+	assert.NoError(t, err)
+}
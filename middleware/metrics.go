@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ngereci/xm_interview/metrics"
+	"strconv"
+	"time"
+)
+
+// unmatchedRoute labels requests that matched no route, so that arbitrary paths do not create new series.
+const unmatchedRoute = "unmatched"
+
+// Metrics records the count and the latency of every request labelled by its route template, not by its path.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ngereci/xm_interview/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Metrics())
+	router.GET("/companies/:id", func(c *gin.Context) {
+		c.Status(http.StatusNotFound)
+	})
+	matched := metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/companies/:id", "404")
+	unmatched := metrics.HTTPRequests.WithLabelValues(http.MethodGet, unmatchedRoute, "404")
+	matchedBefore, unmatchedBefore := testutil.ToFloat64(matched), testutil.ToFloat64(unmatched)
+
+	for _, path := range []string{"/companies/1", "/companies/2", "/unknown"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	}
+
+	assert.Equal(t, matchedBefore+2, testutil.ToFloat64(matched))
+	assert.Equal(t, unmatchedBefore+1, testutil.ToFloat64(unmatched))
+}
@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/ngereci/xm_interview/auth"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc/peer"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// idleLimiterTimeout is how long the limiter of a client is kept after its last request
+const idleLimiterTimeout = 10 * time.Minute
+
+// KeyFunc returns the client a request is counted against
+type KeyFunc func(c *gin.Context) string
+
+// ClientIPKey counts requests per client IP
+func ClientIPKey(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// UserKey counts requests per authenticated user, it falls back to the client IP before authentication.
+func UserKey(c *gin.Context) string {
+	if username, ok := c.Get("username"); ok {
+		if username, ok := username.(string); ok && username != "" {
+			return "user:" + username
+		}
+	}
+	return ClientIPKey(c)
+}
+
+// ContextKeyFunc returns the client a call is counted against by the context of the call, for gRPC calls and
+// GraphQL mutations, which are not limited by a gin handler
+type ContextKeyFunc func(ctx context.Context) string
+
+// ContextUserKey counts calls per authenticated user like UserKey, it falls back to the address of the gRPC peer.
+func ContextUserKey(ctx context.Context) string {
+	if username := auth.Username(ctx); username != "" {
+		return "user:" + username
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return "ip:" + host
+		}
+		return "ip:" + p.Addr.String()
+	}
+	return "ip:"
+}
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+type rateLimiter struct {
+	mu        sync.Mutex
+	limit     rate.Limit
+	burst     int
+	key       KeyFunc
+	clients   map[string]*clientLimiter
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// RateLimit limits every client to requestsPerSecond with bursts of burst requests using a token bucket per key.
+// Requests over the limit are answered with 429 and a Retry-After header, a non positive rate disables the limit.
+func RateLimit(requestsPerSecond float64, burst int, key KeyFunc) gin.HandlerFunc {
+	if requestsPerSecond <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return newRateLimiter(requestsPerSecond, burst, key).handle
+}
+
+// Limiter limits clients like RateLimit where there is no gin handler to limit, like the mutations of a GraphQL request
+type Limiter struct {
+	limiter *rateLimiter
+}
+
+// NewLimiter limits every client to requestsPerSecond with bursts of burst, a non positive rate disables the limit.
+func NewLimiter(requestsPerSecond float64, burst int) *Limiter {
+	if requestsPerSecond <= 0 {
+		return &Limiter{}
+	}
+	return &Limiter{limiter: newRateLimiter(requestsPerSecond, burst, nil)}
+}
+
+// Reserve takes a token of the client key and returns zero, or how long the client has to wait for the next token.
+func (l *Limiter) Reserve(key string) time.Duration {
+	if l == nil || l.limiter == nil {
+		return 0
+	}
+	return l.limiter.reserve(key)
+}
+
+func newRateLimiter(requestsPerSecond float64, burst int, key KeyFunc) *rateLimiter {
+	return &rateLimiter{
+		limit:   rate.Limit(requestsPerSecond),
+		burst:   burst,
+		key:     key,
+		clients: make(map[string]*clientLimiter),
+		now:     time.Now,
+	}
+}
+
+func (l *rateLimiter) handle(c *gin.Context) {
+	if delay := l.reserve(l.key(c)); delay > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+		return
+	}
+	c.Next()
+}
+
+// reserve takes a token of the client and returns zero, or how long the client has to wait for the next token.
+func (l *rateLimiter) reserve(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.sweep(now)
+	client, ok := l.clients[key]
+	if !ok {
+		client = &clientLimiter{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[key] = client
+	}
+	client.lastSeen = now
+
+	reservation := client.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return time.Duration(float64(time.Second) / float64(l.limit))
+	}
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return delay
+	}
+	return 0
+}
+
+// sweep drops the limiters of idle clients, their buckets are full again anyway
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleLimiterTimeout {
+		return
+	}
+	for key, client := range l.clients {
+		if now.Sub(client.lastSeen) >= idleLimiterTimeout {
+			delete(l.clients, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/peer"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Now()
+	limiter := newRateLimiter(0.5, 2, ClientIPKey)
+	limiter.now = func() time.Time { return now }
+	router := gin.New()
+	router.POST("/", limiter.handle, func(c *gin.Context) { c.Status(http.StatusOK) })
+	request := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, request("10.0.0.1:1000").Code)
+	assert.Equal(t, http.StatusOK, request("10.0.0.1:1001").Code)
+	limited := request("10.0.0.1:1002")
+	assert.Equal(t, http.StatusTooManyRequests, limited.Code)
+	assert.Equal(t, "2", limited.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error":"rate limit exceeded"}`, limited.Body.String())
+	// other clients have their own bucket
+	assert.Equal(t, http.StatusOK, request("10.0.0.2:1000").Code)
+
+	// a token is refilled every two seconds
+	now = now.Add(time.Second)
+	assert.Equal(t, "1", request("10.0.0.1:1003").Header().Get("Retry-After"))
+	now = now.Add(time.Second)
+	assert.Equal(t, http.StatusOK, request("10.0.0.1:1004").Code)
+	assert.Equal(t, http.StatusTooManyRequests, request("10.0.0.1:1005").Code)
+}
+
+func TestRateLimit_UserKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if username := c.GetHeader("X-User"); username != "" {
+			c.Set("username", username)
+		}
+	})
+	router.POST("/", RateLimit(1, 1, UserKey), func(c *gin.Context) { c.Status(http.StatusOK) })
+	request := func(username string) int {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("X-User", username)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request("alice"))
+	assert.Equal(t, http.StatusTooManyRequests, request("alice"))
+	// users behind the same address are limited separately
+	assert.Equal(t, http.StatusOK, request("bob"))
+	assert.Equal(t, http.StatusOK, request(""))
+}
+
+func TestRateLimit_Disabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/", RateLimit(0, 0, ClientIPKey), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for i := 0; i < 10; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	limiter := NewLimiter(0.5, 1)
+
+	assert.Zero(t, limiter.Reserve("user:alice"))
+	assert.Greater(t, limiter.Reserve("user:alice"), time.Duration(0))
+	assert.Zero(t, limiter.Reserve("user:bob"))
+	// a non positive rate disables the limit
+	disabled := NewLimiter(0, 0)
+	assert.Zero(t, disabled.Reserve("user:alice"))
+	assert.Zero(t, disabled.Reserve("user:alice"))
+}
+
+func TestContextUserKey(t *testing.T) {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1000}})
+
+	assert.Equal(t, "ip:10.0.0.1", ContextUserKey(ctx))
+}
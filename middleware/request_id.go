@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/logging"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	// maxRequestIDLength bounds the request ids accepted from clients, longer ones are replaced
+	maxRequestIDLength = 128
+)
+
+// RequestID propagates the X-Request-ID of the request, or assigns a new one when it is missing or not acceptable.
+// The id is returned in the response header and attached to every log line written with the request context.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.New().String()
+		}
+		c.Header(RequestIDHeader, requestID)
+		c.Request = c.Request.WithContext(logging.WithFields(c.Request.Context(), log.Fields{logging.FieldRequestID: requestID}))
+		c.Next()
+	}
+}
+
+// validRequestID accepts ids that are safe to log and to echo in a header
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range requestID {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' || r == ':') {
+			return false
+		}
+	}
+	return true
+}
+
+// AccessLog writes a log line per request once it is handled, it replaces the text logger of gin. The query is left
+// out, it can carry credentials like the stream tickets of browsers.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		entry := logging.FromContext(c.Request.Context()).WithFields(log.Fields{
+			"method":     c.Request.Method,
+			"path":       c.Request.URL.Path,
+			"route":      c.FullPath(),
+			"status":     c.Writer.Status(),
+			"latency_ms": time.Since(start).Milliseconds(),
+			"client_ip":  c.ClientIP(),
+		})
+		if len(c.Errors) > 0 {
+			entry = entry.WithField("errors", c.Errors.String())
+		}
+		entry.Info("request handled")
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID())
+	var logged any
+	router.GET("/", func(c *gin.Context) {
+		logged = logging.FromContext(c.Request.Context()).Data[logging.FieldRequestID]
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name      string
+		requestID string
+		propagate bool
+	}{
+		{name: "propagated", requestID: "3f1c2a-request_1.a:b", propagate: true},
+		{name: "missing", requestID: ""},
+		{name: "unsafe", requestID: "id\r\nSet-Cookie: x"},
+		{name: "too long", requestID: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if test.requestID != "" {
+				req.Header.Set(RequestIDHeader, test.requestID)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			requestID := w.Header().Get(RequestIDHeader)
+			if test.propagate {
+				assert.Equal(t, test.requestID, requestID)
+			} else {
+				assert.NotEmpty(t, requestID)
+				assert.NotEqual(t, test.requestID, requestID)
+			}
+			assert.Equal(t, requestID, logged)
+		})
+	}
+}
@@ -3,4 +3,7 @@ set -x
 mockgen -source ../company/company_repository.go -destination mock_company/repository/mock_company_repository.go -package mock_company_repository
 mockgen -source ../company/company_service.go -destination mock_company/service/mock_company_service.go -package mock_company_service
 mockgen -source ../event/kafka.go -destination mock_company/event/mock_kafka.go -package mock_kafka
+mockgen -source ../snapshot/snapshot_service.go -destination mock_snapshot/service/mock_snapshot_service.go -package mock_snapshot_service
+mockgen -source ../apikey/apikey_service.go -destination mock_apikey/service/mock_apikey_service.go -package mock_apikey_service
+mockgen -source ../search/search_service.go -destination mock_search/service/mock_search_service.go -package mock_search_service
 git add .
\ No newline at end of file
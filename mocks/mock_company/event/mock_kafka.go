@@ -5,6 +5,7 @@
 package mock_kafka
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -48,30 +49,58 @@ func (mr *MockKafkaAdapterMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockKafkaAdapter)(nil).Close))
 }
 
+// Ping mocks base method.
+func (m *MockKafkaAdapter) Ping(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockKafkaAdapterMockRecorder) Ping(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockKafkaAdapter)(nil).Ping), ctx)
+}
+
 // SendEvent mocks base method.
-func (m *MockKafkaAdapter) SendEvent(event *event.Event) error {
+func (m *MockKafkaAdapter) SendEvent(ctx context.Context, event *event.Event) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SendEvent", event)
+	ret := m.ctrl.Call(m, "SendEvent", ctx, event)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SendEvent indicates an expected call of SendEvent.
-func (mr *MockKafkaAdapterMockRecorder) SendEvent(event interface{}) *gomock.Call {
+func (mr *MockKafkaAdapterMockRecorder) SendEvent(ctx, event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendEvent", reflect.TypeOf((*MockKafkaAdapter)(nil).SendEvent), ctx, event)
+}
+
+// SendEventToTopic mocks base method.
+func (m *MockKafkaAdapter) SendEventToTopic(ctx context.Context, topic string, event *event.Event) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendEventToTopic", ctx, topic, event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendEventToTopic indicates an expected call of SendEventToTopic.
+func (mr *MockKafkaAdapterMockRecorder) SendEventToTopic(ctx, topic, event interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendEvent", reflect.TypeOf((*MockKafkaAdapter)(nil).SendEvent), event)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendEventToTopic", reflect.TypeOf((*MockKafkaAdapter)(nil).SendEventToTopic), ctx, topic, event)
 }
 
 // SendEventWithPayload mocks base method.
-func (m *MockKafkaAdapter) SendEventWithPayload(eventType event.EventType, payload any) error {
+func (m *MockKafkaAdapter) SendEventWithPayload(ctx context.Context, eventType event.EventType, payload any) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SendEventWithPayload", eventType, payload)
+	ret := m.ctrl.Call(m, "SendEventWithPayload", ctx, eventType, payload)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SendEventWithPayload indicates an expected call of SendEventWithPayload.
-func (mr *MockKafkaAdapterMockRecorder) SendEventWithPayload(eventType, payload interface{}) *gomock.Call {
+func (mr *MockKafkaAdapterMockRecorder) SendEventWithPayload(ctx, eventType, payload interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendEventWithPayload", reflect.TypeOf((*MockKafkaAdapter)(nil).SendEventWithPayload), eventType, payload)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendEventWithPayload", reflect.TypeOf((*MockKafkaAdapter)(nil).SendEventWithPayload), ctx, eventType, payload)
 }
@@ -5,6 +5,7 @@
 package mock_company_repository
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -36,74 +37,166 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 }
 
 // CountByName mocks base method.
-func (m *MockRepository) CountByName(name string) (int, error) {
+func (m *MockRepository) CountByName(ctx context.Context, tenantID, name string) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CountByName", name)
+	ret := m.ctrl.Call(m, "CountByName", ctx, tenantID, name)
 	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CountByName indicates an expected call of CountByName.
-func (mr *MockRepositoryMockRecorder) CountByName(name interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) CountByName(ctx, tenantID, name interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountByName", reflect.TypeOf((*MockRepository)(nil).CountByName), name)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountByName", reflect.TypeOf((*MockRepository)(nil).CountByName), ctx, tenantID, name)
+}
+
+// CountByType mocks base method.
+func (m *MockRepository) CountByType(ctx context.Context, companyType model.CompanyType) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountByType", ctx, companyType)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountByType indicates an expected call of CountByType.
+func (mr *MockRepositoryMockRecorder) CountByType(ctx, companyType interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountByType", reflect.TypeOf((*MockRepository)(nil).CountByType), ctx, companyType)
 }
 
 // Create mocks base method.
-func (m *MockRepository) Create(company *model.Company) error {
+func (m *MockRepository) Create(ctx context.Context, company *model.Company) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", company)
+	ret := m.ctrl.Call(m, "Create", ctx, company)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockRepositoryMockRecorder) Create(company interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) Create(ctx, company interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockRepository)(nil).Create), company)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockRepository)(nil).Create), ctx, company)
 }
 
 // Delete mocks base method.
-func (m *MockRepository) Delete(id uuid.UUID) error {
+func (m *MockRepository) Delete(ctx context.Context, tenantID string, id uuid.UUID) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", id)
+	ret := m.ctrl.Call(m, "Delete", ctx, tenantID, id)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockRepositoryMockRecorder) Delete(id interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) Delete(ctx, tenantID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockRepository)(nil).Delete), ctx, tenantID, id)
+}
+
+// DeleteTag mocks base method.
+func (m *MockRepository) DeleteTag(ctx context.Context, tenantID string, id uuid.UUID, key string) (*model.Company, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTag", ctx, tenantID, id, key)
+	ret0, _ := ret[0].(*model.Company)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteTag indicates an expected call of DeleteTag.
+func (mr *MockRepositoryMockRecorder) DeleteTag(ctx, tenantID, id, key interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockRepository)(nil).Delete), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTag", reflect.TypeOf((*MockRepository)(nil).DeleteTag), ctx, tenantID, id, key)
 }
 
 // GetByID mocks base method.
-func (m *MockRepository) GetByID(id uuid.UUID) (*model.Company, error) {
+func (m *MockRepository) GetByID(ctx context.Context, tenantID string, id uuid.UUID) (*model.Company, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetByID", id)
+	ret := m.ctrl.Call(m, "GetByID", ctx, tenantID, id)
 	ret0, _ := ret[0].(*model.Company)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetByID indicates an expected call of GetByID.
-func (mr *MockRepositoryMockRecorder) GetByID(id interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) GetByID(ctx, tenantID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockRepository)(nil).GetByID), ctx, tenantID, id)
+}
+
+// GetByIDs mocks base method.
+func (m *MockRepository) GetByIDs(ctx context.Context, tenantID string, ids []uuid.UUID) ([]*model.Company, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByIDs", ctx, tenantID, ids)
+	ret0, _ := ret[0].([]*model.Company)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByIDs indicates an expected call of GetByIDs.
+func (mr *MockRepositoryMockRecorder) GetByIDs(ctx, tenantID, ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByIDs", reflect.TypeOf((*MockRepository)(nil).GetByIDs), ctx, tenantID, ids)
+}
+
+// List mocks base method.
+func (m *MockRepository) List(ctx context.Context, pageState []byte, pageSize int) ([]*model.Company, []byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, pageState, pageSize)
+	ret0, _ := ret[0].([]*model.Company)
+	ret1, _ := ret[1].([]byte)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// List indicates an expected call of List.
+func (mr *MockRepositoryMockRecorder) List(ctx, pageState, pageSize interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockRepository)(nil).List), ctx, pageState, pageSize)
+}
+
+// ListByTenant mocks base method.
+func (m *MockRepository) ListByTenant(ctx context.Context, tenantID string, pageState []byte, pageSize int) ([]*model.Company, []byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByTenant", ctx, tenantID, pageState, pageSize)
+	ret0, _ := ret[0].([]*model.Company)
+	ret1, _ := ret[1].([]byte)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListByTenant indicates an expected call of ListByTenant.
+func (mr *MockRepositoryMockRecorder) ListByTenant(ctx, tenantID, pageState, pageSize interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByTenant", reflect.TypeOf((*MockRepository)(nil).ListByTenant), ctx, tenantID, pageState, pageSize)
+}
+
+// SetTag mocks base method.
+func (m *MockRepository) SetTag(ctx context.Context, tenantID string, id uuid.UUID, key, value string) (*model.Company, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTag", ctx, tenantID, id, key, value)
+	ret0, _ := ret[0].(*model.Company)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetTag indicates an expected call of SetTag.
+func (mr *MockRepositoryMockRecorder) SetTag(ctx, tenantID, id, key, value interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockRepository)(nil).GetByID), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTag", reflect.TypeOf((*MockRepository)(nil).SetTag), ctx, tenantID, id, key, value)
 }
 
 // Update mocks base method.
-func (m *MockRepository) Update(company *model.Company) (*model.Company, error) {
+func (m *MockRepository) Update(ctx context.Context, company *model.Company) (*model.Company, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", company)
+	ret := m.ctrl.Call(m, "Update", ctx, company)
 	ret0, _ := ret[0].(*model.Company)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Update indicates an expected call of Update.
-func (mr *MockRepositoryMockRecorder) Update(company interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) Update(ctx, company interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockRepository)(nil).Update), company)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockRepository)(nil).Update), ctx, company)
 }
@@ -5,6 +5,7 @@
 package mock_company_service
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -36,60 +37,210 @@ func (m *MockService) EXPECT() *MockServiceMockRecorder {
 }
 
 // CreateCompany mocks base method.
-func (m *MockService) CreateCompany(newCompany *model.Company) (*model.Company, error) {
+func (m *MockService) CreateCompany(ctx context.Context, newCompany *model.Company) (*model.Company, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateCompany", newCompany)
+	ret := m.ctrl.Call(m, "CreateCompany", ctx, newCompany)
 	ret0, _ := ret[0].(*model.Company)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateCompany indicates an expected call of CreateCompany.
-func (mr *MockServiceMockRecorder) CreateCompany(newCompany interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) CreateCompany(ctx, newCompany interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCompany", reflect.TypeOf((*MockService)(nil).CreateCompany), newCompany)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCompany", reflect.TypeOf((*MockService)(nil).CreateCompany), ctx, newCompany)
 }
 
 // DeleteCompany mocks base method.
-func (m *MockService) DeleteCompany(id uuid.UUID) error {
+func (m *MockService) DeleteCompany(ctx context.Context, id uuid.UUID) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteCompany", id)
+	ret := m.ctrl.Call(m, "DeleteCompany", ctx, id)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteCompany indicates an expected call of DeleteCompany.
-func (mr *MockServiceMockRecorder) DeleteCompany(id interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) DeleteCompany(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCompany", reflect.TypeOf((*MockService)(nil).DeleteCompany), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCompany", reflect.TypeOf((*MockService)(nil).DeleteCompany), ctx, id)
+}
+
+// GetCompaniesByIDs mocks base method.
+func (m *MockService) GetCompaniesByIDs(ctx context.Context, ids []uuid.UUID) ([]*model.Company, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCompaniesByIDs", ctx, ids)
+	ret0, _ := ret[0].([]*model.Company)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCompaniesByIDs indicates an expected call of GetCompaniesByIDs.
+func (mr *MockServiceMockRecorder) GetCompaniesByIDs(ctx, ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCompaniesByIDs", reflect.TypeOf((*MockService)(nil).GetCompaniesByIDs), ctx, ids)
 }
 
 // GetCompanyByID mocks base method.
-func (m *MockService) GetCompanyByID(id uuid.UUID) (*model.Company, error) {
+func (m *MockService) GetCompanyByID(ctx context.Context, id uuid.UUID) (*model.Company, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetCompanyByID", id)
+	ret := m.ctrl.Call(m, "GetCompanyByID", ctx, id)
 	ret0, _ := ret[0].(*model.Company)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetCompanyByID indicates an expected call of GetCompanyByID.
-func (mr *MockServiceMockRecorder) GetCompanyByID(id interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) GetCompanyByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCompanyByID", reflect.TypeOf((*MockService)(nil).GetCompanyByID), ctx, id)
+}
+
+// ListCompanies mocks base method.
+func (m *MockService) ListCompanies(ctx context.Context, pageState []byte, pageSize int) ([]*model.Company, []byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCompanies", ctx, pageState, pageSize)
+	ret0, _ := ret[0].([]*model.Company)
+	ret1, _ := ret[1].([]byte)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListCompanies indicates an expected call of ListCompanies.
+func (mr *MockServiceMockRecorder) ListCompanies(ctx, pageState, pageSize interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCompanies", reflect.TypeOf((*MockService)(nil).ListCompanies), ctx, pageState, pageSize)
+}
+
+// RemoveTag mocks base method.
+func (m *MockService) RemoveTag(ctx context.Context, id uuid.UUID, key string) (*model.Company, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveTag", ctx, id, key)
+	ret0, _ := ret[0].(*model.Company)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveTag indicates an expected call of RemoveTag.
+func (mr *MockServiceMockRecorder) RemoveTag(ctx, id, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveTag", reflect.TypeOf((*MockService)(nil).RemoveTag), ctx, id, key)
+}
+
+// SetTag mocks base method.
+func (m *MockService) SetTag(ctx context.Context, id uuid.UUID, key, value string) (*model.Company, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTag", ctx, id, key, value)
+	ret0, _ := ret[0].(*model.Company)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetTag indicates an expected call of SetTag.
+func (mr *MockServiceMockRecorder) SetTag(ctx, id, key, value interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCompanyByID", reflect.TypeOf((*MockService)(nil).GetCompanyByID), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTag", reflect.TypeOf((*MockService)(nil).SetTag), ctx, id, key, value)
 }
 
 // UpdateCompany mocks base method.
-func (m *MockService) UpdateCompany(id uuid.UUID, forUpdateCompany *model.Company) (*model.Company, error) {
+func (m *MockService) UpdateCompany(ctx context.Context, id uuid.UUID, forUpdateCompany *model.Company) (*model.Company, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateCompany", id, forUpdateCompany)
+	ret := m.ctrl.Call(m, "UpdateCompany", ctx, id, forUpdateCompany)
 	ret0, _ := ret[0].(*model.Company)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // UpdateCompany indicates an expected call of UpdateCompany.
-func (mr *MockServiceMockRecorder) UpdateCompany(id, forUpdateCompany interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) UpdateCompany(ctx, id, forUpdateCompany interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCompany", reflect.TypeOf((*MockService)(nil).UpdateCompany), ctx, id, forUpdateCompany)
+}
+
+// MockTypeChecker is a mock of TypeChecker interface.
+type MockTypeChecker struct {
+	ctrl     *gomock.Controller
+	recorder *MockTypeCheckerMockRecorder
+}
+
+// MockTypeCheckerMockRecorder is the mock recorder for MockTypeChecker.
+type MockTypeCheckerMockRecorder struct {
+	mock *MockTypeChecker
+}
+
+// NewMockTypeChecker creates a new mock instance.
+func NewMockTypeChecker(ctrl *gomock.Controller) *MockTypeChecker {
+	mock := &MockTypeChecker{ctrl: ctrl}
+	mock.recorder = &MockTypeCheckerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTypeChecker) EXPECT() *MockTypeCheckerMockRecorder {
+	return m.recorder
+}
+
+// CheckCompany mocks base method.
+func (m *MockTypeChecker) CheckCompany(ctx context.Context, company, previous *model.Company) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckCompany", ctx, company, previous)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckCompany indicates an expected call of CheckCompany.
+func (mr *MockTypeCheckerMockRecorder) CheckCompany(ctx, company, previous interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckCompany", reflect.TypeOf((*MockTypeChecker)(nil).CheckCompany), ctx, company, previous)
+}
+
+// MockRelationships is a mock of Relationships interface.
+type MockRelationships struct {
+	ctrl     *gomock.Controller
+	recorder *MockRelationshipsMockRecorder
+}
+
+// MockRelationshipsMockRecorder is the mock recorder for MockRelationships.
+type MockRelationshipsMockRecorder struct {
+	mock *MockRelationships
+}
+
+// NewMockRelationships creates a new mock instance.
+func NewMockRelationships(ctrl *gomock.Controller) *MockRelationships {
+	mock := &MockRelationships{ctrl: ctrl}
+	mock.recorder = &MockRelationshipsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRelationships) EXPECT() *MockRelationshipsMockRecorder {
+	return m.recorder
+}
+
+// CheckDelete mocks base method.
+func (m *MockRelationships) CheckDelete(ctx context.Context, company *model.Company) (func(), error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckDelete", ctx, company)
+	ret0, _ := ret[0].(func())
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckDelete indicates an expected call of CheckDelete.
+func (mr *MockRelationshipsMockRecorder) CheckDelete(ctx, company interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckDelete", reflect.TypeOf((*MockRelationships)(nil).CheckDelete), ctx, company)
+}
+
+// RemoveCompany mocks base method.
+func (m *MockRelationships) RemoveCompany(ctx context.Context, company *model.Company) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveCompany", ctx, company)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveCompany indicates an expected call of RemoveCompany.
+func (mr *MockRelationshipsMockRecorder) RemoveCompany(ctx, company interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCompany", reflect.TypeOf((*MockService)(nil).UpdateCompany), id, forUpdateCompany)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveCompany", reflect.TypeOf((*MockRelationships)(nil).RemoveCompany), ctx, company)
 }
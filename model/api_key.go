@@ -0,0 +1,65 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"time"
+)
+
+// Scope is a permission granted to an API key, users logged in with a password have every scope but ScopeOperator.
+type Scope string
+
+const (
+	ScopeCompaniesRead  Scope = "companies:read"
+	ScopeCompaniesWrite Scope = "companies:write"
+	ScopeAdmin          Scope = "admin"
+	// ScopeOperator changes data shared by all tenants, like the company types. Only operator tokens have it, it
+	// is not in Scopes so API keys and tenant users never get it.
+	ScopeOperator Scope = "operator"
+)
+
+// Scopes are all the scopes an API key can be granted
+var Scopes = []Scope{ScopeCompaniesRead, ScopeCompaniesWrite, ScopeAdmin}
+
+// APIKey is a long-lived credential of a service acting for the tenant TenantID. Only the hash of
+// the key is stored, the key itself is returned once when it is created.
+type APIKey struct {
+	ID         uuid.UUID  `json:"id"`
+	TenantID   string     `json:"tenant_id"`
+	Name       string     `json:"name"`
+	Hash       string     `json:"-"`
+	Scopes     []Scope    `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// HasScope reports whether the key was granted scope
+func (k *APIKey) HasScope(scope Scope) bool {
+	for _, granted := range k.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type ErrAPIKeyNotFound struct {
+	Id uuid.UUID
+}
+
+func (e ErrAPIKeyNotFound) Error() string {
+	return fmt.Sprintf("api key %v not found", e.Id)
+}
+
+type ErrUnknownScope struct {
+	Scope Scope
+}
+
+func (e ErrUnknownScope) Error() string {
+	return fmt.Sprintf("unknown scope %v", e.Scope)
+}
+
+// ErrInvalidAPIKey is returned for keys that are malformed, unknown or revoked alike
+var ErrInvalidAPIKey = errors.New("invalid api key")
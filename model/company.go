@@ -5,22 +5,58 @@ import (
 	"github.com/google/uuid"
 )
 
-type CompanyType string
+// AddressType tells what an address of a company is used for
+type AddressType string
 
 const (
-	Corporation        CompanyType = "Corporation"
-	NonProfit          CompanyType = "NonProfit"
-	Cooperative        CompanyType = "Cooperative"
-	SoleProprietorship CompanyType = "SoleProprietorship"
+	AddressRegistered   AddressType = "Registered"
+	AddressHeadquarters AddressType = "Headquarters"
+	AddressBilling      AddressType = "Billing"
+	AddressOperational  AddressType = "Operational"
 )
 
+// Company belongs to the tenant TenantID, which is set from the authenticated user and never from the request.
+// Countries are ISO 3166-1 alpha-2 codes. The validate tags are checked by Validate together with the rules
+// it implements itself.
 type Company struct {
 	ID          uuid.UUID   `json:"id"`
-	Name        string      `json:"name" binding:"required"`
+	TenantID    string      `json:"tenant_id"`
+	Name        string      `json:"name" validate:"required"`
 	Description string      `json:"description,omitempty"`
-	Employees   int         `json:"employees" binding:"required"`
+	Employees   int         `json:"employees"`
 	Registered  bool        `json:"registered"`
-	Type        CompanyType `json:"type" binding:"required"`
+	Type        CompanyType `json:"type" validate:"required"`
+	// RegistrationNumber is the number of the company in the register of its Country of incorporation
+	RegistrationNumber string `json:"registration_number,omitempty" validate:"omitempty,max=64"`
+	Country            string `json:"country,omitempty" validate:"omitempty,iso3166_1_alpha2"`
+	// VATNumber is the VAT identification number including its country prefix, like DE123456789
+	VATNumber string `json:"vat_number,omitempty" validate:"omitempty,min=4,max=16,alphanum,uppercase"`
+	// LEI is the ISO 17442 Legal Entity Identifier
+	LEI       string    `json:"lei,omitempty" validate:"omitempty,len=20,alphanum,uppercase"`
+	Website   string    `json:"website,omitempty" validate:"omitempty,max=2048,http_url"`
+	Addresses []Address `json:"addresses,omitempty" validate:"omitempty,max=10,dive"`
+	Contacts  []Contact `json:"contacts,omitempty" validate:"omitempty,max=20,dive"`
+	// Tags are labels of the tenant like env=prod, they are set on creation and changed one by one afterwards
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// Address is a postal address of a company. The cql tags map it to the address type of Cassandra.
+type Address struct {
+	Type       AddressType `json:"type" cql:"type" validate:"required,oneof=Registered Headquarters Billing Operational"`
+	Street     string      `json:"street" cql:"street" validate:"required,max=200"`
+	City       string      `json:"city" cql:"city" validate:"required,max=100"`
+	PostalCode string      `json:"postal_code,omitempty" cql:"postal_code" validate:"omitempty,max=20"`
+	Region     string      `json:"region,omitempty" cql:"region" validate:"omitempty,max=100"`
+	Country    string      `json:"country" cql:"country" validate:"required,iso3166_1_alpha2"`
+}
+
+// Contact is a person or function to contact at a company, phone numbers are in E.164 format like +4930123456.
+// The cql tags map it to the contact type of Cassandra.
+type Contact struct {
+	Name  string `json:"name" cql:"name" validate:"required,max=200"`
+	Role  string `json:"role,omitempty" cql:"role" validate:"omitempty,max=100"`
+	Email string `json:"email,omitempty" cql:"email" validate:"omitempty,max=254,email"`
+	Phone string `json:"phone,omitempty" cql:"phone" validate:"omitempty,e164"`
 }
 
 type ErrCompanyNotFound struct {
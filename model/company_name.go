@@ -0,0 +1,51 @@
+package model
+
+import (
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"sync/atomic"
+)
+
+// legalForms are the legal forms NormalizeName strips from the end of a name, case folded and without dots.
+// Forms of several words, like "Pty Ltd" or "GmbH & Co. KG", are stripped word by word.
+var legalForms = map[string]bool{
+	"ltd": true, "limited": true, "llc": true, "llp": true, "lp": true, "plc": true, "inc": true, "incorporated": true,
+	"corp": true, "corporation": true, "co": true, "pty": true, "gmbh": true, "ag": true, "kg": true, "sa": true,
+	"sas": true, "sarl": true, "srl": true, "spa": true, "bv": true, "nv": true,
+}
+
+// keepLegalForms is whether NormalizeName keeps trailing legal forms, see SetStripLegalForms
+var keepLegalForms atomic.Bool
+
+// SetStripLegalForms sets whether NormalizeName strips trailing legal forms, it does by default. It is meant to be
+// called once at startup, before repositories are created or migrations run, the normalized names already stored
+// are not updated.
+func SetStripLegalForms(strip bool) {
+	keepLegalForms.Store(!strip)
+}
+
+// NormalizeName returns the canonical form of a company name, two names with the same canonical form name the same
+// company. It applies Unicode NFKC normalization and case folding, collapses whitespace and strips trailing legal
+// forms, so "Acme Ltd", "ACME, Ltd." and " acme  ltd " all normalize to "acme". A name consisting of legal forms only
+// keeps its first word. Legal forms are kept if disabled with SetStripLegalForms, then "Acme Ltd" normalizes to
+// "acme ltd" and is a different company than "Acme".
+func NormalizeName(name string) string {
+	words := strings.Fields(cases.Fold().String(norm.NFKC.String(name)))
+	if keepLegalForms.Load() {
+		return strings.Join(words, " ")
+	}
+	for len(words) > 1 && isLegalForm(words[len(words)-1]) {
+		words = words[:len(words)-1]
+		// the separators between the name and its legal form, as in "Acme, Ltd." or "Acme & Co"
+		for len(words) > 1 && strings.Trim(words[len(words)-1], ",&") == "" {
+			words = words[:len(words)-1]
+		}
+		words[len(words)-1] = strings.TrimRight(words[len(words)-1], ",")
+	}
+	return strings.Join(words, " ")
+}
+
+func isLegalForm(word string) bool {
+	return legalForms[strings.Trim(strings.ReplaceAll(word, ".", ""), ",")]
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "Acme", want: "acme"},
+		{name: "acme ltd ", want: "acme"},
+		{name: "Acme Ltd", want: "acme"},
+		{name: "ACME, Ltd.", want: "acme"},
+		{name: "  Acme \t Widgets\nInc.", want: "acme widgets"},
+		{name: "Acme Pty Ltd", want: "acme"},
+		{name: "Acme GmbH & Co. KG", want: "acme"},
+		{name: "Acme & Co", want: "acme"},
+		{name: "Ltd", want: "ltd"},
+		{name: "Co. Ltd.", want: "co."},
+		{name: "Limited Editions", want: "limited editions"},
+		{name: "Straße", want: "strasse"},
+		{name: "STRASSE", want: "strasse"},
+		{name: "Ａｃｍｅ", want: "acme"},
+		{name: "Café", want: "café"},
+		{name: "Café", want: "café"},
+		{name: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NormalizeName(tt.name))
+		})
+	}
+}
+
+func TestNormalizeName_KeepLegalForms(t *testing.T) {
+	SetStripLegalForms(false)
+	t.Cleanup(func() { SetStripLegalForms(true) })
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "Acme", want: "acme"},
+		{name: "Acme Ltd", want: "acme ltd"},
+		{name: "ACME, Ltd.", want: "acme, ltd."},
+		{name: "  Acme \t Widgets\nInc.", want: "acme widgets inc."},
+		{name: "Acme GmbH & Co. KG", want: "acme gmbh & co. kg"},
+		{name: "Straße", want: "strasse"},
+		{name: "Ａｃｍｅ", want: "acme"},
+		{name: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NormalizeName(tt.name))
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package model
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"time"
+)
+
+// RelationshipType tells how a company belongs to its parent
+type RelationshipType string
+
+const (
+	// Subsidiary is a company of its own controlled by its parent, it stays when the parent is deleted
+	Subsidiary RelationshipType = "Subsidiary"
+	// Branch is a part of its parent without a legal existence of its own, a parent can not be deleted while it has
+	// branches and branches have no subsidiaries or branches themselves
+	Branch RelationshipType = "Branch"
+)
+
+// MaxRelationshipDepth limits the levels ancestors and descendants are traversed to
+const MaxRelationshipDepth = 10
+
+// CompanyRelationship links the company CompanyID to its parent ParentID, both of the tenant TenantID.
+// A company has at most one parent and the relationships of a tenant never form a cycle.
+type CompanyRelationship struct {
+	TenantID  string           `json:"tenant_id"`
+	CompanyID uuid.UUID        `json:"company_id"`
+	ParentID  uuid.UUID        `json:"parent_id"`
+	Type      RelationshipType `json:"type"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// RelatedCompany is a company found by traversing the relationships, Depth is its distance in levels
+// from the company the traversal started at. Relationship links the company to its parent.
+type RelatedCompany struct {
+	Company      *Company             `json:"company"`
+	Relationship *CompanyRelationship `json:"relationship"`
+	Depth        int                  `json:"depth"`
+}
+
+// Validate checks the relationship itself and returns ErrInvalidRelationship listing every invalid field, or nil.
+// Whether the companies exist and their hierarchy allows the relationship is up to the relationship service.
+func (r *CompanyRelationship) Validate() error {
+	var fields []FieldError
+	switch {
+	case r.ParentID == uuid.Nil:
+		fields = append(fields, FieldError{Field: "parent_id", Message: "is required"})
+	case r.ParentID == r.CompanyID:
+		fields = append(fields, FieldError{Field: "parent_id", Message: "must not be the company itself"})
+	}
+	if r.Type != Subsidiary && r.Type != Branch {
+		fields = append(fields, FieldError{Field: "type", Message: fmt.Sprintf("must be one of %v %v", Subsidiary, Branch)})
+	}
+	if len(fields) > 0 {
+		return ErrInvalidRelationship{Fields: fields}
+	}
+	return nil
+}
+
+// ErrRelationshipNotFound is returned when the company has no parent
+type ErrRelationshipNotFound struct {
+	CompanyID uuid.UUID
+}
+
+func (e ErrRelationshipNotFound) Error() string {
+	return fmt.Sprintf("company %v has no parent", e.CompanyID)
+}
+
+// ErrRelationshipCycle is returned when the parent is a descendant of the company, which would make it its own ancestor
+type ErrRelationshipCycle struct {
+	CompanyID uuid.UUID
+	ParentID  uuid.UUID
+}
+
+func (e ErrRelationshipCycle) Error() string {
+	return fmt.Sprintf("company %v is a descendant of company %v and can not be its parent", e.ParentID, e.CompanyID)
+}
+
+// ErrCompanyHasBranches is returned when deleting a company that still has branches, which have to be deleted first
+type ErrCompanyHasBranches struct {
+	Id       uuid.UUID
+	Branches int
+}
+
+func (e ErrCompanyHasBranches) Error() string {
+	return fmt.Sprintf("company %v has %v branches, delete them first", e.Id, e.Branches)
+}
+
+// ErrInvalidRelationship lists every invalid field of a relationship
+type ErrInvalidRelationship struct {
+	Fields []FieldError
+}
+
+func (e ErrInvalidRelationship) Error() string {
+	return "invalid relationship: " + joinFieldErrors(e.Fields)
+}
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCompanyRelationship_Validate(t *testing.T) {
+	companyID := uuid.New()
+	assert.NoError(t, (&CompanyRelationship{CompanyID: companyID, ParentID: uuid.New(), Type: Subsidiary}).Validate())
+	assert.NoError(t, (&CompanyRelationship{CompanyID: companyID, ParentID: uuid.New(), Type: Branch}).Validate())
+
+	var invalid ErrInvalidRelationship
+	require.ErrorAs(t, (&CompanyRelationship{CompanyID: companyID, Type: "Parent"}).Validate(), &invalid)
+	assert.Equal(t, []FieldError{
+		{Field: "parent_id", Message: "is required"},
+		{Field: "type", Message: "must be one of Subsidiary Branch"},
+	}, invalid.Fields)
+
+	require.ErrorAs(t, (&CompanyRelationship{CompanyID: companyID, ParentID: companyID, Type: Branch}).Validate(), &invalid)
+	assert.Equal(t, []FieldError{{Field: "parent_id", Message: "must not be the company itself"}}, invalid.Fields)
+}
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The limits of the tags of a company
+const (
+	MaxTags           = 50
+	MaxTagValueLength = 256
+)
+
+// tagKeyPattern are the keys of tags, lower case so that teams do not end up with env and Env next to each other.
+// Keys are a segment of the path of the tag endpoints, so they can not contain slashes.
+var tagKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._:-]{0,62}$`)
+
+const tagKeyMessage = "must start with a lower case letter or digit followed by up to 62 lower case letters, digits and . _ : -"
+
+type ErrTagNotFound struct {
+	Id  uuid.UUID
+	Key string
+}
+
+func (e ErrTagNotFound) Error() string {
+	return fmt.Sprintf("company %v has no tag %v", e.Id, e.Key)
+}
+
+// ValidTagKey returns whether key is a well-formed tag key
+func ValidTagKey(key string) bool {
+	return tagKeyPattern.MatchString(key)
+}
+
+// ValidateTag checks a single tag like Validate checks the tags of a company, it returns ErrInvalidCompany
+// with the field of the tag if it is invalid
+func ValidateTag(key string, value string) error {
+	if fields := checkTags(map[string]string{key: value}); len(fields) > 0 {
+		return ErrInvalidCompany{Fields: fields}
+	}
+	return nil
+}
+
+// HasTag returns whether the company has the tag key, with the value value unless value is nil
+func (c *Company) HasTag(key string, value *string) bool {
+	tagValue, ok := c.Tags[key]
+	return ok && (value == nil || tagValue == *value)
+}
+
+// checkTags returns the invalid tags as fields named like tags.env, in the order of their keys
+func checkTags(tags map[string]string) []FieldError {
+	var fields []FieldError
+	if len(tags) > MaxTags {
+		fields = append(fields, FieldError{Field: "tags", Message: fmt.Sprintf("must have at most %v entries", MaxTags)})
+	}
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := tags[key]
+		switch {
+		case !ValidTagKey(key):
+			fields = append(fields, FieldError{Field: "tags." + key, Message: "key " + tagKeyMessage})
+		case !utf8.ValidString(value):
+			fields = append(fields, FieldError{Field: "tags." + key, Message: "must be valid UTF-8"})
+		case utf8.RuneCountInString(value) > MaxTagValueLength:
+			fields = append(fields, FieldError{Field: "tags." + key, Message: fmt.Sprintf("must be at most %v characters long", MaxTagValueLength)})
+		case strings.IndexFunc(value, unicode.IsControl) >= 0:
+			fields = append(fields, FieldError{Field: "tags." + key, Message: "must not contain control characters"})
+		}
+	}
+	return fields
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestValidTagKey(t *testing.T) {
+	for _, key := range []string{"env", "a", "cost-center", "team:owner", "k8s.io_name", "1st", strings.Repeat("a", 63)} {
+		assert.True(t, ValidTagKey(key), key)
+	}
+	for _, key := range []string{"", "Env", "-env", "team owner", "team/owner", "env=prod", "über", strings.Repeat("a", 64)} {
+		assert.False(t, ValidTagKey(key), key)
+	}
+}
+
+func TestCompany_HasTag(t *testing.T) {
+	company := &Company{Tags: map[string]string{"env": "prod", "vip": ""}}
+	prod, staging, empty := "prod", "staging", ""
+
+	assert.True(t, company.HasTag("env", nil))
+	assert.True(t, company.HasTag("env", &prod))
+	assert.False(t, company.HasTag("env", &staging))
+	assert.True(t, company.HasTag("vip", &empty))
+	assert.False(t, company.HasTag("team", nil))
+	assert.False(t, (&Company{}).HasTag("env", nil))
+}
+
+func TestErrTagNotFound_Error(t *testing.T) {
+	id := uuid.MustParse("8a1f6e2c-3c4d-4a5b-9c6d-7e8f9a0b1c2d")
+	assert.Equal(t, "company 8a1f6e2c-3c4d-4a5b-9c6d-7e8f9a0b1c2d has no tag env", ErrTagNotFound{Id: id, Key: "env"}.Error())
+}
@@ -0,0 +1,127 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// CompanyType names one of the CompanyTypeDefinitions, which are managed at runtime
+type CompanyType string
+
+// The types every storage starts with, see DefaultCompanyTypes
+const (
+	Corporation        CompanyType = "Corporation"
+	NonProfit          CompanyType = "NonProfit"
+	Cooperative        CompanyType = "Cooperative"
+	SoleProprietorship CompanyType = "SoleProprietorship"
+)
+
+// MaxCompanyTypeDescriptionLength is the limit of the description of a company type
+const MaxCompanyTypeDescriptionLength = 500
+
+// companyTypeName is the format of the name of a company type, like NonProfit
+var companyTypeName = regexp.MustCompile(`^[A-Z][A-Za-z0-9]{0,49}$`)
+
+// CompanyTypeDefinition is a type companies can have together with the rules companies of the type follow.
+// Deprecated types are kept for the companies that have them, but no other company can take them.
+type CompanyTypeDefinition struct {
+	Name        CompanyType `json:"name"`
+	Description string      `json:"description,omitempty"`
+	// RequiresRegistration requires the companies of the type to be registered
+	RequiresRegistration bool `json:"requires_registration"`
+	// RequiresRegistrationNumber requires the companies of the type to have a registration number
+	RequiresRegistrationNumber bool `json:"requires_registration_number"`
+	// MaxEmployees limits the employees of the companies of the type, 0 is no limit
+	MaxEmployees int       `json:"max_employees,omitempty"`
+	Deprecated   bool      `json:"deprecated"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// DefaultCompanyTypes are the definitions the storages are seeded with
+func DefaultCompanyTypes() []CompanyTypeDefinition {
+	return []CompanyTypeDefinition{
+		{Name: Corporation, Description: "A company owned by its shareholders"},
+		{Name: NonProfit, Description: "An organization that does not distribute its profits", RequiresRegistration: true},
+		{Name: Cooperative, Description: "A company owned and run by its members"},
+		{Name: SoleProprietorship, Description: "A business owned and run by a single person"},
+	}
+}
+
+// Validate checks the definition itself and returns ErrInvalidCompanyType listing every invalid field, or nil
+func (d *CompanyTypeDefinition) Validate() error {
+	var fields []FieldError
+	if !ValidCompanyTypeName(d.Name) {
+		fields = append(fields, FieldError{Field: "name", Message: companyTypeNameMessage})
+	}
+	if len([]rune(d.Description)) > MaxCompanyTypeDescriptionLength {
+		fields = append(fields, FieldError{Field: "description",
+			Message: fmt.Sprintf("must be at most %v characters long", MaxCompanyTypeDescriptionLength)})
+	}
+	if d.MaxEmployees < 0 {
+		fields = append(fields, FieldError{Field: "max_employees", Message: "must not be negative"})
+	}
+	if len(fields) > 0 {
+		return ErrInvalidCompanyType{Fields: fields}
+	}
+	return nil
+}
+
+// Check returns the fields of company that break the rules of the type, company has to be of the type
+func (d *CompanyTypeDefinition) Check(company *Company) []FieldError {
+	var fields []FieldError
+	if d.RequiresRegistration && !company.Registered {
+		fields = append(fields, FieldError{Field: "registered", Message: fmt.Sprintf("must be true for type %v", d.Name)})
+	}
+	if d.RequiresRegistrationNumber && strings.TrimSpace(company.RegistrationNumber) == "" {
+		fields = append(fields, FieldError{Field: "registration_number", Message: fmt.Sprintf("is required for type %v", d.Name)})
+	}
+	if d.MaxEmployees > 0 && company.Employees > d.MaxEmployees {
+		fields = append(fields, FieldError{Field: "employees", Message: fmt.Sprintf("must be at most %v for type %v", d.MaxEmployees, d.Name)})
+	}
+	return fields
+}
+
+const companyTypeNameMessage = "must start with an upper case letter followed by up to 49 letters and digits"
+
+// ValidCompanyTypeName tells if name has the format of a company type, whether such a type exists or not
+func ValidCompanyTypeName(name CompanyType) bool {
+	return companyTypeName.MatchString(string(name))
+}
+
+type ErrCompanyTypeNotFound struct {
+	Name CompanyType
+}
+
+func (e ErrCompanyTypeNotFound) Error() string {
+	return fmt.Sprintf("company type %v not found", e.Name)
+}
+
+type ErrCompanyTypeExists struct {
+	Name CompanyType
+}
+
+func (e ErrCompanyTypeExists) Error() string {
+	return fmt.Sprintf("company type %v exists", e.Name)
+}
+
+// ErrCompanyTypeInUse is returned when deleting a type companies still have, which can be deprecated instead
+type ErrCompanyTypeInUse struct {
+	Name      CompanyType
+	Companies int
+}
+
+func (e ErrCompanyTypeInUse) Error() string {
+	return fmt.Sprintf("company type %v is used by %v companies, deprecate it instead", e.Name, e.Companies)
+}
+
+// ErrInvalidCompanyType lists every invalid field of a company type definition
+type ErrInvalidCompanyType struct {
+	Fields []FieldError
+}
+
+func (e ErrInvalidCompanyType) Error() string {
+	return "invalid company type: " + joinFieldErrors(e.Fields)
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestCompanyTypeDefinition_Validate(t *testing.T) {
+	for _, definition := range DefaultCompanyTypes() {
+		assert.NoError(t, definition.Validate(), definition.Name)
+	}
+
+	definition := &CompanyTypeDefinition{Name: "partnership", Description: strings.Repeat("a", MaxCompanyTypeDescriptionLength+1), MaxEmployees: -1}
+	var invalid ErrInvalidCompanyType
+	require.ErrorAs(t, definition.Validate(), &invalid)
+	assert.Equal(t, []string{"name", "description", "max_employees"},
+		[]string{invalid.Fields[0].Field, invalid.Fields[1].Field, invalid.Fields[2].Field})
+}
+
+func TestCompanyTypeDefinition_Check(t *testing.T) {
+	definition := &CompanyTypeDefinition{Name: "Foundation", RequiresRegistration: true, RequiresRegistrationNumber: true, MaxEmployees: 50}
+
+	assert.Empty(t, definition.Check(&Company{Registered: true, RegistrationNumber: "HRB 1234", Employees: 50}))
+	assert.Equal(t, []FieldError{
+		{Field: "registered", Message: "must be true for type Foundation"},
+		{Field: "registration_number", Message: "is required for type Foundation"},
+		{Field: "employees", Message: "must be at most 50 for type Foundation"},
+	}, definition.Check(&Company{RegistrationNumber: " ", Employees: 51}))
+	assert.Empty(t, (&CompanyTypeDefinition{Name: Corporation}).Check(&Company{Employees: MaxEmployees}))
+}
+
+func TestValidCompanyTypeName(t *testing.T) {
+	assert.True(t, ValidCompanyTypeName("NonProfit"))
+	assert.True(t, ValidCompanyTypeName("B2B"))
+	assert.False(t, ValidCompanyTypeName(""))
+	assert.False(t, ValidCompanyTypeName("nonProfit"))
+	assert.False(t, ValidCompanyTypeName("Non Profit"))
+	assert.False(t, ValidCompanyTypeName(CompanyType("A"+strings.Repeat("b", 50))))
+}
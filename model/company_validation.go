@@ -0,0 +1,159 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The limits of the fields of a company
+const (
+	MaxNameLength        = 100
+	MaxDescriptionLength = 3000
+	MaxEmployees         = 10_000_000
+)
+
+// nameSymbols are the characters besides letters, digits and spaces a company name may contain
+const nameSymbols = "&'’.,-()/+!@:"
+
+// FieldError tells why a single field of a company is invalid. Field is the JSON name of the field,
+// elements of lists are indexed like addresses[0].country.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ErrInvalidCompany lists every invalid field of a company
+type ErrInvalidCompany struct {
+	Fields []FieldError
+}
+
+func (e ErrInvalidCompany) Error() string {
+	return "invalid company: " + joinFieldErrors(e.Fields)
+}
+
+func joinFieldErrors(fields []FieldError) string {
+	messages := make([]string, 0, len(fields))
+	for _, field := range fields {
+		messages = append(messages, field.Field+" "+field.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+var companyValidator = newCompanyValidator()
+
+// newCompanyValidator returns a validator of the validate tags which names fields by their JSON names
+func newCompanyValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		return name
+	})
+	return v
+}
+
+// Validate checks a company from any source, be it a request or a message, before it is stored.
+// The type only has to be well-formed, see CompanyTypeDefinition.Check for the rules of the type itself. It returns ErrInvalidCompany listing every invalid field, or nil if the company is valid.
+func (c *Company) Validate() error {
+	var fields []FieldError
+	var tagErrors validator.ValidationErrors
+	if err := companyValidator.Struct(c); errors.As(err, &tagErrors) {
+		for _, tagError := range tagErrors {
+			fields = append(fields, FieldError{Field: fieldPath(tagError), Message: tagMessage(tagError)})
+		}
+	} else if err != nil {
+		return err
+	}
+	invalid := func(field, format string, args ...any) {
+		fields = append(fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	if c.Name != "" {
+		if message := checkName(c.Name); message != "" {
+			invalid("name", message)
+		}
+	}
+	if !utf8.ValidString(c.Description) {
+		invalid("description", "must be valid UTF-8")
+	} else if utf8.RuneCountInString(c.Description) > MaxDescriptionLength {
+		invalid("description", "must be at most %v characters long", MaxDescriptionLength)
+	} else if strings.IndexFunc(c.Description, isControl) >= 0 {
+		invalid("description", "must not contain control characters other than line breaks and tabs")
+	}
+	if c.Employees < 0 {
+		invalid("employees", "must not be negative")
+	} else if c.Employees > MaxEmployees {
+		invalid("employees", "must be at most %v", MaxEmployees)
+	}
+	// whether the type exists and the rules of the type are checked against the CompanyTypeDefinition
+	if c.Type != "" && !ValidCompanyTypeName(c.Type) {
+		invalid("type", companyTypeNameMessage)
+	}
+	fields = append(fields, checkTags(c.Tags)...)
+	if len(fields) > 0 {
+		return ErrInvalidCompany{Fields: fields}
+	}
+	return nil
+}
+
+// checkName returns why name is not a valid company name, or an empty string if it is
+func checkName(name string) string {
+	switch {
+	case !utf8.ValidString(name):
+		return "must be valid UTF-8"
+	case utf8.RuneCountInString(name) > MaxNameLength:
+		return fmt.Sprintf("must be at most %v characters long", MaxNameLength)
+	case strings.IndexFunc(name, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) < 0:
+		return "must contain a letter or digit"
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsMark(r) && !unicode.IsDigit(r) && r != ' ' && !strings.ContainsRune(nameSymbols, r) {
+			return fmt.Sprintf("must only contain letters, digits, spaces and %v", nameSymbols)
+		}
+	}
+	return ""
+}
+
+func isControl(r rune) bool {
+	return unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t'
+}
+
+// fieldPath returns the path of the field below the company, like addresses[0].country
+func fieldPath(tagError validator.FieldError) string {
+	_, path, _ := strings.Cut(tagError.Namespace(), ".")
+	return path
+}
+
+// tagMessage describes the validate tag a field failed
+func tagMessage(tagError validator.FieldError) string {
+	switch tagError.Tag() {
+	case "required":
+		return "is required"
+	case "max":
+		if tagError.Kind() == reflect.Slice {
+			return fmt.Sprintf("must have at most %v entries", tagError.Param())
+		}
+		return fmt.Sprintf("must be at most %v characters long", tagError.Param())
+	case "min":
+		return fmt.Sprintf("must be at least %v characters long", tagError.Param())
+	case "len":
+		return fmt.Sprintf("must be %v characters long", tagError.Param())
+	case "alphanum", "uppercase":
+		return "must only contain upper case letters and digits"
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(tagError.Param(), " ", ", ")
+	case "iso3166_1_alpha2":
+		return "must be an ISO 3166-1 alpha-2 country code"
+	case "http_url":
+		return "must be an http or https URL"
+	case "email":
+		return "must be an email address"
+	case "e164":
+		return "must be a phone number in E.164 format like +4930123456"
+	default:
+		return "must satisfy " + tagError.Tag()
+	}
+}
@@ -0,0 +1,115 @@
+package model
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func validCompany() *Company {
+	return &Company{
+		Name:        "Acme & Sons, Ltd.",
+		Description: "Widgets\nand gadgets",
+		Employees:   100,
+		Type:        Corporation,
+		Country:     "DE",
+		Addresses:   []Address{{Type: AddressRegistered, Street: "Hauptstraße 1", City: "Berlin", Country: "DE"}},
+		Contacts:    []Contact{{Name: "Jane Doe", Email: "jane@example.com", Phone: "+4930123456"}},
+	}
+}
+
+func TestCompany_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(company *Company)
+		want   []FieldError
+	}{
+		{name: "valid", change: func(company *Company) {}},
+		{name: "unicode name", change: func(company *Company) { company.Name = "Société Générale S.A." }},
+		{name: "spaced name", change: func(company *Company) { company.Name = " Acme  Ltd. " }},
+		{name: "missing fields", change: func(company *Company) { *company = Company{} }, want: []FieldError{
+			{Field: "name", Message: "is required"},
+			{Field: "type", Message: "is required"},
+		}},
+		{name: "long name", change: func(company *Company) { company.Name = strings.Repeat("a", MaxNameLength+1) },
+			want: []FieldError{{Field: "name", Message: "must be at most 100 characters long"}}},
+		{name: "symbols only", change: func(company *Company) { company.Name = "&-." },
+			want: []FieldError{{Field: "name", Message: "must contain a letter or digit"}}},
+		{name: "control character in name", change: func(company *Company) { company.Name = "Acme\nLtd" },
+			want: []FieldError{{Field: "name", Message: "must only contain letters, digits, spaces and " + nameSymbols}}},
+		{name: "markup in name", change: func(company *Company) { company.Name = "<b>Acme</b>" },
+			want: []FieldError{{Field: "name", Message: "must only contain letters, digits, spaces and " + nameSymbols}}},
+		{name: "invalid UTF-8", change: func(company *Company) { company.Name = "Acme\xff" },
+			want: []FieldError{{Field: "name", Message: "must be valid UTF-8"}}},
+		{name: "long description", change: func(company *Company) { company.Description = strings.Repeat("ä", MaxDescriptionLength+1) },
+			want: []FieldError{{Field: "description", Message: "must be at most 3000 characters long"}}},
+		{name: "control character in description", change: func(company *Company) { company.Description = "Widgets\x00" },
+			want: []FieldError{{Field: "description", Message: "must not contain control characters other than line breaks and tabs"}}},
+		{name: "no employees", change: func(company *Company) { company.Employees = 0 }},
+		{name: "negative employees", change: func(company *Company) { company.Employees = -1 },
+			want: []FieldError{{Field: "employees", Message: "must not be negative"}}},
+		{name: "too many employees", change: func(company *Company) { company.Employees = MaxEmployees + 1 },
+			want: []FieldError{{Field: "employees", Message: "must be at most 10000000"}}},
+		{name: "any well-formed type", change: func(company *Company) { company.Type = "Partnership" }},
+		{name: "malformed type", change: func(company *Company) { company.Type = "non-profit" },
+			want: []FieldError{{Field: "type", Message: "must start with an upper case letter followed by up to 49 letters and digits"}}},
+		{name: "invalid details", change: func(company *Company) {
+			company.LEI = "5299000j2n45ddne4y28"
+			company.Addresses[0].Country = "Germany"
+			company.Contacts[0].Phone = "030 123456"
+		}, want: []FieldError{
+			{Field: "lei", Message: "must only contain upper case letters and digits"},
+			{Field: "addresses[0].country", Message: "must be an ISO 3166-1 alpha-2 country code"},
+			{Field: "contacts[0].phone", Message: "must be a phone number in E.164 format like +4930123456"},
+		}},
+		{name: "tags", change: func(company *Company) {
+			company.Tags = map[string]string{"env": "prod", "team:owner": "", "cost-center": "4711"}
+		}},
+		{name: "invalid tags", change: func(company *Company) {
+			company.Tags = map[string]string{"Env": "prod", "note": "a\tb", "env": strings.Repeat("x", MaxTagValueLength+1)}
+		}, want: []FieldError{
+			{Field: "tags.Env", Message: "key " + tagKeyMessage},
+			{Field: "tags.env", Message: "must be at most 256 characters long"},
+			{Field: "tags.note", Message: "must not contain control characters"},
+		}},
+		{name: "too many tags", change: func(company *Company) {
+			company.Tags = map[string]string{}
+			for i := 0; i <= MaxTags; i++ {
+				company.Tags[fmt.Sprintf("tag-%v", i)] = "x"
+			}
+		}, want: []FieldError{{Field: "tags", Message: "must have at most 50 entries"}}},
+		{name: "several fields", change: func(company *Company) {
+			company.Employees = -5
+			company.Type = "Non Profit"
+			company.Website = "ftp://example.com"
+		}, want: []FieldError{
+			{Field: "website", Message: "must be an http or https URL"},
+			{Field: "employees", Message: "must not be negative"},
+			{Field: "type", Message: "must start with an upper case letter followed by up to 49 letters and digits"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			company := validCompany()
+			tt.change(company)
+
+			err := company.Validate()
+
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var invalid ErrInvalidCompany
+			require.ErrorAs(t, err, &invalid)
+			assert.Equal(t, tt.want, invalid.Fields)
+		})
+	}
+}
+
+func TestErrInvalidCompany_Error(t *testing.T) {
+	err := ErrInvalidCompany{Fields: []FieldError{{Field: "name", Message: "is required"}, {Field: "employees", Message: "must not be negative"}}}
+
+	assert.Equal(t, "invalid company: name is required; employees must not be negative", err.Error())
+}
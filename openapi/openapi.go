@@ -0,0 +1,83 @@
+// Package openapi serves the OpenAPI 3 description of the API in openapi.yaml and validates requests
+// and responses against it. The document is the reference for clients, a route missing from it is a bug.
+package openapi
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// SpecPath and DocsPath are the routes of the document and of its Swagger UI
+const (
+	SpecPath = "/openapi.json"
+	DocsPath = "/docs"
+)
+
+//go:embed openapi.yaml
+var spec []byte
+
+// docsPage renders the document with the Swagger UI served by a CDN, which keeps it out of the binary
+var docsPage = []byte(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Companies API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+<script>
+  window.onload = () => {
+    window.ui = SwaggerUIBundle({url: "` + SpecPath + `", dom_id: "#swagger-ui"});
+  };
+</script>
+</body>
+</html>`)
+
+func init() {
+	openapi3.DefineStringFormat("uuid", openapi3.FormatOfStringForUUIDOfRFC4122)
+}
+
+// Load parses and validates the embedded document.
+func Load() (*openapi3.T, error) {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(spec)
+	if err != nil {
+		return nil, fmt.Errorf("error loading OpenAPI document: %w", err)
+	}
+	if err = doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	return doc, nil
+}
+
+type Controller interface {
+	Spec(ctx *gin.Context)
+	Docs(ctx *gin.Context)
+}
+
+type controller struct {
+	specJSON []byte
+}
+
+// NewController serves doc, which is rendered to JSON once.
+func NewController(doc *openapi3.T) (Controller, error) {
+	specJSON, err := doc.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("error rendering OpenAPI document: %w", err)
+	}
+	return &controller{specJSON: specJSON}, nil
+}
+
+func (c *controller) Spec(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, gin.MIMEJSON, c.specJSON)
+}
+
+func (c *controller) Docs(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, gin.MIMEHTML, docsPage)
+}
@@ -0,0 +1,34 @@
+package openapi
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	doc, err := Load()
+	require.NoError(t, err)
+	assert.NotNil(t, doc.Paths.Find("/api/v1/companies/{id}"))
+}
+
+func TestController(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	doc, err := Load()
+	require.NoError(t, err)
+	controller, err := NewController(doc)
+	require.NoError(t, err)
+	router := gin.New()
+	router.GET(SpecPath, controller.Spec)
+	router.GET(DocsPath, controller.Docs)
+
+	w := serve(router, "GET", SpecPath, "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"openapi":"3.0.3"`)
+
+	w = serve(router, "GET", DocsPath, "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `url: "/openapi.json"`)
+}
@@ -0,0 +1,190 @@
+package openapi
+
+import (
+	"bytes"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+	"github.com/ngereci/xm_interview/logging"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const mimeEventStream = "text/event-stream"
+
+// Validate rejects requests of documented routes that do not match doc with 400. Routes missing from doc are passed on
+// unchecked. With validateResponses the responses of documented routes are buffered and checked as well, a response
+// that does not match doc, including an undocumented status, is replaced by a 500. That is meant for tests and
+// staging, where it catches handlers drifting from the document.
+//
+// Authentication is left to the auth middleware, requests without a Content-Type are taken as JSON like the handlers do.
+// Streaming responses, which the document describes as text/event-stream or as a 101 switch to WebSocket, are never buffered.
+func Validate(doc *openapi3.T, validateResponses bool) gin.HandlerFunc {
+	options := &openapi3filter.Options{
+		AuthenticationFunc:    openapi3filter.NoopAuthenticationFunc,
+		SkipSettingDefaults:   true,
+		IncludeResponseStatus: true,
+	}
+	options.WithCustomSchemaErrorFunc(schemaError)
+	return func(c *gin.Context) {
+		route := findRoute(doc, c)
+		if route == nil {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength != 0 && c.GetHeader("Content-Type") == "" {
+			c.Request.Header.Set("Content-Type", gin.MIMEJSON)
+		}
+		pathParams := make(map[string]string, len(c.Params))
+		for _, param := range c.Params {
+			pathParams[param.Key] = param.Value
+		}
+		requestInput := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    options,
+		}
+		if err := openapi3filter.ValidateRequest(c.Request.Context(), requestInput); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !validateResponses || streams(route.Operation) {
+			c.Next()
+			return
+		}
+
+		writer := &bufferedWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if isJSON(writer.Header().Get("Content-Type")) {
+			responseInput := &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: requestInput,
+				Status:                 writer.status,
+				Header:                 writer.Header(),
+				Options:                options,
+			}
+			if err := openapi3filter.ValidateResponse(c.Request.Context(), responseInput.SetBodyBytes(writer.body.Bytes())); err != nil {
+				logging.FromContext(c.Request.Context()).Errorf("Response does not match the OpenAPI document: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "response does not match the OpenAPI document: " + err.Error()})
+				return
+			}
+		}
+		writer.flush()
+	}
+}
+
+// findRoute returns the operation of the gin route of c, whose :name parameters are {name} in doc
+func findRoute(doc *openapi3.T, c *gin.Context) *routers.Route {
+	fullPath := c.FullPath()
+	if fullPath == "" {
+		return nil
+	}
+	segments := strings.Split(fullPath, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	path := strings.Join(segments, "/")
+	pathItem := doc.Paths.Find(path)
+	if pathItem == nil {
+		return nil
+	}
+	operation := pathItem.GetOperation(c.Request.Method)
+	if operation == nil {
+		return nil
+	}
+	return &routers.Route{
+		Spec:      doc,
+		Path:      path,
+		PathItem:  pathItem,
+		Method:    c.Request.Method,
+		Operation: operation,
+	}
+}
+
+// schemaError leaves the schema and the value out of the message, which can be long and echo secrets such as passwords
+func schemaError(err *openapi3.SchemaError) string {
+	if pointer := err.JSONPointer(); len(pointer) > 0 {
+		return "field " + strings.Join(pointer, ".") + ": " + err.Reason
+	}
+	return err.Reason
+}
+
+// streams reports whether the responses of operation are streamed
+func streams(operation *openapi3.Operation) bool {
+	if operation.Responses.Get(http.StatusSwitchingProtocols) != nil {
+		return true
+	}
+	for _, response := range operation.Responses {
+		if response.Value != nil && response.Value.Content.Get(mimeEventStream) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == gin.MIMEJSON
+}
+
+// bufferedWriter holds back the response until it is validated
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status  int
+	written bool
+	body    bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Flush() {}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.written
+}
+
+// flush writes the held back response
+func (w *bufferedWriter) flush() {
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.body.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.body.Bytes())
+	} else {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
@@ -0,0 +1,110 @@
+package openapi
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const validCompany = `{"id":"0b4d5a3e-2b5c-4a43-9a3a-3f3c1f6a4b21","tenant_id":"default","name":"Acme","employees":10,"registered":true,"type":"Corporation"}`
+
+func newTestRouter(t *testing.T, validateResponses bool, status int, response string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	doc, err := Load()
+	require.NoError(t, err)
+	router := gin.New()
+	router.Use(Validate(doc, validateResponses))
+	handler := func(c *gin.Context) {
+		c.Data(status, gin.MIMEJSON, []byte(response))
+	}
+	router.POST("/api/v1/companies", handler)
+	router.GET("/api/v1/companies/:id", handler)
+	router.GET("/undocumented", handler)
+	return router
+}
+
+func serve(router *gin.Engine, method, path, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestValidate_Requests(t *testing.T) {
+	router := newTestRouter(t, false, http.StatusCreated, validCompany)
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+	}{
+		{name: "valid", contentType: gin.MIMEJSON, body: `{"name":"Acme","employees":10,"type":"Corporation"}`, status: http.StatusCreated},
+		{name: "valid without content type", body: `{"name":"Acme","employees":10,"type":"Corporation"}`, status: http.StatusCreated},
+		{name: "malformed type", contentType: gin.MIMEJSON, body: `{"name":"Acme","employees":10,"type":"non-profit"}`, status: http.StatusBadRequest},
+		{name: "no employees", contentType: gin.MIMEJSON, body: `{"name":"Acme","employees":0,"type":"Corporation"}`, status: http.StatusCreated},
+		{name: "missing employees", contentType: gin.MIMEJSON, body: `{"name":"Acme","type":"Corporation"}`, status: http.StatusBadRequest},
+		{name: "empty body", contentType: gin.MIMEJSON, status: http.StatusBadRequest},
+		{name: "not JSON", contentType: "text/plain", body: "Acme", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(router, "POST", "/api/v1/companies", tt.contentType, tt.body)
+
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestValidate_ErrorLeavesValueOut(t *testing.T) {
+	router := newTestRouter(t, false, http.StatusCreated, validCompany)
+
+	w := serve(router, "POST", "/api/v1/companies", gin.MIMEJSON, `{"name":"Acme","employees":"secret","type":"Corporation"}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "field employees")
+	assert.NotContains(t, w.Body.String(), "secret")
+}
+
+func TestValidate_UndocumentedRoute(t *testing.T) {
+	router := newTestRouter(t, true, http.StatusTeapot, `{"anything":1}`)
+
+	w := serve(router, "GET", "/undocumented", "", "")
+
+	assert.Equal(t, http.StatusTeapot, w.Code)
+	assert.Equal(t, `{"anything":1}`, w.Body.String())
+}
+
+func TestValidate_Responses(t *testing.T) {
+	tests := []struct {
+		name              string
+		validateResponses bool
+		status            int
+		response          string
+		expectedStatus    int
+	}{
+		{name: "valid", validateResponses: true, status: http.StatusOK, response: validCompany, expectedStatus: http.StatusOK},
+		{name: "valid error", validateResponses: true, status: http.StatusNotFound, response: `{"error":"company not found"}`, expectedStatus: http.StatusNotFound},
+		{name: "missing field", validateResponses: true, status: http.StatusOK, response: `{"id":"0b4d5a3e-2b5c-4a43-9a3a-3f3c1f6a4b21"}`, expectedStatus: http.StatusInternalServerError},
+		{name: "undocumented status", validateResponses: true, status: http.StatusConflict, response: `{"error":"exists"}`, expectedStatus: http.StatusInternalServerError},
+		{name: "not validated", validateResponses: false, status: http.StatusOK, response: `{}`, expectedStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newTestRouter(t, tt.validateResponses, tt.status, tt.response)
+
+			w := serve(router, "GET", "/api/v1/companies/0b4d5a3e-2b5c-4a43-9a3a-3f3c1f6a4b21", "", "")
+
+			assert.Equal(t, tt.expectedStatus, w.Code, w.Body.String())
+			if tt.expectedStatus == tt.status {
+				assert.Equal(t, tt.response, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package relationship
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/company"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/model"
+	"net/http"
+)
+
+// defaultDepth traverses the parent or the children of a company only
+const defaultDepth = 1
+
+// TraversalRequest is the query of the ancestors and descendants of a company
+type TraversalRequest struct {
+	Depth *int `form:"depth"`
+}
+
+type Controller interface {
+	SetParent(ctx *gin.Context)
+	RemoveParent(ctx *gin.Context)
+	Ancestors(ctx *gin.Context)
+	Descendants(ctx *gin.Context)
+}
+
+type controller struct {
+	service Service
+}
+
+func NewController(service Service) Controller {
+	return &controller{service: service}
+}
+
+func (c *controller) SetParent(ctx *gin.Context) {
+	companyID, ok := parseID(ctx)
+	if !ok {
+		return
+	}
+	var relationship model.CompanyRelationship
+	if err := ctx.ShouldBindJSON(&relationship); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	stored, err := c.service.SetParent(ctx.Request.Context(), companyID, &relationship)
+	if err != nil {
+		ctx.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, stored)
+}
+
+func (c *controller) RemoveParent(ctx *gin.Context) {
+	companyID, ok := parseID(ctx)
+	if !ok {
+		return
+	}
+	if err := c.service.RemoveParent(ctx.Request.Context(), companyID); err != nil {
+		ctx.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+func (c *controller) Ancestors(ctx *gin.Context) {
+	c.traverse(ctx, c.service.Ancestors)
+}
+
+func (c *controller) Descendants(ctx *gin.Context) {
+	c.traverse(ctx, c.service.Descendants)
+}
+
+// traverse answers the companies traversal finds from the company of the request up to the requested depth
+func (c *controller) traverse(ctx *gin.Context, traversal func(context.Context, uuid.UUID, int) ([]*model.RelatedCompany, error)) {
+	companyID, ok := parseID(ctx)
+	if !ok {
+		return
+	}
+	var request TraversalRequest
+	if err := ctx.ShouldBindQuery(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	depth := defaultDepth
+	if request.Depth != nil {
+		depth = *request.Depth
+	}
+	related, err := traversal(ctx.Request.Context(), companyID, depth)
+	if err != nil {
+		ctx.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+	if related == nil {
+		related = []*model.RelatedCompany{}
+	}
+	ctx.JSON(http.StatusOK, related)
+}
+
+func parseID(ctx *gin.Context) (uuid.UUID, bool) {
+	id := ctx.Param("id")
+	companyID, err := uuid.Parse(id)
+	if err != nil {
+		logging.FromContext(ctx.Request.Context()).Warnf("id:%v UUID parse error:%v", id, err)
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return uuid.Nil, false
+	}
+	return companyID, true
+}
+
+// errorBody is the response body of an error, invalid relationships list their invalid fields
+func errorBody(err error) gin.H {
+	var invalid model.ErrInvalidRelationship
+	if errors.As(err, &invalid) {
+		return gin.H{"error": err.Error(), "fields": invalid.Fields}
+	}
+	return gin.H{"error": err.Error()}
+}
+
+// errorStatus maps a service error to the response status, a cycle conflicts with the relationships stored before
+func errorStatus(err error) int {
+	var (
+		companyNotFound      model.ErrCompanyNotFound
+		relationshipNotFound model.ErrRelationshipNotFound
+		cycle                model.ErrRelationshipCycle
+		invalid              model.ErrInvalidRelationship
+	)
+	switch {
+	case errors.As(err, &invalid):
+		return http.StatusBadRequest
+	case errors.As(err, &companyNotFound), errors.As(err, &relationshipNotFound):
+		return http.StatusNotFound
+	case errors.As(err, &cycle):
+		return http.StatusConflict
+	case errors.Is(err, context.Canceled):
+		return company.StatusClientClosedRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,75 @@
+package relationship
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serve calls handler with a request of the test tenant with body, id is the path parameter and query the query string
+func serve(handler gin.HandlerFunc, id string, query string, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodPut, "/?"+query, strings.NewReader(body)).WithContext(tenantContext())
+	ctx.Params = gin.Params{{Key: "id", Value: id}}
+	handler(ctx)
+	return w
+}
+
+func TestController_SetParent(t *testing.T) {
+	group := newTestGroup(t)
+	controller := NewController(group.service)
+	parent, child := group.newCompany(t), group.newCompany(t)
+
+	w := serve(controller.SetParent, child.ID.String(), "", `{"parent_id":"`+parent.ID.String()+`","type":"Subsidiary"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"tenant_id":"tenant-a","company_id":"`+child.ID.String()+`","parent_id":"`+parent.ID.String()+`",`+
+		`"type":"Subsidiary","created_at":"2024-03-01T12:00:00Z"}`, w.Body.String())
+
+	w = serve(controller.SetParent, parent.ID.String(), "", `{"parent_id":"`+child.ID.String()+`","type":"Subsidiary"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = serve(controller.SetParent, child.ID.String(), "", `{"parent_id":"`+parent.ID.String()+`","type":"Parent"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"fields":[{"field":"type"`)
+	assert.Equal(t, http.StatusBadRequest, serve(controller.SetParent, child.ID.String(), "", `{"parent_id":`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, serve(controller.SetParent, "acme", "", `{}`).Code)
+}
+
+func TestController_RemoveParent(t *testing.T) {
+	group := newTestGroup(t)
+	controller := NewController(group.service)
+	parent, child := group.newCompany(t), group.newCompany(t)
+	group.link(t, child, parent, model.Branch)
+
+	assert.Equal(t, http.StatusOK, serve(controller.RemoveParent, child.ID.String(), "", "").Code)
+	w := serve(controller.RemoveParent, child.ID.String(), "", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"error":"company `+child.ID.String()+` has no parent"}`, w.Body.String())
+}
+
+func TestController_Traversal(t *testing.T) {
+	group := newTestGroup(t)
+	controller := NewController(group.service)
+	top, middle, bottom := group.newCompany(t), group.newCompany(t), group.newCompany(t)
+	group.link(t, middle, top, model.Subsidiary)
+	group.link(t, bottom, middle, model.Branch)
+
+	w := serve(controller.Ancestors, bottom.ID.String(), "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"depth":1`)
+	assert.NotContains(t, w.Body.String(), top.ID.String())
+	w = serve(controller.Ancestors, bottom.ID.String(), "depth=2", "")
+	assert.Contains(t, w.Body.String(), `"company":{"id":"`+top.ID.String()+`"`)
+
+	w = serve(controller.Descendants, bottom.ID.String(), "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[]`, w.Body.String())
+	w = serve(controller.Descendants, top.ID.String(), "depth=11", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"fields":[{"field":"depth"`)
+	assert.Equal(t, http.StatusBadRequest, serve(controller.Descendants, top.ID.String(), "depth=all", "").Code)
+}
@@ -0,0 +1,181 @@
+package relationship
+
+import (
+	"bytes"
+	"context"
+	"github.com/gocql/gocql"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/company"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/model"
+	"sort"
+	"time"
+)
+
+const (
+	// lockTTL is how long the Cassandra lock of a hierarchy is kept when its owner does not unlock it, far longer
+	// than a request holds it
+	lockTTL = 30 * time.Second
+	// lockRetryInterval is how often a locked hierarchy is tried again
+	lockRetryInterval = 20 * time.Millisecond
+)
+
+// Repository stores the relationships between the companies of all tenants, every relationship is stored by its company.
+// Whether the companies exist and the relationships form a hierarchy is up to the Service.
+type Repository interface {
+	// Put stores the relationship for its CompanyID, replacing the previous parent of the company
+	Put(ctx context.Context, relationship *model.CompanyRelationship) error
+	// GetParent returns nil without error when the company has no parent
+	GetParent(ctx context.Context, tenantID string, companyID uuid.UUID) (*model.CompanyRelationship, error)
+	// ListChildren returns the relationships of the companies whose parent is parentID, by company id
+	ListChildren(ctx context.Context, tenantID string, parentID uuid.UUID) ([]*model.CompanyRelationship, error)
+	// Delete returns model.ErrRelationshipNotFound when the company has no parent
+	Delete(ctx context.Context, tenantID string, companyID uuid.UUID) error
+	// Lock waits until no one else holds the lock of the hierarchy of the tenant and takes it until unlock is called.
+	// It does not keep anyone from writing, the Service takes it to check and change the hierarchy in one go.
+	Lock(ctx context.Context, tenantID string) (unlock func(), err error)
+}
+
+type relationshipRepository struct {
+	session *gocql.Session
+}
+
+// NewRepository creates a Repository on the company_relationship table of the Cassandra keyspace.
+func NewRepository(session *gocql.Session) Repository {
+	return &relationshipRepository{session: session}
+}
+
+func (r *relationshipRepository) Put(ctx context.Context, relationship *model.CompanyRelationship) error {
+	err := r.session.Query(`
+		INSERT INTO company_relationship (tenant_id, company_id, parent_id, type, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, relationship.TenantID, relationship.CompanyID.String(), relationship.ParentID.String(), relationship.Type,
+		relationship.CreatedAt).WithContext(ctx).Exec()
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company:%v relationship Put error:%v", relationship.CompanyID, err)
+	}
+	return err
+}
+
+func (r *relationshipRepository) GetParent(ctx context.Context, tenantID string, companyID uuid.UUID) (*model.CompanyRelationship, error) {
+	scanner := r.session.Query(`
+		SELECT tenant_id, company_id, parent_id, type, created_at
+		FROM company_relationship
+		WHERE tenant_id = ? AND company_id = ?
+	`, tenantID, companyID.String()).WithContext(ctx).Iter().Scanner()
+	relationships, err := scanRelationships(scanner)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company:%v relationship GetParent error:%v", companyID, err)
+		return nil, err
+	}
+	if len(relationships) == 0 {
+		return nil, nil
+	}
+	return relationships[0], nil
+}
+
+func (r *relationshipRepository) ListChildren(ctx context.Context, tenantID string, parentID uuid.UUID) ([]*model.CompanyRelationship, error) {
+	// the index on parent_id is restricted to the partition of the tenant
+	scanner := r.session.Query(`
+		SELECT tenant_id, company_id, parent_id, type, created_at
+		FROM company_relationship
+		WHERE tenant_id = ? AND parent_id = ?
+	`, tenantID, parentID.String()).WithContext(ctx).Iter().Scanner()
+	relationships, err := scanRelationships(scanner)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company:%v relationship ListChildren error:%v", parentID, err)
+		return nil, err
+	}
+	sortRelationships(relationships)
+	return relationships, nil
+}
+
+func (r *relationshipRepository) Delete(ctx context.Context, tenantID string, companyID uuid.UUID) error {
+	applied, err := r.session.Query(`
+		DELETE FROM company_relationship
+		WHERE tenant_id = ? AND company_id = ?
+		IF EXISTS
+	`, tenantID, companyID.String()).WithContext(ctx).MapScanCAS(map[string]any{})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company:%v relationship Delete error:%v", companyID, err)
+		return err
+	}
+	if !applied {
+		return model.ErrRelationshipNotFound{CompanyID: companyID}
+	}
+	return nil
+}
+
+// Lock inserts the lock row of the tenant with a lightweight transaction, the row expires after lockTTL so that the
+// hierarchy of a crashed owner does not stay locked
+func (r *relationshipRepository) Lock(ctx context.Context, tenantID string) (func(), error) {
+	owner := gocql.TimeUUID()
+	for {
+		applied, err := r.session.Query(`
+			INSERT INTO company_relationship_lock (tenant_id, owner)
+			VALUES (?, ?)
+			IF NOT EXISTS
+			USING TTL ?
+		`, tenantID, owner, int(lockTTL.Seconds())).WithContext(ctx).MapScanCAS(map[string]any{})
+		if err != nil {
+			logging.FromContext(ctx).Errorf("tenant:%v relationship Lock error:%v", tenantID, err)
+			return nil, err
+		}
+		if applied {
+			break
+		}
+		if err = waitForLock(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return func() {
+		// the lock is released even if the request is done
+		err := r.session.Query(`
+			DELETE FROM company_relationship_lock
+			WHERE tenant_id = ?
+			IF owner = ?
+		`, tenantID, owner).WithContext(company.RollbackContext(ctx)).Exec()
+		if err != nil {
+			logging.FromContext(ctx).Errorf("tenant:%v relationship unlock error:%v, the lock expires in %v", tenantID, err, lockTTL)
+		}
+	}, nil
+}
+
+// waitForLock waits lockRetryInterval before a lock is tried again, it returns the error of ctx when it is done first
+func waitForLock(ctx context.Context) error {
+	timer := time.NewTimer(lockRetryInterval)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func scanRelationships(scanner gocql.Scanner) ([]*model.CompanyRelationship, error) {
+	var relationships []*model.CompanyRelationship
+	for scanner.Next() {
+		var (
+			companyID, parentID gocql.UUID
+			relationshipType    string
+			relationship        model.CompanyRelationship
+		)
+		err := scanner.Scan(&relationship.TenantID, &companyID, &parentID, &relationshipType, &relationship.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		relationship.CompanyID = uuid.UUID(companyID)
+		relationship.ParentID = uuid.UUID(parentID)
+		relationship.Type = model.RelationshipType(relationshipType)
+		relationships = append(relationships, &relationship)
+	}
+	return relationships, scanner.Err()
+}
+
+// sortRelationships sorts by the bytes of the company ids, Cassandra orders UUIDs differently
+func sortRelationships(relationships []*model.CompanyRelationship) {
+	sort.Slice(relationships, func(i, j int) bool {
+		return bytes.Compare(relationships[i].CompanyID[:], relationships[j].CompanyID[:]) < 0
+	})
+}
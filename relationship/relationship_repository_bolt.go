@@ -0,0 +1,130 @@
+package relationship
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/model"
+	bolt "go.etcd.io/bbolt"
+	"sync"
+)
+
+var boltRelationshipBucket = []byte("company_relationship")
+
+type boltRelationshipRepository struct {
+	db *bolt.DB
+	// hierarchy is the lock of the hierarchies of all tenants, the database file is used by a single process
+	hierarchy sync.Mutex
+}
+
+// NewBoltRepository creates a Repository on an embedded bbolt database file, for single node and local use.
+func NewBoltRepository(db *bolt.DB) (Repository, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltRelationshipBucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &boltRelationshipRepository{db: db}, nil
+}
+
+func (r *boltRelationshipRepository) Put(ctx context.Context, relationship *model.CompanyRelationship) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	value, err := json.Marshal(relationship)
+	if err != nil {
+		return err
+	}
+	err = r.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltRelationshipBucket).Put(boltRelationshipKey(relationship.TenantID, relationship.CompanyID), value)
+	})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company:%v relationship Put error:%v", relationship.CompanyID, err)
+	}
+	return err
+}
+
+func (r *boltRelationshipRepository) GetParent(ctx context.Context, tenantID string, companyID uuid.UUID) (relationship *model.CompanyRelationship, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	err = r.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(boltRelationshipBucket).Get(boltRelationshipKey(tenantID, companyID))
+		if value == nil {
+			return nil
+		}
+		relationship, err = unmarshalBoltRelationship(value)
+		return err
+	})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company:%v relationship GetParent error:%v", companyID, err)
+		return nil, err
+	}
+	return relationship, nil
+}
+
+func (r *boltRelationshipRepository) ListChildren(ctx context.Context, tenantID string, parentID uuid.UUID) ([]*model.CompanyRelationship, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var relationships []*model.CompanyRelationship
+	// the keys of a tenant share its prefix and are ordered by company id, the children are filtered from them
+	prefix := []byte(tenantID + "/")
+	err := r.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(boltRelationshipBucket).Cursor()
+		for key, value := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, value = cursor.Next() {
+			relationship, err := unmarshalBoltRelationship(value)
+			if err != nil {
+				return err
+			}
+			if relationship.ParentID == parentID {
+				relationships = append(relationships, relationship)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company:%v relationship ListChildren error:%v", parentID, err)
+		return nil, err
+	}
+	return relationships, nil
+}
+
+func (r *boltRelationshipRepository) Delete(ctx context.Context, tenantID string, companyID uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltRelationshipBucket)
+		key := boltRelationshipKey(tenantID, companyID)
+		if bucket.Get(key) == nil {
+			return model.ErrRelationshipNotFound{CompanyID: companyID}
+		}
+		return bucket.Delete(key)
+	})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company:%v relationship Delete error:%v", companyID, err)
+	}
+	return err
+}
+
+func boltRelationshipKey(tenantID string, companyID uuid.UUID) []byte {
+	return []byte(tenantID + "/" + companyID.String())
+}
+
+func unmarshalBoltRelationship(value []byte) (*model.CompanyRelationship, error) {
+	var relationship model.CompanyRelationship
+	if err := json.Unmarshal(value, &relationship); err != nil {
+		return nil, err
+	}
+	return &relationship, nil
+}
+
+func (r *boltRelationshipRepository) Lock(ctx context.Context, tenantID string) (func(), error) {
+	r.hierarchy.Lock()
+	return r.hierarchy.Unlock, nil
+}
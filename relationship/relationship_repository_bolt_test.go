@@ -0,0 +1,24 @@
+package relationship_test
+
+import (
+	"github.com/ngereci/xm_interview/relationship"
+	"github.com/ngereci/xm_interview/relationship/relationshiptest"
+	bolt "go.etcd.io/bbolt"
+	"path/filepath"
+	"testing"
+)
+
+func TestBoltRepository(t *testing.T) {
+	relationshiptest.RunRepositorySuite(t, func(t *testing.T) relationship.Repository {
+		database, err := bolt.Open(filepath.Join(t.TempDir(), "companies.db"), 0o600, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { database.Close() })
+		repo, err := relationship.NewBoltRepository(database)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repo
+	})
+}
@@ -0,0 +1,73 @@
+package relationship
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/model"
+	"sync"
+)
+
+// memoryKey identifies the relationship of a company of a tenant
+type memoryKey struct {
+	tenantID  string
+	companyID uuid.UUID
+}
+
+type memoryRelationshipRepository struct {
+	mu sync.RWMutex
+	// hierarchy is the lock of the hierarchies of all tenants
+	hierarchy     sync.Mutex
+	relationships map[memoryKey]model.CompanyRelationship
+}
+
+// NewMemoryRepository creates a Repository keeping the relationships in memory, for tests and as a reference implementation.
+func NewMemoryRepository() Repository {
+	return &memoryRelationshipRepository{relationships: make(map[memoryKey]model.CompanyRelationship)}
+}
+
+func (r *memoryRelationshipRepository) Put(ctx context.Context, relationship *model.CompanyRelationship) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.relationships[memoryKey{tenantID: relationship.TenantID, companyID: relationship.CompanyID}] = *relationship
+	return nil
+}
+
+func (r *memoryRelationshipRepository) GetParent(ctx context.Context, tenantID string, companyID uuid.UUID) (*model.CompanyRelationship, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	relationship, ok := r.relationships[memoryKey{tenantID: tenantID, companyID: companyID}]
+	if !ok {
+		return nil, nil
+	}
+	return &relationship, nil
+}
+
+func (r *memoryRelationshipRepository) ListChildren(ctx context.Context, tenantID string, parentID uuid.UUID) ([]*model.CompanyRelationship, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var relationships []*model.CompanyRelationship
+	for _, relationship := range r.relationships {
+		if relationship.TenantID == tenantID && relationship.ParentID == parentID {
+			stored := relationship
+			relationships = append(relationships, &stored)
+		}
+	}
+	sortRelationships(relationships)
+	return relationships, nil
+}
+
+func (r *memoryRelationshipRepository) Delete(ctx context.Context, tenantID string, companyID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := memoryKey{tenantID: tenantID, companyID: companyID}
+	if _, ok := r.relationships[key]; !ok {
+		return model.ErrRelationshipNotFound{CompanyID: companyID}
+	}
+	delete(r.relationships, key)
+	return nil
+}
+
+func (r *memoryRelationshipRepository) Lock(ctx context.Context, tenantID string) (func(), error) {
+	r.hierarchy.Lock()
+	return r.hierarchy.Unlock, nil
+}
@@ -0,0 +1,13 @@
+package relationship_test
+
+import (
+	"github.com/ngereci/xm_interview/relationship"
+	"github.com/ngereci/xm_interview/relationship/relationshiptest"
+	"testing"
+)
+
+func TestMemoryRepository(t *testing.T) {
+	relationshiptest.RunRepositorySuite(t, func(t *testing.T) relationship.Repository {
+		return relationship.NewMemoryRepository()
+	})
+}
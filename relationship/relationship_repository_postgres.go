@@ -0,0 +1,137 @@
+package relationship
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/model"
+)
+
+type postgresRelationshipRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository creates a Repository on the company_relationship table of the
+// Postgres database, see db/migrations/postgres for the schema.
+func NewPostgresRepository(db *sql.DB) Repository {
+	return &postgresRelationshipRepository{db: db}
+}
+
+func (r *postgresRelationshipRepository) Put(ctx context.Context, relationship *model.CompanyRelationship) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO company_relationship (tenant_id, company_id, parent_id, type, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (tenant_id, company_id) DO UPDATE
+		SET parent_id = EXCLUDED.parent_id, type = EXCLUDED.type, created_at = EXCLUDED.created_at
+	`, relationship.TenantID, relationship.CompanyID, relationship.ParentID, relationship.Type, relationship.CreatedAt)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company:%v relationship Put error:%v", relationship.CompanyID, err)
+	}
+	return err
+}
+
+func (r *postgresRelationshipRepository) GetParent(ctx context.Context, tenantID string, companyID uuid.UUID) (*model.CompanyRelationship, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT tenant_id, company_id, parent_id, type, created_at
+		FROM company_relationship
+		WHERE tenant_id = $1 AND company_id = $2
+	`, tenantID, companyID)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company:%v relationship GetParent error:%v", companyID, err)
+		return nil, err
+	}
+	relationships, err := scanPostgresRelationships(rows)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company:%v relationship GetParent error:%v", companyID, err)
+		return nil, err
+	}
+	if len(relationships) == 0 {
+		return nil, nil
+	}
+	return relationships[0], nil
+}
+
+func (r *postgresRelationshipRepository) ListChildren(ctx context.Context, tenantID string, parentID uuid.UUID) ([]*model.CompanyRelationship, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT tenant_id, company_id, parent_id, type, created_at
+		FROM company_relationship
+		WHERE tenant_id = $1 AND parent_id = $2
+		ORDER BY company_id
+	`, tenantID, parentID)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company:%v relationship ListChildren error:%v", parentID, err)
+		return nil, err
+	}
+	relationships, err := scanPostgresRelationships(rows)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company:%v relationship ListChildren error:%v", parentID, err)
+		return nil, err
+	}
+	return relationships, nil
+}
+
+func (r *postgresRelationshipRepository) Delete(ctx context.Context, tenantID string, companyID uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM company_relationship
+		WHERE tenant_id = $1 AND company_id = $2
+	`, tenantID, companyID)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("company:%v relationship Delete error:%v", companyID, err)
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return model.ErrRelationshipNotFound{CompanyID: companyID}
+	}
+	return nil
+}
+
+// Lock takes an advisory lock on the name of the hierarchy of the tenant in a transaction, which holds it until unlock
+// ends the transaction. The lock is tried instead of waited for, so that waiting does not take a connection from the
+// holder of the lock.
+func (r *postgresRelationshipRepository) Lock(ctx context.Context, tenantID string) (func(), error) {
+	for {
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("tenant:%v relationship Lock error:%v", tenantID, err)
+			return nil, err
+		}
+		var locked bool
+		err = tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock(hashtext($1))`, "company_relationship/"+tenantID).Scan(&locked)
+		if err != nil {
+			_ = tx.Rollback()
+			logging.FromContext(ctx).Errorf("tenant:%v relationship Lock error:%v", tenantID, err)
+			return nil, err
+		}
+		if locked {
+			return func() { _ = tx.Rollback() }, nil
+		}
+		_ = tx.Rollback()
+		if err = waitForLock(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func scanPostgresRelationships(rows *sql.Rows) ([]*model.CompanyRelationship, error) {
+	defer rows.Close()
+	var relationships []*model.CompanyRelationship
+	for rows.Next() {
+		var (
+			relationshipType string
+			relationship     model.CompanyRelationship
+		)
+		err := rows.Scan(&relationship.TenantID, &relationship.CompanyID, &relationship.ParentID, &relationshipType, &relationship.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		relationship.Type = model.RelationshipType(relationshipType)
+		relationship.CreatedAt = relationship.CreatedAt.UTC()
+		relationships = append(relationships, &relationship)
+	}
+	return relationships, rows.Err()
+}
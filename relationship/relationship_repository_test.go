@@ -0,0 +1,76 @@
+//go:build integration
+// +build integration
+
+package relationship_test
+
+import (
+	"database/sql"
+	"github.com/gocql/gocql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/ngereci/xm_interview/db"
+	"github.com/ngereci/xm_interview/env"
+	"github.com/ngereci/xm_interview/relationship"
+	"github.com/ngereci/xm_interview/relationship/relationshiptest"
+	"github.com/spf13/viper"
+	"testing"
+)
+
+func readTestConfig(t *testing.T) {
+	viper.SetConfigFile("../config/config_test.env")
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	viper.AutomaticEnv()
+}
+
+func TestCassandraRepository(t *testing.T) {
+	readTestConfig(t)
+	cluster := gocql.NewCluster(viper.GetString(env.COMPANY_CASSANDRA_HOST))
+	cluster.Keyspace = viper.GetString(env.COMPANY_CASSANDRA_KEYSPACE)
+	cluster.Consistency = gocql.Quorum
+	if err := db.CreateKeyspace(cluster, viper.GetInt(env.COMPANY_CASSANDRA_REPLICATION_FACTOR)); err != nil {
+		t.Fatal(err)
+	}
+	session, err := cluster.CreateSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+	migrator, err := db.NewCassandraMigrator(session, viper.GetDuration(env.COMPANY_CASSANDRA_MIGRATION_LOCK_TIMEOUT))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = migrator.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	relationshiptest.RunRepositorySuite(t, func(t *testing.T) relationship.Repository {
+		if err := session.Query(`TRUNCATE company_relationship`).Exec(); err != nil {
+			t.Fatal(err)
+		}
+		return relationship.NewRepository(session)
+	})
+}
+
+func TestPostgresRepository(t *testing.T) {
+	readTestConfig(t)
+	database, err := sql.Open("pgx", viper.GetString(env.COMPANY_POSTGRES_DSN))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	migrator, err := db.NewPostgresMigrator(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = migrator.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	relationshiptest.RunRepositorySuite(t, func(t *testing.T) relationship.Repository {
+		if _, err := database.Exec(`TRUNCATE company_relationship`); err != nil {
+			t.Fatal(err)
+		}
+		return relationship.NewPostgresRepository(database)
+	})
+}
@@ -0,0 +1,357 @@
+package relationship
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/company"
+	"github.com/ngereci/xm_interview/event"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/metrics"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/ngereci/xm_interview/tenant"
+	"time"
+)
+
+// Service manages the relationships between the companies of the tenant in the context of each call, see tenant.WithID,
+// and applies their rules to the deletion of companies, see company.Relationships.
+// Every change is sent as a relationship event. The hierarchy of the tenant is locked while it is checked and changed,
+// see Repository.Lock, so that concurrent changes can not form a cycle or add branches to a company being deleted.
+type Service interface {
+	// SetParent links the company to the parent of relationship, replacing the previous parent of the company.
+	// It returns model.ErrRelationshipCycle when the parent is a descendant of the company.
+	SetParent(ctx context.Context, companyID uuid.UUID, relationship *model.CompanyRelationship) (*model.CompanyRelationship, error)
+	// RemoveParent returns model.ErrRelationshipNotFound when the company has no parent
+	RemoveParent(ctx context.Context, companyID uuid.UUID) error
+	// Ancestors returns the parent of the company, the parent of the parent and so on up to depth levels, nearest first
+	Ancestors(ctx context.Context, companyID uuid.UUID, depth int) ([]*model.RelatedCompany, error)
+	// Descendants returns the children of the company, their children and so on up to depth levels, level by level
+	Descendants(ctx context.Context, companyID uuid.UUID, depth int) ([]*model.RelatedCompany, error)
+	company.Relationships
+}
+
+type relationshipService struct {
+	repo          Repository
+	companies     company.Repository
+	kafkaProducer event.KafkaAdapter
+	now           func() time.Time
+}
+
+// NewService creates a Service on repo, the related companies are looked up in companies
+func NewService(repo Repository, companies company.Repository, kafkaProducer event.KafkaAdapter) Service {
+	return &relationshipService{repo: repo, companies: companies, kafkaProducer: kafkaProducer, now: time.Now}
+}
+
+func (s *relationshipService) SetParent(ctx context.Context, companyID uuid.UUID, relationship *model.CompanyRelationship) (*model.CompanyRelationship, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx = logging.WithField(ctx, logging.FieldCompanyID, companyID)
+	relationship.TenantID = tenantID
+	relationship.CompanyID = companyID
+	if err = relationship.Validate(); err != nil {
+		return nil, err
+	}
+	unlock, err := s.repo.Lock(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if err = s.checkCompanies(ctx, relationship); err != nil {
+		return nil, err
+	}
+	if err = s.checkHierarchy(ctx, relationship); err != nil {
+		return nil, err
+	}
+	previous, err := s.repo.GetParent(ctx, tenantID, companyID)
+	if err != nil {
+		return nil, err
+	}
+	if previous != nil && previous.ParentID == relationship.ParentID && previous.Type == relationship.Type {
+		return previous, nil
+	}
+
+	// Cassandra stores timestamps in milliseconds
+	relationship.CreatedAt = s.now().UTC().Truncate(time.Millisecond)
+	if err = s.repo.Put(ctx, relationship); err != nil {
+		return nil, err
+	}
+	eventType := event.EVENT_RELATIONSHIP_CREATE
+	if previous != nil {
+		eventType = event.EVENT_RELATIONSHIP_UPDATE
+	}
+	if kafkaErr := s.kafkaProducer.SendEventWithPayload(ctx, eventType, relationship); kafkaErr != nil {
+		logging.FromContext(ctx).Errorf("company:%v parent set but send event failed, rolling back. error:%v", companyID, kafkaErr)
+		//handle rollback
+		if previous == nil {
+			err = s.repo.Delete(company.RollbackContext(ctx), tenantID, companyID)
+		} else {
+			err = s.repo.Put(company.RollbackContext(ctx), previous)
+		}
+		metrics.ObserveRollback("set_parent", err)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("company:%v rollback failed. error:%v", companyID, err)
+			return nil, err
+		}
+		logging.FromContext(ctx).Infof("company:%v rollback success", companyID)
+		return nil, kafkaErr
+	}
+	logging.FromContext(ctx).Infof("company:%v is a %v of company:%v", companyID, relationship.Type, relationship.ParentID)
+	return relationship, nil
+}
+
+func (s *relationshipService) RemoveParent(ctx context.Context, companyID uuid.UUID) error {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	ctx = logging.WithField(ctx, logging.FieldCompanyID, companyID)
+	// the rollback puts the parent back, which is only checked while no one else changes the hierarchy
+	unlock, err := s.repo.Lock(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	existing, err := s.repo.GetParent(ctx, tenantID, companyID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return model.ErrRelationshipNotFound{CompanyID: companyID}
+	}
+	if err = s.repo.Delete(ctx, tenantID, companyID); err != nil {
+		return err
+	}
+	if kafkaErr := s.kafkaProducer.SendEventWithPayload(ctx, event.EVENT_RELATIONSHIP_DELETE, existing); kafkaErr != nil {
+		logging.FromContext(ctx).Errorf("company:%v parent removed but send event failed, rolling back. error:%v", companyID, kafkaErr)
+		//handle rollback
+		err = s.repo.Put(company.RollbackContext(ctx), existing)
+		metrics.ObserveRollback("remove_parent", err)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("company:%v rollback failed. error:%v", companyID, err)
+			return err
+		}
+		logging.FromContext(ctx).Infof("company:%v rollback success", companyID)
+		return kafkaErr
+	}
+	logging.FromContext(ctx).Infof("company:%v parent company:%v removed", companyID, existing.ParentID)
+	return nil
+}
+
+func (s *relationshipService) Ancestors(ctx context.Context, companyID uuid.UUID, depth int) ([]*model.RelatedCompany, error) {
+	tenantID, err := s.startTraversal(ctx, companyID, depth)
+	if err != nil {
+		return nil, err
+	}
+	var relationships []*model.CompanyRelationship
+	visited := map[uuid.UUID]bool{companyID: true}
+	for id := companyID; len(relationships) < depth; {
+		parent, err := s.repo.GetParent(ctx, tenantID, id)
+		if err != nil {
+			return nil, err
+		}
+		// a cycle can only be left behind by concurrent changes, it ends the traversal
+		if parent == nil || visited[parent.ParentID] {
+			break
+		}
+		visited[parent.ParentID] = true
+		relationships = append(relationships, parent)
+		id = parent.ParentID
+	}
+	related := make([]*model.RelatedCompany, 0, len(relationships))
+	for level, relationship := range relationships {
+		related = append(related, &model.RelatedCompany{Relationship: relationship, Depth: level + 1})
+	}
+	return s.withCompanies(ctx, tenantID, related, func(r *model.RelatedCompany) uuid.UUID { return r.Relationship.ParentID })
+}
+
+func (s *relationshipService) Descendants(ctx context.Context, companyID uuid.UUID, depth int) ([]*model.RelatedCompany, error) {
+	tenantID, err := s.startTraversal(ctx, companyID, depth)
+	if err != nil {
+		return nil, err
+	}
+	var related []*model.RelatedCompany
+	visited := map[uuid.UUID]bool{companyID: true}
+	level := []uuid.UUID{companyID}
+	for current := 1; current <= depth && len(level) > 0; current++ {
+		var next []uuid.UUID
+		for _, parentID := range level {
+			children, err := s.repo.ListChildren(ctx, tenantID, parentID)
+			if err != nil {
+				return nil, err
+			}
+			for _, child := range children {
+				if visited[child.CompanyID] {
+					continue
+				}
+				visited[child.CompanyID] = true
+				related = append(related, &model.RelatedCompany{Relationship: child, Depth: current})
+				next = append(next, child.CompanyID)
+			}
+		}
+		level = next
+	}
+	return s.withCompanies(ctx, tenantID, related, func(r *model.RelatedCompany) uuid.UUID { return r.Relationship.CompanyID })
+}
+
+// CheckDelete keeps companies with branches, a branch has no existence without its parent. The hierarchy stays locked
+// until the company is deleted, SetParent checks that the parent exists while it holds the lock.
+func (s *relationshipService) CheckDelete(ctx context.Context, deleted *model.Company) (func(), error) {
+	unlock, err := s.repo.Lock(ctx, deleted.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	children, err := s.repo.ListChildren(ctx, deleted.TenantID, deleted.ID)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	branches := 0
+	for _, child := range children {
+		if child.Type == model.Branch {
+			branches++
+		}
+	}
+	if branches > 0 {
+		unlock()
+		return nil, model.ErrCompanyHasBranches{Id: deleted.ID, Branches: branches}
+	}
+	return unlock, nil
+}
+
+// RemoveCompany removes the relationship of the deleted company to its parent and those of its children to it, the
+// subsidiaries become companies without parent. Every removed relationship is sent as a delete event, events that
+// can not be sent are logged and not rolled back since the company is gone.
+func (s *relationshipService) RemoveCompany(ctx context.Context, deleted *model.Company) error {
+	children, err := s.repo.ListChildren(ctx, deleted.TenantID, deleted.ID)
+	if err != nil {
+		return err
+	}
+	parent, err := s.repo.GetParent(ctx, deleted.TenantID, deleted.ID)
+	if err != nil {
+		return err
+	}
+	removed := children
+	if parent != nil {
+		removed = append(removed, parent)
+	}
+	var errs []error
+	for _, relationship := range removed {
+		if err = s.repo.Delete(ctx, relationship.TenantID, relationship.CompanyID); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if kafkaErr := s.kafkaProducer.SendEventWithPayload(ctx, event.EVENT_RELATIONSHIP_DELETE, relationship); kafkaErr != nil {
+			logging.FromContext(ctx).Errorf("company:%v parent removed but send event failed. error:%v", relationship.CompanyID, kafkaErr)
+		}
+	}
+	logging.FromContext(ctx).Infof("company:%v deleted, %v relationships removed", deleted.ID, len(removed)-len(errs))
+	return errors.Join(errs...)
+}
+
+// checkCompanies returns model.ErrCompanyNotFound when the company is missing and model.ErrInvalidRelationship
+// when its parent is
+func (s *relationshipService) checkCompanies(ctx context.Context, relationship *model.CompanyRelationship) error {
+	existing, err := s.companies.GetByID(ctx, relationship.TenantID, relationship.CompanyID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return model.ErrCompanyNotFound{Id: relationship.CompanyID}
+	}
+	parent, err := s.companies.GetByID(ctx, relationship.TenantID, relationship.ParentID)
+	if err != nil {
+		return err
+	}
+	if parent == nil {
+		return model.ErrInvalidRelationship{Fields: []model.FieldError{{Field: "parent_id", Message: "is not a company"}}}
+	}
+	return nil
+}
+
+// checkHierarchy rejects parents that are branches, branches with children and parents among the descendants of the company
+func (s *relationshipService) checkHierarchy(ctx context.Context, relationship *model.CompanyRelationship) error {
+	grandparent, err := s.repo.GetParent(ctx, relationship.TenantID, relationship.ParentID)
+	if err != nil {
+		return err
+	}
+	if grandparent != nil && grandparent.Type == model.Branch {
+		return model.ErrInvalidRelationship{Fields: []model.FieldError{{Field: "parent_id", Message: "is a branch, which has no subsidiaries or branches"}}}
+	}
+	if relationship.Type == model.Branch {
+		children, err := s.repo.ListChildren(ctx, relationship.TenantID, relationship.CompanyID)
+		if err != nil {
+			return err
+		}
+		if len(children) > 0 {
+			return model.ErrInvalidRelationship{Fields: []model.FieldError{{Field: "type",
+				Message: fmt.Sprintf("can not be %v for a company with %v subsidiaries or branches", model.Branch, len(children))}}}
+		}
+	}
+	// the parent must not have the company among its ancestors
+	visited := map[uuid.UUID]bool{relationship.ParentID: true}
+	for ancestor := grandparent; ancestor != nil; {
+		if ancestor.ParentID == relationship.CompanyID {
+			return model.ErrRelationshipCycle{CompanyID: relationship.CompanyID, ParentID: relationship.ParentID}
+		}
+		if visited[ancestor.ParentID] {
+			break
+		}
+		visited[ancestor.ParentID] = true
+		if ancestor, err = s.repo.GetParent(ctx, relationship.TenantID, ancestor.ParentID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startTraversal checks the depth and that the company exists and returns the tenant of ctx
+func (s *relationshipService) startTraversal(ctx context.Context, companyID uuid.UUID, depth int) (string, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	if depth < 1 || depth > model.MaxRelationshipDepth {
+		return "", model.ErrInvalidRelationship{Fields: []model.FieldError{{Field: "depth",
+			Message: fmt.Sprintf("must be between 1 and %v", model.MaxRelationshipDepth)}}}
+	}
+	existing, err := s.companies.GetByID(ctx, tenantID, companyID)
+	if err != nil {
+		return "", err
+	}
+	if existing == nil {
+		return "", model.ErrCompanyNotFound{Id: companyID}
+	}
+	return tenantID, nil
+}
+
+// withCompanies sets the company with the id of every related company and leaves out the ones whose company is missing,
+// relationships of deleted companies are only left behind if removing them failed
+func (s *relationshipService) withCompanies(ctx context.Context, tenantID string, related []*model.RelatedCompany,
+	id func(*model.RelatedCompany) uuid.UUID) ([]*model.RelatedCompany, error) {
+	if len(related) == 0 {
+		return related, nil
+	}
+	ids := make([]uuid.UUID, 0, len(related))
+	for _, r := range related {
+		ids = append(ids, id(r))
+	}
+	companies, err := s.companies.GetByIDs(ctx, tenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*model.Company, len(companies))
+	for _, c := range companies {
+		byID[c.ID] = c
+	}
+	found := make([]*model.RelatedCompany, 0, len(related))
+	for _, r := range related {
+		if c, ok := byID[id(r)]; ok {
+			r.Company = c
+			found = append(found, r)
+		}
+	}
+	return found, nil
+}
@@ -0,0 +1,293 @@
+package relationship
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/company"
+	"github.com/ngereci/xm_interview/event"
+	mock_kafka "github.com/ngereci/xm_interview/mocks/mock_company/event"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/ngereci/xm_interview/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+const testTenant = "tenant-a"
+
+var testNow = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// testGroup is a service on the companies of a corporate group together with the events it sent
+type testGroup struct {
+	service   Service
+	repo      Repository
+	companies company.Repository
+	events    *event.MemoryAdapter
+}
+
+func newTestGroup(t *testing.T) *testGroup {
+	group := &testGroup{repo: NewMemoryRepository(), companies: company.NewMemoryRepository(), events: event.NewMemoryAdapter("companies", 0)}
+	service := NewService(group.repo, group.companies, group.events).(*relationshipService)
+	service.now = func() time.Time { return testNow }
+	group.service = service
+	return group
+}
+
+// newCompany stores a company of the test tenant
+func (g *testGroup) newCompany(t *testing.T) *model.Company {
+	stored := &model.Company{ID: uuid.New(), TenantID: testTenant, Name: "Acme " + uuid.NewString(), Employees: 10, Type: model.Corporation}
+	require.NoError(t, g.companies.Create(context.Background(), stored))
+	return stored
+}
+
+// link makes child a company of parent
+func (g *testGroup) link(t *testing.T, child, parent *model.Company, relationshipType model.RelationshipType) {
+	_, err := g.service.SetParent(tenantContext(), child.ID, &model.CompanyRelationship{ParentID: parent.ID, Type: relationshipType})
+	require.NoError(t, err)
+}
+
+// eventTypes returns the types of the events sent so far
+func (g *testGroup) eventTypes() []event.EventType {
+	var types []event.EventType
+	for _, message := range g.events.Messages() {
+		types = append(types, message.Event.EventType)
+	}
+	return types
+}
+
+func tenantContext() context.Context {
+	return tenant.WithID(context.Background(), testTenant)
+}
+
+// companyIDs returns the ids of the companies of related in order
+func companyIDs(related []*model.RelatedCompany) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(related))
+	for _, r := range related {
+		ids = append(ids, r.Company.ID)
+	}
+	return ids
+}
+
+func TestRelationshipService_SetParent(t *testing.T) {
+	group := newTestGroup(t)
+	parent, child := group.newCompany(t), group.newCompany(t)
+
+	created, err := group.service.SetParent(tenantContext(), child.ID, &model.CompanyRelationship{ParentID: parent.ID, Type: model.Subsidiary, TenantID: "tenant-b"})
+	require.NoError(t, err)
+	assert.Equal(t, &model.CompanyRelationship{TenantID: testTenant, CompanyID: child.ID, ParentID: parent.ID, Type: model.Subsidiary, CreatedAt: testNow}, created)
+	stored, err := group.repo.GetParent(context.Background(), testTenant, child.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created, stored)
+
+	// setting the same parent again changes nothing
+	_, err = group.service.SetParent(tenantContext(), child.ID, &model.CompanyRelationship{ParentID: parent.ID, Type: model.Subsidiary})
+	require.NoError(t, err)
+	// another parent replaces the previous one
+	other := group.newCompany(t)
+	group.link(t, child, other, model.Branch)
+	assert.Equal(t, []event.EventType{event.EVENT_RELATIONSHIP_CREATE, event.EVENT_RELATIONSHIP_UPDATE}, group.eventTypes())
+
+	var payload model.CompanyRelationship
+	require.NoError(t, json.Unmarshal(group.events.Messages()[1].Event.Payload, &payload))
+	assert.Equal(t, other.ID, payload.ParentID)
+	assert.Equal(t, model.Branch, payload.Type)
+}
+
+func TestRelationshipService_SetParent_Invalid(t *testing.T) {
+	group := newTestGroup(t)
+	parent, child := group.newCompany(t), group.newCompany(t)
+	ctx := tenantContext()
+
+	_, err := group.service.SetParent(ctx, child.ID, &model.CompanyRelationship{ParentID: child.ID, Type: model.Subsidiary})
+	assert.ErrorAs(t, err, &model.ErrInvalidRelationship{})
+	_, err = group.service.SetParent(ctx, child.ID, &model.CompanyRelationship{ParentID: uuid.New(), Type: model.Subsidiary})
+	assert.Equal(t, model.ErrInvalidRelationship{Fields: []model.FieldError{{Field: "parent_id", Message: "is not a company"}}}, err)
+	missing := uuid.New()
+	_, err = group.service.SetParent(ctx, missing, &model.CompanyRelationship{ParentID: parent.ID, Type: model.Subsidiary})
+	assert.Equal(t, model.ErrCompanyNotFound{Id: missing}, err)
+	// the companies of other tenants are missing
+	_, err = group.service.SetParent(tenant.WithID(context.Background(), "tenant-b"), child.ID, &model.CompanyRelationship{ParentID: parent.ID, Type: model.Subsidiary})
+	assert.Equal(t, model.ErrCompanyNotFound{Id: child.ID}, err)
+	_, err = group.service.SetParent(context.Background(), child.ID, &model.CompanyRelationship{ParentID: parent.ID, Type: model.Subsidiary})
+	assert.Equal(t, tenant.ErrMissing, err)
+	assert.Empty(t, group.events.Messages())
+}
+
+func TestRelationshipService_SetParent_Cycle(t *testing.T) {
+	group := newTestGroup(t)
+	top, middle, bottom := group.newCompany(t), group.newCompany(t), group.newCompany(t)
+	group.link(t, middle, top, model.Subsidiary)
+	group.link(t, bottom, middle, model.Subsidiary)
+
+	_, err := group.service.SetParent(tenantContext(), top.ID, &model.CompanyRelationship{ParentID: bottom.ID, Type: model.Subsidiary})
+	assert.Equal(t, model.ErrRelationshipCycle{CompanyID: top.ID, ParentID: bottom.ID}, err)
+	_, err = group.service.SetParent(tenantContext(), middle.ID, &model.CompanyRelationship{ParentID: bottom.ID, Type: model.Subsidiary})
+	assert.Equal(t, model.ErrRelationshipCycle{CompanyID: middle.ID, ParentID: bottom.ID}, err)
+	// moving a company within its own hierarchy is fine
+	group.link(t, bottom, top, model.Subsidiary)
+}
+
+func TestRelationshipService_SetParent_Branches(t *testing.T) {
+	group := newTestGroup(t)
+	parent, branch, subsidiary := group.newCompany(t), group.newCompany(t), group.newCompany(t)
+	group.link(t, branch, parent, model.Branch)
+
+	_, err := group.service.SetParent(tenantContext(), subsidiary.ID, &model.CompanyRelationship{ParentID: branch.ID, Type: model.Subsidiary})
+	assert.Equal(t, model.ErrInvalidRelationship{Fields: []model.FieldError{{Field: "parent_id", Message: "is a branch, which has no subsidiaries or branches"}}}, err)
+
+	group.link(t, subsidiary, parent, model.Subsidiary)
+	_, err = group.service.SetParent(tenantContext(), parent.ID, &model.CompanyRelationship{ParentID: group.newCompany(t).ID, Type: model.Branch})
+	assert.Equal(t, model.ErrInvalidRelationship{Fields: []model.FieldError{{Field: "type", Message: "can not be Branch for a company with 2 subsidiaries or branches"}}}, err)
+}
+
+func TestRelationshipService_SetParent_KafkaFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	group := newTestGroup(t)
+	parent, other, child := group.newCompany(t), group.newCompany(t), group.newCompany(t)
+	group.link(t, child, parent, model.Subsidiary)
+	previous, err := group.repo.GetParent(context.Background(), testTenant, child.ID)
+	require.NoError(t, err)
+
+	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
+	mockKafka.EXPECT().SendEventWithPayload(gomock.Any(), event.EVENT_RELATIONSHIP_UPDATE, gomock.Any()).Return(errors.New("something went wrong"))
+	svc := NewService(group.repo, group.companies, mockKafka)
+	_, err = svc.SetParent(tenantContext(), child.ID, &model.CompanyRelationship{ParentID: other.ID, Type: model.Subsidiary})
+	assert.Error(t, err)
+
+	// the previous parent is restored
+	stored, err := group.repo.GetParent(context.Background(), testTenant, child.ID)
+	require.NoError(t, err)
+	assert.Equal(t, previous, stored)
+}
+
+func TestRelationshipService_RemoveParent(t *testing.T) {
+	group := newTestGroup(t)
+	parent, child := group.newCompany(t), group.newCompany(t)
+	group.link(t, child, parent, model.Subsidiary)
+
+	require.NoError(t, group.service.RemoveParent(tenantContext(), child.ID))
+	stored, err := group.repo.GetParent(context.Background(), testTenant, child.ID)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+	assert.Equal(t, []event.EventType{event.EVENT_RELATIONSHIP_CREATE, event.EVENT_RELATIONSHIP_DELETE}, group.eventTypes())
+
+	assert.Equal(t, model.ErrRelationshipNotFound{CompanyID: child.ID}, group.service.RemoveParent(tenantContext(), child.ID))
+}
+
+func TestRelationshipService_Traversal(t *testing.T) {
+	group := newTestGroup(t)
+	holding := group.newCompany(t)
+	europe, america := group.newCompany(t), group.newCompany(t)
+	germany, berlin := group.newCompany(t), group.newCompany(t)
+	group.link(t, europe, holding, model.Subsidiary)
+	group.link(t, america, holding, model.Subsidiary)
+	group.link(t, germany, europe, model.Subsidiary)
+	group.link(t, berlin, germany, model.Branch)
+	ctx := tenantContext()
+
+	ancestors, err := group.service.Ancestors(ctx, berlin.ID, model.MaxRelationshipDepth)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{germany.ID, europe.ID, holding.ID}, companyIDs(ancestors))
+	assert.Equal(t, []int{1, 2, 3}, []int{ancestors[0].Depth, ancestors[1].Depth, ancestors[2].Depth})
+	assert.Equal(t, model.Branch, ancestors[0].Relationship.Type)
+	ancestors, err = group.service.Ancestors(ctx, berlin.ID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{germany.ID, europe.ID}, companyIDs(ancestors))
+	ancestors, err = group.service.Ancestors(ctx, holding.ID, 1)
+	require.NoError(t, err)
+	assert.Empty(t, ancestors)
+
+	descendants, err := group.service.Descendants(ctx, holding.ID, 1)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{europe.ID, america.ID}, companyIDs(descendants))
+	descendants, err = group.service.Descendants(ctx, holding.ID, 3)
+	require.NoError(t, err)
+	require.Len(t, descendants, 4)
+	assert.ElementsMatch(t, []uuid.UUID{europe.ID, america.ID}, companyIDs(descendants[:2]))
+	assert.Equal(t, []uuid.UUID{germany.ID, berlin.ID}, companyIDs(descendants[2:]))
+	assert.Equal(t, 3, descendants[3].Depth)
+
+	_, err = group.service.Descendants(ctx, holding.ID, model.MaxRelationshipDepth+1)
+	assert.ErrorAs(t, err, &model.ErrInvalidRelationship{})
+	_, err = group.service.Ancestors(ctx, holding.ID, 0)
+	assert.ErrorAs(t, err, &model.ErrInvalidRelationship{})
+	missing := uuid.New()
+	_, err = group.service.Descendants(ctx, missing, 1)
+	assert.Equal(t, model.ErrCompanyNotFound{Id: missing}, err)
+}
+
+func TestRelationshipService_DeleteRules(t *testing.T) {
+	group := newTestGroup(t)
+	holding, parent := group.newCompany(t), group.newCompany(t)
+	branch, subsidiary := group.newCompany(t), group.newCompany(t)
+	group.link(t, parent, holding, model.Subsidiary)
+	group.link(t, branch, parent, model.Branch)
+	group.link(t, subsidiary, parent, model.Subsidiary)
+	ctx := tenantContext()
+
+	_, err := group.service.CheckDelete(ctx, parent)
+	assert.Equal(t, model.ErrCompanyHasBranches{Id: parent.ID, Branches: 1}, err)
+	require.NoError(t, group.service.RemoveParent(ctx, branch.ID))
+	unlock, err := group.service.CheckDelete(ctx, parent)
+	require.NoError(t, err)
+
+	require.NoError(t, group.companies.Delete(ctx, testTenant, parent.ID))
+	require.NoError(t, group.service.RemoveCompany(ctx, parent))
+	unlock()
+
+	// the subsidiary is left without parent and the holding without the deleted company
+	ancestors, err := group.service.Ancestors(ctx, subsidiary.ID, 1)
+	require.NoError(t, err)
+	assert.Empty(t, ancestors)
+	descendants, err := group.service.Descendants(ctx, holding.ID, model.MaxRelationshipDepth)
+	require.NoError(t, err)
+	assert.Empty(t, descendants)
+	types := group.eventTypes()
+	assert.Equal(t, []event.EventType{event.EVENT_RELATIONSHIP_DELETE, event.EVENT_RELATIONSHIP_DELETE}, types[len(types)-2:])
+}
+
+func TestRelationshipService_BranchOfDeletedCompany(t *testing.T) {
+	group := newTestGroup(t)
+	parent, branch := group.newCompany(t), group.newCompany(t)
+	ctx := tenantContext()
+	unlock, err := group.service.CheckDelete(ctx, parent)
+	require.NoError(t, err)
+
+	// the branch is added while the parent is deleted, it waits for the deletion and finds the parent gone
+	linked := make(chan error)
+	go func() {
+		_, err := group.service.SetParent(ctx, branch.ID, &model.CompanyRelationship{ParentID: parent.ID, Type: model.Branch})
+		linked <- err
+	}()
+	select {
+	case err = <-linked:
+		t.Fatalf("parent set while the hierarchy was locked, error:%v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	require.NoError(t, group.companies.Delete(ctx, testTenant, parent.ID))
+	require.NoError(t, group.service.RemoveCompany(ctx, parent))
+	unlock()
+
+	assert.ErrorAs(t, <-linked, &model.ErrInvalidRelationship{})
+	found, err := group.repo.GetParent(ctx, testTenant, branch.ID)
+	require.NoError(t, err)
+	assert.Nil(t, found)
+}
+
+func TestRelationshipService_SkipsMissingCompanies(t *testing.T) {
+	group := newTestGroup(t)
+	parent, child := group.newCompany(t), group.newCompany(t)
+	group.link(t, child, parent, model.Subsidiary)
+	// a company deleted without removing its relationships
+	require.NoError(t, group.companies.Delete(context.Background(), testTenant, child.ID))
+
+	descendants, err := group.service.Descendants(tenantContext(), parent.ID, 1)
+	require.NoError(t, err)
+	assert.Empty(t, descendants)
+}
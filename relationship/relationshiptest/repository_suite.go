@@ -0,0 +1,157 @@
+// Package relationshiptest provides a conformance test suite for relationship.Repository implementations.
+package relationshiptest
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/ngereci/xm_interview/relationship"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// NewRepository returns an empty repository, it is called once per test.
+type NewRepository func(t *testing.T) relationship.Repository
+
+// RunRepositorySuite runs the contract every relationship.Repository implementation has to fulfil.
+func RunRepositorySuite(t *testing.T, newRepository NewRepository) {
+	t.Run("PutAndGetParent", func(t *testing.T) { testPutAndGetParent(t, newRepository(t)) })
+	t.Run("PutReplacesParent", func(t *testing.T) { testPutReplacesParent(t, newRepository(t)) })
+	t.Run("GetParentNotFound", func(t *testing.T) { testGetParentNotFound(t, newRepository(t)) })
+	t.Run("ListChildren", func(t *testing.T) { testListChildren(t, newRepository(t)) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, newRepository(t)) })
+	t.Run("TenantIsolation", func(t *testing.T) { testTenantIsolation(t, newRepository(t)) })
+	t.Run("Lock", func(t *testing.T) { testLock(t, newRepository(t)) })
+}
+
+// newRelationship returns a relationship created now, timestamps are stored in milliseconds
+func newRelationship(tenantID string, parentID uuid.UUID, relationshipType model.RelationshipType) *model.CompanyRelationship {
+	return &model.CompanyRelationship{
+		TenantID:  tenantID,
+		CompanyID: uuid.New(),
+		ParentID:  parentID,
+		Type:      relationshipType,
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+	}
+}
+
+// assertRelationship compares the relationships field by field, timestamps may come back in another location
+func assertRelationship(t *testing.T, expected, actual *model.CompanyRelationship) {
+	require.NotNil(t, actual)
+	assert.Equal(t, expected.TenantID, actual.TenantID)
+	assert.Equal(t, expected.CompanyID, actual.CompanyID)
+	assert.Equal(t, expected.ParentID, actual.ParentID)
+	assert.Equal(t, expected.Type, actual.Type)
+	assert.True(t, expected.CreatedAt.Equal(actual.CreatedAt))
+}
+
+func testPutAndGetParent(t *testing.T, repo relationship.Repository) {
+	ctx := context.Background()
+	created := newRelationship("acme", uuid.New(), model.Subsidiary)
+	require.NoError(t, repo.Put(ctx, created))
+
+	found, err := repo.GetParent(ctx, "acme", created.CompanyID)
+	require.NoError(t, err)
+	assertRelationship(t, created, found)
+}
+
+func testPutReplacesParent(t *testing.T, repo relationship.Repository) {
+	ctx := context.Background()
+	oldParentID := uuid.New()
+	relationship := newRelationship("acme", oldParentID, model.Subsidiary)
+	require.NoError(t, repo.Put(ctx, relationship))
+
+	relationship.ParentID = uuid.New()
+	relationship.Type = model.Branch
+	require.NoError(t, repo.Put(ctx, relationship))
+
+	found, err := repo.GetParent(ctx, "acme", relationship.CompanyID)
+	require.NoError(t, err)
+	assertRelationship(t, relationship, found)
+	children, err := repo.ListChildren(ctx, "acme", oldParentID)
+	require.NoError(t, err)
+	assert.Empty(t, children)
+}
+
+func testGetParentNotFound(t *testing.T, repo relationship.Repository) {
+	found, err := repo.GetParent(context.Background(), "acme", uuid.New())
+	require.NoError(t, err)
+	assert.Nil(t, found)
+}
+
+func testListChildren(t *testing.T, repo relationship.Repository) {
+	ctx := context.Background()
+	parentID := uuid.New()
+	first := newRelationship("acme", parentID, model.Subsidiary)
+	first.CompanyID = uuid.MustParse("00000000-0000-4000-8000-000000000001")
+	second := newRelationship("acme", parentID, model.Branch)
+	second.CompanyID = uuid.MustParse("ffffffff-0000-4000-8000-000000000002")
+	third := newRelationship("acme", parentID, model.Subsidiary)
+	third.CompanyID = uuid.MustParse("80000000-0000-1000-8000-000000000003")
+	for _, relationship := range []*model.CompanyRelationship{second, third, first, newRelationship("acme", uuid.New(), model.Subsidiary)} {
+		require.NoError(t, repo.Put(ctx, relationship))
+	}
+
+	children, err := repo.ListChildren(ctx, "acme", parentID)
+	require.NoError(t, err)
+	require.Len(t, children, 3)
+	assertRelationship(t, first, children[0])
+	assertRelationship(t, third, children[1])
+	assertRelationship(t, second, children[2])
+}
+
+func testDelete(t *testing.T, repo relationship.Repository) {
+	ctx := context.Background()
+	relationship := newRelationship("acme", uuid.New(), model.Subsidiary)
+	require.NoError(t, repo.Put(ctx, relationship))
+
+	require.NoError(t, repo.Delete(ctx, "acme", relationship.CompanyID))
+
+	found, err := repo.GetParent(ctx, "acme", relationship.CompanyID)
+	require.NoError(t, err)
+	assert.Nil(t, found)
+	assert.Equal(t, model.ErrRelationshipNotFound{CompanyID: relationship.CompanyID}, repo.Delete(ctx, "acme", relationship.CompanyID))
+}
+
+func testTenantIsolation(t *testing.T, repo relationship.Repository) {
+	ctx := context.Background()
+	relationship := newRelationship("acme", uuid.New(), model.Subsidiary)
+	require.NoError(t, repo.Put(ctx, relationship))
+
+	found, err := repo.GetParent(ctx, "globex", relationship.CompanyID)
+	require.NoError(t, err)
+	assert.Nil(t, found)
+	children, err := repo.ListChildren(ctx, "globex", relationship.ParentID)
+	require.NoError(t, err)
+	assert.Empty(t, children)
+	assert.Equal(t, model.ErrRelationshipNotFound{CompanyID: relationship.CompanyID}, repo.Delete(ctx, "globex", relationship.CompanyID))
+}
+
+func testLock(t *testing.T, repo relationship.Repository) {
+	ctx := context.Background()
+	unlock, err := repo.Lock(ctx, "acme")
+	require.NoError(t, err)
+
+	// the lock is taken once it is unlocked
+	locked := make(chan func())
+	go func() {
+		unlock, err := repo.Lock(ctx, "acme")
+		assert.NoError(t, err)
+		locked <- unlock
+	}()
+	select {
+	case <-locked:
+		t.Fatal("locked twice")
+	case <-time.After(100 * time.Millisecond):
+	}
+	unlock()
+	select {
+	case unlock = <-locked:
+		require.NotNil(t, unlock)
+		unlock()
+	case <-time.After(5 * time.Second):
+		t.Fatal("not locked after unlock")
+	}
+}
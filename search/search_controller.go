@@ -0,0 +1,84 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/ngereci/xm_interview/company"
+	"net/http"
+)
+
+const defaultLimit = 20
+
+// Request is the query of a search, Q holds the words to search for
+type Request struct {
+	Q     string `form:"q" binding:"required"`
+	Limit *int   `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+type Response struct {
+	Results []*Result `json:"results"`
+}
+
+type RebuildResponse struct {
+	Companies int `json:"companies"`
+}
+
+type Controller interface {
+	Search(ctx *gin.Context)
+	RebuildIndex(ctx *gin.Context)
+}
+
+type controller struct {
+	service Service
+}
+
+func NewController(service Service) Controller {
+	return &controller{service: service}
+}
+
+func (c *controller) Search(ctx *gin.Context) {
+	var request Request
+	if err := ctx.ShouldBindQuery(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit := defaultLimit
+	if request.Limit != nil {
+		limit = *request.Limit
+	}
+	results, err := c.service.Search(ctx.Request.Context(), request.Q, limit)
+	if err != nil {
+		ctx.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if results == nil {
+		results = []*Result{}
+	}
+	ctx.JSON(http.StatusOK, Response{Results: results})
+}
+
+// RebuildIndex rebuilds the index synchronously, the companies of all tenants are indexed
+func (c *controller) RebuildIndex(ctx *gin.Context) {
+	count, err := c.service.RebuildIndex(ctx.Request.Context())
+	if err != nil {
+		if errors.Is(err, ErrRebuilding) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, RebuildResponse{Companies: count})
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return company.StatusClientClosedRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,118 @@
+package search_test
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mock_search_service "github.com/ngereci/xm_interview/mocks/mock_search/service"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/ngereci/xm_interview/search"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestRouter(t *testing.T) (*mock_search_service.MockService, *gin.Engine) {
+	gin.SetMode(gin.TestMode)
+	service := mock_search_service.NewMockService(gomock.NewController(t))
+	controller := search.NewController(service)
+	router := gin.New()
+	router.GET("/search", controller.Search)
+	router.POST("/rebuild", controller.RebuildIndex)
+	return service, router
+}
+
+func serve(router *gin.Engine, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func TestController_Search(t *testing.T) {
+	service, router := newTestRouter(t)
+	acme := &model.Company{
+		ID:        uuid.MustParse("56f86115-a58f-43db-8a1b-9aa2908f7a18"),
+		TenantID:  "tenant-a",
+		Name:      "Acme",
+		Employees: 10,
+		Type:      model.Corporation,
+	}
+	service.EXPECT().Search(gomock.Any(), "acme ltd", 5).Return([]*search.Result{{Company: acme, Score: 1.5}}, nil)
+
+	w := serve(router, http.MethodGet, "/search?q=acme+ltd&limit=5")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"results":[{"company":{
+		"id":"56f86115-a58f-43db-8a1b-9aa2908f7a18",
+		"tenant_id":"tenant-a",
+		"name":"Acme",
+		"employees":10,
+		"registered":false,
+		"type":"Corporation"
+	},"score":1.5}]}`, w.Body.String())
+}
+
+func TestController_Search_DefaultLimit(t *testing.T) {
+	service, router := newTestRouter(t)
+	service.EXPECT().Search(gomock.Any(), "acme", 20).Return(nil, nil)
+
+	w := serve(router, http.MethodGet, "/search?q=acme")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"results":[]}`, w.Body.String())
+}
+
+func TestController_Search_InvalidRequest(t *testing.T) {
+	for _, path := range []string{"/search", "/search?q=", "/search?q=acme&limit=0", "/search?q=acme&limit=101", "/search?q=acme&limit=ten"} {
+		t.Run(path, func(t *testing.T) {
+			_, router := newTestRouter(t)
+
+			w := serve(router, http.MethodGet, path)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestController_Search_Error(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "failed", err: errors.New("test error"), want: http.StatusInternalServerError},
+		{name: "timed out", err: context.DeadlineExceeded, want: http.StatusGatewayTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, router := newTestRouter(t)
+			service.EXPECT().Search(gomock.Any(), "acme", 20).Return(nil, tt.err)
+
+			w := serve(router, http.MethodGet, "/search?q=acme")
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestController_RebuildIndex(t *testing.T) {
+	service, router := newTestRouter(t)
+	service.EXPECT().RebuildIndex(gomock.Any()).Return(3, nil)
+
+	w := serve(router, http.MethodPost, "/rebuild")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"companies":3}`, w.Body.String())
+}
+
+func TestController_RebuildIndex_Running(t *testing.T) {
+	service, router := newTestRouter(t)
+	service.EXPECT().RebuildIndex(gomock.Any()).Return(0, search.ErrRebuilding)
+
+	w := serve(router, http.MethodPost, "/rebuild")
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
@@ -0,0 +1,269 @@
+// Package search finds companies by the words of their name and description, see Index.
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/standard"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/event"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/model"
+	"sync"
+	"time"
+)
+
+const (
+	fieldTenantID    = "tenant_id"
+	fieldName        = "name"
+	fieldDescription = "description"
+	// nameBoost ranks matches in the name above matches in the description
+	nameBoost = 2
+	// rebuildPageSize is the page size the companies are read with when the index is rebuilt
+	rebuildPageSize = 500
+)
+
+// ErrRebuilding is returned by Rebuild while another rebuild is running
+var ErrRebuilding = errors.New("search index is being rebuilt")
+
+// Lister pages through the companies of all tenants, company.Repository implements it
+type Lister interface {
+	List(ctx context.Context, pageState []byte, pageSize int) ([]*model.Company, []byte, error)
+}
+
+// Hit is a company matching a search, the higher Score the better it matches
+type Hit struct {
+	ID    uuid.UUID
+	Score float64
+}
+
+// Index is an inverted index of the name and description of the companies held in memory. It is kept up to date
+// with the company events of this instance and rebuilt from the table to catch up with the changes of other instances.
+type Index interface {
+	event.Listener
+	// Search returns at most limit companies of tenantID matching every word of text, best matches first. Words match
+	// case-insensitively and with up to two typos, depending on their length.
+	Search(ctx context.Context, tenantID string, text string, limit int) ([]Hit, error)
+	// Rebuild replaces the index with the companies of lister and returns how many there are
+	Rebuild(ctx context.Context, lister Lister) (int, error)
+	Close() error
+}
+
+type bleveIndex struct {
+	// mu guards index against being swapped and closed by Rebuild while it is used
+	mu    sync.RWMutex
+	index bleve.Index
+	// pending holds the events received during a rebuild, they are applied to the new index before it is swapped in
+	pending    []*event.Event
+	rebuilding bool
+	rebuildMu  sync.Mutex
+}
+
+// NewIndex creates an empty Index, call Rebuild to fill it
+func NewIndex() (Index, error) {
+	index, err := bleve.NewMemOnly(newMapping())
+	if err != nil {
+		return nil, err
+	}
+	return &bleveIndex{index: index}, nil
+}
+
+func newMapping() mapping.IndexMapping {
+	text := bleve.NewTextFieldMapping()
+	text.Analyzer = standard.Name
+	text.Store = false
+	tenantID := bleve.NewKeywordFieldMapping()
+	tenantID.Analyzer = keyword.Name
+	tenantID.Store = false
+
+	document := bleve.NewDocumentStaticMapping()
+	document.AddFieldMappingsAt(fieldTenantID, tenantID)
+	document.AddFieldMappingsAt(fieldName, text)
+	document.AddFieldMappingsAt(fieldDescription, text)
+	indexMapping := bleve.NewIndexMapping()
+	indexMapping.DefaultMapping = document
+	indexMapping.DefaultAnalyzer = standard.Name
+	return indexMapping
+}
+
+func document(company *model.Company) map[string]any {
+	return map[string]any{
+		fieldTenantID:    company.TenantID,
+		fieldName:        company.Name,
+		fieldDescription: company.Description,
+	}
+}
+
+// OnEvent indexes the company of create and update events and removes the company of delete events
+func (i *bleveIndex) OnEvent(ctx context.Context, companyEvent *event.Event) {
+	switch companyEvent.EventType {
+	case event.EVENT_CREATE, event.EVENT_UPDATE, event.EVENT_DELETE:
+	default:
+		return
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if err := apply(i.index, companyEvent); err != nil {
+		logging.FromContext(ctx).Errorf("event:%v not indexed. error:%v", companyEvent.ID, err)
+	}
+	if i.rebuilding {
+		i.pending = append(i.pending, companyEvent)
+	}
+}
+
+func apply(index bleve.Index, companyEvent *event.Event) error {
+	var company model.Company
+	if err := json.Unmarshal(companyEvent.Payload, &company); err != nil {
+		return err
+	}
+	if companyEvent.EventType == event.EVENT_DELETE {
+		return index.Delete(company.ID.String())
+	}
+	return index.Index(company.ID.String(), document(&company))
+}
+
+func (i *bleveIndex) Search(ctx context.Context, tenantID string, text string, limit int) ([]Hit, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	index := i.index
+
+	words := index.Mapping().AnalyzerNamed(standard.Name).Analyze([]byte(text))
+	if len(words) == 0 {
+		return nil, nil
+	}
+	tenantQuery := bleve.NewTermQuery(tenantID)
+	tenantQuery.SetField(fieldTenantID)
+	conjuncts := []query.Query{tenantQuery}
+	for _, word := range words {
+		conjuncts = append(conjuncts, bleve.NewDisjunctionQuery(
+			fuzzyQuery(string(word.Term), fieldName, nameBoost),
+			fuzzyQuery(string(word.Term), fieldDescription, 1),
+		))
+	}
+	request := bleve.NewSearchRequestOptions(bleve.NewConjunctionQuery(conjuncts...), limit, 0, false)
+	result, err := index.SearchInContext(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	hits := make([]Hit, 0, len(result.Hits))
+	for _, match := range result.Hits {
+		id, err := uuid.Parse(match.ID)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, Hit{ID: id, Score: match.Score})
+	}
+	return hits, nil
+}
+
+// fuzzyQuery matches word in field with as many typos as its length allows: none up to two characters,
+// one up to five characters and two beyond.
+func fuzzyQuery(word string, field string, boost float64) query.Query {
+	fuzzy := bleve.NewFuzzyQuery(word)
+	fuzzy.SetField(field)
+	fuzzy.SetBoost(boost)
+	switch length := len([]rune(word)); {
+	case length <= 2:
+		fuzzy.SetFuzziness(0)
+	case length <= 5:
+		fuzzy.SetFuzziness(1)
+	default:
+		fuzzy.SetFuzziness(2)
+	}
+	return fuzzy
+}
+
+func (i *bleveIndex) Rebuild(ctx context.Context, lister Lister) (int, error) {
+	if !i.rebuildMu.TryLock() {
+		return 0, ErrRebuilding
+	}
+	defer i.rebuildMu.Unlock()
+	start := time.Now()
+	i.mu.Lock()
+	i.rebuilding = true
+	i.pending = nil
+	i.mu.Unlock()
+	defer func() {
+		i.mu.Lock()
+		i.rebuilding = false
+		i.pending = nil
+		i.mu.Unlock()
+	}()
+
+	index, err := bleve.NewMemOnly(newMapping())
+	if err != nil {
+		return 0, err
+	}
+	count, err := fill(ctx, index, lister)
+	if err != nil {
+		_ = index.Close()
+		return 0, err
+	}
+
+	i.mu.Lock()
+	for _, companyEvent := range i.pending {
+		if err = apply(index, companyEvent); err != nil {
+			logging.FromContext(ctx).Errorf("event:%v not indexed. error:%v", companyEvent.ID, err)
+		}
+	}
+	previous := i.index
+	i.index = index
+	i.mu.Unlock()
+	logging.FromContext(ctx).Infof("search index rebuilt with %v companies in %v", count, time.Since(start))
+	return count, previous.Close()
+}
+
+// fill indexes the companies of lister in batches of a page
+func fill(ctx context.Context, index bleve.Index, lister Lister) (int, error) {
+	count := 0
+	var pageState []byte
+	for {
+		companies, nextPageState, err := lister.List(ctx, pageState, rebuildPageSize)
+		if err != nil {
+			return 0, err
+		}
+		batch := index.NewBatch()
+		for _, company := range companies {
+			if err = batch.Index(company.ID.String(), document(company)); err != nil {
+				return 0, err
+			}
+		}
+		if err = index.Batch(batch); err != nil {
+			return 0, err
+		}
+		count += len(companies)
+		if len(nextPageState) == 0 {
+			return count, nil
+		}
+		pageState = nextPageState
+	}
+}
+
+func (i *bleveIndex) Close() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.index.Close()
+}
+
+// RebuildPeriodically rebuilds index from lister right away and then every interval until ctx is done,
+// a non positive interval rebuilds it once.
+func RebuildPeriodically(ctx context.Context, index Index, lister Lister, interval time.Duration) {
+	for {
+		if _, err := index.Rebuild(ctx, lister); err != nil && !errors.Is(err, ErrRebuilding) {
+			logging.FromContext(ctx).Errorf("RebuildPeriodically error:%v", err)
+		}
+		if interval <= 0 {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
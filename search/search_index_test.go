@@ -0,0 +1,167 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/company"
+	"github.com/ngereci/xm_interview/event"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func newCompany(tenantID, name, description string) *model.Company {
+	return &model.Company{ID: uuid.New(), TenantID: tenantID, Name: name, Description: description, Employees: 1, Type: model.Corporation}
+}
+
+func newTestIndex(t *testing.T) Index {
+	index, err := NewIndex()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = index.Close() })
+	return index
+}
+
+func publish(t *testing.T, listener event.Listener, eventType event.EventType, company *model.Company) {
+	companyEvent, err := event.NewEvent(eventType, company)
+	require.NoError(t, err)
+	listener.OnEvent(context.Background(), companyEvent)
+}
+
+// ids returns the ids of hits in order
+func ids(hits []Hit) []uuid.UUID {
+	result := make([]uuid.UUID, 0, len(hits))
+	for _, hit := range hits {
+		result = append(result, hit.ID)
+	}
+	return result
+}
+
+func TestIndex_Search(t *testing.T) {
+	index := newTestIndex(t)
+	acme := newCompany("tenant-a", "Acme Logistics", "Freight forwarding across Europe")
+	globex := newCompany("tenant-a", "Globex", "Logistics software for Acme and others")
+	initech := newCompany("tenant-a", "Initech", "Printers")
+	otherTenant := newCompany("tenant-b", "Acme Logistics", "")
+	for _, c := range []*model.Company{acme, globex, initech, otherTenant} {
+		publish(t, index, event.EVENT_CREATE, c)
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want []uuid.UUID
+	}{
+		{name: "name ranks above description", text: "acme", want: []uuid.UUID{acme.ID, globex.ID}},
+		{name: "case insensitive", text: "INITECH", want: []uuid.UUID{initech.ID}},
+		{name: "typo", text: "logistcs", want: []uuid.UUID{acme.ID, globex.ID}},
+		{name: "two typos in long words", text: "frieght forwarring", want: []uuid.UUID{acme.ID}},
+		{name: "every word has to match", text: "acme printers", want: []uuid.UUID{}},
+		{name: "short words have to match exactly", text: "eu", want: []uuid.UUID{}},
+		{name: "no words", text: " ,. ", want: []uuid.UUID{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, err := index.Search(context.Background(), "tenant-a", tt.text, 10)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ids(hits))
+		})
+	}
+}
+
+func TestIndex_Search_Limit(t *testing.T) {
+	index := newTestIndex(t)
+	for i := 0; i < 3; i++ {
+		publish(t, index, event.EVENT_CREATE, newCompany("tenant-a", "Acme", ""))
+	}
+
+	hits, err := index.Search(context.Background(), "tenant-a", "acme", 2)
+
+	require.NoError(t, err)
+	assert.Len(t, hits, 2)
+}
+
+func TestIndex_OnEvent(t *testing.T) {
+	index := newTestIndex(t)
+	acme := newCompany("tenant-a", "Acme", "")
+	publish(t, index, event.EVENT_CREATE, acme)
+	renamed := *acme
+	renamed.Name = "Globex"
+	publish(t, index, event.EVENT_UPDATE, &renamed)
+	// snapshots do not change companies
+	publish(t, index, event.EVENT_SNAPSHOT, acme)
+
+	hits, err := index.Search(context.Background(), "tenant-a", "acme", 10)
+	require.NoError(t, err)
+	assert.Empty(t, hits)
+	hits, err = index.Search(context.Background(), "tenant-a", "globex", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{acme.ID}, ids(hits))
+
+	publish(t, index, event.EVENT_DELETE, &renamed)
+	hits, err = index.Search(context.Background(), "tenant-a", "globex", 10)
+	require.NoError(t, err)
+	assert.Empty(t, hits)
+}
+
+// blockingLister lists companies, publishing events to index while the first page is read
+type blockingLister struct {
+	company.Repository
+	onList func()
+}
+
+func (l *blockingLister) List(ctx context.Context, pageState []byte, pageSize int) ([]*model.Company, []byte, error) {
+	if l.onList != nil {
+		l.onList()
+		l.onList = nil
+	}
+	return l.Repository.List(ctx, pageState, pageSize)
+}
+
+func TestIndex_Rebuild(t *testing.T) {
+	index := newTestIndex(t)
+	repository := company.NewMemoryRepository()
+	stale := newCompany("tenant-a", "Stale", "")
+	publish(t, index, event.EVENT_CREATE, stale)
+	stored := make([]*model.Company, 0, rebuildPageSize+1)
+	for i := 0; i <= rebuildPageSize; i++ {
+		c := newCompany("tenant-a", fmt.Sprintf("Acme %v", i), "")
+		require.NoError(t, repository.Create(context.Background(), c))
+		stored = append(stored, c)
+	}
+	deleted := stored[0]
+	created := newCompany("tenant-a", "Acme", "")
+	lister := &blockingLister{Repository: repository, onList: func() {
+		// events arriving during the rebuild are applied to the new index, even if the table was already read
+		publish(t, index, event.EVENT_CREATE, created)
+		publish(t, index, event.EVENT_DELETE, deleted)
+	}}
+
+	count, err := index.Rebuild(context.Background(), lister)
+
+	require.NoError(t, err)
+	assert.Equal(t, rebuildPageSize+1, count)
+	hits, err := index.Search(context.Background(), "tenant-a", "stale", 10)
+	require.NoError(t, err)
+	assert.Empty(t, hits)
+	hits, err = index.Search(context.Background(), "tenant-a", "acme", 1000)
+	require.NoError(t, err)
+	assert.Len(t, hits, rebuildPageSize+1)
+	assert.Contains(t, ids(hits), created.ID)
+	assert.NotContains(t, ids(hits), deleted.ID)
+}
+
+func TestIndex_Rebuild_Running(t *testing.T) {
+	index := newTestIndex(t)
+	lister := &blockingLister{Repository: company.NewMemoryRepository()}
+	lister.onList = func() {
+		_, err := index.Rebuild(context.Background(), lister)
+		assert.Equal(t, ErrRebuilding, err)
+	}
+
+	_, err := index.Rebuild(context.Background(), lister)
+
+	require.NoError(t, err)
+}
@@ -0,0 +1,69 @@
+package search
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/company"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/ngereci/xm_interview/tenant"
+)
+
+// Result is a company matching a search, the higher Score the better it matches
+type Result struct {
+	Company *model.Company `json:"company"`
+	Score   float64        `json:"score"`
+}
+
+type Service interface {
+	// Search returns at most limit companies matching text, best matches first
+	Search(ctx context.Context, text string, limit int) ([]*Result, error)
+	// RebuildIndex rebuilds the index from the table and returns how many companies it holds
+	RebuildIndex(ctx context.Context) (int, error)
+}
+
+type searchService struct {
+	index     Index
+	lister    Lister
+	companies company.Service
+}
+
+// NewService searches the companies of index, whose current state is read from companies so that results never
+// show stale data. The index is rebuilt from lister.
+func NewService(index Index, lister Lister, companies company.Service) Service {
+	return &searchService{index: index, lister: lister, companies: companies}
+}
+
+func (s *searchService) Search(ctx context.Context, text string, limit int) ([]*Result, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hits, err := s.index.Search(ctx, tenantID, text, limit)
+	if err != nil || len(hits) == 0 {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+	companies, err := s.companies.GetCompaniesByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*model.Company, len(companies))
+	for _, company := range companies {
+		byID[company.ID] = company
+	}
+	results := make([]*Result, 0, len(hits))
+	for _, hit := range hits {
+		// companies deleted since they were indexed are left out
+		if company, ok := byID[hit.ID]; ok {
+			results = append(results, &Result{Company: company, Score: hit.Score})
+		}
+	}
+	return results, nil
+}
+
+func (s *searchService) RebuildIndex(ctx context.Context) (int, error) {
+	return s.index.Rebuild(ctx, s.lister)
+}
@@ -0,0 +1,87 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/company"
+	"github.com/ngereci/xm_interview/event"
+	mock_company_service "github.com/ngereci/xm_interview/mocks/mock_company/service"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/ngereci/xm_interview/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestService_Search(t *testing.T) {
+	index := newTestIndex(t)
+	companies := mock_company_service.NewMockService(gomock.NewController(t))
+	service := NewService(index, company.NewMemoryRepository(), companies)
+	acme := newCompany("tenant-a", "Acme", "")
+	acmeLogistics := newCompany("tenant-a", "Acme Logistics", "")
+	deleted := newCompany("tenant-a", "Acme Deleted", "")
+	for _, c := range []*model.Company{acme, acmeLogistics, deleted} {
+		publish(t, index, event.EVENT_CREATE, c)
+	}
+	ctx := tenant.WithID(context.Background(), "tenant-a")
+	// the current state is returned, companies deleted meanwhile are left out
+	companies.EXPECT().GetCompaniesByIDs(ctx, gomock.Len(3)).Return([]*model.Company{acmeLogistics, acme}, nil)
+
+	results, err := service.Search(ctx, "acme", 10)
+
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, acme, results[0].Company)
+	assert.Equal(t, acmeLogistics, results[1].Company)
+	assert.Greater(t, results[0].Score, results[1].Score)
+}
+
+func TestService_Search_NoHits(t *testing.T) {
+	companies := mock_company_service.NewMockService(gomock.NewController(t))
+	service := NewService(newTestIndex(t), company.NewMemoryRepository(), companies)
+
+	results, err := service.Search(tenant.WithID(context.Background(), "tenant-a"), "acme", 10)
+
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestService_Search_Error(t *testing.T) {
+	index := newTestIndex(t)
+	companies := mock_company_service.NewMockService(gomock.NewController(t))
+	service := NewService(index, company.NewMemoryRepository(), companies)
+	publish(t, index, event.EVENT_CREATE, newCompany("tenant-a", "Acme", ""))
+	testErr := errors.New("test error")
+	companies.EXPECT().GetCompaniesByIDs(gomock.Any(), gomock.Any()).Return(nil, testErr)
+
+	_, err := service.Search(tenant.WithID(context.Background(), "tenant-a"), "acme", 10)
+
+	assert.Equal(t, testErr, err)
+}
+
+func TestService_Search_NoTenant(t *testing.T) {
+	companies := mock_company_service.NewMockService(gomock.NewController(t))
+	service := NewService(newTestIndex(t), company.NewMemoryRepository(), companies)
+
+	_, err := service.Search(context.Background(), "acme", 10)
+
+	assert.Error(t, err)
+}
+
+func TestService_RebuildIndex(t *testing.T) {
+	index := newTestIndex(t)
+	repository := company.NewMemoryRepository()
+	acme := newCompany("tenant-a", "Acme", "")
+	require.NoError(t, repository.Create(context.Background(), acme))
+	service := NewService(index, repository, mock_company_service.NewMockService(gomock.NewController(t)))
+
+	count, err := service.RebuildIndex(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	hits, err := index.Search(context.Background(), "tenant-a", "acme", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{acme.ID}, ids(hits))
+}
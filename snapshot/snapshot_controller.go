@@ -0,0 +1,62 @@
+package snapshot
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/ngereci/xm_interview/env"
+	"github.com/ngereci/xm_interview/tenant"
+	"github.com/spf13/viper"
+	"net/http"
+)
+
+type Controller interface {
+	PublishSnapshot(ctx *gin.Context)
+}
+
+type controller struct {
+	service Service
+}
+
+func NewController(service Service) Controller {
+	return &controller{service: service}
+}
+
+// PublishSnapshot publishes snapshot events of the tenant of the caller synchronously, at most COMPANY_SNAPSHOT_MAX_PER_REQUEST
+// companies per call. The returned progress is posted back as "resume" to continue the run.
+func (c *controller) PublishSnapshot(ctx *gin.Context) {
+	var request Request
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tenantID, err := tenant.FromContext(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	request.Filter.TenantID = tenantID
+	if request.Topic == "" {
+		request.Topic = viper.GetString(env.COMPANY_SNAPSHOT_TOPIC)
+	}
+	if request.Rate <= 0 {
+		request.Rate = viper.GetFloat64(env.COMPANY_SNAPSHOT_RATE)
+	}
+	if request.PageSize <= 0 {
+		request.PageSize = viper.GetInt(env.COMPANY_SNAPSHOT_PAGE_SIZE)
+	}
+	maxPerRequest := viper.GetInt(env.COMPANY_SNAPSHOT_MAX_PER_REQUEST)
+	if request.Limit <= 0 || (maxPerRequest > 0 && request.Limit > maxPerRequest) {
+		request.Limit = maxPerRequest
+	}
+
+	progress, err := c.service.Publish(ctx.Request.Context(), &request, nil)
+	if errors.Is(err, ErrProgressMismatch) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "progress": progress})
+		return
+	}
+	ctx.JSON(http.StatusOK, progress)
+}
@@ -0,0 +1,83 @@
+package snapshot_test
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/ngereci/xm_interview/env"
+	mock_snapshot_service "github.com/ngereci/xm_interview/mocks/mock_snapshot/service"
+	"github.com/ngereci/xm_interview/snapshot"
+	"github.com/ngereci/xm_interview/tenant"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestController_PublishSnapshot(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	viper.Set(env.COMPANY_SNAPSHOT_TOPIC, "snapshots")
+	viper.Set(env.COMPANY_SNAPSHOT_RATE, 50)
+	viper.Set(env.COMPANY_SNAPSHOT_PAGE_SIZE, 10)
+	viper.Set(env.COMPANY_SNAPSHOT_MAX_PER_REQUEST, 100)
+
+	mockService := mock_snapshot_service.NewMockService(ctrl)
+	mockController := snapshot.NewController(mockService)
+
+	expectedRequest := &snapshot.Request{Topic: "snapshots", Filter: snapshot.Filter{TenantID: "tenant-a"}, Rate: 50, PageSize: 10, Limit: 100, Resume: &snapshot.Progress{Topic: "snapshots", TenantID: "tenant-a", PageState: []byte("page"), Scanned: 10}}
+	mockService.EXPECT().Publish(gomock.Any(), expectedRequest, nil).Return(&snapshot.Progress{Topic: "snapshots", TenantID: "tenant-a", Scanned: 20, Published: 20, Done: true}, nil)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"limit":500,"resume":{"topic":"snapshots","filter":{},"tenantId":"tenant-a","pageState":"cGFnZQ==","scanned":10}}`))
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = r.WithContext(tenant.WithID(r.Context(), "tenant-a"))
+
+	mockController.PublishSnapshot(ctx)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"topic":"snapshots","filter":{},"tenantId":"tenant-a","scanned":20,"published":20,"done":true}`, w.Body.String())
+}
+
+func TestController_PublishSnapshot_InternalError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mock_snapshot_service.NewMockService(ctrl)
+	mockController := snapshot.NewController(mockService)
+
+	mockService.EXPECT().Publish(gomock.Any(), gomock.Any(), nil).Return(&snapshot.Progress{Topic: "other", TenantID: "tenant-a", Scanned: 5, Published: 4}, errors.New("test error"))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"topic":"other"}`))
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = r.WithContext(tenant.WithID(r.Context(), "tenant-a"))
+
+	mockController.PublishSnapshot(ctx)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.JSONEq(t, `{"error":"test error","progress":{"topic":"other","filter":{},"tenantId":"tenant-a","scanned":5,"published":4,"done":false}}`, w.Body.String())
+}
+
+func TestController_PublishSnapshot_ProgressMismatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mock_snapshot_service.NewMockService(ctrl)
+	mockController := snapshot.NewController(mockService)
+
+	mockService.EXPECT().Publish(gomock.Any(), gomock.Any(), nil).Return(nil, snapshot.ErrProgressMismatch)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"topic":"other","resume":{"topic":"snapshots","scanned":10}}`))
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = r.WithContext(tenant.WithID(r.Context(), "tenant-a"))
+
+	mockController.PublishSnapshot(ctx)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"`+snapshot.ErrProgressMismatch.Error()+`"}`, w.Body.String())
+}
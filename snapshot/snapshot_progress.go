@@ -0,0 +1,49 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+// LoadProgress reads the progress of a previous run from path.
+// A missing file is not an error, it returns nil progress so that the run starts from the beginning.
+func LoadProgress(path string) (*Progress, error) {
+	body, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var progress Progress
+	if err = json.Unmarshal(body, &progress); err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+// FileCheckpoint returns a Checkpoint that persists the progress to path.
+// The file is replaced atomically so that a crash never leaves a truncated checkpoint behind.
+func FileCheckpoint(path string) Checkpoint {
+	return func(progress *Progress) error {
+		body, err := json.Marshal(progress)
+		if err != nil {
+			return err
+		}
+		tmp := path + ".tmp"
+		if err = os.WriteFile(tmp, body, 0o600); err != nil {
+			return err
+		}
+		return os.Rename(tmp, path)
+	}
+}
+
+// RemoveProgress removes the progress file of a finished run so that the next run with it starts from the beginning.
+// A missing file is not an error.
+func RemoveProgress(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
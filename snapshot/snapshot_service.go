@@ -0,0 +1,218 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/company"
+	"github.com/ngereci/xm_interview/event"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/model"
+	"golang.org/x/time/rate"
+)
+
+const defaultPageSize = 100
+
+// Filter restricts a snapshot to a subset of companies. Empty fields match everything.
+type Filter struct {
+	IDs   []uuid.UUID         `json:"ids,omitempty"`
+	Types []model.CompanyType `json:"types,omitempty"`
+	// TenantID restricts the snapshot to a single tenant, it is required to filter by id.
+	// It is not read from requests, the snapshot endpoint sets it to the tenant of the caller.
+	TenantID string `json:"-"`
+}
+
+var errIDsWithoutTenant = errors.New("a snapshot of companies by id requires a tenant")
+
+// ErrProgressMismatch is returned for a resume of a run to another topic or with another filter, which would
+// skip the companies the earlier run did not publish to that topic.
+var ErrProgressMismatch = errors.New("the progress to resume is of a snapshot with another topic or filter")
+
+// Request describes a single snapshot run.
+type Request struct {
+	// Topic the snapshot events are published to.
+	Topic  string `json:"topic"`
+	Filter Filter `json:"filter"`
+	// Rate is the maximum number of events published per second, 0 means unlimited.
+	Rate     float64 `json:"rate"`
+	PageSize int     `json:"pageSize"`
+	// Limit stops the run at the end of the page on which Limit companies have been scanned, 0 means no limit.
+	Limit int `json:"limit"`
+	// Resume continues a previous run from its last checkpoint.
+	Resume *Progress `json:"resume,omitempty"`
+}
+
+// Progress of a snapshot run. A run is resumed at page granularity, so events of a page
+// that was interrupted are published again (at-least-once). Runs by id are resumed at the next id.
+// Topic, Filter and TenantID are those of the run, which only resumes with the same ones.
+type Progress struct {
+	Topic     string `json:"topic"`
+	Filter    Filter `json:"filter"`
+	TenantID  string `json:"tenantId,omitempty"`
+	PageState []byte `json:"pageState,omitempty"`
+	Scanned   int    `json:"scanned"`
+	Published int    `json:"published"`
+	Done      bool   `json:"done"`
+}
+
+// Checkpoint is called after every fully published page, and after every company of a run by id.
+type Checkpoint func(progress *Progress) error
+
+type Service interface {
+	Publish(ctx context.Context, request *Request, checkpoint Checkpoint) (*Progress, error)
+}
+
+type snapshotService struct {
+	repo          company.Repository
+	kafkaProducer event.KafkaAdapter
+}
+
+func NewService(repo company.Repository, kafkaProducer event.KafkaAdapter) Service {
+	return &snapshotService{repo: repo, kafkaProducer: kafkaProducer}
+}
+
+func (s *snapshotService) Publish(ctx context.Context, request *Request, checkpoint Checkpoint) (*Progress, error) {
+	progress := &Progress{Topic: request.Topic, Filter: request.Filter, TenantID: request.Filter.TenantID}
+	if request.Resume != nil {
+		if !request.Resume.matches(request) {
+			return request.Resume, ErrProgressMismatch
+		}
+		*progress = *request.Resume
+	}
+	if progress.Done {
+		return progress, nil
+	}
+	pageSize := request.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	limit := rate.Inf
+	if request.Rate > 0 {
+		limit = rate.Limit(request.Rate)
+	}
+	limiter := rate.NewLimiter(limit, 1)
+
+	if len(request.Filter.IDs) > 0 {
+		return s.publishByID(ctx, request, limiter, progress, checkpoint)
+	}
+
+	scannedBefore := progress.Scanned
+	for {
+		companies, nextPageState, err := s.repo.List(ctx, progress.PageState, pageSize)
+		if err != nil {
+			return progress, err
+		}
+		published := 0
+		for _, c := range companies {
+			if !request.Filter.matches(c) {
+				continue
+			}
+			if err = s.publish(ctx, request.Topic, limiter, c); err != nil {
+				return progress, err
+			}
+			published++
+		}
+		progress.Scanned += len(companies)
+		progress.Published += published
+		progress.PageState = nextPageState
+		progress.Done = len(nextPageState) == 0
+		if checkpoint != nil {
+			if err = checkpoint(progress); err != nil {
+				logging.FromContext(ctx).Errorf("snapshot checkpoint error:%v", err)
+				return progress, err
+			}
+		}
+		if progress.Done || (request.Limit > 0 && progress.Scanned-scannedBefore >= request.Limit) {
+			logging.FromContext(ctx).Infof("snapshot to topic:%v scanned:%v published:%v done:%v", request.Topic, progress.Scanned, progress.Published, progress.Done)
+			return progress, nil
+		}
+	}
+}
+
+// publishByID publishes the requested companies directly instead of scanning the table.
+func (s *snapshotService) publishByID(ctx context.Context, request *Request, limiter *rate.Limiter, progress *Progress, checkpoint Checkpoint) (*Progress, error) {
+	if request.Filter.TenantID == "" {
+		return progress, errIDsWithoutTenant
+	}
+	ids := request.Filter.IDs
+	if progress.Scanned < len(ids) {
+		ids = ids[progress.Scanned:]
+	} else {
+		ids = nil
+	}
+	for _, id := range ids {
+		c, err := s.repo.GetByID(ctx, request.Filter.TenantID, id)
+		if err != nil {
+			return progress, err
+		}
+		if c != nil && request.Filter.matches(c) {
+			if err = s.publish(ctx, request.Topic, limiter, c); err != nil {
+				return progress, err
+			}
+			progress.Published++
+		}
+		progress.Scanned++
+		progress.Done = progress.Scanned >= len(request.Filter.IDs)
+		if checkpoint != nil {
+			if err = checkpoint(progress); err != nil {
+				logging.FromContext(ctx).Errorf("snapshot checkpoint error:%v", err)
+				return progress, err
+			}
+		}
+	}
+	progress.Done = true
+	return progress, nil
+}
+
+func (s *snapshotService) publish(ctx context.Context, topic string, limiter *rate.Limiter, c *model.Company) error {
+	if err := limiter.Wait(ctx); err != nil {
+		return err
+	}
+	snapshotEvent, err := event.NewEvent(event.EVENT_SNAPSHOT, c)
+	if err != nil {
+		return err
+	}
+	if err = s.kafkaProducer.SendEventToTopic(ctx, topic, snapshotEvent); err != nil {
+		logging.FromContext(ctx).Errorf("company:%v snapshot send to topic:%v failed. error:%v", c.ID, topic, err)
+		return err
+	}
+	return nil
+}
+
+// matches returns whether the progress is of a run of the topic and filter of request
+func (p *Progress) matches(request *Request) bool {
+	return p.Topic == request.Topic && p.TenantID == request.Filter.TenantID && p.Filter.equal(&request.Filter)
+}
+
+// equal compares the ids and types of the filters in their order, TenantID is not part of the JSON of a filter
+func (f *Filter) equal(other *Filter) bool {
+	if len(f.IDs) != len(other.IDs) || len(f.Types) != len(other.Types) {
+		return false
+	}
+	for i := range f.IDs {
+		if f.IDs[i] != other.IDs[i] {
+			return false
+		}
+	}
+	for i := range f.Types {
+		if f.Types[i] != other.Types[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *Filter) matches(c *model.Company) bool {
+	if f.TenantID != "" && f.TenantID != c.TenantID {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range f.Types {
+		if t == c.Type {
+			return true
+		}
+	}
+	return false
+}
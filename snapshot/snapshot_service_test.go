@@ -0,0 +1,231 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/event"
+	mock_kafka "github.com/ngereci/xm_interview/mocks/mock_company/event"
+	mock_company_repository "github.com/ngereci/xm_interview/mocks/mock_company/repository"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var (
+	corporation = &model.Company{ID: uuid.New(), TenantID: "tenant-a", Name: "Corporation", Employees: 10, Type: model.Corporation}
+	nonProfit   = &model.Company{ID: uuid.New(), TenantID: "tenant-b", Name: "NonProfit", Employees: 5, Type: model.NonProfit}
+	cooperative = &model.Company{ID: uuid.New(), TenantID: "tenant-a", Name: "Cooperative", Employees: 7, Type: model.Cooperative}
+	testErr     = errors.New("test error")
+)
+
+func expectSnapshot(mockKafka *mock_kafka.MockKafkaAdapter, topic string, company *model.Company) *gomock.Call {
+	return mockKafka.EXPECT().SendEventToTopic(gomock.Any(), topic, gomock.Any()).DoAndReturn(func(_ context.Context, _ string, e *event.Event) error {
+		expected, _ := event.NewEvent(event.EVENT_SNAPSHOT, company)
+		if e.EventType != event.EVENT_SNAPSHOT || string(e.Payload) != string(expected.Payload) {
+			return errors.New("unexpected snapshot event " + e.String())
+		}
+		return nil
+	})
+}
+
+func TestSnapshotService_Publish(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_company_repository.NewMockRepository(ctrl)
+	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
+
+	mockRepo.EXPECT().List(gomock.Any(), nil, 2).Return([]*model.Company{corporation, nonProfit}, []byte("page2"), nil)
+	mockRepo.EXPECT().List(gomock.Any(), []byte("page2"), 2).Return([]*model.Company{cooperative}, nil, nil)
+	gomock.InOrder(
+		expectSnapshot(mockKafka, "snapshots", corporation),
+		expectSnapshot(mockKafka, "snapshots", nonProfit),
+		expectSnapshot(mockKafka, "snapshots", cooperative),
+	)
+
+	var checkpoints []Progress
+	svc := NewService(mockRepo, mockKafka)
+	progress, err := svc.Publish(context.Background(), &Request{Topic: "snapshots", PageSize: 2}, func(progress *Progress) error {
+		checkpoints = append(checkpoints, *progress)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &Progress{Topic: "snapshots", Scanned: 3, Published: 3, Done: true}, progress)
+	assert.Equal(t, []Progress{
+		{Topic: "snapshots", PageState: []byte("page2"), Scanned: 2, Published: 2},
+		{Topic: "snapshots", Scanned: 3, Published: 3, Done: true},
+	}, checkpoints)
+}
+
+func TestSnapshotService_Publish_FilterByType(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_company_repository.NewMockRepository(ctrl)
+	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
+
+	mockRepo.EXPECT().List(gomock.Any(), nil, defaultPageSize).Return([]*model.Company{corporation, nonProfit, cooperative}, nil, nil)
+	expectSnapshot(mockKafka, "snapshots", nonProfit)
+
+	svc := NewService(mockRepo, mockKafka)
+	filter := Filter{Types: []model.CompanyType{model.NonProfit}}
+	progress, err := svc.Publish(context.Background(), &Request{Topic: "snapshots", Filter: filter}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &Progress{Topic: "snapshots", Filter: filter, Scanned: 3, Published: 1, Done: true}, progress)
+}
+
+func TestSnapshotService_Publish_FilterByID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_company_repository.NewMockRepository(ctrl)
+	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
+
+	missing := uuid.New()
+	mockRepo.EXPECT().GetByID(gomock.Any(), "tenant-a", cooperative.ID).Return(cooperative, nil)
+	mockRepo.EXPECT().GetByID(gomock.Any(), "tenant-a", missing).Return(nil, nil)
+	expectSnapshot(mockKafka, "snapshots", cooperative)
+
+	var checkpoints []Progress
+	svc := NewService(mockRepo, mockKafka)
+	filter := Filter{IDs: []uuid.UUID{cooperative.ID, missing}, TenantID: "tenant-a"}
+	progress, err := svc.Publish(context.Background(), &Request{Topic: "snapshots", Filter: filter}, func(progress *Progress) error {
+		checkpoints = append(checkpoints, *progress)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &Progress{Topic: "snapshots", Filter: filter, TenantID: "tenant-a", Scanned: 2, Published: 1, Done: true}, progress)
+	assert.Equal(t, []Progress{
+		{Topic: "snapshots", Filter: filter, TenantID: "tenant-a", Scanned: 1, Published: 1},
+		{Topic: "snapshots", Filter: filter, TenantID: "tenant-a", Scanned: 2, Published: 1, Done: true},
+	}, checkpoints)
+}
+
+func TestSnapshotService_Publish_ResumeByID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_company_repository.NewMockRepository(ctrl)
+	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), "tenant-a", cooperative.ID).Return(cooperative, nil)
+	expectSnapshot(mockKafka, "snapshots", cooperative)
+
+	svc := NewService(mockRepo, mockKafka)
+	filter := Filter{IDs: []uuid.UUID{corporation.ID, cooperative.ID}, TenantID: "tenant-a"}
+	resume := &Progress{Topic: "snapshots", Filter: filter, TenantID: "tenant-a", Scanned: 1, Published: 1}
+	progress, err := svc.Publish(context.Background(), &Request{Topic: "snapshots", Filter: filter, Resume: resume}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &Progress{Topic: "snapshots", Filter: filter, TenantID: "tenant-a", Scanned: 2, Published: 2, Done: true}, progress)
+}
+
+func TestSnapshotService_Publish_FilterByIDWithoutTenant(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc := NewService(mock_company_repository.NewMockRepository(ctrl), mock_kafka.NewMockKafkaAdapter(ctrl))
+	_, err := svc.Publish(context.Background(), &Request{Topic: "snapshots", Filter: Filter{IDs: []uuid.UUID{cooperative.ID}}}, nil)
+
+	assert.Equal(t, errIDsWithoutTenant, err)
+}
+
+func TestSnapshotService_Publish_FilterByTenant(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_company_repository.NewMockRepository(ctrl)
+	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
+
+	mockRepo.EXPECT().List(gomock.Any(), nil, defaultPageSize).Return([]*model.Company{corporation, nonProfit, cooperative}, nil, nil)
+	gomock.InOrder(
+		expectSnapshot(mockKafka, "snapshots", corporation),
+		expectSnapshot(mockKafka, "snapshots", cooperative),
+	)
+
+	svc := NewService(mockRepo, mockKafka)
+	progress, err := svc.Publish(context.Background(), &Request{Topic: "snapshots", Filter: Filter{TenantID: "tenant-a"}}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &Progress{Topic: "snapshots", Filter: Filter{TenantID: "tenant-a"}, TenantID: "tenant-a", Scanned: 3, Published: 2, Done: true}, progress)
+}
+
+func TestSnapshotService_Publish_Limit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_company_repository.NewMockRepository(ctrl)
+	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
+
+	mockRepo.EXPECT().List(gomock.Any(), nil, 2).Return([]*model.Company{corporation, nonProfit}, []byte("page2"), nil)
+	expectSnapshot(mockKafka, "snapshots", corporation)
+	expectSnapshot(mockKafka, "snapshots", nonProfit)
+
+	svc := NewService(mockRepo, mockKafka)
+	progress, err := svc.Publish(context.Background(), &Request{Topic: "snapshots", PageSize: 2, Limit: 1}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &Progress{Topic: "snapshots", PageState: []byte("page2"), Scanned: 2, Published: 2}, progress)
+}
+
+func TestSnapshotService_Publish_Resume(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_company_repository.NewMockRepository(ctrl)
+	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
+
+	mockRepo.EXPECT().List(gomock.Any(), []byte("page2"), 2).Return([]*model.Company{cooperative}, nil, nil)
+	expectSnapshot(mockKafka, "snapshots", cooperative)
+
+	svc := NewService(mockRepo, mockKafka)
+	resume := &Progress{Topic: "snapshots", PageState: []byte("page2"), Scanned: 2, Published: 2}
+	progress, err := svc.Publish(context.Background(), &Request{Topic: "snapshots", PageSize: 2, Resume: resume}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &Progress{Topic: "snapshots", Scanned: 3, Published: 3, Done: true}, progress)
+}
+
+func TestSnapshotService_Publish_ResumeMismatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc := NewService(mock_company_repository.NewMockRepository(ctrl), mock_kafka.NewMockKafkaAdapter(ctrl))
+	resume := &Progress{Topic: "snapshots", PageState: []byte("page2"), Scanned: 2, Published: 2}
+	for name, request := range map[string]*Request{
+		"topic":  {Topic: "other", Resume: resume},
+		"types":  {Topic: "snapshots", Filter: Filter{Types: []model.CompanyType{model.NonProfit}}, Resume: resume},
+		"tenant": {Topic: "snapshots", Filter: Filter{TenantID: "tenant-a"}, Resume: resume},
+	} {
+		t.Run(name, func(t *testing.T) {
+			progress, err := svc.Publish(context.Background(), request, nil)
+
+			assert.Equal(t, ErrProgressMismatch, err)
+			assert.Equal(t, resume, progress)
+		})
+	}
+}
+
+func TestSnapshotService_Publish_KafkaFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_company_repository.NewMockRepository(ctrl)
+	mockKafka := mock_kafka.NewMockKafkaAdapter(ctrl)
+
+	mockRepo.EXPECT().List(gomock.Any(), []byte("page2"), 2).Return([]*model.Company{cooperative}, nil, nil)
+	mockKafka.EXPECT().SendEventToTopic(gomock.Any(), "snapshots", gomock.Any()).Return(testErr)
+
+	svc := NewService(mockRepo, mockKafka)
+	resume := &Progress{Topic: "snapshots", PageState: []byte("page2"), Scanned: 2, Published: 2}
+	progress, err := svc.Publish(context.Background(), &Request{Topic: "snapshots", PageSize: 2, Resume: resume}, nil)
+
+	assert.Equal(t, testErr, err)
+	// the interrupted page is not checkpointed, so a resumed run starts at the same page
+	assert.Equal(t, resume, progress)
+}
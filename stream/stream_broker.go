@@ -0,0 +1,238 @@
+// Package stream pushes the changes of companies to the clients following them, see Broker.
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/event"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/model"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventReset tells a client that changes after its Last-Event-ID are no longer buffered, it has to reload the companies it shows
+const EventReset event.EventType = "Reset"
+
+// subscriberBufferSize is how many changes a subscriber can fall behind before it is dropped
+const subscriberBufferSize = 64
+
+// ErrClosed is returned by Subscribe once the broker is closed
+var ErrClosed = errors.New("stream closed")
+
+// Change is a created, updated or deleted company as it is sent to clients. ID orders the changes of a broker.
+type Change struct {
+	ID        string          `json:"id,omitempty"`
+	Type      event.EventType `json:"type"`
+	Company   *model.Company  `json:"company,omitempty"`
+	Timestamp time.Time       `json:"timestamp"`
+	seq       uint64
+}
+
+// Filter selects the changes of a subscription, an empty field matches every company
+type Filter struct {
+	IDs   []uuid.UUID
+	Types []model.CompanyType
+}
+
+func (f Filter) matches(company *model.Company) bool {
+	if len(f.IDs) > 0 && !containsID(f.IDs, company.ID) {
+		return false
+	}
+	if len(f.Types) > 0 && !containsType(f.Types, company.Type) {
+		return false
+	}
+	return true
+}
+
+func containsID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+func containsType(types []model.CompanyType, companyType model.CompanyType) bool {
+	for _, candidate := range types {
+		if candidate == companyType {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription receives the changes of the companies of a tenant matching a filter
+type Subscription struct {
+	// Changes is closed when the subscription or the broker is closed, or when the subscriber fell too far behind.
+	// The client can then resume from the last change it received.
+	Changes <-chan Change
+	// Missed is set if the changes after the Last-Event-ID the subscription resumes from are no longer buffered
+	Missed bool
+	close  func()
+}
+
+// Close stops the subscription
+func (s *Subscription) Close() {
+	s.close()
+}
+
+// Broker fans the company events of this instance out to the subscriptions of the tenant of the company.
+// It keeps the last changes, so that clients reconnecting with the id of the last change they received miss nothing.
+type Broker interface {
+	event.Listener
+	// Subscribe follows the changes of the companies of tenantID matching filter. Unless lastEventID is empty the
+	// buffered changes after it are sent first.
+	Subscribe(tenantID string, filter Filter, lastEventID string) (*Subscription, error)
+	// Close closes all subscriptions, it is called on shutdown so that streaming requests complete.
+	Close()
+}
+
+type subscriber struct {
+	tenantID string
+	filter   Filter
+	changes  chan Change
+}
+
+type broker struct {
+	mu sync.Mutex
+	// epoch tells the ids of this broker from those of earlier runs, which can not be resumed from
+	epoch       string
+	seq         uint64
+	capacity    int
+	buffer      []Change
+	subscribers map[*subscriber]struct{}
+	closed      bool
+}
+
+// NewBroker creates a Broker buffering the last capacity changes for resumption
+func NewBroker(capacity int) Broker {
+	return &broker{
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		capacity:    capacity,
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// OnEvent publishes the company of create, update and delete events, other events are ignored
+func (b *broker) OnEvent(ctx context.Context, companyEvent *event.Event) {
+	switch companyEvent.EventType {
+	case event.EVENT_CREATE, event.EVENT_UPDATE, event.EVENT_DELETE:
+	default:
+		return
+	}
+	var company model.Company
+	if err := json.Unmarshal(companyEvent.Payload, &company); err != nil {
+		logging.FromContext(ctx).Warnf("event:%v not streamed, payload is not a company. error:%v", companyEvent.ID, err)
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.seq++
+	change := Change{
+		ID:        b.epoch + "-" + strconv.FormatUint(b.seq, 10),
+		Type:      companyEvent.EventType,
+		Company:   &company,
+		Timestamp: companyEvent.Timestamp,
+		seq:       b.seq,
+	}
+	if b.capacity > 0 {
+		if len(b.buffer) >= b.capacity {
+			b.buffer = b.buffer[1:]
+		}
+		b.buffer = append(b.buffer, change)
+	}
+	for s := range b.subscribers {
+		if s.tenantID != company.TenantID || !s.filter.matches(&company) {
+			continue
+		}
+		select {
+		case s.changes <- change:
+		default:
+			logging.FromContext(ctx).Warnf("stream subscriber of tenant:%v fell behind, dropping it", s.tenantID)
+			b.remove(s)
+		}
+	}
+}
+
+func (b *broker) Subscribe(tenantID string, filter Filter, lastEventID string) (*Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrClosed
+	}
+	var (
+		replay []Change
+		missed bool
+	)
+	if lastEventID != "" {
+		replay, missed = b.after(lastEventID)
+	}
+	s := &subscriber{tenantID: tenantID, filter: filter, changes: make(chan Change, subscriberBufferSize+len(replay))}
+	for _, change := range replay {
+		if change.Company.TenantID == tenantID && filter.matches(change.Company) {
+			s.changes <- change
+		}
+	}
+	b.subscribers[s] = struct{}{}
+	return &Subscription{
+		Changes: s.changes,
+		Missed:  missed,
+		close: func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			b.remove(s)
+		},
+	}, nil
+}
+
+// after returns the buffered changes after the change lastEventID, or whether some of them are no longer buffered
+func (b *broker) after(lastEventID string) ([]Change, bool) {
+	seq, err := b.parseID(lastEventID)
+	if err != nil || seq > b.seq {
+		return nil, true
+	}
+	if seq == b.seq {
+		return nil, false
+	}
+	if len(b.buffer) == 0 || b.buffer[0].seq > seq+1 {
+		return nil, true
+	}
+	return append([]Change(nil), b.buffer[seq+1-b.buffer[0].seq:]...), false
+}
+
+// parseID returns the sequence number of the id of a change of this broker
+func (b *broker) parseID(id string) (uint64, error) {
+	epoch, seq, ok := strings.Cut(id, "-")
+	if !ok || epoch != b.epoch {
+		return 0, fmt.Errorf("event id %v is not one of this stream", id)
+	}
+	return strconv.ParseUint(seq, 10, 64)
+}
+
+// remove closes the channel of s unless it was removed already, b.mu is held
+func (b *broker) remove(s *subscriber) {
+	if _, ok := b.subscribers[s]; ok {
+		delete(b.subscribers, s)
+		close(s.changes)
+	}
+}
+
+func (b *broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for s := range b.subscribers {
+		b.remove(s)
+	}
+}
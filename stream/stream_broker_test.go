@@ -0,0 +1,146 @@
+package stream
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/ngereci/xm_interview/event"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func publish(t *testing.T, b Broker, eventType event.EventType, company *model.Company) {
+	companyEvent, err := event.NewEvent(eventType, company)
+	require.NoError(t, err)
+	b.OnEvent(context.Background(), companyEvent)
+}
+
+// received returns the changes waiting in subscription
+func received(subscription *Subscription) []Change {
+	var changes []Change
+	for {
+		select {
+		case change, ok := <-subscription.Changes:
+			if !ok {
+				return changes
+			}
+			changes = append(changes, change)
+		default:
+			return changes
+		}
+	}
+}
+
+func newCompany(tenantID string, companyType model.CompanyType) *model.Company {
+	return &model.Company{ID: uuid.New(), TenantID: tenantID, Name: "Acme", Employees: 1, Type: companyType}
+}
+
+func TestBroker_Subscribe(t *testing.T) {
+	b := NewBroker(10)
+	defer b.Close()
+	acme := newCompany("tenant-a", model.Corporation)
+	all, err := b.Subscribe("tenant-a", Filter{}, "")
+	require.NoError(t, err)
+	byID, err := b.Subscribe("tenant-a", Filter{IDs: []uuid.UUID{acme.ID}}, "")
+	require.NoError(t, err)
+	byType, err := b.Subscribe("tenant-a", Filter{Types: []model.CompanyType{model.NonProfit}}, "")
+	require.NoError(t, err)
+	otherTenant, err := b.Subscribe("tenant-b", Filter{}, "")
+	require.NoError(t, err)
+
+	publish(t, b, event.EVENT_CREATE, acme)
+	publish(t, b, event.EVENT_UPDATE, newCompany("tenant-a", model.NonProfit))
+	publish(t, b, event.EVENT_SNAPSHOT, acme)
+
+	assert.Len(t, received(all), 2)
+	changes := received(byID)
+	require.Len(t, changes, 1)
+	assert.Equal(t, event.EVENT_CREATE, changes[0].Type)
+	assert.Equal(t, acme, changes[0].Company)
+	changes = received(byType)
+	require.Len(t, changes, 1)
+	assert.Equal(t, event.EVENT_UPDATE, changes[0].Type)
+	assert.Empty(t, received(otherTenant))
+}
+
+func TestBroker_Resume(t *testing.T) {
+	b := NewBroker(2)
+	defer b.Close()
+	subscription, err := b.Subscribe("tenant-a", Filter{}, "")
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		publish(t, b, event.EVENT_CREATE, newCompany("tenant-a", model.Corporation))
+	}
+	changes := received(subscription)
+	require.Len(t, changes, 3)
+	subscription.Close()
+
+	resumed, err := b.Subscribe("tenant-a", Filter{}, changes[1].ID)
+	require.NoError(t, err)
+	assert.False(t, resumed.Missed)
+	assert.Equal(t, changes[2:], received(resumed))
+
+	upToDate, err := b.Subscribe("tenant-a", Filter{}, changes[2].ID)
+	require.NoError(t, err)
+	assert.False(t, upToDate.Missed)
+	assert.Empty(t, received(upToDate))
+
+	// the first change itself is no longer buffered, but all the changes after it are
+	afterFirst, err := b.Subscribe("tenant-a", Filter{}, changes[0].ID)
+	require.NoError(t, err)
+	assert.False(t, afterFirst.Missed)
+	assert.Equal(t, changes[1:], received(afterFirst))
+
+	for _, lastEventID := range []string{"unknown", "other-1", "not-a-number"} {
+		unknown, err := b.Subscribe("tenant-a", Filter{}, lastEventID)
+		require.NoError(t, err)
+		assert.True(t, unknown.Missed, lastEventID)
+		assert.Empty(t, received(unknown))
+	}
+}
+
+func TestBroker_Resume_Missed(t *testing.T) {
+	b := NewBroker(1)
+	defer b.Close()
+	subscription, err := b.Subscribe("tenant-a", Filter{}, "")
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		publish(t, b, event.EVENT_CREATE, newCompany("tenant-a", model.Corporation))
+	}
+	changes := received(subscription)
+
+	resumed, err := b.Subscribe("tenant-a", Filter{}, changes[0].ID)
+	require.NoError(t, err)
+	assert.True(t, resumed.Missed)
+}
+
+func TestBroker_DropsSlowSubscribers(t *testing.T) {
+	b := NewBroker(0)
+	defer b.Close()
+	subscription, err := b.Subscribe("tenant-a", Filter{}, "")
+	require.NoError(t, err)
+
+	for i := 0; i <= subscriberBufferSize; i++ {
+		publish(t, b, event.EVENT_CREATE, newCompany("tenant-a", model.Corporation))
+	}
+
+	assert.Len(t, received(subscription), subscriberBufferSize)
+	_, ok := <-subscription.Changes
+	assert.False(t, ok)
+	subscription.Close()
+}
+
+func TestBroker_Close(t *testing.T) {
+	b := NewBroker(10)
+	subscription, err := b.Subscribe("tenant-a", Filter{}, "")
+	require.NoError(t, err)
+
+	b.Close()
+
+	_, ok := <-subscription.Changes
+	assert.False(t, ok)
+	subscription.Close()
+	_, err = b.Subscribe("tenant-a", Filter{}, "")
+	assert.Equal(t, ErrClosed, err)
+}
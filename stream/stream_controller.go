@@ -0,0 +1,200 @@
+package stream
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/ngereci/xm_interview/logging"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/ngereci/xm_interview/tenant"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// LastEventIDHeader is sent by EventSource clients when they reconnect, other clients can use the last_event_id parameter
+	LastEventIDHeader = "Last-Event-ID"
+	// webSocketWriteWait bounds the write of a single WebSocket message
+	webSocketWriteWait = 10 * time.Second
+)
+
+type Controller interface {
+	// Events streams the changes as Server-Sent Events
+	Events(ctx *gin.Context)
+	// WebSocket streams the changes as JSON messages over a WebSocket
+	WebSocket(ctx *gin.Context)
+}
+
+type controller struct {
+	broker    Broker
+	heartbeat time.Duration
+	upgrader  websocket.Upgrader
+}
+
+// NewController streams the changes of broker, idle streams are kept alive with a heartbeat every heartbeat.
+// WebSockets are only accepted from the origin of the API, like browsers enforce for other requests.
+func NewController(broker Broker, heartbeat time.Duration) Controller {
+	return &controller{broker: broker, heartbeat: heartbeat}
+}
+
+// subscribe subscribes to the changes the query of the request selects, or answers the request with the error
+func (c *controller) subscribe(ctx *gin.Context) (*Subscription, bool) {
+	var filter Filter
+	for _, id := range ctx.QueryArray("id") {
+		companyUuid, err := uuid.Parse(id)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid id %v", id)})
+			return nil, false
+		}
+		filter.IDs = append(filter.IDs, companyUuid)
+	}
+	for _, companyType := range ctx.QueryArray("type") {
+		filter.Types = append(filter.Types, model.CompanyType(companyType))
+	}
+	lastEventID := ctx.GetHeader(LastEventIDHeader)
+	if lastEventID == "" {
+		lastEventID = ctx.Query("last_event_id")
+	}
+	tenantID, err := tenant.FromContext(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	subscription, err := c.broker.Subscribe(tenantID, filter, lastEventID)
+	if err != nil {
+		if errors.Is(err, ErrClosed) {
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return subscription, true
+}
+
+func (c *controller) Events(ctx *gin.Context) {
+	subscription, ok := c.subscribe(ctx)
+	if !ok {
+		return
+	}
+	defer subscription.Close()
+	// the stream outlives the write timeout of the server, clients notice dead streams by the missing heartbeat
+	_ = http.NewResponseController(ctx.Writer).SetWriteDeadline(time.Time{})
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	if subscription.Missed {
+		_ = writeEvent(ctx.Writer, resetChange())
+	}
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(c.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		var err error
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case change, ok := <-subscription.Changes:
+			if !ok {
+				return
+			}
+			err = writeEvent(ctx.Writer, change)
+		case <-heartbeat.C:
+			_, err = io.WriteString(ctx.Writer, ": heartbeat\n\n")
+		}
+		if err != nil {
+			logging.FromContext(ctx.Request.Context()).Debugf("event stream closed. error:%v", err)
+			return
+		}
+		ctx.Writer.Flush()
+	}
+}
+
+// writeEvent writes change as a Server-Sent Event, whose id EventSource clients send back in the Last-Event-ID header.
+// Resets are reset events without an id, so that clients can listen to them separately and keep their last id.
+func writeEvent(w io.Writer, change Change) error {
+	data, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	if change.Type == EventReset {
+		_, err = fmt.Fprintf(w, "event: reset\ndata: %s\n\n", data)
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %v\ndata: %s\n\n", change.ID, data)
+	return err
+}
+
+func resetChange() Change {
+	return Change{Type: EventReset, Timestamp: time.Now().UTC()}
+}
+
+func (c *controller) WebSocket(ctx *gin.Context) {
+	subscription, ok := c.subscribe(ctx)
+	if !ok {
+		return
+	}
+	defer subscription.Close()
+	conn, err := c.upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		// the upgrader answered the request already
+		logging.FromContext(ctx.Request.Context()).Warnf("WebSocket upgrade failed. error:%v", err)
+		return
+	}
+	defer conn.Close()
+
+	// clients only send control messages, reading processes them and notices when the client is gone
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		conn.SetReadLimit(512)
+		_ = conn.SetReadDeadline(time.Now().Add(2 * c.heartbeat))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(2 * c.heartbeat))
+		})
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	if subscription.Missed {
+		if err = writeMessage(conn, resetChange()); err != nil {
+			return
+		}
+	}
+	heartbeat := time.NewTicker(c.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case change, ok := <-subscription.Changes:
+			if !ok {
+				_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(webSocketWriteWait))
+				return
+			}
+			err = writeMessage(conn, change)
+		case <-heartbeat.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(webSocketWriteWait))
+		}
+		if err != nil {
+			logging.FromContext(ctx.Request.Context()).Debugf("WebSocket stream closed. error:%v", err)
+			return
+		}
+	}
+}
+
+func writeMessage(conn *websocket.Conn, change Change) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(webSocketWriteWait)); err != nil {
+		return err
+	}
+	return conn.WriteJSON(change)
+}
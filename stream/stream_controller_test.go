@@ -0,0 +1,138 @@
+package stream
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/ngereci/xm_interview/event"
+	"github.com/ngereci/xm_interview/model"
+	"github.com/ngereci/xm_interview/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestServer serves the streams of b for the tenant tenant-a
+func newTestServer(t *testing.T, b Broker) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	controller := NewController(b, time.Minute)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(tenant.WithID(c.Request.Context(), "tenant-a"))
+	})
+	router.GET("/stream", controller.Events)
+	router.GET("/stream/ws", controller.WebSocket)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// readEvent reads the next event of an event stream, skipping comments
+func readEvent(t *testing.T, scanner *bufio.Scanner) map[string]string {
+	fields := map[string]string{}
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" && len(fields) > 0 {
+			return fields
+		}
+		if name, value, ok := strings.Cut(line, ": "); ok && name != "" {
+			fields[name] = value
+		}
+	}
+	require.NoError(t, scanner.Err())
+	return fields
+}
+
+func TestController_Events(t *testing.T) {
+	b := NewBroker(10)
+	defer b.Close()
+	server := newTestServer(t, b)
+	acme := newCompany("tenant-a", model.Corporation)
+
+	req, err := http.NewRequest("GET", server.URL+"/stream?type=Corporation", nil)
+	require.NoError(t, err)
+	req.Header.Set(LastEventIDHeader, "unknown")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	scanner := bufio.NewScanner(resp.Body)
+
+	reset := readEvent(t, scanner)
+	assert.Equal(t, "reset", reset["event"])
+
+	publish(t, b, event.EVENT_UPDATE, newCompany("tenant-a", model.NonProfit))
+	publish(t, b, event.EVENT_DELETE, acme)
+	deleted := readEvent(t, scanner)
+	var change Change
+	require.NoError(t, json.Unmarshal([]byte(deleted["data"]), &change))
+	assert.Equal(t, deleted["id"], change.ID)
+	assert.Equal(t, event.EVENT_DELETE, change.Type)
+	assert.Equal(t, acme, change.Company)
+
+	// the stream completes when the broker is closed on shutdown
+	b.Close()
+	assert.Empty(t, readEvent(t, scanner))
+}
+
+func TestController_Events_InvalidID(t *testing.T) {
+	server := newTestServer(t, NewBroker(10))
+
+	resp, err := http.Get(server.URL + "/stream?id=not-a-uuid")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestController_Events_Closed(t *testing.T) {
+	b := NewBroker(10)
+	b.Close()
+	server := newTestServer(t, b)
+
+	resp, err := http.Get(server.URL + "/stream")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
+
+func TestController_WebSocket(t *testing.T) {
+	b := NewBroker(10)
+	defer b.Close()
+	server := newTestServer(t, b)
+	acme := newCompany("tenant-a", model.Corporation)
+	subscription := mustSubscribe(t, b, Filter{}, "")
+	publish(t, b, event.EVENT_CREATE, acme)
+	publish(t, b, event.EVENT_CREATE, newCompany("tenant-a", model.Corporation))
+	publish(t, b, event.EVENT_DELETE, acme)
+	lastEventID := received(subscription)[0].ID
+
+	// resumes after the creation of acme, of the buffered changes only the deletion of acme passes the filter
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/stream/ws?id="+acme.ID.String()+"&last_event_id="+lastEventID, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	var change Change
+	require.NoError(t, conn.ReadJSON(&change))
+	assert.Equal(t, event.EVENT_DELETE, change.Type)
+	assert.Equal(t, acme.ID, change.Company.ID)
+
+	b.Close()
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+}
+
+func mustSubscribe(t *testing.T, b Broker, filter Filter, lastEventID string) *Subscription {
+	subscription, err := b.Subscribe("tenant-a", filter, lastEventID)
+	require.NoError(t, err)
+	t.Cleanup(subscription.Close)
+	return subscription
+}
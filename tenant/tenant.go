@@ -0,0 +1,41 @@
+// Package tenant carries the tenant a request acts for. Every company belongs to exactly one tenant
+// and is neither visible to nor changeable by the others.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"regexp"
+)
+
+const (
+	// Default is the tenant of the built-in admin user and of the data created before tenants existed
+	Default = "default"
+	// Claim is the JWT claim holding the tenant of a user
+	Claim = "tenant"
+)
+
+// ErrMissing is returned when a tenant scoped operation is called without a tenant in its context
+var ErrMissing = errors.New("no tenant in context")
+
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+type idKey struct{}
+
+// Valid reports whether id can be used as a tenant id, which also keeps it safe to use in storage keys.
+func Valid(id string) bool {
+	return validID.MatchString(id)
+}
+
+// WithID returns a copy of ctx acting for the tenant id.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, idKey{}, id)
+}
+
+// FromContext returns the tenant ctx acts for, or ErrMissing.
+func FromContext(ctx context.Context) (string, error) {
+	if id, ok := ctx.Value(idKey{}).(string); ok && id != "" {
+		return id, nil
+	}
+	return "", ErrMissing
+}
@@ -0,0 +1,51 @@
+package tracing
+
+import (
+	"context"
+	"github.com/gocql/gocql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+)
+
+const cassandraInstrumentation = "github.com/ngereci/xm_interview/tracing/cassandra"
+
+type cassandraObserver struct {
+	tracerProvider trace.TracerProvider
+}
+
+// NewCassandraObserver creates a query observer recording a client span for every query the session executes,
+// including every page of an iterator and every retry, as a child of the span in the context of the query.
+func NewCassandraObserver() gocql.QueryObserver {
+	return &cassandraObserver{tracerProvider: otel.GetTracerProvider()}
+}
+
+func (o *cassandraObserver) ObserveQuery(ctx context.Context, query gocql.ObservedQuery) {
+	operation := queryOperation(query.Statement)
+	_, span := o.tracerProvider.Tracer(cassandraInstrumentation).Start(ctx, "cassandra "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(query.Start),
+		trace.WithAttributes(
+			semconv.DBSystemCassandra,
+			semconv.DBName(query.Keyspace),
+			semconv.DBOperation(operation),
+			semconv.DBStatement(strings.Join(strings.Fields(query.Statement), " ")),
+		),
+	)
+	if query.Attempt > 0 {
+		span.SetAttributes(attribute.Int("db.cassandra.attempt", query.Attempt))
+	}
+	RecordError(span, query.Err)
+	span.End(trace.WithTimestamp(query.End))
+}
+
+// queryOperation returns the CQL verb of the statement, such as SELECT or INSERT
+func queryOperation(statement string) string {
+	fields := strings.Fields(statement)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
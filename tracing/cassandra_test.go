@@ -0,0 +1,43 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"testing"
+	"time"
+)
+
+func TestCassandraObserver(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	observer := &cassandraObserver{tracerProvider: provider}
+	parentCtx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+	start := time.Now().Add(-time.Second)
+	end := start.Add(10 * time.Millisecond)
+
+	observer.ObserveQuery(parentCtx, gocql.ObservedQuery{
+		Keyspace:  "companies",
+		Statement: "\n\t\tselect id\n\t\tFROM company WHERE id = ?",
+		Start:     start,
+		End:       end,
+		Err:       errors.New("timeout"),
+	})
+	parent.End()
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	span := spans[0]
+	assert.Equal(t, "cassandra SELECT", span.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	assert.Equal(t, start, span.StartTime())
+	assert.Equal(t, end, span.EndTime())
+	assert.Contains(t, span.Attributes(), semconv.DBStatement("select id FROM company WHERE id = ?"))
+	assert.Contains(t, span.Attributes(), semconv.DBName("companies"))
+	assert.Equal(t, codes.Error, span.Status().Code)
+}